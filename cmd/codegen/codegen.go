@@ -38,6 +38,13 @@ func Generate(ctx context.Context, cfg generator.Config, dag *dagger.Client) (er
 		}
 	}
 
+	// subscriptions are only served over websockets, which generated clients
+	// don't speak
+	if sub := introspectionSchema.Subscription(); sub != nil {
+		introspectionSchema.ScrubType(sub.Name)
+		introspectionSchema.SubscriptionType = nil
+	}
+
 	for ctx.Err() == nil {
 		generated, err := generate(ctx, introspectionSchema, cfg)
 		if err != nil {
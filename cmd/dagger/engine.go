@@ -40,6 +40,7 @@ func withEngine(
 		Processors: telemetry.LogProcessors,
 	}
 	params.WithTerminal = withTerminal
+	params.Interactive = interactive
 
 	sess, ctx, err := client.Connect(ctx, params)
 	if err != nil {
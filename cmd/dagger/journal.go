@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/spf13/cobra"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	otlplogsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	otlptracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"dagger.io/dagger/telemetry"
+)
+
+// journalPath is the file to record the telemetry of the run to, if set.
+var journalPath string
+
+// journalEntry is a single line of a journal. Each line holds either a batch
+// of spans or a batch of logs, in the OTLP JSON encoding.
+type journalEntry struct {
+	Spans json.RawMessage `json:"spans,omitempty"`
+	Logs  json.RawMessage `json:"logs,omitempty"`
+}
+
+// journal records all spans and logs of a run to a file, so that it can be
+// replayed or inspected later with `dagger replay`.
+type journal struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+func openJournal(path string) (*journal, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create journal: %w", err)
+	}
+	return &journal{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (j *journal) write(entry journalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	return j.enc.Encode(entry)
+}
+
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+func (j *journal) SpanExporter() sdktrace.SpanExporter {
+	return journalSpanExporter{j}
+}
+
+func (j *journal) LogExporter() sdklog.Exporter {
+	return journalLogExporter{j}
+}
+
+type journalSpanExporter struct {
+	*journal
+}
+
+func (j journalSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	payload, err := protojson.Marshal(&otlptracev1.TracesData{
+		ResourceSpans: telemetry.SpansToPB(spans),
+	})
+	if err != nil {
+		return err
+	}
+	return j.write(journalEntry{Spans: payload})
+}
+
+func (j journalSpanExporter) Shutdown(ctx context.Context) error {
+	// closed by the caller once all exporters are shut down
+	return nil
+}
+
+type journalLogExporter struct {
+	*journal
+}
+
+func (j journalLogExporter) Export(ctx context.Context, logs []sdklog.Record) error {
+	payload, err := protojson.Marshal(&otlplogsv1.LogsData{
+		ResourceLogs: telemetry.LogsToPB(logs),
+	})
+	if err != nil {
+		return err
+	}
+	return j.write(journalEntry{Logs: payload})
+}
+
+func (j journalLogExporter) Shutdown(ctx context.Context) error {
+	// closed by the caller once all exporters are shut down
+	return nil
+}
+
+func (j journalLogExporter) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay [options] <journal>",
+	Short: "Replay a run recorded with --journal",
+	Long: `Replay a run recorded with --journal.
+
+The recorded progress and logs are rendered with the selected progress output,
+so --progress=json can be used to inspect them programmatically.`,
+	Example: `dagger --journal=run.jsonl call build
+dagger replay run.jsonl`,
+	Args: cobra.ExactArgs(1),
+	RunE: Replay,
+}
+
+func Replay(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	spanExp := Frontend.SpanExporter()
+	logExp := Frontend.LogExporter()
+
+	recorded := map[trace.SpanID]sdktrace.ReadOnlySpan{}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("%s:%d: %w", args[0], line, err)
+		}
+		if entry.Spans != nil {
+			var data otlptracev1.TracesData
+			if err := protojson.Unmarshal(entry.Spans, &data); err != nil {
+				return fmt.Errorf("%s:%d: %w", args[0], line, err)
+			}
+			spans := telemetry.SpansFromPB(data.GetResourceSpans())
+			for _, span := range spans {
+				recorded[span.SpanContext().SpanID()] = span
+			}
+			if err := spanExp.ExportSpans(ctx, spans); err != nil {
+				return err
+			}
+		}
+		if entry.Logs != nil {
+			var data otlplogsv1.LogsData
+			if err := protojson.Unmarshal(entry.Logs, &data); err != nil {
+				return fmt.Errorf("%s:%d: %w", args[0], line, err)
+			}
+			if err := logExp.Export(ctx, telemetry.LogsFromPB(data.GetResourceLogs())); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// the recorded run's root span becomes the primary span, so its output is
+	// displayed just like it was in the original run
+	var root sdktrace.ReadOnlySpan
+	for _, span := range recorded {
+		if _, ok := recorded[span.Parent().SpanID()]; ok {
+			continue
+		}
+		if root == nil || span.StartTime().Before(root.StartTime()) {
+			root = span
+		}
+	}
+	if root != nil {
+		Frontend.SetPrimary(root.SpanContext().SpanID())
+	}
+	return nil
+}
@@ -49,10 +49,11 @@ var (
 
 	workdir string
 
-	debug     bool
-	verbosity int = idtui.DefaultVerbosity
-	silent    bool
-	progress  string
+	debug       bool
+	verbosity   int = idtui.DefaultVerbosity
+	silent      bool
+	progress    string
+	interactive bool
 
 	stdoutIsTTY = isatty.IsTerminal(os.Stdout.Fd())
 	stderrIsTTY = isatty.IsTerminal(os.Stderr.Fd())
@@ -72,7 +73,9 @@ func init() {
 		listenCmd,
 		versionCmd,
 		queryCmd,
+		replayCmd,
 		runCmd,
+		scheduleCmd,
 		watchCmd,
 		configCmd,
 		moduleInitCmd,
@@ -173,7 +176,9 @@ func installGlobalFlags(flags *pflag.FlagSet) {
 	flags.CountVarP(&verbosity, "verbose", "v", "increase verbosity (use -vv or -vvv for more)")
 	flags.BoolVarP(&debug, "debug", "d", debug, "show debug logs and full verbosity")
 	flags.BoolVarP(&silent, "silent", "s", silent, "disable terminal UI and progress output")
-	flags.StringVar(&progress, "progress", "auto", "progress output format (auto, plain, tty)")
+	flags.StringVar(&progress, "progress", "auto", "progress output format (auto, plain, tty, json)")
+	flags.StringVar(&journalPath, "journal", "", "record progress and logs to a file, to replay with 'dagger replay'")
+	flags.BoolVarP(&interactive, "interactive", "i", false, "open a terminal in the container of a failed exec, to debug it")
 
 	for _, fl := range []string{"workdir"} {
 		if err := flags.MarkHidden(fl); err != nil {
@@ -260,6 +265,8 @@ func main() {
 	switch progress {
 	case "plain":
 		Frontend = idtui.NewPlain()
+	case "json":
+		Frontend = idtui.NewJSON()
 	case "tty":
 		if !hasTTY {
 			fmt.Fprintf(os.Stderr, "no tty available for progress %q\n", progress)
@@ -292,6 +299,16 @@ func main() {
 			telemetryCfg.LiveTraceExporters = append(telemetryCfg.LiveTraceExporters, spans)
 			telemetryCfg.LiveLogExporters = append(telemetryCfg.LiveLogExporters, logs)
 		}
+		if journalPath != "" {
+			j, err := openJournal(journalPath)
+			if err != nil {
+				return err
+			}
+			// closed after telemetry is flushed below
+			defer j.close()
+			telemetryCfg.LiveTraceExporters = append(telemetryCfg.LiveTraceExporters, j.SpanExporter())
+			telemetryCfg.LiveLogExporters = append(telemetryCfg.LiveLogExporters, j.LogExporter())
+		}
 		// Init tracing as early as possible and shutdown after the command
 		// completes, ensuring progress is fully flushed to the frontend.
 		ctx = telemetry.Init(ctx, telemetryCfg)
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -37,6 +39,22 @@ jq -n '{query:"{container{id}}"}' | \
     -H "content-type:application/json" \
     -d @- \
     http://127.0.0.1:$DAGGER_SESSION_PORT/query'
+´´´
+
+With ´--unix-socket´, the session is served on a unix socket in a private
+directory instead of a localhost TCP port, and ´DAGGER_SESSION_SOCKET´ is
+injected instead of ´DAGGER_SESSION_PORT´. This avoids port conflicts and
+keeps other users on the same machine from reaching the session.
+
+For example:
+´´´shell
+jq -n '{query:"{container{id}}"}' | \
+  dagger run --unix-socket sh -c 'curl -s \
+    --unix-socket $DAGGER_SESSION_SOCKET \
+    -u $DAGGER_SESSION_TOKEN: \
+    -H "content-type:application/json" \
+    -d @- \
+    http://dagger/query'
 ´´´`,
 		"´",
 		"`",
@@ -55,6 +73,7 @@ dagger run python main.py
 
 var waitDelay time.Duration
 var runFocus bool
+var runUnixSocket bool
 
 func init() {
 	// don't require -- to disambiguate subcommand flags
@@ -68,6 +87,8 @@ func init() {
 	)
 
 	runCmd.Flags().BoolVar(&runFocus, "focus", false, "Only show output for focused commands.")
+
+	runCmd.Flags().BoolVar(&runUnixSocket, "unix-socket", false, "Serve the session on a unix socket instead of a localhost TCP port.")
 }
 
 func Run(cmd *cobra.Command, args []string) error {
@@ -100,15 +121,36 @@ func run(cmd *cobra.Command, args []string) error {
 	return withEngine(ctx, client.Params{
 		SecretToken: sessionToken,
 	}, func(ctx context.Context, engineClient *client.Client) error {
-		sessionL, err := net.Listen("tcp", "127.0.0.1:0")
-		if err != nil {
-			return fmt.Errorf("session listen: %w", err)
+		// drop any outer session's address so it doesn't shadow ours
+		env := slices.DeleteFunc(os.Environ(), func(kv string) bool {
+			return strings.HasPrefix(kv, "DAGGER_SESSION_SOCKET=") ||
+				strings.HasPrefix(kv, "DAGGER_SESSION_PORT=")
+		})
+
+		var sessionL net.Listener
+		if runUnixSocket {
+			// only the current user can reach the socket in its private dir
+			sockDir, err := os.MkdirTemp("", "dagger-session-")
+			if err != nil {
+				return fmt.Errorf("session socket dir: %w", err)
+			}
+			defer os.RemoveAll(sockDir)
+			sockPath := filepath.Join(sockDir, "session.sock")
+			sessionL, err = net.Listen("unix", sockPath)
+			if err != nil {
+				return fmt.Errorf("session listen: %w", err)
+			}
+			env = append(env, "DAGGER_SESSION_SOCKET="+sockPath)
+		} else {
+			sessionL, err = net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				return fmt.Errorf("session listen: %w", err)
+			}
+			sessionPort := fmt.Sprintf("%d", sessionL.Addr().(*net.TCPAddr).Port)
+			env = append(env, "DAGGER_SESSION_PORT="+sessionPort)
 		}
 		defer sessionL.Close()
 
-		env := os.Environ()
-		sessionPort := fmt.Sprintf("%d", sessionL.Addr().(*net.TCPAddr).Port)
-		env = append(env, "DAGGER_SESSION_PORT="+sessionPort)
 		env = append(env, "DAGGER_SESSION_TOKEN="+sessionToken)
 		env = append(env, telemetry.PropagationEnv(ctx)...)
 
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/gofrs/flock"
+	"github.com/juju/ansiterm/tabwriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleEvery     time.Duration
+	scheduleName      string
+	scheduleImmediate bool
+	scheduleLimit     int
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Run a command in a Dagger session on a recurring schedule",
+	Annotations: map[string]string{
+		"experimental": "true",
+	},
+	GroupID: execGroup.ID,
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run [options] <command>...",
+	Short: "Run a command in a Dagger session at a fixed interval",
+	Long: `Runs the specified command in a new Dagger session at a fixed interval,
+as with "dagger run", until interrupted.
+
+A run is skipped if the previous one, started by this or any other scheduler
+with the same name, is still in progress. The outcome of every run is
+recorded in a local history that can be shown with "dagger schedule history".`,
+	Example: strings.TrimSpace(`
+dagger schedule run --every 24h --name nightly dagger call build
+dagger schedule run --every 1h -- go run ./ci warm-cache
+`,
+	),
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         ScheduleRun,
+	SilenceUsage: true,
+}
+
+var scheduleHistoryCmd = &cobra.Command{
+	Use:          "history <name>",
+	Short:        "Show the recorded runs of a schedule",
+	Args:         cobra.ExactArgs(1),
+	RunE:         ScheduleHistory,
+	SilenceUsage: true,
+}
+
+func init() {
+	// don't require -- to disambiguate subcommand flags
+	scheduleRunCmd.Flags().SetInterspersed(false)
+
+	scheduleRunCmd.Flags().DurationVar(&scheduleEvery, "every", 24*time.Hour, "Interval between the start of two runs")
+	scheduleRunCmd.Flags().StringVar(&scheduleName, "name", "", "Name of the schedule, used to record its history (defaults to a name derived from the command)")
+	scheduleRunCmd.Flags().BoolVar(&scheduleImmediate, "immediate", true, "Start the first run immediately instead of after the first interval")
+
+	scheduleHistoryCmd.Flags().IntVarP(&scheduleLimit, "limit", "n", 20, "Maximum number of runs to show, most recent last (0 for all)")
+
+	scheduleCmd.AddCommand(scheduleRunCmd, scheduleHistoryCmd)
+}
+
+// scheduleRun is the outcome of a single run of a schedule, persisted as a
+// line of JSON in the schedule's history file.
+type scheduleRun struct {
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Status   string        `json:"status"`
+	ExitCode int           `json:"exitCode,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+const (
+	scheduleStatusSucceeded = "succeeded"
+	scheduleStatusFailed    = "failed"
+	scheduleStatusSkipped   = "skipped"
+)
+
+func ScheduleRun(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if scheduleEvery <= 0 {
+		return fmt.Errorf("--every must be positive")
+	}
+	name := scheduleName
+	if name == "" {
+		name = scheduleNameFor(args)
+	}
+	if err := validateScheduleName(name); err != nil {
+		return err
+	}
+
+	dir := scheduleStateDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create schedule state dir: %w", err)
+	}
+	// the lock protects from overlapping runs across schedulers sharing a
+	// name, not only from overlapping runs of this one
+	lock := flock.New(filepath.Join(dir, name+".lock"))
+	historyPath := filepath.Join(dir, name+".jsonl")
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find dagger executable: %w", err)
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "scheduling %q every %s, recording history in %s\n", name, scheduleEvery, historyPath)
+
+	done := make(chan struct{}, 1)
+	done <- struct{}{}
+	tick := func(started time.Time) {
+		select {
+		case <-done:
+		default:
+			fmt.Fprintf(cmd.ErrOrStderr(), "skipping run of %q: previous run still in progress\n", name)
+			recordScheduleRun(cmd, historyPath, scheduleRun{Started: started, Status: scheduleStatusSkipped})
+			return
+		}
+		go func() {
+			defer func() { done <- struct{}{} }()
+			run := runScheduled(ctx, cmd, lock, self, args)
+			run.Started = started
+			recordScheduleRun(cmd, historyPath, run)
+		}()
+	}
+
+	if scheduleImmediate {
+		tick(time.Now())
+	}
+	ticker := time.NewTicker(scheduleEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// wait for the current run to be interrupted and recorded
+			<-done
+			return ctx.Err()
+		case now := <-ticker.C:
+			tick(now)
+		}
+	}
+}
+
+// runScheduled runs the scheduled command in a new Dagger session, by way of
+// "dagger run" so that every run gets its own session.
+func runScheduled(ctx context.Context, cmd *cobra.Command, lock *flock.Flock, self string, args []string) scheduleRun {
+	started := time.Now()
+	locked, err := lock.TryLock()
+	if err != nil {
+		return scheduleRun{Status: scheduleStatusFailed, Error: fmt.Sprintf("lock: %s", err)}
+	}
+	if !locked {
+		fmt.Fprintln(cmd.ErrOrStderr(), "skipping run: another scheduler is running it")
+		return scheduleRun{Status: scheduleStatusSkipped}
+	}
+	defer lock.Unlock()
+
+	runArgs := []string{"run", "--progress=plain"}
+	if silent {
+		runArgs = append(runArgs, "--silent")
+	}
+	runArgs = append(runArgs, args...)
+	subCmd := exec.CommandContext(ctx, self, runArgs...) // #nosec
+	subCmd.Stdout = cmd.OutOrStdout()
+	subCmd.Stderr = cmd.ErrOrStderr()
+	ensureChildProcessesAreKilled(subCmd)
+
+	err = subCmd.Run()
+	run := scheduleRun{
+		Duration: time.Since(started),
+		Status:   scheduleStatusSucceeded,
+	}
+	if err != nil {
+		run.Status = scheduleStatusFailed
+		run.Error = err.Error()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			run.ExitCode = exitErr.ExitCode()
+		}
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "run %s in %s\n", run.Status, run.Duration.Round(time.Second))
+	return run
+}
+
+func recordScheduleRun(cmd *cobra.Command, historyPath string, run scheduleRun) {
+	if err := appendScheduleRun(historyPath, run); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "failed to record run: %s\n", err)
+	}
+}
+
+func appendScheduleRun(historyPath string, run scheduleRun) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readScheduleRuns(historyPath string) ([]scheduleRun, error) {
+	f, err := os.Open(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []scheduleRun
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var run scheduleRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", historyPath, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+func ScheduleHistory(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := validateScheduleName(name); err != nil {
+		return err
+	}
+	runs, err := readScheduleRuns(filepath.Join(scheduleStateDir(), name+".jsonl"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no history for schedule %q", name)
+		}
+		return err
+	}
+	if scheduleLimit > 0 && len(runs) > scheduleLimit {
+		runs = runs[len(runs)-scheduleLimit:]
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', tabwriter.DiscardEmptyColumns)
+	fmt.Fprintln(tw, "STARTED\tDURATION\tSTATUS\tERROR")
+	for _, run := range runs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			run.Started.Local().Format(time.RFC3339),
+			run.Duration.Round(time.Second),
+			run.Status,
+			run.Error,
+		)
+	}
+	return tw.Flush()
+}
+
+func scheduleStateDir() string {
+	return filepath.Join(xdg.StateHome, "dagger", "schedules")
+}
+
+var invalidScheduleNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// scheduleNameFor derives a schedule name from the scheduled command.
+func scheduleNameFor(args []string) string {
+	name := invalidScheduleNameChars.ReplaceAllString(strings.Join(args, "-"), "-")
+	name = strings.Trim(name, "-.")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+func validateScheduleName(name string) error {
+	if name == "" || invalidScheduleNameChars.MatchString(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid schedule name %q: must only contain letters, digits, '_', '.' and '-'", name)
+	}
+	return nil
+}
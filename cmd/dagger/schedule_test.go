@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleNameFor(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{
+			args: []string{"dagger", "call", "build"},
+			want: "dagger-call-build",
+		},
+		{
+			args: []string{"go", "run", "./ci", "--target=linux/amd64"},
+			want: "go-run-.-ci---target-linux-amd64",
+		},
+	} {
+		name := scheduleNameFor(tc.args)
+		require.Equal(t, tc.want, name)
+		require.NoError(t, validateScheduleName(name))
+	}
+
+	require.Error(t, validateScheduleName(""))
+	require.Error(t, validateScheduleName("../nightly"))
+	require.Error(t, validateScheduleName(".nightly"))
+}
+
+func TestScheduleHistory(t *testing.T) {
+	historyPath := filepath.Join(t.TempDir(), "nightly.jsonl")
+	started := time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC)
+
+	runs := []scheduleRun{
+		{Started: started, Duration: time.Minute, Status: scheduleStatusSucceeded},
+		{Started: started.Add(time.Hour), Status: scheduleStatusSkipped},
+		{Started: started.Add(2 * time.Hour), Duration: time.Second, Status: scheduleStatusFailed, ExitCode: 1, Error: "exit status 1"},
+	}
+	for _, run := range runs {
+		require.NoError(t, appendScheduleRun(historyPath, run))
+	}
+
+	got, err := readScheduleRuns(historyPath)
+	require.NoError(t, err)
+	require.Equal(t, runs, got)
+}
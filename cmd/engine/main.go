@@ -24,11 +24,13 @@ import (
 	sddaemon "github.com/coreos/go-systemd/v22/daemon"
 	"github.com/docker/docker/pkg/reexec"
 	"github.com/gofrs/flock"
+	controlapi "github.com/moby/buildkit/api/services/control"
 	"github.com/moby/buildkit/cmd/buildkitd/config"
 	"github.com/moby/buildkit/util/apicaps"
 	"github.com/moby/buildkit/util/appcontext"
 	"github.com/moby/buildkit/util/bklog"
 	"github.com/moby/buildkit/util/profiler"
+	resolverconfig "github.com/moby/buildkit/util/resolver/config"
 	"github.com/moby/buildkit/util/stack"
 	"github.com/moby/buildkit/version"
 	sloglogrus "github.com/samber/slog-logrus/v2"
@@ -40,6 +42,7 @@ import (
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 
+	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/engine/buildkit/cacerts"
 	"github.com/dagger/dagger/engine/server"
 	"github.com/dagger/dagger/engine/slog"
@@ -202,6 +205,78 @@ func addFlags(app *cli.App) {
 			Name:  "oci-worker-selinux",
 			Usage: "apply SELinux labels",
 		},
+		cli.BoolFlag{
+			Name:  "strict-schema",
+			Usage: "reject queries that select deprecated fields or pass deprecated arguments",
+		},
+		cli.IntFlag{
+			Name:  "registry-max-concurrent-requests",
+			Usage: "maximum number of concurrent requests made to a single registry host. 0 means unlimited.",
+		},
+		cli.StringFlag{
+			Name:  "http-proxy",
+			Usage: "proxy to use for HTTP requests of the engine, e.g. image pulls and source fetches, and of containers (overrides $HTTP_PROXY)",
+		},
+		cli.StringFlag{
+			Name:  "https-proxy",
+			Usage: "proxy to use for HTTPS requests of the engine, e.g. image pulls and source fetches, and of containers (overrides $HTTPS_PROXY)",
+		},
+		cli.StringFlag{
+			Name:  "no-proxy",
+			Usage: "comma-separated hosts to reach without going through the proxies (overrides $NO_PROXY)",
+		},
+		cli.StringSliceFlag{
+			Name:  "registry-mirror",
+			Usage: "pull-through cache to pull images from instead of a registry, in the form registry=mirror, e.g. docker.io=mirror.gcr.io (repeatable)",
+		},
+		cli.StringFlag{
+			Name:  "cache-config",
+			Usage: "remote caches to import from and export to in every session, e.g. \"type=s3,region=us-east-1,bucket=dagger-cache\". Multiple caches are separated by ';'.",
+		},
+		cli.StringFlag{
+			Name:  "cache-import-config",
+			Usage: "remote caches to import from in every session, in the same form as --cache-config",
+		},
+		cli.StringFlag{
+			Name:  "cache-export-config",
+			Usage: "remote caches to export to in every session, in the same form as --cache-config",
+		},
+		cli.StringSliceFlag{
+			Name:  "allow-device",
+			Usage: "host device that clients can expose to their containers, e.g. /dev/kvm (repeatable)",
+		},
+		cli.StringFlag{
+			Name:  "oidc-issuer",
+			Usage: "require clients to authenticate with an OpenID Connect ID token issued by this issuer, e.g. \"https://token.actions.githubusercontent.com\"",
+		},
+		cli.StringFlag{
+			Name:  "oidc-audience",
+			Usage: "audience that OpenID Connect ID tokens must be issued for",
+		},
+		cli.StringSliceFlag{
+			Name:  "oidc-subject",
+			Usage: "subject allowed to connect with an OpenID Connect ID token, where * matches any characters, e.g. \"repo:my-org/*\" (repeatable, defaults to any subject)",
+		},
+		cli.StringFlag{
+			Name:  "auth-token-file",
+			Usage: "require clients to authenticate with the token in this file, e.g. set as DAGGER_ENGINE_TOKEN (can be combined with --oidc-issuer)",
+		},
+		cli.StringSliceFlag{
+			Name:  "admin-principal",
+			Usage: "authenticated principal allowed to prune the cache and manage the sessions of others, e.g. \"oidc:repo:my-org/infra:*\" or \"token\", where * matches any characters (repeatable)",
+		},
+		cli.StringFlag{
+			Name:  "monitoring-addr",
+			Usage: "address to serve the /metrics and /healthz endpoints on without authentication, e.g. \"127.0.0.1:9090\" (they're otherwise only served to authenticated clients)",
+		},
+		cli.Int64Flag{
+			Name:  "max-exec-output-bytes",
+			Usage: "maximum number of bytes of stdout and stderr captured and streamed of each exec, overriding any higher limit set by clients (0 means unlimited)",
+		},
+		cli.StringFlag{
+			Name:  "audit-log",
+			Usage: "record the queries clients run, the images they pull and push, the host paths they read and the secrets they reference to this file, as JSON lines",
+		},
 		cli.StringFlag{
 			Name:  "oci-max-parallelism",
 			Usage: "maximum number of parallel build steps that can be run at the same time (or \"num-cpu\" to automatically set to the number of CPUs). 0 means unlimited parallelism.",
@@ -218,6 +293,10 @@ func addFlags(app *cli.App) {
 			}(),
 			Hidden: len(defaultConf.Workers.OCI.GCPolicy) != 0,
 		},
+		cli.StringSliceFlag{
+			Name:  "oci-worker-gc-policy",
+			Usage: "GC rule in the form keepBytes=10GB,keepDuration=48h,all=false,filter=type==exec.cachemount (repeatable, replaces the default policy, rules are applied in order)",
+		},
 	)
 
 	if defaultConf.Workers.OCI.GC == nil || *defaultConf.Workers.OCI.GC {
@@ -253,6 +332,12 @@ func main() { //nolint:gocyclo
 		ctx, cancel := context.WithCancel(appcontext.Context())
 		defer cancel()
 
+		// set before anything reads the proxy env vars, since net/http only
+		// reads them once
+		if err := setProxyEnvs(c); err != nil {
+			return err
+		}
+
 		// install CA certs in case the user has a custom engine w/ extra certs installed to
 		// /usr/local/share/ca-certificates
 		if out, err := exec.CommandContext(ctx, "update-ca-certificates").CombinedOutput(); err != nil {
@@ -374,11 +459,71 @@ func main() { //nolint:gocyclo
 			}
 		}
 
+		cacheImportConfigs, cacheExportConfigs, err := cacheConfigs(c)
+		if err != nil {
+			return err
+		}
+
+		var authenticators server.AnyAuthenticator
+		if issuer := c.GlobalString("oidc-issuer"); issuer != "" {
+			oidcAuth, err := server.NewOIDCAuthenticator(server.OIDCConfig{
+				Issuer:   issuer,
+				Audience: c.GlobalString("oidc-audience"),
+				Subjects: c.GlobalStringSlice("oidc-subject"),
+			})
+			if err != nil {
+				return err
+			}
+			authenticators = append(authenticators, oidcAuth)
+		}
+		if tokenFile := c.GlobalString("auth-token-file"); tokenFile != "" {
+			tokenAuth, err := server.NewTokenAuthenticator(tokenFile)
+			if err != nil {
+				return err
+			}
+			authenticators = append(authenticators, tokenAuth)
+		}
+		var authenticator server.Authenticator
+		if len(authenticators) > 0 {
+			authenticator = authenticators
+		}
+
+		var auditLog io.Writer
+		if auditLogPath := c.GlobalString("audit-log"); auditLogPath != "" {
+			f, err := os.OpenFile(auditLogPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+			if err != nil {
+				return fmt.Errorf("open audit log: %w", err)
+			}
+			defer f.Close()
+			auditLog = f
+		}
+
+		// the budget env vars set on the engine cap the budgets declared by
+		// clients for their sessions
+		sessionBudget, err := engine.BudgetFromEnv()
+		if err != nil {
+			return err
+		}
+
 		bklog.G(ctx).Debug("creating engine server")
 		srv, err := server.NewServer(ctx, &server.NewServerOpts{
 			Config:          &cfg,
 			Name:            engineName,
 			TelemetryPubSub: pubsub,
+			StrictSchema:    c.GlobalBool("strict-schema"),
+
+			RegistryMaxConcurrentRequests: c.GlobalInt("registry-max-concurrent-requests"),
+
+			CacheImportConfigs: cacheImportConfigs,
+			CacheExportConfigs: cacheExportConfigs,
+
+			Authenticator:   authenticator,
+			AdminPrincipals: c.GlobalStringSlice("admin-principal"),
+			AllowedDevices:  c.GlobalStringSlice("allow-device"),
+			AuditLog:        auditLog,
+
+			MaxExecOutputBytes: c.GlobalInt64("max-exec-output-bytes"),
+			SessionBudget:      sessionBudget,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create engine: %w", err)
@@ -404,6 +549,20 @@ func main() { //nolint:gocyclo
 		httpServer := &http.Server{
 			ReadHeaderTimeout: 30 * time.Second,
 			Handler: h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r, err := srv.Authenticate(r)
+				if err != nil {
+					bklog.G(ctx).WithError(err).Debug("rejecting unauthenticated client")
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				switch r.URL.Path {
+				case engine.HealthEndpoint:
+					srv.ServeHealth(w, r)
+					return
+				case engine.MetricsEndpoint:
+					srv.ServeMetrics(w, r)
+					return
+				}
 				if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("content-type"), "application/grpc") {
 					// The docs on grpcServer.ServeHTTP warn that some features are missing vs. serving fully "native" gRPC,
 					// but in practice it seems to work fine for us and only be relevant for some advanced features we don't use.
@@ -416,7 +575,27 @@ func main() { //nolint:gocyclo
 		if err := http2.ConfigureServer(httpServer, http2Server); err != nil {
 			return fmt.Errorf("failed to configure http2 server: %w", err)
 		}
-		errCh := make(chan error, 1)
+		// one for the API listeners and one for the monitoring listener
+		errCh := make(chan error, 2)
+		if monitoringAddr := c.GlobalString("monitoring-addr"); monitoringAddr != "" {
+			// probes and scrapers don't have credentials, so they get a listener
+			// of their own that only serves them
+			l, err := net.Listen("tcp", monitoringAddr)
+			if err != nil {
+				return fmt.Errorf("listen for monitoring: %w", err)
+			}
+			monitoringServer := &http.Server{
+				ReadHeaderTimeout: 30 * time.Second,
+				Handler:           srv.MonitoringHandler(),
+			}
+			defer monitoringServer.Close()
+			go func() {
+				logrus.Infof("serving monitoring endpoints on %s", l.Addr())
+				if err := monitoringServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					errCh <- fmt.Errorf("serve monitoring: %w", err)
+				}
+			}()
+		}
 		if err := serveAPI(cfg.GRPC, httpServer, errCh); err != nil {
 			return err
 		}
@@ -610,6 +789,17 @@ func applyMainFlags(c *cli.Context, cfg *config.Config) error {
 		cfg.Workers.OCI.GCKeepStorage = config.DiskSpace{Bytes: c.GlobalInt64("oci-worker-gc-keepstorage") * 1e6}
 	}
 
+	if rules := c.GlobalStringSlice("oci-worker-gc-policy"); len(rules) != 0 {
+		cfg.Workers.OCI.GCPolicy = nil
+		for _, rule := range rules {
+			policy, err := server.ParseGCPolicy(rule)
+			if err != nil {
+				return fmt.Errorf("invalid --oci-worker-gc-policy: %w", err)
+			}
+			cfg.Workers.OCI.GCPolicy = append(cfg.Workers.OCI.GCPolicy, policy)
+		}
+	}
+
 	if c.GlobalIsSet("oci-worker-net") {
 		cfg.Workers.OCI.NetworkConfig.Mode = c.GlobalString("oci-worker-net")
 	}
@@ -634,6 +824,21 @@ func applyMainFlags(c *cli.Context, cfg *config.Config) error {
 	if c.GlobalIsSet("oci-worker-selinux") {
 		cfg.Workers.OCI.SELinux = c.GlobalBool("oci-worker-selinux")
 	}
+	if mirrors := c.GlobalStringSlice("registry-mirror"); len(mirrors) != 0 {
+		hostMirrors, err := attrMap(mirrors)
+		if err != nil {
+			return fmt.Errorf("invalid --registry-mirror: %w", err)
+		}
+		if cfg.Registries == nil {
+			cfg.Registries = make(map[string]resolverconfig.RegistryConfig)
+		}
+		for host, mirror := range hostMirrors {
+			regCfg := cfg.Registries[host]
+			regCfg.Mirrors = append(regCfg.Mirrors, mirror)
+			cfg.Registries[host] = regCfg
+		}
+	}
+
 	if c.GlobalIsSet("oci-max-parallelism") {
 		maxParallelismStr := c.GlobalString("oci-max-parallelism")
 		var maxParallelism int
@@ -766,6 +971,61 @@ func attrMap(sl []string) (map[string]string, error) {
 	return m, nil
 }
 
+// cacheConfigs returns the remote caches configured by the cache flags, with
+// --cache-config applying to both imports and exports.
+func cacheConfigs(c *cli.Context) (imports, exports []*controlapi.CacheOptionsEntry, _ error) {
+	for _, flag := range []struct {
+		name    string
+		imports bool
+		exports bool
+	}{
+		{"cache-import-config", true, false},
+		{"cache-export-config", false, true},
+		{"cache-config", true, true},
+	} {
+		val := c.GlobalString(flag.name)
+		if val == "" {
+			continue
+		}
+		cfgs, err := engine.ParseCacheConfigs(val)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --%s: %w", flag.name, err)
+		}
+		if flag.imports {
+			imports = append(imports, cfgs...)
+		}
+		if flag.exports {
+			exports = append(exports, cfgs...)
+		}
+	}
+	return imports, exports, nil
+}
+
+// setProxyEnvs sets the proxy env vars of the engine from the proxy flags, so
+// that they apply everywhere proxies from the engine's environment do: image
+// pulls, git and http sources, and containers.
+func setProxyEnvs(c *cli.Context) error {
+	for _, flag := range []struct {
+		name string
+		env  string
+	}{
+		{"http-proxy", engine.HTTPProxyEnvName},
+		{"https-proxy", engine.HTTPSProxyEnvName},
+		{"no-proxy", engine.NoProxyEnvName},
+	} {
+		if !c.GlobalIsSet(flag.name) {
+			continue
+		}
+		val := c.GlobalString(flag.name)
+		for _, env := range []string{flag.env, strings.ToLower(flag.env)} {
+			if err := os.Setenv(env, val); err != nil {
+				return fmt.Errorf("failed to set %s: %w", env, err)
+			}
+		}
+	}
+	return nil
+}
+
 type networkConfig struct {
 	NetName       string
 	NetCIDR       string
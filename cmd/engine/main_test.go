@@ -45,6 +45,37 @@ func TestParallelismFlag(t *testing.T) {
 	})
 }
 
+func TestRegistryMirrorFlag(t *testing.T) {
+	t.Parallel()
+	app := cli.NewApp()
+	addFlags(app)
+
+	var cfg *config.Config
+	app.Action = func(c *cli.Context) error {
+		cfg = &config.Config{}
+		return applyMainFlags(c, cfg)
+	}
+
+	t.Run("default", func(t *testing.T) {
+		err := app.Run([]string{"buildkitd"})
+		require.NoError(t, err)
+		require.Empty(t, cfg.Registries)
+	})
+	t.Run("mirrors", func(t *testing.T) {
+		err := app.Run([]string{"buildkitd",
+			"--registry-mirror", "docker.io=mirror.gcr.io",
+			"--registry-mirror", "ghcr.io=ghcr.example.com",
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"mirror.gcr.io"}, cfg.Registries["docker.io"].Mirrors)
+		require.Equal(t, []string{"ghcr.example.com"}, cfg.Registries["ghcr.io"].Mirrors)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		err := app.Run([]string{"buildkitd", "--registry-mirror", "mirror.gcr.io"})
+		require.Error(t, err)
+	})
+}
+
 func TestEngineNameLabel(t *testing.T) {
 	app := cli.NewApp()
 	addFlags(app)
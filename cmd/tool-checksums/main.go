@@ -0,0 +1,73 @@
+// Command tool-checksums prints the checksums of the artifacts of a release of
+// a tool in the format of core/tool_checksums.txt, to pin them in the engine.
+//
+// Usage:
+//
+//	go run ./cmd/tool-checksums <name> <version> >> core/tool_checksums.txt
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/dagger/dagger/core"
+)
+
+// platforms are the platforms to pin the artifacts of, skipping those the
+// tool isn't released for.
+var platforms = []core.Platform{
+	{OS: "linux", Architecture: "amd64"},
+	{OS: "linux", Architecture: "arm64"},
+	{OS: "linux", Architecture: "arm", Variant: "v7"},
+	{OS: "darwin", Architecture: "amd64"},
+	{OS: "darwin", Architecture: "arm64"},
+	{OS: "windows", Architecture: "amd64"},
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: tool-checksums <name> <version>")
+		os.Exit(2)
+	}
+	if err := run(os.Args[1], os.Args[2]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(name, version string) error {
+	client := &http.Client{Timeout: time.Minute}
+	for _, platform := range platforms {
+		release, err := core.ResolveTool(name, version, platform)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: %s\n", platform.Format(), err)
+			continue
+		}
+		if release.Checksum != "" {
+			fmt.Fprintf(os.Stderr, "skipping %s: already pinned\n", platform.Format())
+			continue
+		}
+		resp, err := client.Get(release.ChecksumURL)
+		if err != nil {
+			return err
+		}
+		contents, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("fetch %s: %s", release.ChecksumURL, resp.Status)
+		}
+		dgst, err := core.ParseToolChecksum(string(contents), path.Base(release.URL))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s  %s\n", dgst.Encoded(), release.URL)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/moby/buildkit/util/entitlements"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/dagql/call"
+	"github.com/dagger/dagger/engine"
+)
+
+// EngineCapability is a GraphQL enum type.
+type EngineCapability string
+
+var EngineCapabilities = dagql.NewEnum[EngineCapability]()
+
+var (
+	EngineCapabilityGPU = EngineCapabilities.Register("GPU",
+		"Exposing GPUs to containers.")
+	EngineCapabilityPrivileged = EngineCapabilities.Register("PRIVILEGED",
+		"Executing commands with all root capabilities.")
+	EngineCapabilityWindows = EngineCapabilities.Register("WINDOWS",
+		"Executing commands in Windows containers.")
+	EngineCapabilityDevices = EngineCapabilities.Register("DEVICES",
+		"Exposing host devices to containers.")
+)
+
+func (capability EngineCapability) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "EngineCapability",
+		NonNull:   true,
+	}
+}
+
+func (capability EngineCapability) TypeDescription() string {
+	return "An optional feature that an engine may not support."
+}
+
+func (capability EngineCapability) Decoder() dagql.InputDecoder {
+	return EngineCapabilities
+}
+
+func (capability EngineCapability) ToLiteral() call.Literal {
+	return EngineCapabilities.Literal(capability)
+}
+
+type Capability struct {
+	Name      EngineCapability `field:"true" doc:"The name of the capability."`
+	Supported bool             `field:"true" doc:"Whether the engine supports the capability."`
+	Reason    string           `field:"true" doc:"Why the engine does not support the capability, or an empty string if it does."`
+}
+
+func (Capability) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "Capability",
+		NonNull:   true,
+	}
+}
+
+func (Capability) TypeDescription() string {
+	return "Whether the engine supports an optional feature."
+}
+
+// Capabilities returns whether the engine supports each of its optional
+// features.
+func (q *Query) Capabilities(ctx context.Context) ([]Capability, error) {
+	var capabilities []Capability
+	for _, name := range []EngineCapability{
+		EngineCapabilityGPU,
+		EngineCapabilityPrivileged,
+		EngineCapabilityWindows,
+		EngineCapabilityDevices,
+	} {
+		capability, err := q.Capability(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		capabilities = append(capabilities, capability)
+	}
+	return capabilities, nil
+}
+
+// Capability returns whether the engine supports the given optional feature.
+func (q *Query) Capability(ctx context.Context, name EngineCapability) (Capability, error) {
+	capability := Capability{Name: name}
+	switch name {
+	case EngineCapabilityGPU:
+		if os.Getenv(engine.GPUSupportEnv) == "" {
+			capability.Reason = fmt.Sprintf("GPU support is not enabled, set %s", engine.GPUSupportEnv)
+		}
+	case EngineCapabilityPrivileged:
+		if _, ok := q.Buildkit.Entitlements[entitlements.EntitlementSecurityInsecure]; !ok {
+			capability.Reason = fmt.Sprintf("the %s entitlement is not enabled", entitlements.EntitlementSecurityInsecure)
+		}
+	case EngineCapabilityWindows:
+		capability.Reason = "no worker supports the windows platform"
+		for _, platform := range q.Buildkit.Worker.Platforms(false) {
+			if platform.OS == "windows" {
+				capability.Reason = ""
+				break
+			}
+		}
+	case EngineCapabilityDevices:
+		if len(q.Buildkit.Worker.AllowedDevices()) == 0 {
+			capability.Reason = "no host devices are allowed, start the engine with --allow-device"
+		}
+	default:
+		return Capability{}, fmt.Errorf("unknown capability %q", name)
+	}
+	capability.Supported = capability.Reason == ""
+	return capability, nil
+}
+
+// RequireCapability returns an *UnsupportedError if the engine does not
+// support the given optional feature.
+func (q *Query) RequireCapability(ctx context.Context, name EngineCapability) error {
+	capability, err := q.Capability(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !capability.Supported {
+		return &UnsupportedError{
+			Capability: name,
+			Reason:     capability.Reason,
+		}
+	}
+	return nil
+}
+
+// UnsupportedError is returned when an operation requires a capability that
+// the engine does not support.
+type UnsupportedError struct {
+	Capability EngineCapability
+	Reason     string
+}
+
+var _ dagql.ExtendedError = (*UnsupportedError)(nil)
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is unsupported on this engine: %s", e.Capability, e.Reason)
+}
+
+func (e *UnsupportedError) Extensions() map[string]any {
+	return map[string]any{
+		"_type":      "UNSUPPORTED",
+		"capability": string(e.Capability),
+		"reason":     e.Reason,
+	}
+}
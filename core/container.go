@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/fs"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,6 +35,7 @@ import (
 	"github.com/dagger/dagger/core/pipeline"
 	"github.com/dagger/dagger/dagql"
 	"github.com/dagger/dagger/dagql/call"
+	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/engine/buildkit"
 )
 
@@ -61,12 +64,18 @@ type Container struct {
 	// List of GPU devices that will be exposed to the container
 	EnabledGPUs []string `json:"enabledGPUs,omitempty"`
 
+	// Paths of the host devices that will be exposed to the container
+	Devices []string `json:"devices,omitempty"`
+
 	// Mount points configured for the container.
 	Mounts ContainerMounts `json:"mounts,omitempty"`
 
 	// Meta is the /dagger filesystem. It will be null if nothing has run yet.
 	Meta *pb.Definition `json:"meta,omitempty"`
 
+	// Problem matchers of the last executed command.
+	ProblemMatchers []ProblemMatcher `json:"problemMatchers,omitempty"`
+
 	// The platform of the container's rootfs.
 	Platform Platform `json:"platform,omitempty"`
 
@@ -79,12 +88,25 @@ type Container struct {
 	// Image reference
 	ImageRef string `json:"image_ref,omitempty"`
 
+	// Annotations to set on the manifest of the image when it's published or
+	// exported.
+	Annotations []ContainerAnnotation `json:"annotations,omitempty"`
+
 	// Ports to expose from the container.
 	Ports []Port `json:"ports,omitempty"`
 
 	// Services to start before running the container.
 	Services ServiceBindings `json:"services,omitempty"`
 
+	// Extra entries for the hosts file of the container.
+	ExtraHosts []ExtraHost `json:"extraHosts,omitempty"`
+
+	// Nameservers replacing the engine's in the resolv.conf of the container.
+	DNSNameservers []string `json:"dnsNameservers,omitempty"`
+
+	// Search domains added to the resolv.conf of the container.
+	DNSSearchDomains []string `json:"dnsSearchDomains,omitempty"`
+
 	// Focused indicates whether subsequent operations will be
 	// focused, i.e. shown more prominently in the UI.
 	Focused bool `json:"focused"`
@@ -155,10 +177,16 @@ func (container *Container) Clone() *Container {
 	cp.Config.Volumes = cloneMap(cp.Config.Volumes)
 	cp.Config.Labels = cloneMap(cp.Config.Labels)
 	cp.Mounts = cloneSlice(cp.Mounts)
+	cp.ProblemMatchers = cloneSlice(cp.ProblemMatchers)
 	cp.Secrets = cloneSlice(cp.Secrets)
 	cp.Sockets = cloneSlice(cp.Sockets)
 	cp.Ports = cloneSlice(cp.Ports)
 	cp.Services = cloneSlice(cp.Services)
+	cp.ExtraHosts = cloneSlice(cp.ExtraHosts)
+	cp.DNSNameservers = cloneSlice(cp.DNSNameservers)
+	cp.DNSSearchDomains = cloneSlice(cp.DNSSearchDomains)
+	cp.Devices = cloneSlice(cp.Devices)
+	cp.Annotations = cloneSlice(cp.Annotations)
 	cp.SystemEnvNames = cloneSlice(cp.SystemEnvNames)
 	return &cp
 }
@@ -192,6 +220,20 @@ type ContainerSecret struct {
 	Mode      fs.FileMode `json:"mode,omitempty"`
 }
 
+// ContainerAnnotation is an OCI annotation set on the manifest of a
+// container's image.
+type ContainerAnnotation struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ExtraHost is an entry of the hosts file of a container, mapping a hostname
+// to an IP.
+type ExtraHost struct {
+	Host string `json:"host"`
+	IP   string `json:"ip"`
+}
+
 // ContainerSocket configures a socket to expose, currently as a Unix socket,
 // but potentially as a TCP or UDP address in the future.
 type ContainerSocket struct {
@@ -280,7 +322,7 @@ func (mnts ContainerMounts) With(newMnt ContainerMount) ContainerMounts {
 	return mntsCp
 }
 
-func (container *Container) From(ctx context.Context, addr string) (*Container, error) {
+func (container *Container) From(ctx context.Context, addr string, pullPolicy ImagePullPolicy) (*Container, error) {
 	bk := container.Query.Buildkit
 
 	container = container.Clone()
@@ -294,10 +336,24 @@ func (container *Container) From(ctx context.Context, addr string) (*Container,
 
 	ref := reference.TagNameOnly(refName).String()
 
+	resolveMode := llb.ResolveModeDefault
+	switch pullPolicy {
+	case PullPolicyAlways:
+		resolveMode = llb.ResolveModeForcePull
+	case PullPolicyNever:
+		// without contacting the registry, only a digest can tell which image
+		// to use
+		canonical, ok := refName.(reference.Canonical)
+		if !ok {
+			return nil, fmt.Errorf("pull policy %s requires an address pinned by digest, got %s", pullPolicy, addr)
+		}
+		return container.fromContentStore(ctx, canonical)
+	}
+
 	_, digest, cfgBytes, err := bk.ResolveImageConfig(ctx, ref, sourceresolver.Opt{
 		Platform: ptr(platform.Spec()),
 		ImageOpt: &sourceresolver.ResolveImageOpt{
-			ResolveMode: llb.ResolveModeDefault.String(),
+			ResolveMode: resolveMode.String(),
 		},
 	})
 	if err != nil {
@@ -309,16 +365,116 @@ func (container *Container) From(ctx context.Context, addr string) (*Container,
 		return nil, err
 	}
 
+	if bk.Budget.LimitsPulls() && !bk.Budget.PullRecorded(digested.String()) {
+		size, err := bk.ImagePullSize(ctx, digested.String(), platform.Spec())
+		if err != nil {
+			return nil, err
+		}
+		if err := bk.Budget.AddPull(digested.String(), size); err != nil {
+			return nil, err
+		}
+	}
+
 	var imgSpec specs.Image
 	if err := json.Unmarshal(cfgBytes, &imgSpec); err != nil {
 		return nil, err
 	}
 
-	fsSt := llb.Image(
-		digested.String(),
+	imgOpts := []llb.ImageOption{
 		llb.WithCustomNamef("pull %s", ref),
-	)
+		resolveMode,
+	}
+	if bk.Budget.LimitsParallelism() {
+		clientMetadata, err := engine.ClientMetadataFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		imgOpts = append(imgOpts, buildkit.WithSessionID(clientMetadata.SessionID))
+	}
+	fsSt := llb.Image(digested.String(), imgOpts...)
+
+	def, err := fsSt.Marshal(ctx, llb.Platform(platform.Spec()))
+	if err != nil {
+		return nil, err
+	}
+
+	container.FS = def.ToPB()
+
+	container.Config = mergeImageConfig(container.Config, imgSpec.Config)
+	container.ImageRef = digested.String()
+	container.Platform = Platform(platforms.Normalize(imgSpec.Platform))
+
+	return container, nil
+}
+
+// fromContentStore is From for images fully present in the engine's content
+// store, loading them from there instead of pulling them so that the registry
+// is never contacted.
+func (container *Container) fromContentStore(ctx context.Context, ref reference.Canonical) (*Container, error) {
+	store := container.Query.OCIStore
+	platform := container.Platform
+
+	notPresent := func(err error) error {
+		return fmt.Errorf("image %s is not present in the engine and pull policy is %s: %w", ref, PullPolicyNever, err)
+	}
+
+	info, err := store.Info(ctx, ref.Digest())
+	if err != nil {
+		return nil, notPresent(err)
+	}
+	desc := specs.Descriptor{
+		Digest: info.Digest,
+		Size:   info.Size,
+	}
+	blob, err := content.ReadBlob(ctx, store, desc)
+	if err != nil {
+		return nil, notPresent(err)
+	}
+	// the media type is only known from the blob itself
+	var versioned struct {
+		MediaType string          `json:"mediaType"`
+		Manifests json.RawMessage `json:"manifests"`
+	}
+	if err := json.Unmarshal(blob, &versioned); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", ref, err)
+	}
+	switch {
+	case versioned.MediaType != "":
+		desc.MediaType = versioned.MediaType
+	case versioned.Manifests != nil:
+		desc.MediaType = specs.MediaTypeImageIndex
+	default:
+		desc.MediaType = specs.MediaTypeImageManifest
+	}
 
+	manifest, err := images.Manifest(ctx, store, desc, platforms.Only(platform.Spec()))
+	if err != nil {
+		return nil, notPresent(err)
+	}
+	for _, layer := range manifest.Layers {
+		if _, err := store.Info(ctx, layer.Digest); err != nil {
+			return nil, notPresent(err)
+		}
+	}
+	cfgBytes, err := content.ReadBlob(ctx, store, manifest.Config)
+	if err != nil {
+		return nil, notPresent(err)
+	}
+	var imgSpec specs.Image
+	if err := json.Unmarshal(cfgBytes, &imgSpec); err != nil {
+		return nil, err
+	}
+
+	digested, err := reference.WithDigest(reference.TrimNamed(ref), ref.Digest())
+	if err != nil {
+		return nil, err
+	}
+	fsSt := llb.OCILayout(
+		digested.String(),
+		llb.OCIStore("", buildkit.OCIStoreName),
+		llb.Platform(platform.Spec()),
+		llb.WithCustomNamef("load %s", digested),
+	)
 	def, err := fsSt.Marshal(ctx, llb.Platform(platform.Spec()))
 	if err != nil {
 		return nil, err
@@ -537,6 +693,27 @@ func (container *Container) WithNewFile(ctx context.Context, dest string, conten
 	})
 }
 
+func (container *Container) WithNewDirectory(ctx context.Context, dest string, permissions fs.FileMode, owner string) (*Container, error) {
+	container = container.Clone()
+
+	return container.writeToPath(ctx, path.Dir(dest), func(dir *Directory) (*Directory, error) {
+		ownership, err := container.ownership(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+
+		return dir.WithNewDirectory(ctx, path.Base(dest), permissions, ownership)
+	})
+}
+
+func (container *Container) WithSymlink(ctx context.Context, target, linkName string) (*Container, error) {
+	container = container.Clone()
+
+	return container.writeToPath(ctx, path.Dir(linkName), func(dir *Directory) (*Directory, error) {
+		return dir.WithSymlink(ctx, target, path.Base(linkName))
+	})
+}
+
 func (container *Container) WithMountedDirectory(ctx context.Context, target string, dir *Directory, owner string, readonly bool) (*Container, error) {
 	container = container.Clone()
 
@@ -551,7 +728,7 @@ func (container *Container) WithMountedFile(ctx context.Context, target string,
 
 var SeenCacheKeys = new(sync.Map)
 
-func (container *Container) WithMountedCache(ctx context.Context, target string, cache *CacheVolume, source *Directory, sharingMode CacheSharingMode, owner string) (*Container, error) {
+func (container *Container) WithMountedCache(ctx context.Context, target string, cache *CacheVolume, source *Directory, sharingMode CacheSharingMode, owner string, permissions *int) (*Container, error) {
 	container = container.Clone()
 
 	target = absPath(container.Config.WorkingDir, target)
@@ -571,6 +748,20 @@ func (container *Container) WithMountedCache(ctx context.Context, target string,
 		mount.SourcePath = source.Dir
 	}
 
+	if permissions != nil {
+		var err error
+		mount.Source, mount.SourcePath, err = withRootPermissions(
+			ctx,
+			mount.Source,
+			mount.SourcePath,
+			fs.FileMode(*permissions),
+			llb.Platform(container.Platform.Spec()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if owner != "" {
 		var err error
 		mount.Source, mount.SourcePath, err = container.chown(
@@ -955,6 +1146,36 @@ func (container *Container) chown(
 	return def.ToPB(), srcPath, nil
 }
 
+// withRootPermissions returns a copy of the directory at srcPath in srcDef
+// (or an empty directory if srcDef is nil) whose root has the given
+// permissions.
+func withRootPermissions(
+	ctx context.Context,
+	srcDef *pb.Definition,
+	srcPath string,
+	permissions fs.FileMode,
+	opts ...llb.ConstraintsOpt,
+) (*pb.Definition, string, error) {
+	const rootPath = "/root"
+
+	fileAction := llb.Mkdir(rootPath, permissions)
+	if srcDef != nil {
+		srcSt, err := defToState(srcDef)
+		if err != nil {
+			return nil, "", err
+		}
+		fileAction = fileAction.Copy(srcSt, srcPath, rootPath, &llb.CopyInfo{
+			CopyDirContentsOnly: true,
+		})
+	}
+
+	def, err := llb.Scratch().File(fileAction).Marshal(ctx, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	return def.ToPB(), rootPath, nil
+}
+
 func (container *Container) writeToPath(ctx context.Context, subdir string, fn func(dir *Directory) (*Directory, error)) (*Container, error) {
 	dir, mount, err := locatePath(container, subdir, NewDirectory)
 	if err != nil {
@@ -995,6 +1216,67 @@ func (container *Container) WithPipeline(ctx context.Context, name, description
 	return container, nil
 }
 
+func (container *Container) WithAnnotation(ctx context.Context, key, value string) (*Container, error) {
+	container = container.Clone()
+
+	for i, annotation := range container.Annotations {
+		if annotation.Key == key {
+			container.Annotations[i].Value = value
+			return container, nil
+		}
+	}
+
+	container.Annotations = append(container.Annotations, ContainerAnnotation{
+		Key:   key,
+		Value: value,
+	})
+
+	return container, nil
+}
+
+func (container *Container) WithoutAnnotation(ctx context.Context, key string) (*Container, error) {
+	container = container.Clone()
+
+	for i, annotation := range container.Annotations {
+		if annotation.Key == key {
+			container.Annotations = append(container.Annotations[:i], container.Annotations[i+1:]...)
+			break
+		}
+	}
+
+	return container, nil
+}
+
+// annotationOpts returns the image exporter options setting the annotations
+// of each variant on its manifest, and those of the main container on the
+// index of multi-platform images.
+func annotationOpts(container *Container, platformVariants []*Container) map[string]string {
+	var variants []*Container
+	for _, variant := range append([]*Container{container}, platformVariants...) {
+		if variant.FS != nil {
+			variants = append(variants, variant)
+		}
+	}
+
+	opts := map[string]string{}
+	if len(variants) == 1 {
+		for _, annotation := range variants[0].Annotations {
+			opts[exptypes.AnnotationManifestKey(nil, annotation.Key)] = annotation.Value
+		}
+		return opts
+	}
+	for _, variant := range variants {
+		platform := variant.Platform.Spec()
+		for _, annotation := range variant.Annotations {
+			opts[exptypes.AnnotationManifestKey(&platform, annotation.Key)] = annotation.Value
+		}
+	}
+	for _, annotation := range container.Annotations {
+		opts[exptypes.AnnotationIndexKey(annotation.Key)] = annotation.Value
+	}
+	return opts
+}
+
 type ContainerGPUOpts struct {
 	Devices []string
 }
@@ -1005,6 +1287,52 @@ func (container *Container) WithGPU(ctx context.Context, gpuOpts ContainerGPUOpt
 	return container, nil
 }
 
+func (container *Container) WithDevice(ctx context.Context, devicePath string) (*Container, error) {
+	if !path.IsAbs(devicePath) || path.Clean(devicePath) != devicePath {
+		return nil, fmt.Errorf("invalid device path %q: must be an absolute, clean path", devicePath)
+	}
+
+	container = container.Clone()
+	if !slices.Contains(container.Devices, devicePath) {
+		container.Devices = append(container.Devices, devicePath)
+	}
+	return container, nil
+}
+
+func (container *Container) WithExtraHost(ctx context.Context, host, ip string) (*Container, error) {
+	if host == "" {
+		return nil, fmt.Errorf("extra host name must not be empty")
+	}
+	if net.ParseIP(ip) == nil {
+		return nil, fmt.Errorf("invalid IP %q for extra host %q", ip, host)
+	}
+
+	container = container.Clone()
+	container.ExtraHosts = slices.DeleteFunc(container.ExtraHosts, func(h ExtraHost) bool {
+		return h.Host == host
+	})
+	container.ExtraHosts = append(container.ExtraHosts, ExtraHost{Host: host, IP: ip})
+	return container, nil
+}
+
+func (container *Container) WithDNS(ctx context.Context, nameservers, searchDomains []string) (*Container, error) {
+	for _, ns := range nameservers {
+		if net.ParseIP(ns) == nil {
+			return nil, fmt.Errorf("invalid nameserver IP %q", ns)
+		}
+	}
+	for _, domain := range searchDomains {
+		if domain == "" || strings.ContainsAny(domain, " \t\n") {
+			return nil, fmt.Errorf("invalid search domain %q", domain)
+		}
+	}
+
+	container = container.Clone()
+	container.DNSNameservers = nameservers
+	container.DNSSearchDomains = searchDomains
+	return container, nil
+}
+
 func (container Container) Evaluate(ctx context.Context) (*buildkit.Result, error) {
 	if container.FS == nil {
 		return nil, nil
@@ -1034,12 +1362,20 @@ func (container Container) Evaluate(ctx context.Context) (*buildkit.Result, erro
 	})
 }
 
+// IsCached reports whether the execs of the container's root filesystem are
+// already cached, without running them.
+func (container *Container) IsCached(ctx context.Context) (bool, error) {
+	return container.Query.Buildkit.IsCached(ctx, container.FS)
+}
+
 func (container *Container) Publish(
 	ctx context.Context,
 	ref string,
 	platformVariants []*Container,
 	forcedCompression ImageLayerCompression,
+	compressionLevel *int,
 	mediaTypes ImageMediaTypes,
+	sourceDateEpoch *int,
 ) (string, error) {
 	if mediaTypes == "" {
 		// Modern registry implementations support oci types and docker daemons
@@ -1088,6 +1424,16 @@ func (container *Container) Publish(
 		opts[string(exptypes.OptKeyLayerCompression)] = strings.ToLower(string(forcedCompression))
 		opts[string(exptypes.OptKeyForceCompression)] = strconv.FormatBool(true)
 	}
+	if compressionLevel != nil {
+		opts[string(exptypes.OptKeyCompressionLevel)] = strconv.Itoa(*compressionLevel)
+	}
+	for k, v := range annotationOpts(container, platformVariants) {
+		opts[k] = v
+	}
+	if sourceDateEpoch != nil {
+		opts[string(exptypes.OptKeySourceDateEpoch)] = strconv.Itoa(*sourceDateEpoch)
+		opts[string(exptypes.OptKeyRewriteTimestamp)] = strconv.FormatBool(true)
+	}
 
 	svcs := container.Query.Services
 	bk := container.Query.Buildkit
@@ -1131,7 +1477,9 @@ func (container *Container) Export(
 	dest string,
 	platformVariants []*Container,
 	forcedCompression ImageLayerCompression,
+	compressionLevel *int,
 	mediaTypes ImageMediaTypes,
+	sourceDateEpoch *int,
 ) error {
 	svcs := container.Query.Services
 	bk := container.Query.Buildkit
@@ -1183,6 +1531,16 @@ func (container *Container) Export(
 		opts[string(exptypes.OptKeyLayerCompression)] = strings.ToLower(string(forcedCompression))
 		opts[string(exptypes.OptKeyForceCompression)] = strconv.FormatBool(true)
 	}
+	if compressionLevel != nil {
+		opts[string(exptypes.OptKeyCompressionLevel)] = strconv.Itoa(*compressionLevel)
+	}
+	for k, v := range annotationOpts(container, platformVariants) {
+		opts[k] = v
+	}
+	if sourceDateEpoch != nil {
+		opts[string(exptypes.OptKeySourceDateEpoch)] = strconv.Itoa(*sourceDateEpoch)
+		opts[string(exptypes.OptKeyRewriteTimestamp)] = strconv.FormatBool(true)
+	}
 
 	detach, _, err := svcs.StartBindings(ctx, services)
 	if err != nil {
@@ -1198,7 +1556,9 @@ func (container *Container) AsTarball(
 	ctx context.Context,
 	platformVariants []*Container,
 	forcedCompression ImageLayerCompression,
+	compressionLevel *int,
 	mediaTypes ImageMediaTypes,
+	sourceDateEpoch *int,
 ) (*File, error) {
 	bk := container.Query.Buildkit
 	svcs := container.Query.Services
@@ -1246,6 +1606,16 @@ func (container *Container) AsTarball(
 		opts[string(exptypes.OptKeyLayerCompression)] = strings.ToLower(string(forcedCompression))
 		opts[string(exptypes.OptKeyForceCompression)] = strconv.FormatBool(true)
 	}
+	if compressionLevel != nil {
+		opts[string(exptypes.OptKeyCompressionLevel)] = strconv.Itoa(*compressionLevel)
+	}
+	for k, v := range annotationOpts(container, platformVariants) {
+		opts[k] = v
+	}
+	if sourceDateEpoch != nil {
+		opts[string(exptypes.OptKeySourceDateEpoch)] = strconv.Itoa(*sourceDateEpoch)
+		opts[string(exptypes.OptKeyRewriteTimestamp)] = strconv.FormatBool(true)
+	}
 
 	detach, _, err := svcs.StartBindings(ctx, services)
 	if err != nil {
@@ -1614,3 +1984,35 @@ func (proto ImageMediaTypes) Decoder() dagql.InputDecoder {
 func (proto ImageMediaTypes) ToLiteral() call.Literal {
 	return ImageMediaTypesEnum.Literal(proto)
 }
+
+type ImagePullPolicy string
+
+var ImagePullPolicies = dagql.NewEnum[ImagePullPolicy]()
+
+var (
+	PullPolicyAlways = ImagePullPolicies.Register("ALWAYS",
+		"Always resolve the address against the registry and pull the image.")
+	PullPolicyIfNotPresent = ImagePullPolicies.Register("IF_NOT_PRESENT",
+		"Resolve the address against the registry and only pull missing layers.")
+	PullPolicyNever = ImagePullPolicies.Register("NEVER",
+		"Never contact the registry, failing if the image isn't already in the engine.")
+)
+
+func (policy ImagePullPolicy) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "ImagePullPolicy",
+		NonNull:   true,
+	}
+}
+
+func (policy ImagePullPolicy) TypeDescription() string {
+	return "Policy to follow when pulling an image."
+}
+
+func (policy ImagePullPolicy) Decoder() dagql.InputDecoder {
+	return ImagePullPolicies
+}
+
+func (policy ImagePullPolicy) ToLiteral() call.Literal {
+	return ImagePullPolicies.Literal(policy)
+}
@@ -3,16 +3,19 @@ package core
 import (
 	"context"
 	"fmt"
-	"os"
+	"net"
 	"path"
 	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/dagger/dagger/dagql"
 	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/engine/buildkit"
 	"github.com/moby/buildkit/client/llb"
 	"github.com/moby/buildkit/identity"
+	"github.com/moby/buildkit/solver/pb"
 	"github.com/pkg/errors"
 )
 
@@ -39,6 +42,30 @@ type ContainerExecOpts struct {
 	// Grant the process all root capabilities
 	InsecureRootCapabilities bool `default:"false"`
 
+	// Execute the command again rather than using a cached result
+	NoCache bool `default:"false"`
+
+	// Return the container even if the command exits with a non-zero code
+	AllowFailure bool `default:"false"`
+
+	// Number of CPUs the command may use, e.g. 1.5 (0 for no limit)
+	CPUs float64 `name:"cpus" default:"0"`
+
+	// Memory the command may use in bytes (0 for no limit)
+	MemoryBytes int `default:"0"`
+
+	// Bytes of stdout and stderr to capture (0 for the session's default)
+	MaxOutputBytes int `default:"0"`
+
+	// Run the command without network access
+	NoNetwork bool `default:"false"`
+
+	// Mount the root filesystem read-only, so that only mounts are writable
+	ReadOnlyRootfs bool `default:"false"`
+
+	// Matchers extracting diagnostics from the command's output
+	ProblemMatchers []ProblemMatcher `name:"-"`
+
 	// (Internal-only) If this is a nested exec, exec metadata to use for it
 	NestedExecMetadata *buildkit.ExecutionMetadata `name:"-"`
 }
@@ -58,6 +85,12 @@ func (container *Container) WithExec(ctx context.Context, opts ContainerExecOpts
 		return nil, err
 	}
 
+	for _, matcher := range opts.ProblemMatchers {
+		if _, err := matcher.Compile(); err != nil {
+			return nil, err
+		}
+	}
+
 	spanName := fmt.Sprintf("exec %s", strings.Join(args, " "))
 
 	runOpts := []llb.RunOption{
@@ -81,11 +114,48 @@ func (container *Container) WithExec(ctx context.Context, opts ContainerExecOpts
 	execMD.RedirectStderrPath = opts.RedirectStderr
 	execMD.SystemEnvNames = container.SystemEnvNames
 	execMD.EnabledGPUs = container.EnabledGPUs
+	execMD.Devices = container.Devices
+	execMD.AllowFailure = opts.AllowFailure
+	execMD.DNSNameservers = container.DNSNameservers
+	execMD.DNSSearchDomains = container.DNSSearchDomains
+	execMD.ReadOnlyRootfs = opts.ReadOnlyRootfs
+
+	if opts.CPUs < 0 {
+		return nil, fmt.Errorf("invalid cpus %v: must not be negative", opts.CPUs)
+	}
+	if opts.MemoryBytes < 0 {
+		return nil, fmt.Errorf("invalid memoryBytes %d: must not be negative", opts.MemoryBytes)
+	}
+	execMD.CPUs = opts.CPUs
+	execMD.MemoryBytes = int64(opts.MemoryBytes)
+
+	if opts.MaxOutputBytes < 0 {
+		return nil, fmt.Errorf("invalid maxOutputBytes %d: must not be negative", opts.MaxOutputBytes)
+	}
+	execMD.MaxOutputBytes = container.Query.Buildkit.ExecOutputLimit(int64(opts.MaxOutputBytes))
 
 	// if GPU parameters are set for this container pass them over:
 	if len(execMD.EnabledGPUs) > 0 {
-		if gpuSupportEnabled := os.Getenv("_EXPERIMENTAL_DAGGER_GPU_SUPPORT"); gpuSupportEnabled == "" {
-			return nil, fmt.Errorf("GPU support is not enabled, set _EXPERIMENTAL_DAGGER_GPU_SUPPORT")
+		if err := container.Query.RequireCapability(ctx, EngineCapabilityGPU); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(execMD.Devices) > 0 {
+		if err := container.Query.RequireCapability(ctx, EngineCapabilityDevices); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.InsecureRootCapabilities {
+		if err := container.Query.RequireCapability(ctx, EngineCapabilityPrivileged); err != nil {
+			return nil, err
+		}
+	}
+
+	if platform.OS == "windows" {
+		if err := container.Query.RequireCapability(ctx, EngineCapabilityWindows); err != nil {
+			return nil, err
 		}
 	}
 
@@ -115,6 +185,57 @@ func (container *Container) WithExec(ctx context.Context, opts ContainerExecOpts
 		}
 	}
 
+	if opts.NoCache {
+		// a unique value busts the cache of this exec, and of everything that
+		// depends on it
+		runOpts = append(runOpts, llb.AddEnv(buildkit.DaggerCacheBusterEnv, identity.NewID()))
+	}
+
+	if opts.NoNetwork {
+		if opts.ExperimentalPrivilegedNesting {
+			return nil, fmt.Errorf("noNetwork is incompatible with experimentalPrivilegedNesting, which requires network access to the Dagger API")
+		}
+		// only a loopback interface, which also keeps the result's cache
+		// separate from networked execs
+		runOpts = append(runOpts, llb.Network(pb.NetMode_NONE))
+	}
+
+	if opts.ReadOnlyRootfs {
+		// the root filesystem is made read-only by the executor, so scope the
+		// cache to it explicitly
+		runOpts = append(runOpts, llb.AddEnv(buildkit.DaggerReadOnlyRootfsEnv, "1"))
+	}
+
+	if execMD.MaxOutputBytes > 0 {
+		// the output is truncated by the executor, so scope the cache to the
+		// limit explicitly
+		runOpts = append(runOpts, llb.AddEnv(buildkit.DaggerMaxOutputBytesEnv, strconv.FormatInt(execMD.MaxOutputBytes, 10)))
+	}
+
+	if len(execMD.Devices) > 0 {
+		// the devices are exposed by the executor, so scope the cache to them
+		// explicitly, sorted since their order doesn't matter
+		devices := slices.Clone(execMD.Devices)
+		slices.Sort(devices)
+		runOpts = append(runOpts, llb.AddEnv(buildkit.DaggerDevicesEnv, strings.Join(devices, ",")))
+	}
+
+	if len(execMD.DNSNameservers) > 0 || len(execMD.DNSSearchDomains) > 0 {
+		// resolv.conf is written by the executor, so scope the cache to the DNS
+		// config explicitly, in order since it's the resolver's order too
+		runOpts = append(runOpts, llb.AddEnv(buildkit.DaggerDNSEnv,
+			strings.Join(execMD.DNSNameservers, ",")+";"+strings.Join(execMD.DNSSearchDomains, ",")))
+	}
+
+	for _, h := range container.ExtraHosts {
+		runOpts = append(runOpts, llb.AddExtraHost(h.Host, net.ParseIP(h.IP)))
+	}
+
+	if opts.AllowFailure {
+		// ensure a failed result isn't reused by an exec expecting success
+		runOpts = append(runOpts, llb.AddEnv(buildkit.DaggerAllowFailureEnv, "1"))
+	}
+
 	runOpts = append(runOpts, llb.WithCustomName(spanName))
 
 	metaSt, metaSourcePath := metaMount(opts.Stdin)
@@ -155,7 +276,15 @@ func (container *Container) WithExec(ctx context.Context, opts ContainerExecOpts
 		runOpts = append(runOpts, llb.Dir(cfg.WorkingDir))
 	}
 
-	for _, env := range cfg.Env {
+	// sort the env by name so that logically identical environments built up
+	// in a different order produce the same exec, and thus share its cache
+	env := slices.Clone(cfg.Env)
+	slices.SortStableFunc(env, func(a, b string) int {
+		aName, _, _ := strings.Cut(a, "=")
+		bName, _, _ := strings.Cut(b, "=")
+		return strings.Compare(aName, bName)
+	})
+	for _, env := range env {
 		name, val, ok := strings.Cut(env, "=")
 		if !ok {
 			// it's OK to not be OK
@@ -287,6 +416,7 @@ func (container *Container) WithExec(ctx context.Context, opts ContainerExecOpts
 	}
 
 	container.Meta = metaDef.ToPB()
+	container.ProblemMatchers = opts.ProblemMatchers
 
 	for i, mnt := range mounts {
 		if mnt.Tmpfs || mnt.CacheVolumeID != "" {
@@ -312,13 +442,26 @@ func (container *Container) WithExec(ctx context.Context, opts ContainerExecOpts
 	return container, nil
 }
 
+// MetaFileContents returns the contents of a file written by the last executed
+// command to the meta mount, e.g. its stdout, as a valid UTF-8 string in which
+// invalid bytes are replaced with the Unicode replacement character.
 func (container *Container) MetaFileContents(ctx context.Context, filePath string) (string, error) {
+	content, err := container.MetaFileBytes(ctx, filePath)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToValidUTF8(string(content), string(utf8.RuneError)), nil
+}
+
+// MetaFileBytes returns the raw contents of a file written by the last
+// executed command to the meta mount.
+func (container *Container) MetaFileBytes(ctx context.Context, filePath string) ([]byte, error) {
 	if container.Meta == nil {
 		ctr, err := container.WithExec(ctx, ContainerExecOpts{})
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		return ctr.MetaFileContents(ctx, filePath)
+		return ctr.MetaFileBytes(ctx, filePath)
 	}
 
 	file := NewFile(
@@ -329,12 +472,196 @@ func (container *Container) MetaFileContents(ctx context.Context, filePath strin
 		container.Services,
 	)
 
-	content, err := file.Contents(ctx)
+	return file.Contents(ctx)
+}
+
+// lostOutputMarker replaces followed output that was dropped because the client
+// fell behind.
+const lostOutputMarker = "\n[%d bytes of output lost]\n"
+
+// FollowOutput runs the last executed command and sends what it writes to its
+// stdout or stderr, depending on the given meta mount path, as it's written.
+//
+// If the command doesn't run because it's cached, its whole output is sent at
+// once when it's loaded instead. Output dropped because the client fell behind
+// is replaced by a line saying how much was lost.
+func (container *Container) FollowOutput(ctx context.Context, filePath string, send func(string) error) error {
+	if container.Meta == nil {
+		ctr, err := container.WithExec(ctx, ContainerExecOpts{})
+		if err != nil {
+			return err
+		}
+		return ctr.FollowOutput(ctx, filePath, send)
+	}
+
+	var fd int
+	switch filePath {
+	case buildkit.MetaMountStdoutPath:
+		fd = 1
+	case buildkit.MetaMountStderrPath:
+		fd = 2
+	default:
+		return fmt.Errorf("cannot follow output file %q", filePath)
+	}
+
+	dgst, err := buildkit.ExecDigest(container.Meta)
 	if err != nil {
-		return "", err
+		return err
+	}
+	sub := container.Query.Buildkit.FollowExecOutput(dgst)
+	defer sub.Close()
+	outputs := sub.Output()
+
+	type result struct {
+		contents string
+		err      error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan result, 1)
+	go func() {
+		contents, err := container.MetaFileContents(ctx, filePath)
+		done <- result{contents, err}
+	}()
+
+	// chunks can split multi-byte characters, so incomplete ones are held
+	// until the rest is written
+	var followed bool
+	var pending []byte
+	sendLost := func(lost int) error {
+		if len(pending) > 0 {
+			if err := send(strings.ToValidUTF8(string(pending), string(utf8.RuneError))); err != nil {
+				return err
+			}
+			pending = nil
+		}
+		return send(fmt.Sprintf(lostOutputMarker, lost))
+	}
+	sendOutput := func(out buildkit.ExecOutput) error {
+		if out.Fd != fd {
+			return nil
+		}
+		followed = true
+		if out.Lost > 0 {
+			if err := sendLost(out.Lost); err != nil {
+				return err
+			}
+		}
+		data := slices.Concat(pending, out.Data)
+		pending = nil
+		for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				if !utf8.FullRune(data[i:]) {
+					pending = data[i:]
+					data = data[:i]
+				}
+				break
+			}
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		return send(strings.ToValidUTF8(string(data), string(utf8.RuneError)))
+	}
+
+	for {
+		select {
+		case out := <-outputs:
+			if err := sendOutput(out); err != nil {
+				return err
+			}
+		case res := <-done:
+			if res.err != nil {
+				return res.err
+			}
+			// the command is over, so all of its output has been published
+			for len(outputs) > 0 {
+				if err := sendOutput(<-outputs); err != nil {
+					return err
+				}
+			}
+			if !followed {
+				if res.contents == "" {
+					return nil
+				}
+				return send(res.contents)
+			}
+			if lost := sub.Lost(fd); lost > 0 {
+				return sendLost(lost)
+			}
+			if len(pending) > 0 {
+				return send(strings.ToValidUTF8(string(pending), string(utf8.RuneError)))
+			}
+			return nil
+		}
+	}
+}
+
+// ExitCode returns the exit code of the last executed command, which is only
+// non-zero if it was executed with AllowFailure.
+func (container *Container) ExitCode(ctx context.Context) (int, error) {
+	if container.Meta == nil {
+		ctr, err := container.WithExec(ctx, ContainerExecOpts{})
+		if err != nil {
+			return 0, err
+		}
+		return ctr.ExitCode(ctx)
+	}
+
+	// the exit code is only recorded when the command fails
+	meta := NewDirectory(
+		container.Query,
+		container.Meta,
+		buildkit.MetaMountDestPath,
+		container.Platform,
+		container.Services,
+	)
+	entries, err := meta.Entries(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(entries, buildkit.MetaMountExitCodePath) {
+		return 0, nil
+	}
+
+	content, err := container.MetaFileContents(ctx, buildkit.MetaMountExitCodePath)
+	if err != nil {
+		return 0, err
+	}
+	exitCode, err := strconv.Atoi(strings.TrimSpace(content))
+	if err != nil {
+		return 0, fmt.Errorf("invalid exit code %q: %w", content, err)
+	}
+	return exitCode, nil
+}
+
+// Diagnostics returns the diagnostics extracted from the output of the last
+// executed command by its problem matchers.
+func (container *Container) Diagnostics(ctx context.Context) ([]Diagnostic, error) {
+	if container.Meta == nil {
+		ctr, err := container.WithExec(ctx, ContainerExecOpts{})
+		if err != nil {
+			return nil, err
+		}
+		return ctr.Diagnostics(ctx)
+	}
+	if len(container.ProblemMatchers) == 0 {
+		return []Diagnostic{}, nil
 	}
 
-	return string(content), nil
+	diagnostics := []Diagnostic{}
+	for _, filePath := range []string{buildkit.MetaMountStdoutPath, buildkit.MetaMountStderrPath} {
+		output, err := container.MetaFileContents(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+		found, err := MatchProblems(container.ProblemMatchers, output)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, found...)
+	}
+	return diagnostics, nil
 }
 
 func metaMount(stdin string) (llb.State, string) {
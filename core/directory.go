@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	containerdfs "github.com/containerd/continuity/fs"
 	"github.com/moby/buildkit/client/llb"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
 	"github.com/moby/buildkit/solver/pb"
@@ -179,6 +180,12 @@ func (dir *Directory) Evaluate(ctx context.Context) (*buildkit.Result, error) {
 	})
 }
 
+// IsCached reports whether the execs the directory is made of are already
+// cached, without running them.
+func (dir *Directory) IsCached(ctx context.Context) (bool, error) {
+	return dir.Query.Buildkit.IsCached(ctx, dir.LLB)
+}
+
 func (dir *Directory) Stat(ctx context.Context, bk *buildkit.Client, svcs *Services, src string) (*fstypes.Stat, error) {
 	src = path.Join(dir.Dir, src)
 
@@ -334,6 +341,48 @@ func (dir *Directory) Glob(ctx context.Context, src string, pattern string) ([]s
 	return paths, nil
 }
 
+// GitVersion computes version information from the git repository at src
+// within the directory, which must include its .git directory.
+func (dir *Directory) GitVersion(ctx context.Context, src string, prefix string) (*GitVersion, error) {
+	svcs := dir.Query.Services
+	bk := dir.Query.Buildkit
+
+	detach, _, err := svcs.StartBindings(ctx, dir.Services)
+	if err != nil {
+		return nil, err
+	}
+	defer detach()
+
+	res, err := bk.Solve(ctx, bkgw.SolveRequest{
+		Definition: dir.LLB,
+		Evaluate:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ref, err := res.SingleRef()
+	if err != nil {
+		return nil, err
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("%s: not a git repository", src)
+	}
+
+	var version *GitVersion
+	err = ref.WithLocalMount(ctx, func(root string) error {
+		repoPath, err := containerdfs.RootPath(root, path.Join(dir.Dir, src))
+		if err != nil {
+			return err
+		}
+		version, err = ComputeGitVersion(repoPath, prefix)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
 func (dir *Directory) WithNewFile(ctx context.Context, dest string, content []byte, permissions fs.FileMode, ownership *Ownership) (*Directory, error) {
 	dir = dir.Clone()
 
@@ -374,6 +423,48 @@ func (dir *Directory) WithNewFile(ctx context.Context, dest string, content []by
 	return dir, nil
 }
 
+func (dir *Directory) WithSymlink(ctx context.Context, target, linkName string) (*Directory, error) {
+	dir = dir.Clone()
+
+	err := validateFileName(linkName)
+	if err != nil {
+		return nil, err
+	}
+
+	linkName = path.Clean(linkName)
+	if linkName == ".." || strings.HasPrefix(linkName, "../") {
+		return nil, fmt.Errorf("cannot create symlink outside parent: %s", linkName)
+	}
+
+	// be sure to create the symlink under the working directory
+	dest := path.Join(dir.Dir, linkName)
+
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+
+	linkDef, _, err := dir.Query.Buildkit.SymlinkToBlob(ctx, path.Base(dest), target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create symlink blob: %w", err)
+	}
+	linkSt, err := defToState(linkDef)
+	if err != nil {
+		return nil, err
+	}
+
+	st = st.File(llb.Copy(linkSt, path.Base(dest), dest, &llb.CopyInfo{
+		CreateDestPath: true,
+	}))
+
+	err = dir.SetState(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+
+	return dir, nil
+}
+
 func (dir *Directory) Directory(ctx context.Context, subdir string) (*Directory, error) {
 	dir = dir.Clone()
 
@@ -614,7 +705,7 @@ func (dir *Directory) WithTimestamps(ctx context.Context, unix int) (*Directory,
 	return dir, nil
 }
 
-func (dir *Directory) WithNewDirectory(ctx context.Context, dest string, permissions fs.FileMode) (*Directory, error) {
+func (dir *Directory) WithNewDirectory(ctx context.Context, dest string, permissions fs.FileMode, ownership *Ownership) (*Directory, error) {
 	dir = dir.Clone()
 
 	dest = path.Clean(dest)
@@ -634,7 +725,79 @@ func (dir *Directory) WithNewDirectory(ctx context.Context, dest string, permiss
 		permissions = 0755
 	}
 
-	st = st.File(llb.Mkdir(dest, permissions, llb.WithParents(true)))
+	opts := []llb.MkdirOption{llb.WithParents(true)}
+	if ownership != nil {
+		opts = append(opts, ownership.Opt())
+	}
+
+	st = st.File(llb.Mkdir(dest, permissions, opts...))
+
+	err = dir.SetState(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+
+	return dir, nil
+}
+
+func (dir *Directory) WithPermissions(ctx context.Context, target string, permissions fs.FileMode) (*Directory, error) {
+	return dir.copyInPlace(ctx, target, &llb.CopyInfo{
+		Mode: &permissions,
+	})
+}
+
+func (dir *Directory) WithOwner(ctx context.Context, target string, owner string) (*Directory, error) {
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+
+	ownership, err := resolveUIDGID(ctx, st, dir.Query.Buildkit, dir.Platform, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	copyInfo := &llb.CopyInfo{}
+	ownership.Opt().SetCopyOption(copyInfo)
+	return dir.copyInPlace(ctx, target, copyInfo)
+}
+
+// copyInPlace replaces the entry at target with a copy of itself made with
+// the given options, which applies them recursively like chmod -R or chown -R.
+func (dir *Directory) copyInPlace(ctx context.Context, target string, copyInfo *llb.CopyInfo) (*Directory, error) {
+	dir = dir.Clone()
+
+	target = path.Clean(target)
+	if target == ".." || strings.HasPrefix(target, "../") {
+		return nil, fmt.Errorf("cannot modify path outside parent: %s", target)
+	}
+
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+
+	if target == "." || target == "/" {
+		copyInfo.CopyDirContentsOnly = true
+		st = llb.Scratch().File(llb.Copy(st, dir.Dir, ".", copyInfo))
+
+		err = dir.SetState(ctx, st)
+		if err != nil {
+			return nil, err
+		}
+
+		dir.Dir = ""
+
+		return dir, nil
+	}
+
+	// be sure to modify the entry under the working directory
+	target = path.Join(dir.Dir, target)
+
+	copyInfo.CreateDestPath = true
+	st = st.
+		File(llb.Rm(target)).
+		File(llb.Copy(st, target, target, copyInfo))
 
 	err = dir.SetState(ctx, st)
 	if err != nil {
@@ -695,6 +858,33 @@ func (dir *Directory) Without(ctx context.Context, path string) (*Directory, err
 	return dir, nil
 }
 
+func (dir *Directory) WithoutEntries(ctx context.Context, patterns []string) (*Directory, error) {
+	dir = dir.Clone()
+
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+
+	// llb.Rm only supports single-level wildcards, so copy everything except
+	// the matching entries to scratch instead
+	st = llb.Scratch().File(
+		llb.Copy(st, dir.Dir, ".", &llb.CopyInfo{
+			CopyDirContentsOnly: true,
+			ExcludePatterns:     patterns,
+		}),
+	)
+
+	err = dir.SetState(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+
+	dir.Dir = ""
+
+	return dir, nil
+}
+
 func (dir *Directory) Export(ctx context.Context, destPath string, merge bool) (rerr error) {
 	svcs := dir.Query.Services
 	bk := dir.Query.Buildkit
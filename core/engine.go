@@ -0,0 +1,172 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	bkclient "github.com/moby/buildkit/client"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+type Engine struct {
+	Query *Query
+}
+
+func (*Engine) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "Engine",
+		NonNull:   true,
+	}
+}
+
+func (*Engine) TypeDescription() string {
+	return "The Dagger engine serving the current session."
+}
+
+// BuildkitVersion returns the version of the Buildkit module embedded in the
+// engine, or "unknown" if the engine was built without module information.
+func (*Engine) BuildkitVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != "github.com/moby/buildkit" {
+			continue
+		}
+		if dep.Replace != nil {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return "unknown"
+}
+
+type CachePruneResult struct {
+	ReclaimedBytes int `field:"true" doc:"The disk space reclaimed, in bytes."`
+	PrunedRecords  int `field:"true" doc:"The number of cache records removed."`
+}
+
+func (CachePruneResult) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "CachePruneResult",
+		NonNull:   true,
+	}
+}
+
+func (CachePruneResult) TypeDescription() string {
+	return "The outcome of pruning the cache of an engine."
+}
+
+// Prune removes the cache records of the engine that match the filters and
+// are not in use, until the cache fits in keepBytes. The cache is shared by
+// every session, so only admins of the engine can prune it.
+func (engine *Engine) Prune(ctx context.Context, filters []string, keepBytes int64, keepDuration time.Duration, all bool) (CachePruneResult, error) {
+	if err := engine.Query.CheckEngineAdmin(ctx); err != nil {
+		return CachePruneResult{}, fmt.Errorf("prune: %w", err)
+	}
+	pruned, err := engine.Query.Buildkit.PruneCache(ctx, bkclient.PruneInfo{
+		Filter:       filters,
+		All:          all,
+		KeepBytes:    keepBytes,
+		KeepDuration: keepDuration,
+	})
+	if err != nil {
+		return CachePruneResult{}, err
+	}
+	result := CachePruneResult{PrunedRecords: len(pruned)}
+	for _, record := range pruned {
+		result.ReclaimedBytes += int(record.Size)
+	}
+	return result, nil
+}
+
+type CacheUsage struct {
+	RecordType       string `field:"true" doc:"The type of the records (e.g., \"regular\" for image layers and exec results, \"exec.cachemount\" for cache volumes, \"source.local\" for host directories, \"source.git.checkout\" for git checkouts)."`
+	Records          int    `field:"true" doc:"The number of records of this type."`
+	SizeBytes        int    `field:"true" doc:"The disk space used by records of this type, in bytes."`
+	ReclaimableBytes int    `field:"true" doc:"The disk space used by records of this type that are not in use, and could be pruned, in bytes."`
+}
+
+func (CacheUsage) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "CacheUsage",
+		NonNull:   true,
+	}
+}
+
+func (CacheUsage) TypeDescription() string {
+	return "The disk usage of the cache records of an engine of one type."
+}
+
+// CacheUsage returns the disk usage of the cache of the engine, by record
+// type, from the largest to the smallest.
+func (engine *Engine) CacheUsage(ctx context.Context) ([]CacheUsage, error) {
+	records, err := engine.Query.Buildkit.CacheUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byType := map[string]*CacheUsage{}
+	for _, record := range records {
+		usage, ok := byType[string(record.RecordType)]
+		if !ok {
+			usage = &CacheUsage{RecordType: string(record.RecordType)}
+			byType[usage.RecordType] = usage
+		}
+		usage.Records++
+		usage.SizeBytes += int(record.Size)
+		if !record.InUse {
+			usage.ReclaimableBytes += int(record.Size)
+		}
+	}
+	usages := make([]CacheUsage, 0, len(byType))
+	for _, usage := range byType {
+		usages = append(usages, *usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].SizeBytes != usages[j].SizeBytes {
+			return usages[i].SizeBytes > usages[j].SizeBytes
+		}
+		return usages[i].RecordType < usages[j].RecordType
+	})
+	return usages, nil
+}
+
+type EngineSession struct {
+	SessionID      string   `field:"true" name:"sessionID" doc:"The ID of the session."`
+	ClientHostname string   `field:"true" doc:"The hostname of the client that started the session."`
+	Services       []string `field:"true" doc:"The hostnames of the services running in the session."`
+	LastHeartbeat  string   `field:"true" doc:"When the client last sent a heartbeat, in RFC 3339 format, or an empty string if it does not send heartbeats."`
+	Stale          bool     `field:"true" doc:"Whether the client stopped sending heartbeats, in which case the session is about to be removed."`
+	Current        bool     `field:"true" doc:"Whether this is the current session."`
+}
+
+func (EngineSession) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "EngineSession",
+		NonNull:   true,
+	}
+}
+
+func (EngineSession) TypeDescription() string {
+	return "A session of a Dagger engine, holding the state of a client and its services."
+}
+
+// Sessions returns the sessions of the engine.
+func (engine *Engine) Sessions(ctx context.Context) ([]EngineSession, error) {
+	return engine.Query.EngineSessions(ctx)
+}
+
+// Ping checks that the engine is healthy.
+func (engine *Engine) Ping(ctx context.Context) error {
+	return engine.Query.EngineHealth(ctx)
+}
+
+// RemoveSession forcibly removes a session of the engine, stopping its
+// services and releasing its containers.
+func (engine *Engine) RemoveSession(ctx context.Context, sessionID string) error {
+	return engine.Query.RemoveEngineSession(ctx, sessionID)
+}
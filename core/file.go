@@ -11,12 +11,15 @@ import (
 	"github.com/moby/buildkit/client/llb"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
 	"github.com/moby/buildkit/solver/pb"
+	"github.com/opencontainers/go-digest"
 	fstypes "github.com/tonistiigi/fsutil/types"
 	"github.com/vektah/gqlparser/v2/ast"
 
 	"dagger.io/dagger/telemetry"
 	"github.com/dagger/dagger/core/pipeline"
 	"github.com/dagger/dagger/core/reffs"
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/dagql/call"
 	"github.com/dagger/dagger/engine/buildkit"
 )
 
@@ -133,6 +136,12 @@ func (file *File) Evaluate(ctx context.Context) (*buildkit.Result, error) {
 	})
 }
 
+// IsCached reports whether the execs the file is made of are already cached,
+// without running them.
+func (file *File) IsCached(ctx context.Context) (bool, error) {
+	return file.Query.Buildkit.IsCached(ctx, file.LLB)
+}
+
 // Contents handles file content retrieval
 func (file *File) Contents(ctx context.Context) ([]byte, error) {
 	svcs := file.Query.Services
@@ -207,6 +216,23 @@ func (file *File) Stat(ctx context.Context) (*fstypes.Stat, error) {
 	})
 }
 
+// Digest computes the digest of the file contents engine-side, without
+// transferring them to the client.
+func (file *File) Digest(ctx context.Context, algorithm DigestAlgorithm) (digest.Digest, error) {
+	alg, err := algorithm.Algorithm()
+	if err != nil {
+		return "", err
+	}
+
+	r, err := file.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	return alg.FromReader(r)
+}
+
 func (file *File) WithName(ctx context.Context, filename string) (*File, error) {
 	// Clone the file
 	file = file.Clone()
@@ -316,3 +342,44 @@ func bkRef(ctx context.Context, bk *buildkit.Client, def *pb.Definition) (bkgw.R
 
 	return ref, nil
 }
+
+// DigestAlgorithm is a GraphQL enum type.
+type DigestAlgorithm string
+
+var DigestAlgorithms = dagql.NewEnum[DigestAlgorithm]()
+
+var (
+	DigestAlgorithmSHA256 = DigestAlgorithms.Register("SHA256")
+	DigestAlgorithmSHA512 = DigestAlgorithms.Register("SHA512")
+)
+
+func (alg DigestAlgorithm) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "DigestAlgorithm",
+		NonNull:   true,
+	}
+}
+
+func (alg DigestAlgorithm) TypeDescription() string {
+	return "Hash algorithm used to compute the digest of a file."
+}
+
+func (alg DigestAlgorithm) Decoder() dagql.InputDecoder {
+	return DigestAlgorithms
+}
+
+func (alg DigestAlgorithm) ToLiteral() call.Literal {
+	return DigestAlgorithms.Literal(alg)
+}
+
+// Algorithm returns the corresponding go-digest algorithm.
+func (alg DigestAlgorithm) Algorithm() (digest.Algorithm, error) {
+	switch alg {
+	case DigestAlgorithmSHA256:
+		return digest.SHA256, nil
+	case DigestAlgorithmSHA512:
+		return digest.SHA512, nil
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %q", alg)
+	}
+}
@@ -26,6 +26,10 @@ type GitRepository struct {
 
 	AuthToken  *Secret `json:"authToken"`
 	AuthHeader *Secret `json:"authHeader"`
+
+	// Username to pair with AuthToken, if the remote expects something other
+	// than the default (e.g. "gitlab-ci-token" for GitLab CI job tokens).
+	AuthUsername string `json:"authUsername,omitempty"`
 }
 
 func (*GitRepository) Type() *ast.Type {
@@ -57,8 +61,11 @@ func (*GitRef) TypeDescription() string {
 	return "A git ref (tag, branch, or commit)."
 }
 
-func (ref *GitRef) Tree(ctx context.Context) (*Directory, error) {
-	st, err := ref.getState(ctx)
+// Tree returns the filesystem tree at the ref. If the repository keeps its
+// .git directory, depth is the number of commits of history to include, with
+// zero meaning the full history.
+func (ref *GitRef) Tree(ctx context.Context, depth int) (*Directory, error) {
+	st, err := ref.getState(ctx, depth)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +74,7 @@ func (ref *GitRef) Tree(ctx context.Context) (*Directory, error) {
 
 func (ref *GitRef) Commit(ctx context.Context) (string, error) {
 	bk := ref.Query.Buildkit
-	st, err := ref.getState(ctx)
+	st, err := ref.getState(ctx, 1)
 	if err != nil {
 		return "", err
 	}
@@ -81,11 +88,14 @@ func (ref *GitRef) Commit(ctx context.Context) (string, error) {
 	return p.Sources.Git[0].Commit, nil
 }
 
-func (ref *GitRef) getState(ctx context.Context) (llb.State, error) {
+func (ref *GitRef) getState(ctx context.Context, depth int) (llb.State, error) {
 	opts := []llb.GitOption{}
 
 	if ref.Repo.KeepGitDir {
 		opts = append(opts, llb.KeepGitDir())
+		if depth != 1 {
+			opts = append(opts, gitdns.Depth(depth))
+		}
 	}
 	if ref.Repo.SSHKnownHosts != "" {
 		opts = append(opts, llb.KnownSSHHosts(ref.Repo.SSHKnownHosts))
@@ -95,6 +105,9 @@ func (ref *GitRef) getState(ctx context.Context) (llb.State, error) {
 	}
 	if ref.Repo.AuthToken != nil {
 		opts = append(opts, llb.AuthTokenSecret(ref.Repo.AuthToken.Accessor))
+		if ref.Repo.AuthUsername != "" {
+			opts = append(opts, gitdns.AuthUsername(ref.Repo.AuthUsername))
+		}
 	}
 	if ref.Repo.AuthHeader != nil {
 		opts = append(opts, llb.AuthHeaderSecret(ref.Repo.AuthHeader.Accessor))
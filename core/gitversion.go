@@ -0,0 +1,270 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/vektah/gqlparser/v2/ast"
+	"golang.org/x/mod/semver"
+)
+
+// GitVersion is version information derived from the tags and history of a
+// git repository.
+type GitVersion struct {
+	Commit   string `field:"true" doc:"The commit checked out in the repository."`
+	Tag      string `field:"true" doc:"The nearest semver tag reachable from the commit, or an empty string if there is none."`
+	Version  string `field:"true" doc:"The version of the nearest tag, without its prefix (e.g., \"1.2.3\"). \"0.0.0\" if there is no tag."`
+	Distance int    `field:"true" doc:"The number of commits between the nearest tag and the commit."`
+	Describe string `field:"true" doc:"A description of the commit in the style of \"git describe --tags\" (e.g., \"v1.2.3-4-gabcdef0\")."`
+	Next     string `field:"true" doc:"The next version according to the conventional commits since the nearest tag, without prefix. Equal to version if the commit is tagged."`
+}
+
+func (GitVersion) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "GitVersion",
+		NonNull:   true,
+	}
+}
+
+func (GitVersion) TypeDescription() string {
+	return "Version information derived from the tags and history of a git repository."
+}
+
+// ComputeGitVersion computes version information for the HEAD of the git
+// repository at repoPath, considering only tags that are semver versions
+// once the given prefix is removed.
+func ComputeGitVersion(repoPath string, prefix string) (*GitVersion, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	tagged, err := semverTags(repo, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// find the nearest tagged commit, breadth first like git describe
+	var tagCommit plumbing.Hash
+	var tag string
+	seen := map[plumbing.Hash]struct{}{}
+	queue := []plumbing.Hash{head.Hash()}
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+		if name, ok := tagged[hash]; ok {
+			tagCommit, tag = hash, name
+			break
+		}
+		commit, err := lookupCommit(repo, hash)
+		if err != nil {
+			return nil, err
+		}
+		if commit != nil {
+			queue = append(queue, commit.ParentHashes...)
+		}
+	}
+
+	version := &GitVersion{
+		Commit:  head.Hash().String(),
+		Tag:     tag,
+		Version: "0.0.0",
+	}
+	if tag != "" {
+		version.Version = strings.TrimPrefix(tag, prefix)
+	}
+
+	// collect the commits that are not part of the tag
+	var tagAncestors map[plumbing.Hash]struct{}
+	if tag != "" {
+		tagAncestors, err = ancestors(repo, tagCommit, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	bump := bumpNone
+	since, err := ancestors(repo, head.Hash(), tagAncestors)
+	if err != nil {
+		return nil, err
+	}
+	for hash := range since {
+		commit, err := lookupCommit(repo, hash)
+		if err != nil {
+			return nil, err
+		}
+		if commit != nil {
+			bump = max(bump, conventionalBump(commit.Message))
+		}
+	}
+	version.Distance = len(since)
+
+	short := version.Commit[:7]
+	switch {
+	case tag == "":
+		version.Describe = short
+	case version.Distance == 0:
+		version.Describe = tag
+	default:
+		version.Describe = fmt.Sprintf("%s-%d-g%s", tag, version.Distance, short)
+	}
+
+	version.Next = version.Version
+	if version.Distance > 0 {
+		version.Next = nextVersion(version.Version, max(bump, bumpPatch))
+	}
+	return version, nil
+}
+
+// semverTags returns the highest semver tag pointing to each tagged commit.
+func semverTags(repo *git.Repository, prefix string) (map[plumbing.Hash]string, error) {
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	tagged := map[plumbing.Hash]string{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		if !strings.HasPrefix(name, prefix) || !semver.IsValid("v"+strings.TrimPrefix(name, prefix)) {
+			return nil
+		}
+		target := ref.Hash()
+		tagObj, err := repo.TagObject(target)
+		switch {
+		case err == nil:
+			commit, err := tagObj.Commit()
+			if err != nil {
+				// not a tag of a commit
+				return nil
+			}
+			target = commit.Hash
+		case !errors.Is(err, plumbing.ErrObjectNotFound):
+			return err
+		}
+		if cur, ok := tagged[target]; ok && semverCompare(cur, name, prefix) >= 0 {
+			return nil
+		}
+		tagged[target] = name
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve tags: %w", err)
+	}
+	return tagged, nil
+}
+
+func semverCompare(a, b, prefix string) int {
+	return semver.Compare("v"+strings.TrimPrefix(a, prefix), "v"+strings.TrimPrefix(b, prefix))
+}
+
+// lookupCommit returns the commit with the given hash, or nil if it is
+// missing from the repository, as is the case past the history of a shallow
+// clone.
+func lookupCommit(repo *git.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	commit, err := repo.CommitObject(hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+	return commit, nil
+}
+
+// ancestors returns the commits reachable from the given commit, including
+// itself, stopping at any commit in exclude.
+func ancestors(repo *git.Repository, from plumbing.Hash, exclude map[plumbing.Hash]struct{}) (map[plumbing.Hash]struct{}, error) {
+	found := map[plumbing.Hash]struct{}{}
+	queue := []plumbing.Hash{from}
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if _, ok := found[hash]; ok {
+			continue
+		}
+		if _, ok := exclude[hash]; ok {
+			continue
+		}
+		commit, err := lookupCommit(repo, hash)
+		if err != nil {
+			return nil, err
+		}
+		if commit == nil {
+			continue
+		}
+		found[hash] = struct{}{}
+		queue = append(queue, commit.ParentHashes...)
+	}
+	return found, nil
+}
+
+type versionBump int
+
+const (
+	bumpNone versionBump = iota
+	bumpPatch
+	bumpMinor
+	bumpMajor
+)
+
+var conventionalHeader = regexp.MustCompile(`^(\w+)(\([^)]*\))?(!)?:`)
+
+// conventionalBump returns the version bump implied by a commit message
+// following the conventional commits specification.
+func conventionalBump(msg string) versionBump {
+	header, body, _ := strings.Cut(msg, "\n")
+	if strings.Contains(body, "BREAKING CHANGE:") || strings.Contains(body, "BREAKING-CHANGE:") {
+		return bumpMajor
+	}
+	m := conventionalHeader.FindStringSubmatch(strings.TrimSpace(header))
+	if m == nil {
+		return bumpPatch
+	}
+	if m[3] == "!" {
+		return bumpMajor
+	}
+	if strings.EqualFold(m[1], "feat") {
+		return bumpMinor
+	}
+	return bumpPatch
+}
+
+// nextVersion applies the bump to the given version. Breaking changes bump
+// the minor version while the major version is 0, and a pre-release is
+// released as-is.
+func nextVersion(version string, bump versionBump) string {
+	canonical := semver.Canonical("v" + version)
+	if semver.Prerelease(canonical) != "" {
+		return strings.TrimPrefix(strings.TrimSuffix(canonical, semver.Prerelease(canonical)), "v")
+	}
+	parts := strings.SplitN(strings.TrimPrefix(canonical, "v"), ".", 3)
+	var nums [3]int
+	for i, p := range parts {
+		nums[i], _ = strconv.Atoi(p)
+	}
+	if bump == bumpMajor && nums[0] == 0 {
+		bump = bumpMinor
+	}
+	switch bump {
+	case bumpMajor:
+		nums = [3]int{nums[0] + 1, 0, 0}
+	case bumpMinor:
+		nums = [3]int{nums[0], nums[1] + 1, 0}
+	default:
+		nums = [3]int{nums[0], nums[1], nums[2] + 1}
+	}
+	return fmt.Sprintf("%d.%d.%d", nums[0], nums[1], nums[2])
+}
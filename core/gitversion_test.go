@@ -0,0 +1,102 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeGitVersion(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(0, 0)}
+	commit := func(msg string) plumbing.Hash {
+		hash, err := wt.Commit(msg, &git.CommitOptions{Author: sig, AllowEmptyCommits: true})
+		require.NoError(t, err)
+		return hash
+	}
+
+	first := commit("initial commit")
+
+	version, err := ComputeGitVersion(dir, "v")
+	require.NoError(t, err)
+	require.Equal(t, first.String(), version.Commit)
+	require.Equal(t, "", version.Tag)
+	require.Equal(t, "0.0.0", version.Version)
+	require.Equal(t, 1, version.Distance)
+	require.Equal(t, first.String()[:7], version.Describe)
+	require.Equal(t, "0.0.1", version.Next)
+
+	_, err = repo.CreateTag("v1.2.3", first, nil)
+	require.NoError(t, err)
+	_, err = repo.CreateTag("not-a-version", first, nil)
+	require.NoError(t, err)
+
+	version, err = ComputeGitVersion(dir, "v")
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.3", version.Tag)
+	require.Equal(t, "1.2.3", version.Version)
+	require.Equal(t, 0, version.Distance)
+	require.Equal(t, "v1.2.3", version.Describe)
+	require.Equal(t, "1.2.3", version.Next)
+
+	commit("fix: a bug")
+	version, err = ComputeGitVersion(dir, "v")
+	require.NoError(t, err)
+	require.Equal(t, 1, version.Distance)
+	require.Equal(t, "1.2.4", version.Next)
+
+	head := commit("feat(api): a feature")
+	version, err = ComputeGitVersion(dir, "v")
+	require.NoError(t, err)
+	require.Equal(t, 2, version.Distance)
+	require.Equal(t, "v1.2.3-2-g"+head.String()[:7], version.Describe)
+	require.Equal(t, "1.3.0", version.Next)
+
+	commit("refactor!: drop the old api")
+	version, err = ComputeGitVersion(dir, "v")
+	require.NoError(t, err)
+	require.Equal(t, "2.0.0", version.Next)
+
+	// annotated tags are resolved to their commit
+	tagged := commit("chore: release")
+	_, err = repo.CreateTag("v2.0.0", tagged, &git.CreateTagOptions{Tagger: sig, Message: "v2.0.0"})
+	require.NoError(t, err)
+	version, err = ComputeGitVersion(dir, "v")
+	require.NoError(t, err)
+	require.Equal(t, "v2.0.0", version.Tag)
+	require.Equal(t, 0, version.Distance)
+}
+
+func TestConventionalBump(t *testing.T) {
+	for _, tc := range []struct {
+		msg  string
+		bump versionBump
+	}{
+		{"update readme", bumpPatch},
+		{"fix: a bug", bumpPatch},
+		{"feat: a feature", bumpMinor},
+		{"feat(scope): a feature", bumpMinor},
+		{"feat!: a breaking feature", bumpMajor},
+		{"fix(scope)!: a breaking fix", bumpMajor},
+		{"fix: a bug\n\nBREAKING CHANGE: it was load-bearing", bumpMajor},
+	} {
+		require.Equal(t, tc.bump, conventionalBump(tc.msg), tc.msg)
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	require.Equal(t, "1.2.4", nextVersion("1.2.3", bumpPatch))
+	require.Equal(t, "1.3.0", nextVersion("1.2.3", bumpMinor))
+	require.Equal(t, "2.0.0", nextVersion("1.2.3", bumpMajor))
+	require.Equal(t, "0.3.0", nextVersion("0.2.3", bumpMajor))
+	require.Equal(t, "1.2.3", nextVersion("1.2.3-rc.1", bumpMinor))
+}
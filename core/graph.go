@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/dagql/call"
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+type GraphFormat string
+
+var GraphFormats = dagql.NewEnum[GraphFormat]()
+
+var (
+	GraphFormatJSON = GraphFormats.Register("JSON",
+		"A JSON object listing the vertexes of the graph, inputs first.")
+	GraphFormatDOT = GraphFormats.Register("DOT",
+		"The Graphviz DOT language.")
+)
+
+func (format GraphFormat) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "GraphFormat",
+		NonNull:   true,
+	}
+}
+
+func (format GraphFormat) TypeDescription() string {
+	return "Format to export an operation graph in."
+}
+
+func (format GraphFormat) Decoder() dagql.InputDecoder {
+	return GraphFormats
+}
+
+func (format GraphFormat) ToLiteral() call.Literal {
+	return GraphFormats.Literal(format)
+}
+
+// opGraph returns the operation graph of an evaluated definition in the given
+// format.
+func opGraph(ctx context.Context, bk *buildkit.Client, def *pb.Definition, format GraphFormat) (string, error) {
+	graph, err := bk.OpGraph(ctx, def)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case GraphFormatDOT:
+		return graph.DOT(), nil
+	case GraphFormatJSON:
+		dt, err := json.Marshal(graph)
+		if err != nil {
+			return "", err
+		}
+		return string(dt), nil
+	default:
+		return "", fmt.Errorf("unknown graph format %q", format)
+	}
+}
+
+// Graph evaluates the container's root filesystem and returns the graph of
+// the operations it is made of.
+func (container *Container) Graph(ctx context.Context, format GraphFormat) (string, error) {
+	if _, err := container.Evaluate(ctx); err != nil {
+		return "", err
+	}
+	return opGraph(ctx, container.Query.Buildkit, container.FS, format)
+}
+
+// Graph evaluates the directory and returns the graph of the operations it is
+// made of.
+func (dir *Directory) Graph(ctx context.Context, format GraphFormat) (string, error) {
+	if _, err := dir.Evaluate(ctx); err != nil {
+		return "", err
+	}
+	return opGraph(ctx, dir.Query.Buildkit, dir.LLB, format)
+}
+
+// Graph evaluates the file and returns the graph of the operations it is made
+// of.
+func (file *File) Graph(ctx context.Context, format GraphFormat) (string, error) {
+	if _, err := file.Evaluate(ctx); err != nil {
+		return "", err
+	}
+	return opGraph(ctx, file.Query.Buildkit, file.LLB, format)
+}
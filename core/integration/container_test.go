@@ -14,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/containerd/containerd/platforms"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -88,6 +89,49 @@ func (ContainerSuite) TestFrom(ctx context.Context, t *testctx.T) {
 	require.Equal(t, distconsts.AlpineVersion, strings.TrimSpace(releaseStr))
 }
 
+func (ContainerSuite) TestFromPullPolicy(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	t.Run("always", func(ctx context.Context, t *testctx.T) {
+		out, err := c.Container().
+			From(alpineImage, dagger.ContainerFromOpts{PullPolicy: dagger.Always}).
+			File("/etc/alpine-release").
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, distconsts.AlpineVersion, strings.TrimSpace(out))
+	})
+
+	t.Run("never with local image", func(ctx context.Context, t *testctx.T) {
+		ctr := c.Container().From(alpineImage)
+		ref, err := ctr.ImageRef(ctx)
+		require.NoError(t, err)
+		// pull the layers
+		_, err = ctr.WithExec([]string{"true"}).Sync(ctx)
+		require.NoError(t, err)
+
+		out, err := c.Container().
+			From(ref, dagger.ContainerFromOpts{PullPolicy: dagger.Never}).
+			File("/etc/alpine-release").
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, distconsts.AlpineVersion, strings.TrimSpace(out))
+	})
+
+	t.Run("never with tag", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Container().
+			From(alpineImage, dagger.ContainerFromOpts{PullPolicy: dagger.Never}).
+			Sync(ctx)
+		require.ErrorContains(t, err, "requires an address pinned by digest")
+	})
+
+	t.Run("never with missing image", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Container().
+			From("alpine@sha256:"+strings.Repeat("0", 64), dagger.ContainerFromOpts{PullPolicy: dagger.Never}).
+			Sync(ctx)
+		require.ErrorContains(t, err, "is not present in the engine")
+	})
+}
+
 func (ContainerSuite) TestBuild(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
@@ -423,6 +467,376 @@ func (ContainerSuite) TestExecStdoutStderr(ctx context.Context, t *testctx.T) {
 	require.Equal(t, res.Container.From.WithExec.Stderr, "goodbye\n")
 }
 
+func (ContainerSuite) TestExecNoCache(ctx context.Context, t *testctx.T) {
+	run := func(ctx context.Context, t *testctx.T, noCache bool) string {
+		c := connect(ctx, t)
+		out, err := c.Container().From(alpineImage).
+			WithExec([]string{"sh", "-c", "head -c 16 /dev/urandom | base64; env"}, dagger.ContainerWithExecOpts{
+				NoCache: noCache,
+			}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.NotContains(t, out, "_DAGGER_CACHE_BUSTER")
+		return out
+	}
+
+	t.Run("cached", func(ctx context.Context, t *testctx.T) {
+		require.Equal(t, run(ctx, t, false), run(ctx, t, false))
+	})
+
+	t.Run("no cache", func(ctx context.Context, t *testctx.T) {
+		require.NotEqual(t, run(ctx, t, true), run(ctx, t, true))
+	})
+
+	t.Run("no cache on one client", func(ctx context.Context, t *testctx.T) {
+		c := connect(ctx, t)
+		ctr := c.Container().From(alpineImage).
+			WithExec([]string{"sh", "-c", "head -c 16 /dev/urandom | base64"}, dagger.ContainerWithExecOpts{
+				NoCache: true,
+			})
+		first, err := ctr.Stdout(ctx)
+		require.NoError(t, err)
+		second, err := ctr.Stdout(ctx)
+		require.NoError(t, err)
+		require.NotEqual(t, first, second)
+	})
+}
+
+func (ContainerSuite) TestExecAllowFailure(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	t.Run("failure", func(ctx context.Context, t *testctx.T) {
+		ctr := c.Container().From(alpineImage).
+			WithExec([]string{"sh", "-c", "echo report > /report.txt; echo out; echo err >&2; exit 5"}, dagger.ContainerWithExecOpts{
+				AllowFailure: true,
+			})
+
+		exitCode, err := ctr.ExitCode(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 5, exitCode)
+
+		stdout, err := ctr.Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "out\n", stdout)
+
+		stderr, err := ctr.Stderr(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "err\n", stderr)
+
+		report, err := ctr.File("/report.txt").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "report\n", report)
+	})
+
+	t.Run("success", func(ctx context.Context, t *testctx.T) {
+		exitCode, err := c.Container().From(alpineImage).
+			WithExec([]string{"true"}, dagger.ContainerWithExecOpts{
+				AllowFailure: true,
+			}).
+			ExitCode(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0, exitCode)
+	})
+
+	t.Run("not allowed", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Container().From(alpineImage).
+			WithExec([]string{"sh", "-c", "exit 5"}).
+			ExitCode(ctx)
+		require.Error(t, err)
+	})
+}
+
+func (ContainerSuite) TestExecResourceLimits(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	out, err := c.Container().From(alpineImage).
+		WithExec([]string{"cat", "/sys/fs/cgroup/cpu.max", "/sys/fs/cgroup/memory.max"}, dagger.ContainerWithExecOpts{
+			Cpus:        0.5,
+			MemoryBytes: 64 * 1024 * 1024,
+		}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "50000 100000\n67108864\n", out)
+
+	t.Run("memory exceeded", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Container().From(alpineImage).
+			WithExec([]string{"sh", "-c", "head -c 67108864 /dev/zero | tail"}, dagger.ContainerWithExecOpts{
+				MemoryBytes: 16 * 1024 * 1024,
+			}).
+			Sync(ctx)
+		require.Error(t, err)
+	})
+}
+
+func (ContainerSuite) TestExecNoNetwork(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	out, err := c.Container().From(alpineImage).
+		WithExec([]string{"ip", "-o", "link"}, dagger.ContainerWithExecOpts{
+			NoNetwork: true,
+		}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Len(t, strings.Split(strings.TrimSpace(out), "\n"), 1)
+	require.Contains(t, out, "lo:")
+
+	_, err = c.Container().From(alpineImage).
+		WithExec([]string{"wget", "-T", "5", "-O-", "http://example.com"}, dagger.ContainerWithExecOpts{
+			NoNetwork: true,
+		}).
+		Sync(ctx)
+	require.Error(t, err)
+}
+
+func (ContainerSuite) TestExecReadOnlyRootfs(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	_, err := c.Container().From(alpineImage).
+		WithExec([]string{"touch", "/etc/mutated"}, dagger.ContainerWithExecOpts{
+			ReadOnlyRootfs: true,
+		}).
+		Sync(ctx)
+	require.ErrorContains(t, err, "Read-only file system")
+
+	ctr := c.Container().From(alpineImage).
+		WithMountedTemp("/scratch").
+		WithMountedDirectory("/out", c.Directory()).
+		WithExec([]string{"sh", "-c", "echo hi > /scratch/tmp && cp /scratch/tmp /out/result"}, dagger.ContainerWithExecOpts{
+			ReadOnlyRootfs: true,
+		})
+	out, err := ctr.Directory("/out").File("result").Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "hi\n", out)
+}
+
+func (ContainerSuite) TestExecMaxOutputBytes(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", "printf '%0100d' 0; printf '%050d' 0 >&2"}, dagger.ContainerWithExecOpts{
+			MaxOutputBytes: 10,
+		})
+
+	stdout, err := ctr.Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0000000000"+fmt.Sprintf(buildkit.OutputLimitTruncationMessage, 90, 10), stdout)
+
+	stderr, err := ctr.Stderr(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0000000000"+fmt.Sprintf(buildkit.OutputLimitTruncationMessage, 40, 10), stderr)
+
+	// the limit is part of the exec's cache key
+	stdout, err = c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", "printf '%0100d' 0; printf '%050d' 0 >&2"}, dagger.ContainerWithExecOpts{
+			MaxOutputBytes: 20,
+		}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "00000000000000000000"+fmt.Sprintf(buildkit.OutputLimitTruncationMessage, 80, 20), stdout)
+
+	env, err := c.Container().From(alpineImage).
+		WithExec([]string{"env"}, dagger.ContainerWithExecOpts{
+			MaxOutputBytes: 1000,
+		}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.NotContains(t, env, buildkit.DaggerMaxOutputBytesEnv)
+
+	_, err = c.Container().From(alpineImage).
+		WithExec([]string{"true"}, dagger.ContainerWithExecOpts{
+			MaxOutputBytes: -1,
+		}).
+		Sync(ctx)
+	require.ErrorContains(t, err, "must not be negative")
+}
+
+func (ContainerSuite) TestExecBinaryOutput(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", `printf 'a\377b\000c'; printf '\376' >&2`})
+
+	stdout, err := ctr.Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a\uFFFDb\x00c", stdout)
+
+	stdoutBytes, err := ctr.StdoutBytes(ctx)
+	require.NoError(t, err)
+	decoded, err := base64.StdEncoding.DecodeString(stdoutBytes)
+	require.NoError(t, err)
+	require.Equal(t, []byte("a\xffb\x00c"), decoded)
+
+	stderrBytes, err := ctr.StderrBytes(ctx)
+	require.NoError(t, err)
+	decoded, err = base64.StdEncoding.DecodeString(stderrBytes)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\xfe"), decoded)
+}
+
+func (ContainerSuite) TestWithExtraHost(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	out, err := c.Container().From(alpineImage).
+		WithExtraHost("internal.example", "10.1.2.3").
+		WithExtraHost("pinned.example", "10.0.0.1").
+		WithExtraHost("pinned.example", "10.0.0.2").
+		WithExec([]string{"getent", "hosts", "internal.example", "pinned.example"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Contains(t, out, "10.1.2.3")
+	require.Contains(t, out, "10.0.0.2")
+	require.NotContains(t, out, "10.0.0.1")
+
+	_, err = c.Container().From(alpineImage).
+		WithExtraHost("internal.example", "not-an-ip").
+		Sync(ctx)
+	require.ErrorContains(t, err, "invalid IP")
+}
+
+func (ContainerSuite) TestWithDNS(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	out, err := c.Container().From(alpineImage).
+		WithDNS(dagger.ContainerWithDNSOpts{
+			Nameservers:   []string{"10.0.0.53"},
+			SearchDomains: []string{"corp.example.com"},
+		}).
+		WithExec([]string{"cat", "/etc/resolv.conf"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Contains(t, out, "nameserver 10.0.0.53\n")
+	require.Equal(t, 1, strings.Count(out, "nameserver"))
+	require.Regexp(t, `(?m)^search corp\.example\.com .*\.dagger\.local$`, out)
+
+	// the DNS config is part of the exec's cache key
+	out, err = c.Container().From(alpineImage).
+		WithDNS(dagger.ContainerWithDNSOpts{
+			Nameservers: []string{"10.0.0.54"},
+		}).
+		WithExec([]string{"cat", "/etc/resolv.conf"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Contains(t, out, "nameserver 10.0.0.54\n")
+	require.NotContains(t, out, "corp.example.com")
+
+	env, err := c.Container().From(alpineImage).
+		WithDNS(dagger.ContainerWithDNSOpts{
+			Nameservers: []string{"10.0.0.53"},
+		}).
+		WithExec([]string{"env"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.NotContains(t, env, buildkit.DaggerDNSEnv)
+
+	_, err = c.Container().From(alpineImage).
+		WithDNS(dagger.ContainerWithDNSOpts{Nameservers: []string{"resolver"}}).
+		Sync(ctx)
+	require.ErrorContains(t, err, "invalid nameserver IP")
+}
+
+func (ContainerSuite) TestWithDevice(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	_, err := c.Container().From(alpineImage).
+		WithDevice("dev/fuse").
+		Sync(ctx)
+	require.ErrorContains(t, err, "must be an absolute, clean path")
+
+	devices := c.Capability(dagger.Devices)
+	supported, err := devices.Supported(ctx)
+	require.NoError(t, err)
+	if supported {
+		t.Skip("engine allows host devices")
+	}
+
+	_, err = c.Container().From(alpineImage).
+		WithDevice("/dev/fuse").
+		WithExec([]string{"true"}).
+		Sync(ctx)
+	var unsupported *dagger.UnsupportedError
+	require.ErrorAs(t, err, &unsupported)
+	require.Equal(t, dagger.Devices, unsupported.Capability)
+}
+
+func (ContainerSuite) TestWithDeviceCacheKey(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	// allow host devices on an engine of the test's own
+	devEngine := devEngineContainer(c, 124, func(c *dagger.Container) *dagger.Container {
+		return c.WithEntrypoint([]string{
+			"/usr/local/bin/dagger-entrypoint.sh",
+			"--allow-device", "/dev/fuse",
+			"--allow-device", "/dev/net/tun",
+		})
+	}).AsService()
+	c = connectDevEngine(ctx, t, c, devEngine, 32136)
+
+	run := func(devices ...string) string {
+		ctr := c.Container().From(alpineImage)
+		for _, device := range devices {
+			ctr = ctr.WithDevice(device)
+		}
+		out, err := ctr.
+			WithExec([]string{"sh", "-c", "head -c 16 /dev/urandom | base64; ls /dev/fuse /dev/net/tun 2>&1; env"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.NotContains(t, out, buildkit.DaggerDevicesEnv)
+		return out
+	}
+
+	// the devices are part of the exec's cache key, whatever their order
+	fuse := run("/dev/fuse")
+	require.Contains(t, fuse, "No such file")
+	both := run("/dev/fuse", "/dev/net/tun")
+	require.NotContains(t, both, "No such file")
+	require.Equal(t, both, run("/dev/net/tun", "/dev/fuse"))
+}
+
+func (ContainerSuite) TestExecProblemMatchers(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", "echo main.go:12:5: undefined: foo; echo 'WARN deprecated flag' >/dev/stderr"}, dagger.ContainerWithExecOpts{
+			ProblemMatchers: []dagger.ProblemMatcher{
+				{Pattern: `^(?P<file>[^:\s]+):(?P<line>\d+):(?P<column>\d+): (?P<message>.*)$`},
+				{Pattern: `^WARN (?P<message>.*)$`, Severity: "warning"},
+			},
+		})
+
+	diagnostics, err := ctr.Diagnostics(ctx)
+	require.NoError(t, err)
+	require.Len(t, diagnostics, 2)
+
+	file, err := diagnostics[0].File(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "main.go", file)
+	line, err := diagnostics[0].Line(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 12, line)
+	severity, err := diagnostics[0].Severity(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "error", severity)
+	msg, err := diagnostics[0].Message(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "undefined: foo", msg)
+
+	severity, err = diagnostics[1].Severity(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "warning", severity)
+	msg, err = diagnostics[1].Message(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "deprecated flag", msg)
+
+	t.Run("invalid pattern", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Container().From(alpineImage).
+			WithExec([]string{"true"}, dagger.ContainerWithExecOpts{
+				ProblemMatchers: []dagger.ProblemMatcher{{Pattern: `^(?P<file>.*)$`}},
+			}).
+			Sync(ctx)
+		require.ErrorContains(t, err, `has no "message" group`)
+	})
+}
+
 func (ContainerSuite) TestExecStdin(ctx context.Context, t *testctx.T) {
 	res := struct {
 		Container struct {
@@ -1075,6 +1489,32 @@ func (ContainerSuite) TestEnvVariablesReplace(ctx context.Context, t *testctx.T)
 	require.Contains(t, res.Container.From.WithEnvVariable.WithExec.Stdout, "GOPATH=/gone\n")
 }
 
+func (ContainerSuite) TestExecEnvOrderCached(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	nonce := identity.NewID()
+
+	out1, err := c.Container().
+		From(alpineImage).
+		WithEnvVariable("NONCE", nonce).
+		WithEnvVariable("FOO", "foo").
+		WithEnvVariable("BAR", "bar").
+		WithExec([]string{"sh", "-c", "head -c 16 /dev/urandom | base64"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+
+	out2, err := c.Container().
+		From(alpineImage).
+		WithEnvVariable("BAR", "bar").
+		WithEnvVariable("FOO", "foo").
+		WithEnvVariable("NONCE", nonce).
+		WithExec([]string{"sh", "-c", "head -c 16 /dev/urandom | base64"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, out1, out2)
+}
+
 func (ContainerSuite) TestWithEnvVariableExpand(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
@@ -1269,6 +1709,133 @@ func (ContainerSuite) TestWithWorkdir(ctx context.Context, t *testctx.T) {
 	require.Equal(t, res.Container.From.WithWorkdir.WithExec.Stdout, "/usr\n")
 }
 
+func (ContainerSuite) TestWithStopSignal(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().From(alpineImage).WithStopSignal("SIGQUIT")
+
+	sig, err := ctr.StopSignal(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "SIGQUIT", sig)
+
+	t.Run("persisted in image config", func(ctx context.Context, t *testctx.T) {
+		sig, err := c.Container().Import(ctr.AsTarball()).StopSignal(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "SIGQUIT", sig)
+	})
+
+	t.Run("unset", func(ctx context.Context, t *testctx.T) {
+		sig, err := ctr.WithoutStopSignal().StopSignal(ctx)
+		require.NoError(t, err)
+		require.Empty(t, sig)
+	})
+
+	t.Run("invalid signal", func(ctx context.Context, t *testctx.T) {
+		_, err := ctr.WithStopSignal("SIGNOPE").StopSignal(ctx)
+		require.Error(t, err)
+	})
+}
+
+func (ContainerSuite) TestWithVolume(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().From(alpineImage).
+		WithWorkdir("/srv").
+		WithVolume("/var/lib/data").
+		WithVolume("cache")
+
+	volumes, err := ctr.Volumes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/srv/cache", "/var/lib/data"}, volumes)
+
+	t.Run("persisted in image config", func(ctx context.Context, t *testctx.T) {
+		volumes, err := c.Container().Import(ctr.AsTarball()).Volumes(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"/srv/cache", "/var/lib/data"}, volumes)
+	})
+
+	t.Run("without volume", func(ctx context.Context, t *testctx.T) {
+		volumes, err := ctr.WithoutVolume("/var/lib/data").Volumes(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"/srv/cache"}, volumes)
+	})
+}
+
+func (ContainerSuite) TestImageConfig(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	cfgJSON, err := c.Container().From(alpineImage).
+		WithEnvVariable("FOO", "bar").
+		WithEntrypoint([]string{"sh", "-c"}).
+		WithDefaultArgs([]string{"echo hello"}).
+		WithLabel("org.opencontainers.image.source", "https://github.com/dagger/dagger").
+		WithExposedPort(8080).
+		WithUser("nobody").
+		WithWorkdir("/srv").
+		ImageConfig(ctx)
+	require.NoError(t, err)
+
+	var cfg ocispecs.ImageConfig
+	require.NoError(t, json.Unmarshal([]byte(cfgJSON), &cfg))
+	require.Contains(t, cfg.Env, "FOO=bar")
+	require.Equal(t, []string{"sh", "-c"}, cfg.Entrypoint)
+	require.Equal(t, []string{"echo hello"}, cfg.Cmd)
+	require.Equal(t, "https://github.com/dagger/dagger", cfg.Labels["org.opencontainers.image.source"])
+	require.Contains(t, cfg.ExposedPorts, "8080/tcp")
+	require.Equal(t, "nobody", cfg.User)
+	require.Equal(t, "/srv", cfg.WorkingDir)
+}
+
+func (ContainerSuite) TestGraph(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", "echo " + identity.NewID() + " > /out"})
+
+	graphJSON, err := ctr.Graph(ctx)
+	require.NoError(t, err)
+
+	var graph buildkit.OpGraph
+	require.NoError(t, json.Unmarshal([]byte(graphJSON), &graph))
+	require.NotEmpty(t, graph.Vertexes)
+
+	// inputs come first, so the exec is last
+	exec := graph.Vertexes[len(graph.Vertexes)-1]
+	require.Equal(t, "exec", exec.Kind)
+	require.Len(t, exec.Inputs, 1)
+	src := graph.Vertexes[0]
+	require.Equal(t, "source", src.Kind)
+	require.Equal(t, src.Digest, exec.Inputs[0])
+
+	dot, err := ctr.Graph(ctx, dagger.ContainerGraphOpts{Format: dagger.Dot})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(dot, "digraph {"))
+	require.Contains(t, dot, fmt.Sprintf("%q -> %q;", src.Digest, exec.Digest))
+}
+
+func (ContainerSuite) TestCached(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", "echo " + identity.NewID() + " > /out"})
+
+	cached, err := ctr.Cached(ctx)
+	require.NoError(t, err)
+	require.False(t, cached)
+
+	// probing doesn't run the exec, nor fail it for real
+	_, err = ctr.Sync(ctx)
+	require.NoError(t, err)
+
+	cached, err = ctr.Cached(ctx)
+	require.NoError(t, err)
+	require.True(t, cached)
+
+	cached, err = ctr.WithExec([]string{"true"}).Cached(ctx)
+	require.NoError(t, err)
+	require.False(t, cached)
+}
+
 func (ContainerSuite) TestWithMountedDirectory(ctx context.Context, t *testctx.T) {
 	dirRes := struct {
 		Directory struct {
@@ -1909,6 +2476,47 @@ func (ContainerSuite) TestWithNewFile(ctx context.Context, t *testctx.T) {
 	require.Equal(t, "some-content", contents)
 }
 
+func (ContainerSuite) TestWithNewDirectory(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().
+		From(alpineImage).
+		WithWorkdir("/workdir").
+		WithNewDirectory("some-dir/sub-dir").
+		WithNewDirectory("/private", dagger.ContainerWithNewDirectoryOpts{
+			Permissions: 0o700,
+			Owner:       "nobody",
+		})
+
+	out, err := ctr.WithExec([]string{"stat", "-c", "%a %U", "some-dir/sub-dir", "/private"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "755 root\n700 nobody\n", out)
+}
+
+func (ContainerSuite) TestWithSymlink(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().
+		From(alpineImage).
+		WithWorkdir("/workdir").
+		WithNewFile("some-file", dagger.ContainerWithNewFileOpts{
+			Contents: "some-content",
+		}).
+		WithSymlink("some-file", "some-link").
+		WithSymlink("/workdir/some-file", "/abs-link")
+
+	out, err := ctr.WithExec([]string{"readlink", "some-link", "/abs-link"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "some-file\n/workdir/some-file\n", out)
+
+	contents, err := ctr.WithExec([]string{"cat", "/abs-link"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "some-content", contents)
+}
+
 func (ContainerSuite) TestMountsWithoutMount(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
@@ -2767,6 +3375,28 @@ func (ContainerSuite) TestPublish(ctx context.Context, t *testctx.T) {
 	require.Equal(t, "im-a-entrypoint\n", output)
 }
 
+func (ContainerSuite) TestCopyImage(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	stagingRef := registryRef("container-copy-image-staging")
+	prodRef := registryRef("container-copy-image-prod")
+
+	pushedRef, err := c.Container().From(alpineImage).
+		WithNewFile("/promoted", dagger.ContainerWithNewFileOpts{Contents: "yes"}).
+		Publish(ctx, stagingRef)
+	require.NoError(t, err)
+	_, pushedDigest, ok := strings.Cut(pushedRef, "@")
+	require.True(t, ok)
+
+	copiedRef, err := c.CopyImage(ctx, stagingRef, prodRef)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(copiedRef, "@"+pushedDigest), copiedRef)
+
+	contents, err := c.Container().From(prodRef).File("/promoted").Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "yes", contents)
+}
+
 func (ContainerSuite) TestExecFromScratch(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
@@ -2912,6 +3542,80 @@ func (ContainerSuite) TestAsTarball(ctx context.Context, t *testctx.T) {
 	require.Equal(t, "/foo.tar: POSIX tar archive\n", output)
 }
 
+func (ContainerSuite) TestAsTarballCompressionLevel(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().From(alpineImage).
+		WithNewFile("/hello", dagger.ContainerWithNewFileOpts{Contents: "hello"})
+
+	for _, compression := range []dagger.ImageLayerCompression{dagger.Gzip, dagger.Zstd} {
+		compression := compression
+		t.Run(string(compression), func(ctx context.Context, t *testctx.T) {
+			tarball := ctr.AsTarball(dagger.ContainerAsTarballOpts{
+				ForcedCompression: compression,
+				CompressionLevel:  9,
+			})
+			out, err := c.Container().Import(tarball).
+				WithExec([]string{"cat", "/hello"}).
+				Stdout(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "hello", out)
+		})
+	}
+}
+
+func (ContainerSuite) TestExportAnnotations(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	tarPath := filepath.Join(t.TempDir(), "export.tar")
+	_, err := c.Container().From(alpineImage).
+		WithAnnotation("org.opencontainers.image.source", "https://github.com/dagger/dagger").
+		WithAnnotation("org.opencontainers.image.revision", "deadbeef").
+		WithAnnotation("org.opencontainers.image.created", "2023-01-01T00:00:00Z").
+		WithoutAnnotation("org.opencontainers.image.created").
+		Export(ctx, tarPath)
+	require.NoError(t, err)
+
+	indexBytes := readTarFile(t, tarPath, "index.json")
+	var index ocispecs.Index
+	require.NoError(t, json.Unmarshal(indexBytes, &index))
+
+	manifestDigest := index.Manifests[0].Digest
+	manifestBytes := readTarFile(t, tarPath, "blobs/sha256/"+manifestDigest.Encoded())
+	var manifest ocispecs.Manifest
+	require.NoError(t, json.Unmarshal(manifestBytes, &manifest))
+	require.Equal(t, "https://github.com/dagger/dagger", manifest.Annotations["org.opencontainers.image.source"])
+	require.Equal(t, "deadbeef", manifest.Annotations["org.opencontainers.image.revision"])
+	require.NotContains(t, manifest.Annotations, "org.opencontainers.image.created")
+}
+
+func (ContainerSuite) TestAsTarballSourceDateEpoch(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	build := func() *dagger.Container {
+		return c.Container().From(alpineImage).
+			WithExec([]string{"sh", "-c", "echo hello > /hello"}, dagger.ContainerWithExecOpts{
+				NoCache: true,
+			})
+	}
+
+	opts := dagger.ContainerAsTarballOpts{
+		SourceDateEpoch:   1672531199,
+		ForcedCompression: dagger.Gzip,
+	}
+
+	dgst1, err := build().AsTarball(opts).Digest(ctx)
+	require.NoError(t, err)
+
+	// sleep so the files are written with different timestamps
+	time.Sleep(2 * time.Second)
+
+	dgst2, err := build().AsTarball(opts).Digest(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, dgst1, dgst2)
+}
+
 func (ContainerSuite) TestImport(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
@@ -2977,6 +3681,17 @@ func (ContainerSuite) TestImport(ctx context.Context, t *testctx.T) {
 	})
 }
 
+func (ContainerSuite) TestFromUnknownImageAlias(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	_, err := c.Container().From("base://does-not-exist").Sync(ctx)
+	require.ErrorContains(t, err, `unknown image alias "does-not-exist"`)
+
+	val, err := c.DefaultValue(ctx, "does-not-exist")
+	require.NoError(t, err)
+	require.Empty(t, val)
+}
+
 func (ContainerSuite) TestFromImagePlatform(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
@@ -3705,6 +4420,30 @@ func (ContainerSuite) TestWithMountedCacheOwner(ctx context.Context, t *testctx.
 		require.NoError(t, err)
 		require.Equal(t, "645:auser:agroup\n", out)
 	})
+
+	t.Run("permissions (explicit)", func(ctx context.Context, t *testctx.T) {
+		dir := c.Directory().
+			WithNewFile("foo", "whee", dagger.DirectoryWithNewFileOpts{
+				Permissions: 0o645,
+			})
+
+		ctr := c.Container().From(alpineImage).
+			WithExec([]string{"adduser", "-u", "1234", "-D", "auser"}).
+			WithExec([]string{"addgroup", "-g", "4321", "agroup"}).
+			WithMountedCache("/data", c.CacheVolume("test-permissions"), dagger.ContainerWithMountedCacheOpts{
+				Source:      dir,
+				Owner:       "auser:agroup",
+				Permissions: 0o777,
+			})
+
+		out, err := ctr.WithExec([]string{"stat", "-c", "%a:%U:%G", "/data"}).Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "777:auser:agroup\n", out)
+
+		out, err = ctr.WithExec([]string{"stat", "-c", "%a:%U:%G", "/data/foo"}).Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "645:auser:agroup\n", out)
+	})
 }
 
 func (ContainerSuite) TestWithMountedSecretOwner(ctx context.Context, t *testctx.T) {
@@ -4137,6 +4876,139 @@ func (ContainerSuite) TestNestedExec(ctx context.Context, t *testctx.T) {
 	require.NoError(t, err)
 }
 
+func (ContainerSuite) TestFollowOutput(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	// follow.go subscribes to the given output of a container over the
+	// session's websocket, printing each result on its own line
+	const followGo = `package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/websocket"
+)
+
+func main() {
+	addr := "127.0.0.1:" + os.Getenv("DAGGER_SESSION_PORT")
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(os.Getenv("DAGGER_SESSION_TOKEN")+":"))
+
+	query, _ := json.Marshal(map[string]string{"query": os.Args[1]})
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/query", bytes.NewReader(query))
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	var res struct {
+		Data struct {
+			Container struct {
+				From struct {
+					WithExec struct {
+						ID string
+					}
+				}
+			}
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		panic(err)
+	}
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws://"+addr+"/query", http.Header{"Authorization": {auth}})
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(map[string]any{"type": "connection_init"}); err != nil {
+		panic(err)
+	}
+	if err := conn.WriteJSON(map[string]any{
+		"id":   "1",
+		"type": "subscribe",
+		"payload": map[string]any{
+			"query":     "subscription($ctr: ContainerID!) { output: " + os.Args[2] + "(container: $ctr) }",
+			"variables": map[string]any{"ctr": res.Data.Container.From.WithExec.ID},
+		},
+	}); err != nil {
+		panic(err)
+	}
+	for {
+		var msg struct {
+			Type    string
+			Payload json.RawMessage
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			panic(err)
+		}
+		switch msg.Type {
+		case "next":
+			var next struct {
+				Data struct {
+					Output string
+				}
+				Errors []any
+			}
+			if err := json.Unmarshal(msg.Payload, &next); err != nil {
+				panic(err)
+			}
+			if len(next.Errors) > 0 {
+				panic(string(msg.Payload))
+			}
+			fmt.Printf("%q\n", next.Data.Output)
+		case "error":
+			panic(string(msg.Payload))
+		case "complete":
+			return
+		}
+	}
+}
+`
+
+	follow := c.Container().From(golangImage).
+		WithWorkdir("/src").
+		WithNewFile("/src/main.go", dagger.ContainerWithNewFileOpts{Contents: followGo}).
+		WithExec([]string{"go", "mod", "init", "follow"}).
+		WithExec([]string{"go", "get", "github.com/gorilla/websocket@v1.5.3"}).
+		WithExec([]string{"go", "build", "-o", "/usr/local/bin/follow", "."}).
+		File("/usr/local/bin/follow")
+
+	nonce := identity.NewID()
+	query := `{container{from(address:"` + alpineImage + `"){withExec(args:["sh","-c","echo ` + nonce + `; sleep 1; echo out; echo err >&2"]){id}}}}`
+
+	ctr := c.Container().From(alpineImage).
+		WithMountedFile("/usr/local/bin/follow", follow)
+
+	// the exec isn't cached yet, so its output is followed as it's written
+	stdout, err := ctr.
+		WithExec([]string{"follow", query, "containerStdout"}, dagger.ContainerWithExecOpts{
+			ExperimentalPrivilegedNesting: true,
+		}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Contains(t, stdout, nonce)
+	require.Contains(t, stdout, "out")
+
+	// now that it's cached, its whole output is sent at once
+	stderr, err := ctr.
+		WithExec([]string{"follow", query, "containerStderr"}, dagger.ContainerWithExecOpts{
+			ExperimentalPrivilegedNesting: true,
+		}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, `"err\n"`+"\n", stderr)
+}
+
 func (ContainerSuite) TestEmptyExecDiff(ctx context.Context, t *testctx.T) {
 	// if an exec makes no changes, the diff should be empty, including of files
 	// mounted in by the engine like the init/resolv.conf/etc.
@@ -4,11 +4,11 @@ import (
 	"context"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/moby/buildkit/identity"
 	"github.com/stretchr/testify/require"
 
 	"dagger.io/dagger"
@@ -73,6 +73,84 @@ func (DirectorySuite) TestWithNewFile(ctx context.Context, t *testctx.T) {
 	require.Equal(t, []string{"some-file"}, res.Directory.WithNewFile.Entries)
 }
 
+func (DirectorySuite) TestWithSymlink(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	dir := c.Directory().
+		WithNewFile("bin/python3", "#!/bin/sh\necho hi\n").
+		WithSymlink("python3", "bin/python")
+
+	entries, err := dir.Entries(ctx, dagger.DirectoryEntriesOpts{Path: "bin"})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"python", "python3"}, entries)
+
+	out, err := c.Container().From(alpineImage).
+		WithMountedDirectory("/mnt", dir).
+		WithExec([]string{"readlink", "/mnt/bin/python"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "python3\n", out)
+
+	t.Run("outside parent", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Directory().WithSymlink("foo", "../bar").Sync(ctx)
+		require.ErrorContains(t, err, "cannot create symlink outside parent")
+	})
+}
+
+func (DirectorySuite) TestSyncBranches(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	// measure the cache of an engine of the test's own
+	c = connectDevEngine(ctx, t, c, devEngineContainer(c, 122).AsService(), 32134)
+	regularBytes := func() int {
+		usages, err := c.Engine().CacheUsage(ctx)
+		require.NoError(t, err)
+		for _, usage := range usages {
+			recordType, err := usage.RecordType(ctx)
+			require.NoError(t, err)
+			if recordType != "regular" {
+				continue
+			}
+			size, err := usage.SizeBytes(ctx)
+			require.NoError(t, err)
+			return size
+		}
+		return 0
+	}
+
+	const randomSize = 32 << 20
+	base, err := c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", "mkdir /out && head -c " + strconv.Itoa(randomSize) + " /dev/urandom > /out/random"}).
+		Directory("/out").
+		Sync(ctx)
+	require.NoError(t, err)
+	before := regularBytes()
+
+	a := base.WithNewFile("a", "a")
+	b := base.WithNewFile("b", "b").WithoutFile("random")
+
+	// the branches don't see each other's modifications
+	entries, err := a.Entries(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a", "random"}, entries)
+	entries, err = b.Entries(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"b"}, entries)
+	entries, err = base.Entries(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"random"}, entries)
+
+	digestA, err := a.File("random").Digest(ctx)
+	require.NoError(t, err)
+	digestBase, err := base.File("random").Digest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, digestBase, digestA)
+
+	// the branches are built on top of the evaluated directory rather than
+	// copying it, which would double the size of the cache
+	require.Less(t, regularBytes()-before, randomSize/2)
+}
+
 func (DirectorySuite) TestEntries(ctx context.Context, t *testctx.T) {
 	var res struct {
 		Directory struct {
@@ -123,6 +201,144 @@ func (DirectorySuite) TestEntriesOfPath(ctx context.Context, t *testctx.T) {
 	require.Equal(t, []string{"sub-file"}, res.Directory.WithNewFile.WithNewFile.Entries)
 }
 
+func (DirectorySuite) TestEntriesRecursive(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	dir := c.Directory().
+		WithNewFile("main.go", "").
+		WithNewFile("README.md", "").
+		WithNewFile("pkg/util.go", "").
+		WithNewFile("pkg/sub/deep.go", "")
+
+	t.Run("recursive", func(ctx context.Context, t *testctx.T) {
+		entries, err := dir.Entries(ctx, dagger.DirectoryEntriesOpts{Recursive: true})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{
+			"main.go", "README.md",
+			"pkg", "pkg/util.go", "pkg/sub", "pkg/sub/deep.go",
+		}, entries)
+	})
+
+	t.Run("recursive with pattern", func(ctx context.Context, t *testctx.T) {
+		entries, err := dir.Entries(ctx, dagger.DirectoryEntriesOpts{Recursive: true, Pattern: "**/*.go"})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"main.go", "pkg/util.go", "pkg/sub/deep.go"}, entries)
+	})
+
+	t.Run("recursive with path", func(ctx context.Context, t *testctx.T) {
+		entries, err := dir.Entries(ctx, dagger.DirectoryEntriesOpts{Path: "pkg", Recursive: true})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"util.go", "sub", "sub/deep.go"}, entries)
+	})
+
+	t.Run("pattern", func(ctx context.Context, t *testctx.T) {
+		entries, err := dir.Entries(ctx, dagger.DirectoryEntriesOpts{Pattern: "*.go"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"main.go"}, entries)
+	})
+}
+
+func (DirectorySuite) TestStat(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	dir := c.Directory().
+		WithNewFile("some-dir/some-file", "some-content", dagger.DirectoryWithNewFileOpts{
+			Permissions: 0o755,
+		}).
+		WithSymlink("some-dir/some-file", "some-link")
+
+	t.Run("file", func(ctx context.Context, t *testctx.T) {
+		stat := dir.Stat("some-dir/some-file")
+
+		name, err := stat.Name(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "some-file", name)
+
+		typ, err := stat.Type(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dagger.Regular, typ)
+
+		size, err := stat.Size(ctx)
+		require.NoError(t, err)
+		require.Equal(t, len("some-content"), size)
+
+		mode, err := stat.Mode(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0o755, mode)
+
+		uid, err := stat.UID(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0, uid)
+	})
+
+	t.Run("directory", func(ctx context.Context, t *testctx.T) {
+		typ, err := dir.Stat("some-dir").Type(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dagger.Dir, typ)
+	})
+
+	t.Run("symlink", func(ctx context.Context, t *testctx.T) {
+		stat := dir.Stat("some-link")
+
+		typ, err := stat.Type(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dagger.Symlink, typ)
+
+		target, err := stat.SymlinkTarget(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "some-dir/some-file", target)
+	})
+
+	t.Run("not found", func(ctx context.Context, t *testctx.T) {
+		_, err := dir.Stat("nope").Name(ctx)
+		require.Error(t, err)
+	})
+}
+
+func (DirectorySuite) TestWithPermissionsAndOwner(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	dir := c.Directory().
+		WithNewFile("bin/tool", "#!/bin/sh\n").
+		WithNewFile("bin/other", "#!/bin/sh\n").
+		WithNewFile("README", "")
+
+	t.Run("permissions", func(ctx context.Context, t *testctx.T) {
+		changed := dir.WithPermissions("bin", 0o755)
+
+		mode, err := changed.Stat("bin/tool").Mode(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0o755, mode)
+
+		mode, err = changed.Stat("bin/other").Mode(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0o755, mode)
+
+		mode, err = changed.Stat("README").Mode(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0o644, mode)
+	})
+
+	t.Run("owner", func(ctx context.Context, t *testctx.T) {
+		changed := dir.WithOwner(".", "1000:1001")
+
+		for _, p := range []string{"bin", "bin/tool", "README"} {
+			uid, err := changed.Stat(p).UID(ctx)
+			require.NoError(t, err)
+			require.Equal(t, 1000, uid)
+
+			gid, err := changed.Stat(p).Gid(ctx)
+			require.NoError(t, err)
+			require.Equal(t, 1001, gid)
+		}
+	})
+
+	t.Run("outside parent", func(ctx context.Context, t *testctx.T) {
+		_, err := dir.WithPermissions("../foo", 0o755).Sync(ctx)
+		require.ErrorContains(t, err, "cannot modify path outside parent")
+	})
+}
+
 func (DirectorySuite) TestDirectory(ctx context.Context, t *testctx.T) {
 	var res struct {
 		Directory struct {
@@ -462,8 +678,9 @@ func (DirectorySuite) TestWithTimestamps(ctx context.Context, t *testctx.T) {
 		ls, err := c.Container().
 			From(alpineImage).
 			WithMountedDirectory("/dir", dir).
-			WithEnvVariable("RANDOM", identity.NewID()).
-			WithExec([]string{"sh", "-c", "ls -al /dir && ls -al /dir/sub-dir"}).
+			WithExec([]string{"sh", "-c", "ls -al /dir && ls -al /dir/sub-dir"}, dagger.ContainerWithExecOpts{
+				NoCache: true,
+			}).
 			Stdout(ctx)
 		require.NoError(t, err)
 		require.Regexp(t, regexp.MustCompile(`-rw-r--r--\s+\d+ root\s+root\s+\d+ Oct 26  1985 some-file`), ls)
@@ -475,10 +692,11 @@ func (DirectorySuite) TestWithTimestamps(ctx context.Context, t *testctx.T) {
 		content, err := c.Container().
 			From(alpineImage).
 			WithMountedDirectory("/dir", dir).
-			WithEnvVariable("RANDOM", identity.NewID()).
 			// NB: there's a gotcha here: we need to tar * and not . because the
 			// directory itself has an unstable timestamp. :(
-			WithExec([]string{"sh", "-c", "tar -cf - -C /dir * | sha256sum -"}).
+			WithExec([]string{"sh", "-c", "tar -cf - -C /dir * | sha256sum -"}, dagger.ContainerWithExecOpts{
+				NoCache: true,
+			}).
 			Stdout(ctx)
 		require.NoError(t, err)
 		require.Contains(t, content, "5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef")
@@ -575,6 +793,33 @@ func (DirectorySuite) TestWithoutDirectoryWithoutFile(ctx context.Context, t *te
 	require.Equal(t, []string{"some-other-file"}, entries)
 }
 
+func (DirectorySuite) TestWithoutEntries(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	dir := c.Directory().
+		WithNewFile("main.go", "").
+		WithNewFile("main.test", "").
+		WithNewFile("pkg/util.go", "").
+		WithNewFile("pkg/util.test", "").
+		WithNewFile("node_modules/dep/index.js", "")
+
+	t.Run("removes matching entries", func(ctx context.Context, t *testctx.T) {
+		entries, err := dir.
+			WithoutEntries([]string{"**/*.test", "node_modules"}).
+			Glob(ctx, "**/*")
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"main.go", "pkg", "pkg/util.go"}, entries)
+	})
+
+	t.Run("relative to subdirectory", func(ctx context.Context, t *testctx.T) {
+		entries, err := dir.Directory("pkg").
+			WithoutEntries([]string{"*.test"}).
+			Entries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"util.go"}, entries)
+	})
+}
+
 func (DirectorySuite) TestDiff(ctx context.Context, t *testctx.T) {
 	t.Run("basic", func(ctx context.Context, t *testctx.T) {
 		aID := newDirWithFile(t, "a-file", "a-content")
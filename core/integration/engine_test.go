@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/dagger/dagger/engine/distconsts"
+	"github.com/dagger/dagger/internal/testutil"
 	"github.com/dagger/dagger/testctx"
 	"github.com/moby/buildkit/identity"
 	"github.com/stretchr/testify/require"
@@ -57,6 +59,32 @@ func devEngineContainer(c *dagger.Client, engineInstance uint8, withs ...func(*d
 		})
 }
 
+// connectDevEngine connects to a dev engine from the test itself, through a
+// tunnel on the given host port.
+func connectDevEngine(ctx context.Context, t *testctx.T, c *dagger.Client, devEngine *dagger.Service, port int) *dagger.Client {
+	t.Cleanup(func() {
+		devEngine.Stop(context.Background(), dagger.ServiceStopOpts{Kill: true})
+	})
+
+	hostSvc, err := c.Host().Tunnel(devEngine, dagger.HostTunnelOpts{
+		Ports: []dagger.PortForward{{
+			Backend:  1234,
+			Frontend: port,
+		}},
+	}).Start(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		hostSvc.Stop(context.Background(), dagger.ServiceStopOpts{Kill: true})
+	})
+
+	devClient, err := dagger.Connect(ctx,
+		dagger.WithRunnerHost(fmt.Sprintf("tcp://127.0.0.1:%d", port)),
+		dagger.WithLogOutput(testutil.NewTWriter(t.T)))
+	require.NoError(t, err)
+	t.Cleanup(func() { devClient.Close() })
+	return devClient
+}
+
 func engineClientContainer(ctx context.Context, t *testctx.T, c *dagger.Client, devEngine *dagger.Service) (*dagger.Container, error) {
 	daggerCli := daggerCliFile(t, c)
 
@@ -190,6 +218,49 @@ func (EngineSuite) TestDaggerRun(ctx context.Context, t *testctx.T) {
 	require.Contains(t, stderr, "Container.from")
 }
 
+func (EngineSuite) TestBatchedQueries(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	devEngine := devEngineContainer(c, 108).AsService()
+
+	clientCtr, err := engineClientContainer(ctx, t, c, devEngine)
+	require.NoError(t, err)
+
+	runCommand := fmt.Sprintf(`
+		export NO_COLOR=1
+		jq -n '[
+			{query:"{container{from(address: \"%s\"){file(path: \"/etc/alpine-release\"){contents}}}}"},
+			{query:"query Echo($msg: String!) {directory{withNewFile(path: \"msg\", contents: $msg){file(path: \"msg\"){contents}}}}", variables:{msg:"hello"}},
+			{query:"{nope}"}
+		]' | \
+		dagger run sh -c 'curl -s \
+			-u $DAGGER_SESSION_TOKEN: \
+			-H "content-type:application/json" \
+			-d @- \
+			http://127.0.0.1:$DAGGER_SESSION_PORT/query'`,
+		alpineImage,
+	)
+
+	clientCtr = clientCtr.
+		WithExec([]string{"apk", "add", "jq", "curl"}).
+		WithExec([]string{"sh", "-c", runCommand})
+
+	stdout, err := clientCtr.Stdout(ctx)
+	require.NoError(t, err)
+
+	var results []struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []any           `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &results))
+	require.Len(t, results, 3)
+	require.JSONEq(t, `{"container": {"from": {"file": {"contents": "`+distconsts.AlpineVersion+`\n"}}}}`, string(results[0].Data))
+	require.Empty(t, results[0].Errors)
+	require.JSONEq(t, `{"directory": {"withNewFile": {"file": {"contents": "hello"}}}}`, string(results[1].Data))
+	require.Empty(t, results[1].Errors)
+	require.NotEmpty(t, results[2].Errors)
+}
+
 func (ClientSuite) TestSendsLabelsInTelemetry(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
@@ -331,3 +402,280 @@ func (EngineSuite) TestVersionCompat(ctx context.Context, t *testctx.T) {
 	require.NoError(t, err)
 	require.Contains(t, stderr, "incompatible engine version")
 }
+
+func (EngineSuite) TestCapabilities(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	capabilities, err := c.Capabilities(ctx)
+	require.NoError(t, err)
+	require.Len(t, capabilities, 4)
+
+	supported, err := c.Capability(dagger.Privileged).Supported(ctx)
+	require.NoError(t, err)
+	require.True(t, supported)
+
+	gpu := c.Capability(dagger.Gpu)
+	supported, err = gpu.Supported(ctx)
+	require.NoError(t, err)
+	if supported {
+		t.Skip("engine supports GPUs")
+	}
+	reason, err := gpu.Reason(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, reason)
+
+	_, err = c.Container().From(alpineImage).
+		ExperimentalWithAllGPUs().
+		WithExec([]string{"true"}).
+		Sync(ctx)
+	var unsupported *dagger.UnsupportedError
+	require.ErrorAs(t, err, &unsupported)
+	require.Equal(t, dagger.Gpu, unsupported.Capability)
+	require.Equal(t, reason, unsupported.Reason)
+}
+
+func (EngineSuite) TestVersion(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	version, err := c.Engine().Version(ctx)
+	require.NoError(t, err)
+	queryVersion, err := c.Version(ctx)
+	require.NoError(t, err)
+	require.Equal(t, queryVersion, version)
+
+	buildkitVersion, err := c.Engine().BuildkitVersion(ctx)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(buildkitVersion, "v"), buildkitVersion)
+}
+
+func (EngineSuite) TestHealth(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	t.Run("ping", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Engine().Ping(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("health endpoint", func(ctx context.Context, t *testctx.T) {
+		devEngineSvc := devEngineContainer(c, 107).AsService()
+
+		out, err := c.Container().From(alpineImage).
+			WithServiceBinding("engine", devEngineSvc).
+			WithExec([]string{"wget", "-q", "-O-", "http://engine:1234/healthz"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Contains(t, out, `"version"`)
+		require.NotContains(t, out, `"error"`)
+	})
+}
+
+func (EngineSuite) TestPrune(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	// only prune cache volumes left unused for ten years, i.e. none of them, to
+	// leave the shared engine's cache alone
+	res := c.Engine().Prune(dagger.EnginePruneOpts{
+		Filter:       []string{"type==exec.cachemount"},
+		KeepDuration: "87600h",
+	})
+	reclaimed, err := res.ReclaimedBytes(ctx)
+	require.NoError(t, err)
+	require.Zero(t, reclaimed)
+	pruned, err := res.PrunedRecords(ctx)
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+
+	_, err = c.Engine().Prune(dagger.EnginePruneOpts{
+		KeepDuration: "forever",
+	}).ReclaimedBytes(ctx)
+	require.ErrorContains(t, err, "invalid keepDuration")
+
+	t.Run("nested client", func(ctx context.Context, t *testctx.T) {
+		// nested execs and module functions can't prune the shared cache
+		_, err := c.Container().From(alpineImage).
+			WithMountedFile(testCLIBinPath, daggerCliFile(t, c)).
+			WithNewFile("/query.graphql", dagger.ContainerWithNewFileOpts{
+				Contents: `{ engine { prune(keepDuration: "87600h") { prunedRecords } } }`,
+			}).
+			WithExec([]string{"dagger", "query", "--doc", "/query.graphql"}, dagger.ContainerWithExecOpts{
+				ExperimentalPrivilegedNesting: true,
+				NoCache:                       true,
+			}).
+			Sync(ctx)
+		require.ErrorContains(t, err, "only admins of the engine")
+	})
+}
+
+func (EngineSuite) TestCacheUsage(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	_, err := c.Container().From(alpineImage).
+		WithMountedCache("/cache", c.CacheVolume(identity.NewID())).
+		WithExec([]string{"sh", "-c", "head -c 1048576 /dev/urandom > /cache/data"}).
+		Sync(ctx)
+	require.NoError(t, err)
+
+	usages, err := c.Engine().CacheUsage(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, usages)
+
+	byType := map[string]int{}
+	for _, usage := range usages {
+		recordType, err := usage.RecordType(ctx)
+		require.NoError(t, err)
+		size, err := usage.SizeBytes(ctx)
+		require.NoError(t, err)
+		reclaimable, err := usage.ReclaimableBytes(ctx)
+		require.NoError(t, err)
+		require.LessOrEqual(t, reclaimable, size)
+		byType[recordType] = size
+	}
+	require.GreaterOrEqual(t, byType["exec.cachemount"], 1<<20)
+	require.Positive(t, byType["regular"])
+}
+
+func (EngineSuite) TestSessionBudget(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	// the engine caps the budget of every session, whatever the client declares
+	devEngine := devEngineContainer(c, 109, func(c *dagger.Container) *dagger.Container {
+		return c.WithEnvVariable("DAGGER_BUDGET_MAX_PULL_BYTES", "1")
+	}).AsService()
+	clientCtr, err := engineClientContainer(ctx, t, c, devEngine)
+	require.NoError(t, err)
+	clientCtr = clientCtr.WithEnvVariable("DAGGER_BUDGET_MAX_PULL_BYTES", "1000000000")
+
+	for name, query := range map[string]string{
+		"from": `{ container { from(address: "` + alpineImage + `") { id } } }`,
+		"dockerBuild": `{ directory {
+			withNewFile(path: "Dockerfile", contents: "FROM ` + alpineImage + `\nRUN true") {
+				dockerBuild { sync }
+			}
+		} }`,
+	} {
+		t.Run(name, func(ctx context.Context, t *testctx.T) {
+			_, err := clientCtr.
+				WithNewFile("/query.graphql", dagger.ContainerWithNewFileOpts{
+					Contents: query,
+				}).
+				WithExec([]string{"dagger", "query", "--doc", "/query.graphql"}).
+				Sync(ctx)
+			require.ErrorContains(t, err, "exceeds budget")
+		})
+	}
+}
+
+func (EngineSuite) TestSessions(ctx context.Context, t *testctx.T) {
+	c1 := connect(ctx, t)
+	c2 := connect(ctx, t)
+
+	svc := c2.Container().From(alpineImage).
+		WithExposedPort(8000).
+		WithExec([]string{"nc", "-lk", "-p", "8000"}).
+		AsService()
+	svc, err := svc.Start(ctx)
+	require.NoError(t, err)
+	svcHost, err := svc.Hostname(ctx)
+	require.NoError(t, err)
+
+	currentSessionID := func(c *dagger.Client) string {
+		sessions, err := c.Engine().Sessions(ctx)
+		require.NoError(t, err)
+		for _, sess := range sessions {
+			current, err := sess.Current(ctx)
+			require.NoError(t, err)
+			if current {
+				id, err := sess.SessionID(ctx)
+				require.NoError(t, err)
+				return id
+			}
+		}
+		t.Fatal("current session not listed")
+		return ""
+	}
+	sess1 := currentSessionID(c1)
+	sess2 := currentSessionID(c2)
+	require.NotEqual(t, sess1, sess2)
+
+	sessions, err := c1.Engine().Sessions(ctx)
+	require.NoError(t, err)
+	var services []string
+	for _, sess := range sessions {
+		id, err := sess.SessionID(ctx)
+		require.NoError(t, err)
+		if id != sess2 {
+			continue
+		}
+		services, err = sess.Services(ctx)
+		require.NoError(t, err)
+	}
+	require.Contains(t, services, svcHost)
+
+	_, err = c1.Engine().RemoveSession(ctx, sess1)
+	require.ErrorContains(t, err, "cannot remove the current session")
+
+	t.Run("nested client", func(ctx context.Context, t *testctx.T) {
+		// nested execs and module functions don't act for the client that
+		// started their session, so they can't remove other sessions
+		_, err := c1.Container().From(alpineImage).
+			WithMountedFile(testCLIBinPath, daggerCliFile(t, c1)).
+			WithNewFile("/query.graphql", dagger.ContainerWithNewFileOpts{
+				Contents: `{ engine { removeSession(sessionID: "` + sess2 + `") } }`,
+			}).
+			WithExec([]string{"dagger", "query", "--doc", "/query.graphql"}, dagger.ContainerWithExecOpts{
+				ExperimentalPrivilegedNesting: true,
+				NoCache:                       true,
+			}).
+			Sync(ctx)
+		require.ErrorContains(t, err, "not found")
+	})
+
+	_, err = c1.Engine().RemoveSession(ctx, sess2)
+	require.NoError(t, err)
+
+	sessions, err = c1.Engine().Sessions(ctx)
+	require.NoError(t, err)
+	for _, sess := range sessions {
+		id, err := sess.SessionID(ctx)
+		require.NoError(t, err)
+		require.NotEqual(t, sess2, id)
+	}
+}
+
+func (EngineSuite) TestResumeAfterEngineRestart(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	devEngine := devEngineContainer(c, 123).AsService()
+	devClient := connectDevEngine(ctx, t, c, devEngine, 32135)
+
+	secret := devClient.SetSecret("resume-secret", "hunter2")
+	_, err := secret.ID(ctx)
+	require.NoError(t, err)
+
+	ctr := devClient.Container().From(alpineImage).
+		WithEnvVariable("CACHEBUST", identity.NewID()).
+		WithExec([]string{"sh", "-c", "head -c 16 /dev/urandom | base64"})
+	before, err := ctr.Stdout(ctx)
+	require.NoError(t, err)
+
+	// kill the engine mid-session and start it again with the same state
+	_, err = devEngine.Stop(ctx, dagger.ServiceStopOpts{Kill: true})
+	require.NoError(t, err)
+	_, err = devEngine.Start(ctx)
+	require.NoError(t, err)
+
+	t.Run("cached results", func(ctx context.Context, t *testctx.T) {
+		after, err := ctr.Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, before, after)
+	})
+
+	t.Run("secrets", func(ctx context.Context, t *testctx.T) {
+		out, err := devClient.Container().From(alpineImage).
+			WithMountedSecret("/secret", secret).
+			WithExec([]string{"cat", "/secret"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "hunter2", out)
+	})
+}
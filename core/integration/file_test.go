@@ -11,7 +11,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/moby/buildkit/identity"
 	"github.com/stretchr/testify/require"
 
 	"dagger.io/dagger"
@@ -116,6 +115,41 @@ func (FileSuite) TestSize(ctx context.Context, t *testctx.T) {
 	require.Equal(t, len("some-content"), res.Directory.WithNewFile.File.Size)
 }
 
+func (FileSuite) TestModeAndModTime(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	file := c.Directory().
+		WithNewFile("some-file", "some-content", dagger.DirectoryWithNewFileOpts{
+			Permissions: 0o755,
+		}).
+		File("some-file").
+		WithTimestamps(1672531199)
+
+	mode, err := file.Mode(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0o755, mode)
+
+	modTime, err := file.ModTime(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1672531199, modTime)
+}
+
+func (FileSuite) TestDigest(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	file := c.Directory().
+		WithNewFile("some-file", "some-content").
+		File("some-file")
+
+	dgst, err := file.Digest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "sha256:0a8cac771ca188eacc57e2c96c31f5611925c5ecedccb16b8c236d6c0d325112", dgst)
+
+	dgst, err = file.Digest(ctx, dagger.FileDigestOpts{Algorithm: dagger.Sha512})
+	require.NoError(t, err)
+	require.Equal(t, "sha512:e4694dce7937894bba2b5b4ad33dfd1893d8494fcd4eaac7ca3392c49ddba2ef0497b2a0307c81580611a785a0244a6003b4bae0d978c8d1544f4f691e6c7130", dgst)
+}
+
 func (FileSuite) TestName(ctx context.Context, t *testctx.T) {
 	wd := t.TempDir()
 
@@ -335,8 +369,9 @@ func (FileSuite) TestWithTimestamps(ctx context.Context, t *testctx.T) {
 	ls, err := c.Container().
 		From(alpineImage).
 		WithMountedFile("/file", file).
-		WithEnvVariable("RANDOM", identity.NewID()).
-		WithExec([]string{"stat", "/file"}).
+		WithExec([]string{"stat", "/file"}, dagger.ContainerWithExecOpts{
+			NoCache: true,
+		}).
 		Stdout(ctx)
 	require.NoError(t, err)
 	require.Contains(t, ls, "Access: 1985-10-26 08:15:00.000000000 +0000")
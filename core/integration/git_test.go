@@ -273,6 +273,32 @@ func (GitSuite) TestAuth(ctx context.Context, t *testctx.T) {
 		require.Equal(t, "Hello, world!", dt)
 	})
 
+	t.Run("token auth with username", func(ctx context.Context, t *testctx.T) {
+		dt, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: gitDaemon}).
+			WithAuthToken(c.SetSecret("token", "foobar"), dagger.GitRepositoryWithAuthTokenOpts{
+				Username: "gitlab-ci-token",
+			}).
+			Branch("main").
+			Tree().
+			File("README.md").
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Hello, world!", dt)
+	})
+
+	t.Run("token auth with incorrect username", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: gitDaemon}).
+			WithAuthToken(c.SetSecret("token", "foobar"), dagger.GitRepositoryWithAuthTokenOpts{
+				Username: "wrong-user",
+			}).
+			Branch("main").
+			Tree().
+			File("README.md").
+			Contents(ctx)
+		require.ErrorContains(t, err, "git error")
+		require.ErrorContains(t, err, "failed to fetch remote")
+	})
+
 	t.Run("header auth", func(ctx context.Context, t *testctx.T) {
 		dt, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: gitDaemon}).
 			WithAuthHeader(c.SetSecret("header", "basic "+base64.StdEncoding.EncodeToString([]byte("x-access-token:foobar")))).
@@ -301,6 +327,39 @@ func (GitSuite) TestKeepGitDir(ctx context.Context, t *testctx.T) {
 	})
 }
 
+func (GitSuite) TestDepth(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	countCommits := func(dir *dagger.Directory) string {
+		out, err := c.Container().
+			From(alpineImage).
+			WithExec([]string{"apk", "add", "git"}).
+			WithMountedDirectory("/src", dir).
+			WithWorkdir("/src").
+			WithExec([]string{"git", "rev-list", "--count", "HEAD"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		return strings.TrimSpace(out)
+	}
+
+	repo := c.Git("https://github.com/dagger/dagger", dagger.GitOpts{KeepGitDir: true})
+
+	t.Run("shallow by default", func(ctx context.Context, t *testctx.T) {
+		require.Equal(t, "1", countCommits(repo.Tag("v0.11.9").Tree()))
+	})
+
+	t.Run("custom depth", func(ctx context.Context, t *testctx.T) {
+		require.Equal(t, "5", countCommits(repo.Tag("v0.11.9").Tree(dagger.GitRefTreeOpts{
+			Depth: 5,
+		})))
+	})
+
+	t.Run("invalid depth", func(ctx context.Context, t *testctx.T) {
+		_, err := repo.Tag("v0.11.9").Tree(dagger.GitRefTreeOpts{Depth: -2}).Entries(ctx)
+		require.ErrorContains(t, err, "depth must be positive")
+	})
+}
+
 func (GitSuite) TestServiceStableDigest(ctx context.Context, t *testctx.T) {
 	content := identity.NewID()
 	hostname := func(c *dagger.Client) string {
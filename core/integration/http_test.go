@@ -2,10 +2,12 @@ package core
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/dagger/dagger/testctx"
 	"github.com/moby/buildkit/identity"
+	"github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/require"
 
 	"dagger.io/dagger"
@@ -64,3 +66,113 @@ func (HTTPSuite) TestHTTPServiceStableDigest(ctx context.Context, t *testctx.T)
 	c2 := connect(ctx, t)
 	require.Equal(t, hostname(c1), hostname(c2))
 }
+
+func (HTTPSuite) TestHTTPFileName(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	name, err := c.HTTP("https://raw.githubusercontent.com/dagger/dagger/main/README.md").Name(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "README.md", name)
+
+	svc, url := httpService(ctx, t, c, "Hello, world!")
+	name, err = c.Directory().
+		WithFiles("/", []*dagger.File{c.HTTP(url+"/index.html", dagger.HTTPOpts{
+			ExperimentalServiceHost: svc,
+		})}).
+		File("index.html").
+		Name(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "index.html", name)
+}
+
+func (HTTPSuite) TestHTTPChecksum(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	content := identity.NewID()
+	svc, url := httpService(ctx, t, c, content)
+
+	t.Run("matching checksum", func(ctx context.Context, t *testctx.T) {
+		contents, err := c.HTTP(url, dagger.HTTPOpts{
+			ExperimentalServiceHost: svc,
+			Checksum:                digest.FromString(content).String(),
+		}).Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, content, contents)
+	})
+
+	t.Run("mismatched checksum", func(ctx context.Context, t *testctx.T) {
+		_, err := c.HTTP(url, dagger.HTTPOpts{
+			ExperimentalServiceHost: svc,
+			Checksum:                digest.FromString("nope").String(),
+		}).Contents(ctx)
+		require.ErrorContains(t, err, "digest mismatch")
+	})
+
+	t.Run("invalid checksum", func(ctx context.Context, t *testctx.T) {
+		_, err := c.HTTP(url, dagger.HTTPOpts{
+			ExperimentalServiceHost: svc,
+			Checksum:                "sha256:nope",
+		}).Contents(ctx)
+		require.ErrorContains(t, err, "invalid checksum")
+	})
+}
+
+func (HTTPSuite) TestTool(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	platform, err := c.DefaultPlatform(ctx)
+	require.NoError(t, err)
+
+	// releases that the engine doesn't pin need an explicit checksum, which is
+	// what tests can rely on regardless of the pinned releases
+	publishedChecksum := func(ctx context.Context, t *testctx.T, url string) string {
+		contents, err := c.HTTP(url).Contents(ctx)
+		require.NoError(t, err)
+		return "sha256:" + strings.Fields(contents)[0]
+	}
+
+	t.Run("binary", func(ctx context.Context, t *testctx.T) {
+		checksum := publishedChecksum(ctx, t,
+			"https://dl.k8s.io/release/v1.30.2/bin/"+string(platform)+"/kubectl.sha256")
+		out, err := c.Container().From(alpineImage).
+			WithFile("/usr/local/bin/kubectl", c.Tool("kubectl", "1.30.2", dagger.ToolOpts{
+				Checksum: checksum,
+			})).
+			WithExec([]string{"kubectl", "version", "--client"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Contains(t, out, "v1.30.2")
+	})
+
+	t.Run("archive", func(ctx context.Context, t *testctx.T) {
+		checksum := publishedChecksum(ctx, t,
+			"https://get.helm.sh/helm-v3.15.2-"+strings.ReplaceAll(string(platform), "/", "-")+".tar.gz.sha256sum")
+		out, err := c.Container().From(alpineImage).
+			WithFile("/usr/local/bin/helm", c.Tool("helm", "v3.15.2", dagger.ToolOpts{
+				Checksum: checksum,
+			})).
+			WithExec([]string{"helm", "version", "--short"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Contains(t, out, "v3.15.2")
+	})
+
+	t.Run("mismatched checksum", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Tool("kubectl", "1.30.2", dagger.ToolOpts{
+			Checksum: digest.FromString("nope").String(),
+		}).Sync(ctx)
+		require.ErrorContains(t, err, "digest mismatch")
+	})
+
+	t.Run("unpinned release", func(ctx context.Context, t *testctx.T) {
+		// the checksum published next to the release isn't trusted, since it's
+		// served by the same origin
+		_, err := c.Tool("kubectl", "1.0.0").Sync(ctx)
+		require.ErrorContains(t, err, "is not pinned by the engine")
+	})
+
+	t.Run("unknown tool", func(ctx context.Context, t *testctx.T) {
+		_, err := c.Tool("nope", "1.0.0").Sync(ctx)
+		require.ErrorContains(t, err, `unknown tool "nope"`)
+	})
+}
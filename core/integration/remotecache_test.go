@@ -6,8 +6,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/dagger/dagger/internal/testutil"
 	"github.com/dagger/dagger/testctx"
-	"github.com/moby/buildkit/identity"
 	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
 
@@ -38,10 +38,7 @@ func TestRemoteCache(t *testing.T) {
 func (RemoteCacheSuite) TestRegistry(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
-	registry := c.Pipeline("registry").Container().From("registry:2").
-		WithMountedCache("/var/lib/registry/", c.CacheVolume("remote-cache-registry-"+identity.NewID())).
-		WithExposedPort(5000, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}).
-		AsService()
+	registry := testutil.StartRegistry(ctx, t, c).Service
 
 	cacheEnv := "type=registry,ref=registry:5000/test-cache,mode=max"
 
@@ -117,10 +114,7 @@ The linked PR description above has more details.
 func (RemoteCacheSuite) TestLazyBlobs(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
-	registry := c.Pipeline("registry").Container().From("registry:2").
-		WithMountedCache("/var/lib/registry/", c.CacheVolume("remote-cache-registry-"+identity.NewID())).
-		WithExposedPort(5000, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}).
-		AsService()
+	registry := testutil.StartRegistry(ctx, t, c).Service
 
 	cacheEnv := "type=registry,ref=registry:5000/test-cache,mode=max"
 
@@ -179,28 +173,10 @@ func (RemoteCacheSuite) TestS3(ctx context.Context, t *testctx.T) {
 	t.Run("buildkit s3 caching", func(ctx context.Context, t *testctx.T) {
 		c := connect(ctx, t)
 
-		bucket := "dagger-test-remote-cache-s3-" + identity.NewID()
+		s3 := testutil.StartS3(ctx, t, c)
+		s3Env := "type=s3,mode=max,endpoint_url=" + s3.Endpoint + ",access_key_id=" + s3.AccessKeyID + ",secret_access_key=" + s3.SecretAccessKey + ",region=" + s3.Region + ",use_path_style=true,bucket=" + s3.Bucket
 
-		s3 := c.Pipeline("s3").Container().From("minio/minio").
-			WithMountedCache("/data", c.CacheVolume("minio-cache")).
-			WithExposedPort(9000, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}).
-			WithExec([]string{"server", "/data"}).
-			AsService()
-
-		s3Endpoint, err := s3.Endpoint(ctx, dagger.ServiceEndpointOpts{Port: 9000, Scheme: "http"})
-		require.NoError(t, err)
-
-		minioStdout, err := c.Container().From("minio/mc").
-			WithServiceBinding("s3", s3).
-			WithEntrypoint([]string{"sh"}).
-			WithExec([]string{"-c", "mc alias set minio http://s3:9000 minioadmin minioadmin && mc mb minio/" + bucket}).
-			Stdout(ctx)
-		require.NoError(t, err)
-		require.Contains(t, minioStdout, "Bucket created successfully")
-
-		s3Env := "type=s3,mode=max,endpoint_url=" + s3Endpoint + ",access_key_id=minioadmin,secret_access_key=minioadmin,region=mars,use_path_style=true,bucket=" + bucket
-
-		devEngineA, endpointA, err := getDevEngineForRemoteCache(ctx, c, s3, "s3", 0)
+		devEngineA, endpointA, err := getDevEngineForRemoteCache(ctx, c, s3.Service, "s3", 0)
 		require.NoError(t, err)
 
 		daggerCli := c.Host().Directory("/dagger-dev/", dagger.HostDirectoryOpts{Include: []string{"dagger"}}).File("dagger")
@@ -229,7 +205,7 @@ func (RemoteCacheSuite) TestS3(ctx context.Context, t *testctx.T) {
 		shaA := strings.TrimSpace(gjson.Get(outputA, "container.from.withExec.stdout").String())
 		require.NotEmpty(t, shaA, "shaA is empty")
 
-		devEngineB, endpointB, err := getDevEngineForRemoteCache(ctx, c, s3, "s3", 1)
+		devEngineB, endpointB, err := getDevEngineForRemoteCache(ctx, c, s3.Service, "s3", 1)
 		require.NoError(t, err)
 
 		outputB, err := c.Container().From(alpineImage).
@@ -264,10 +240,7 @@ func (RemoteCacheSuite) TestRegistryMultipleConfigs(ctx context.Context, t *test
 	c := connect(ctx, t)
 	defer c.Close()
 
-	registry := c.Pipeline("registry").Container().From("registry:2").
-		WithMountedCache("/var/lib/registry/", c.CacheVolume("remote-cache-registry-"+identity.NewID())).
-		WithExposedPort(5000, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}).
-		AsService()
+	registry := testutil.StartRegistry(ctx, t, c).Service
 
 	cacheConfigEnv1 := "type=registry,ref=registry:5000/test-cache:latest,mode=max"
 	cacheConfigEnv2 := "type=registry,ref=registry:5000/test-cache-b:latest,mode=max"
@@ -365,10 +338,7 @@ func (RemoteCacheSuite) TestRegistrySeparateImportExport(ctx context.Context, t
 	c := connect(ctx, t)
 	defer c.Close()
 
-	registry := c.Pipeline("registry").Container().From("registry:2").
-		WithMountedCache("/var/lib/registry/", c.CacheVolume("remote-cache-registry-"+identity.NewID())).
-		WithExposedPort(5000, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}).
-		AsService()
+	registry := testutil.StartRegistry(ctx, t, c).Service
 
 	daggerCli := daggerCliFile(t, c)
 
@@ -497,10 +467,7 @@ func (RemoteCacheSuite) TestRegistryFastCacheBlobSource(ctx context.Context, t *
 	c := connect(ctx, t)
 	defer c.Close()
 
-	registry := c.Pipeline("registry").Container().From("registry:2").
-		WithMountedCache("/var/lib/registry/", c.CacheVolume("remote-cache-registry-"+identity.NewID())).
-		WithExposedPort(5000, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}).
-		AsService()
+	registry := testutil.StartRegistry(ctx, t, c).Service
 
 	cacheConfig := "type=registry,ref=registry:5000/test-cache:latest,mode=max"
 
@@ -151,6 +151,29 @@ func (SecretSuite) TestUnsetVariable(ctx context.Context, t *testctx.T) {
 	require.NotContains(t, out, "AWS_KEY")
 }
 
+func (SecretSuite) TestNoCrossTalk(ctx context.Context, t *testctx.T) {
+	c1 := connect(ctx, t)
+	c2 := connect(ctx, t)
+
+	s1 := c1.SetSecret("aws_key", "secret-one")
+	s2 := c2.SetSecret("aws_key", "secret-two")
+
+	// each client sees its own value for the same name
+	plaintext, err := s1.Plaintext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "secret-one", plaintext)
+
+	plaintext, err = s2.Plaintext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "secret-two", plaintext)
+
+	// and can't read the other client's secret from its ID
+	id1, err := s1.ID(ctx)
+	require.NoError(t, err)
+	_, err = c2.LoadSecretFromID(id1).Plaintext(ctx)
+	require.Error(t, err)
+}
+
 func (SecretSuite) TestWhitespaceScrubbed(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
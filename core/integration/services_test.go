@@ -770,11 +770,19 @@ func (ContainerSuite) TestMultiPlatformExportServices(ctx context.Context, t *te
 func (ServiceSuite) TestContainerPublish(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
+	// publish to a registry of the test's own, through a dev engine bound to
+	// it, rather than to the registry shared by every test
+	registry := testutil.StartRegistry(ctx, t, c)
+	devEngine := devEngineContainer(c, 121, func(ctr *dagger.Container) *dagger.Container {
+		return ctr.WithServiceBinding("registry", registry.Service)
+	}).AsService()
+	c2 := connectDevEngine(ctx, t, c, devEngine, 32133)
+
 	content := identity.NewID()
-	srv, url := httpService(ctx, t, c, content)
+	srv, url := httpService(ctx, t, c2, content)
 
-	testRef := registryRef("services-container-publish")
-	pushedRef, err := c.Container().
+	testRef := "registry:5000/services-container-publish:" + identity.NewID()
+	pushedRef, err := c2.Container().
 		From(alpineImage).
 		WithServiceBinding("www", srv).
 		WithExec([]string{"wget", url}).
@@ -783,7 +791,7 @@ func (ServiceSuite) TestContainerPublish(ctx context.Context, t *testctx.T) {
 	require.NotEqual(t, testRef, pushedRef)
 	require.Contains(t, pushedRef, "@sha256:")
 
-	fileContent, err := c.Container().
+	fileContent, err := c2.Container().
 		From(pushedRef).Rootfs().File("/index.html").Contents(ctx)
 	require.NoError(t, err)
 	require.Equal(t, fileContent, content)
@@ -1735,7 +1743,7 @@ server {
 			Contents: config.String(),
 		}).
 		WithMountedDirectory("/usr/share/nginx/html", makeGitDir(c, content, branchName)).
-		WithMountedSecret("/usr/share/nginx/htpasswd", c.SetSecret("htpasswd", "x-access-token:{PLAIN}"+tokenPlaintext), dagger.ContainerWithMountedSecretOpts{
+		WithMountedSecret("/usr/share/nginx/htpasswd", c.SetSecret("htpasswd", "x-access-token:{PLAIN}"+tokenPlaintext+"\ngitlab-ci-token:{PLAIN}"+tokenPlaintext), dagger.ContainerWithMountedSecretOpts{
 			Owner: "nginx",
 		}).
 		WithExposedPort(80).
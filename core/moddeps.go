@@ -127,6 +127,7 @@ func (d *ModDeps) lazilyLoadSchema(ctx context.Context) (
 	dag := dagql.NewServer[*Query](d.root)
 
 	dag.Around(AroundFunc)
+	dag.Observe(d.root.Observer)
 
 	// share the same cache session-wide
 	dag.Cache = d.root.Cache
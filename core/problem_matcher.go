@@ -0,0 +1,113 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Names of the regexp capture groups recognized by a ProblemMatcher.
+const (
+	problemGroupFile     = "file"
+	problemGroupLine     = "line"
+	problemGroupColumn   = "column"
+	problemGroupSeverity = "severity"
+	problemGroupMessage  = "message"
+)
+
+type ProblemMatcher struct {
+	Pattern  string `field:"true" doc:"A regular expression matched against each line of output. The named groups file, line, column, severity and message are extracted into the diagnostic."`
+	Severity string `field:"true" doc:"The severity of diagnostics whose pattern has no severity group, or whose severity group did not match." default:"error"`
+}
+
+func (ProblemMatcher) TypeName() string {
+	return "ProblemMatcher"
+}
+
+func (ProblemMatcher) TypeDescription() string {
+	return "A regular expression extracting diagnostics from the output of a command."
+}
+
+// Compile validates the matcher's pattern.
+func (matcher ProblemMatcher) Compile() (*regexp.Regexp, error) {
+	re, err := regexp.Compile(matcher.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid problem matcher pattern %q: %w", matcher.Pattern, err)
+	}
+	if re.SubexpIndex(problemGroupMessage) < 0 {
+		return nil, fmt.Errorf("problem matcher pattern %q has no %q group", matcher.Pattern, problemGroupMessage)
+	}
+	return re, nil
+}
+
+type Diagnostic struct {
+	File     string `field:"true" doc:"The file the diagnostic refers to, or an empty string if unknown."`
+	Line     int    `field:"true" doc:"The line the diagnostic refers to, or 0 if unknown."`
+	Column   int    `field:"true" doc:"The column the diagnostic refers to, or 0 if unknown."`
+	Severity string `field:"true" doc:"The severity of the diagnostic (e.g., \"error\", \"warning\")."`
+	Message  string `field:"true" doc:"The message of the diagnostic."`
+}
+
+func (Diagnostic) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "Diagnostic",
+		NonNull:   true,
+	}
+}
+
+func (Diagnostic) TypeDescription() string {
+	return "A structured diagnostic extracted from the output of a command."
+}
+
+// MatchProblems returns the diagnostics found by the matchers in each line
+// of output. A line matched by several matchers yields a diagnostic for the
+// first one only.
+func MatchProblems(matchers []ProblemMatcher, output string) ([]Diagnostic, error) {
+	res := make([]*regexp.Regexp, len(matchers))
+	for i, matcher := range matchers {
+		re, err := matcher.Compile()
+		if err != nil {
+			return nil, err
+		}
+		res[i] = re
+	}
+
+	diagnostics := []Diagnostic{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for i, re := range res {
+			m := re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			group := func(name string) string {
+				if idx := re.SubexpIndex(name); idx >= 0 {
+					return strings.TrimSpace(m[idx])
+				}
+				return ""
+			}
+			diag := Diagnostic{
+				File:     group(problemGroupFile),
+				Severity: strings.ToLower(group(problemGroupSeverity)),
+				Message:  group(problemGroupMessage),
+			}
+			diag.Line, _ = strconv.Atoi(group(problemGroupLine))
+			diag.Column, _ = strconv.Atoi(group(problemGroupColumn))
+			if diag.Severity == "" {
+				diag.Severity = matchers[i].Severity
+			}
+			diagnostics = append(diagnostics, diag)
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return diagnostics, nil
+}
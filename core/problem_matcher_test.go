@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchProblems(t *testing.T) {
+	output := "ok  \tpkg/a\n" +
+		"main.go:12:5: undefined: foo\n" +
+		"lib/util.go:3: warning: unused variable\n" +
+		"WARN something happened\n"
+
+	diagnostics, err := MatchProblems([]ProblemMatcher{
+		{Pattern: `^(?P<file>[^:\s]+):(?P<line>\d+):(?:(?P<column>\d+):)?\s*(?:(?P<severity>warning|error):)?\s*(?P<message>.*)$`, Severity: "error"},
+		{Pattern: `^WARN (?P<message>.*)$`, Severity: "warning"},
+	}, output)
+	require.NoError(t, err)
+	require.Equal(t, []Diagnostic{
+		{File: "main.go", Line: 12, Column: 5, Severity: "error", Message: "undefined: foo"},
+		{File: "lib/util.go", Line: 3, Severity: "warning", Message: "unused variable"},
+		{Severity: "warning", Message: "something happened"},
+	}, diagnostics)
+
+	diagnostics, err = MatchProblems(nil, output)
+	require.NoError(t, err)
+	require.Empty(t, diagnostics)
+
+	_, err = MatchProblems([]ProblemMatcher{{Pattern: `(`}}, output)
+	require.ErrorContains(t, err, "invalid problem matcher pattern")
+
+	_, err = MatchProblems([]ProblemMatcher{{Pattern: `^(?P<file>.*)$`}}, output)
+	require.ErrorContains(t, err, `has no "message" group`)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/containerd/containerd/content"
 	"github.com/moby/buildkit/util/leaseutil"
@@ -13,6 +14,7 @@ import (
 	"github.com/dagger/dagger/core/pipeline"
 	"github.com/dagger/dagger/dagql"
 	"github.com/dagger/dagger/dagql/call"
+	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/engine/buildkit"
 )
 
@@ -43,6 +45,9 @@ type QueryOpts struct {
 	// The default platform.
 	Platform Platform
 
+	// The project defaults of the session.
+	Defaults engine.Defaults
+
 	// The default deps of every user module (currently just core)
 	DefaultDeps *ModDeps
 
@@ -52,6 +57,9 @@ type QueryOpts struct {
 	Buildkit *buildkit.Client
 
 	MainClientCallerID string
+
+	// Called after every selection made by the client, cached or not, if set.
+	Observer dagql.ObserveFunc
 }
 
 type Server interface {
@@ -60,6 +68,24 @@ type Server interface {
 	CurrentFunctionCall(context.Context) (*FunctionCall, error)
 	CurrentServedDeps(context.Context) (*ModDeps, error)
 	MuxEndpoint(context.Context, string, http.Handler) error
+	EngineSessions(context.Context) ([]EngineSession, error)
+	RemoveEngineSession(context.Context, string) error
+	CheckEngineAdmin(context.Context) error
+	EngineHealth(context.Context) error
+}
+
+// ResolveImageAlias returns the address of an image alias from the project
+// defaults if addr is formatted as base://<alias>, or else addr.
+func (q *Query) ResolveImageAlias(addr string) (string, error) {
+	alias, ok := strings.CutPrefix(addr, engine.BaseImageScheme)
+	if !ok {
+		return addr, nil
+	}
+	resolved, ok := q.Defaults.Images[alias]
+	if !ok {
+		return "", fmt.Errorf("unknown image alias %q: not configured in project defaults", alias)
+	}
+	return resolved, nil
 }
 
 func NewRoot(opts QueryOpts) *Query {
@@ -111,6 +137,12 @@ func (q *Query) NewHost() *Host {
 	}
 }
 
+func (q *Query) NewEngine() *Engine {
+	return &Engine{
+		Query: q,
+	}
+}
+
 func (q *Query) NewModule() *Module {
 	return &Module{
 		Query: q,
@@ -2,15 +2,19 @@ package schema
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/moby/buildkit/frontend/dockerfile/shell"
+	"github.com/moby/sys/signal"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/vektah/gqlparser/v2/ast"
 
@@ -35,6 +39,21 @@ func (s *containerSchema) Install() {
 				host.`).
 			ArgDoc("platform", `Platform to initialize the container with.`).
 			ArgDeprecated("id", "Use `loadContainerFromID` instead."),
+
+		dagql.Func("copyImage", s.copyImage).
+			Impure("Writes to the specified Docker registry.").
+			Doc(`Copies an image, including all of its platforms, from one address to
+				another using only registry APIs, without pulling its layers into the
+				engine.`,
+				`Layers are mounted from the source repository when both addresses
+				are on the same registry, and streamed between the registries
+				otherwise.`,
+				`Returns the fully qualified ref of the copied image.`).
+			ArgDoc("source",
+				`Address of the image to copy (e.g., "docker.io/dagger/dagger:staging").`).
+			ArgDoc("destination",
+				`Address to copy the image to, formatted as [host]/[user]/[repo]:[tag]
+				(e.g., "docker.io/dagger/dagger:v1.0.0").`),
 	}.Install(s.srv)
 
 	dagql.Fields[*core.Container]{
@@ -42,6 +61,19 @@ func (s *containerSchema) Install() {
 			Doc(`Forces evaluation of the pipeline in the engine.`,
 				`It doesn't run the default command if no exec has been set.`),
 
+		Grapher[*core.Container]().
+			Doc(`Evaluates the container's root filesystem and returns the graph of its
+				operations.`,
+				`Each operation is reported with its cache status, so that accidental
+				serialization or cache misses can be spotted.`),
+
+		CacheChecker[*core.Container]().
+			Doc(`Returns whether the execs of the container's root filesystem are
+				already cached, without running them.`,
+				`Other operations, like pulling images or loading host directories, are
+				still performed, since the cache keys of the execs depend on their
+				results.`),
+
 		dagql.Func("pipeline", s.pipeline).
 			Doc(`Creates a named sub-pipeline.`).
 			ArgDoc("name", "Name of the sub-pipeline.").
@@ -52,7 +84,14 @@ func (s *containerSchema) Install() {
 			Doc(`Initializes this container from a pulled base image.`).
 			ArgDoc("address",
 				`Image's address from its registry.`,
-				`Formatted as [host]/[user]/[repo]:[tag] (e.g., "docker.io/dagger/dagger:main").`),
+				`Formatted as [host]/[user]/[repo]:[tag] (e.g., "docker.io/dagger/dagger:main"),
+				or as base://[alias] for an image alias configured in the project's
+				defaults (e.g., "base://go").`).
+			ArgDoc("pullPolicy",
+				`Policy to follow when pulling the image.`,
+				`NEVER requires the address to be pinned by digest (e.g.,
+				"alpine@sha256:..."), since the registry can't be asked which image
+				a tag points to.`),
 
 		dagql.Func("build", s.build).
 			Doc(`Initializes this container from a Dockerfile build.`).
@@ -107,6 +146,33 @@ func (s *containerSchema) Install() {
 			Doc(`Retrieves this container with an unset working directory.`,
 				`Should default to "/".`),
 
+		dagql.Func("stopSignal", s.stopSignal).
+			Doc("Retrieves the signal sent to stop containers run from the image."),
+
+		dagql.Func("withStopSignal", s.withStopSignal).
+			Doc(`Retrieves this container with a different stop signal.`,
+				`The signal is sent to the main process of containers run from the
+				published image when they are stopped.`).
+			ArgDoc("signal", `The signal to set, by name or number (e.g., "SIGQUIT" or "3").`),
+
+		dagql.Func("withoutStopSignal", s.withoutStopSignal).
+			Doc(`Retrieves this container with an unset stop signal.`,
+				`Should default to "SIGTERM".`),
+
+		dagql.Func("volumes", s.volumes).
+			Doc("Retrieves the paths declared as volumes in the image config."),
+
+		dagql.Func("withVolume", s.withVolume).
+			Doc(`Retrieves this container plus the given path declared as a volume.`,
+				`The declaration is only image metadata: it doesn't mount anything in
+				the container, but tells runtimes of the published image to store the
+				path outside of the container's filesystem.`).
+			ArgDoc("path", `Location of the volume (e.g., "/var/lib/postgresql/data").`),
+
+		dagql.Func("withoutVolume", s.withoutVolume).
+			Doc(`Retrieves this container minus the given volume declaration.`).
+			ArgDoc("path", `Location of the volume to remove (e.g., "/var/lib/postgresql/data").`),
+
 		dagql.Func("envVariables", s.envVariables).
 			Doc(`Retrieves the list of environment variables passed to commands.`),
 
@@ -159,6 +225,18 @@ func (s *containerSchema) Install() {
 			Doc(`Retrieves this container minus the given environment label.`).
 			ArgDoc("name", `The name of the label to remove (e.g., "org.opencontainers.artifact.created").`),
 
+		dagql.Func("withAnnotation", s.withAnnotation).
+			Doc(`Retrieves this container plus the given OCI annotation, set on the
+				manifest of the image when it's published or exported.`,
+				`The annotations of the container the platform variants are published
+				with are also set on the index of multi-platform images.`).
+			ArgDoc("name", `The name of the annotation (e.g., "org.opencontainers.image.source").`).
+			ArgDoc("value", `The value of the annotation (e.g., "https://github.com/dagger/dagger").`),
+
+		dagql.Func("withoutAnnotation", s.withoutAnnotation).
+			Doc(`Retrieves this container minus the given OCI annotation.`).
+			ArgDoc("name", `The name of the annotation to remove (e.g., "org.opencontainers.image.source").`),
+
 		dagql.Func("entrypoint", s.entrypoint).
 			Doc(`Retrieves entrypoint to be prepended to the arguments of all commands.`),
 
@@ -218,7 +296,11 @@ func (s *containerSchema) Install() {
 				the initial filesystem provided by source (if any). It does not have
 				any effect if/when the cache has already been created.`,
 				`The user and group can either be an ID (1000:1000) or a name (foo:bar).`,
-				`If the group is omitted, it defaults to the same as the user.`),
+				`If the group is omitted, it defaults to the same as the user.`).
+			ArgDoc("permissions",
+				`Permission given to the mounted cache directory (e.g., 0777).`,
+				`Like owner, it does not have any effect if/when the cache has already
+				been created.`),
 
 		dagql.Func("withMountedSecret", s.withMountedSecret).
 			Doc(`Retrieves this container plus a secret mounted into a file at the given path.`).
@@ -282,6 +364,20 @@ func (s *containerSchema) Install() {
 				`The user and group can either be an ID (1000:1000) or a name (foo:bar).`,
 				`If the group is omitted, it defaults to the same as the user.`),
 
+		dagql.Func("withNewDirectory", s.withNewDirectory).
+			Doc(`Retrieves this container plus a new directory created at the given path.`).
+			ArgDoc("path", `Location of the directory created (e.g., "/logs").`).
+			ArgDoc("permissions", `Permission granted to the created directory (e.g., 0777).`).
+			ArgDoc("owner",
+				`A user:group to set for the directory.`,
+				`The user and group can either be an ID (1000:1000) or a name (foo:bar).`,
+				`If the group is omitted, it defaults to the same as the user.`),
+
+		dagql.Func("withSymlink", s.withSymlink).
+			Doc(`Retrieves this container plus a new symlink at the given path.`).
+			ArgDoc("target", `Location the symlink points to (e.g., "python3").`).
+			ArgDoc("linkName", `Location of the written symlink (e.g., "/usr/bin/python").`),
+
 		dagql.Func("withDirectory", s.withDirectory).
 			Doc(`Retrieves this container plus a directory written at the given path.`).
 			ArgDoc("path", `Location of the written directory (e.g., "/tmp/directory").`).
@@ -323,14 +419,77 @@ func (s *containerSchema) Install() {
 				running a command with "sudo" or executing "docker run" with the
 				"--privileged" flag. Containerization does not provide any security
 				guarantees when using this option. It should only be used when
-				absolutely necessary and only with trusted commands.`),
+				absolutely necessary and only with trusted commands.`).
+			ArgDoc("noCache",
+				`Execute the command again rather than using a cached result, e.g. for
+				deployments.`,
+				`The command runs again each time the resulting container is
+				evaluated; anything depending on its result is executed again too.`).
+			ArgImpure("noCache", "The command runs again each time.").
+			ArgDoc("allowFailure",
+				`Return the resulting container even if the command exits with a
+				non-zero code, rather than failing.`,
+				`The exit code is available from the exitCode field, along with the
+				output streams.`).
+			ArgDoc("cpus",
+				`Limit the number of CPUs the command can use (e.g., 1.5), or 0 for no
+				limit.`).
+			ArgDoc("memoryBytes",
+				`Limit the memory the command can use in bytes, or 0 for no limit.`,
+				`The command is killed if it exceeds the limit.`).
+			ArgDoc("maxOutputBytes",
+				`Limit the bytes of stdout and stderr captured of the command and
+				streamed to the client, or 0 for no limit of its own.`,
+				`Output beyond the limit is dropped and a truncation message is
+				appended, without interrupting the command. The session's and the
+				engine's limits apply if they're lower.`).
+			ArgDoc("noNetwork",
+				`Execute the command without network access, e.g. to verify that a build
+				is hermetic.`,
+				`Only the loopback interface is available; bound services and the
+				Dagger API can't be reached.`).
+			ArgDoc("readOnlyRootfs",
+				`Mount the container's root filesystem read-only, e.g. to verify that
+				the command doesn't mutate the container's state.`,
+				`Only mounts are writable, like temporary directories from
+				withMountedTemp or cache volumes; the resulting container's root
+				filesystem is left unchanged.`).
+			ArgDoc("problemMatchers",
+				`Regular expressions extracting diagnostics from the command's output
+				(e.g., for IDE or CI annotations).`,
+				`The diagnostics are available from the diagnostics field.`),
+
+		dagql.Func("exitCode", s.exitCode).
+			Doc(`The exit code of the last executed command.`,
+				`Only non-zero if the command was executed with allowFailure.`,
+				`Will execute default command if none is set, or error if there's no default.`),
 
 		dagql.Func("stdout", s.stdout).
 			Doc(`The output stream of the last executed command.`,
-				`Will execute default command if none is set, or error if there's no default.`),
+				`Will execute default command if none is set, or error if there's no default.`,
+				`Bytes that aren't valid UTF-8 are replaced with the Unicode replacement
+				character (U+FFFD). Use stdoutBytes to retrieve binary output as is.`),
+
+		dagql.Func("stdoutBytes", s.stdoutBytes).
+			Doc(`The output stream of the last executed command, base64-encoded.`,
+				`Will execute default command if none is set, or error if there's no default.`,
+				`The stream is returned byte for byte, so binary output is not altered.
+				It is limited to 128 MiB, before encoding.`),
 
 		dagql.Func("stderr", s.stderr).
 			Doc(`The error stream of the last executed command.`,
+				`Will execute default command if none is set, or error if there's no default.`,
+				`Bytes that aren't valid UTF-8 are replaced with the Unicode replacement
+				character (U+FFFD). Use stderrBytes to retrieve binary output as is.`),
+
+		dagql.Func("stderrBytes", s.stderrBytes).
+			Doc(`The error stream of the last executed command, base64-encoded.`,
+				`Will execute default command if none is set, or error if there's no default.`,
+				`The stream is returned byte for byte, so binary output is not altered.
+				It is limited to 128 MiB, before encoding.`),
+
+		dagql.Func("diagnostics", s.diagnostics).
+			Doc(`The diagnostics extracted from the output streams of the last executed command by its problem matchers.`,
 				`Will execute default command if none is set, or error if there's no default.`),
 
 		dagql.Func("publish", s.publish).
@@ -352,11 +511,19 @@ func (s *containerSchema) Install() {
 				compression algorithms for different layers). If this is unset and a
 				layer has no compressed blob in the engine's cache, then it will be
 				compressed using Gzip.`).
+			ArgDoc("compressionLevel",
+				`Compression level to use for the compressed layers, from 0 to 9 for
+				Gzip and EStarGZ and from 0 to 22 for Zstd.`,
+				`If this is unset, the default level of the algorithm is used.`).
 			ArgDoc("mediaTypes",
 				`Use the specified media types for the published image's layers.`,
 				`Defaults to OCI, which is largely compatible with most recent
 				registries, but Docker may be needed for older registries without OCI
-				support.`),
+				support.`).
+			ArgDoc("sourceDateEpoch",
+				`Make the published image reproducible by setting the creation time of its
+				config and clamping the timestamps of the files in its layers to this
+				Unix time (e.g., the SOURCE_DATE_EPOCH of the build).`),
 
 		dagql.Func("platform", s.platform).
 			Doc(`The platform this container executes and publishes as.`),
@@ -378,11 +545,19 @@ func (s *containerSchema) Install() {
 				compression algorithms for different layers). If this is unset and a
 				layer has no compressed blob in the engine's cache, then it will be
 				compressed using Gzip.`).
+			ArgDoc("compressionLevel",
+				`Compression level to use for the compressed layers, from 0 to 9 for
+				Gzip and EStarGZ and from 0 to 22 for Zstd.`,
+				`If this is unset, the default level of the algorithm is used.`).
 			ArgDoc("mediaTypes",
 				`Use the specified media types for the exported image's layers.`,
 				`Defaults to OCI, which is largely compatible with most recent
 				container runtimes, but Docker may be needed for older runtimes without
-				OCI support.`),
+				OCI support.`).
+			ArgDoc("sourceDateEpoch",
+				`Make the exported image reproducible by setting the creation time of its
+				config and clamping the timestamps of the files in its layers to this
+				Unix time (e.g., the SOURCE_DATE_EPOCH of the build).`),
 
 		dagql.Func("asTarball", s.asTarball).
 			Doc(`Returns a File representing the container serialized to a tarball.`).
@@ -396,10 +571,18 @@ func (s *containerSchema) Install() {
 				compression algorithms for different layers). If this is unset and a
 				layer has no compressed blob in the engine's cache, then it will be
 				compressed using Gzip.`).
+			ArgDoc("compressionLevel",
+				`Compression level to use for the compressed layers, from 0 to 9 for
+				Gzip and EStarGZ and from 0 to 22 for Zstd.`,
+				`If this is unset, the default level of the algorithm is used.`).
 			ArgDoc("mediaTypes", `Use the specified media types for the image's layers.`,
 				`Defaults to OCI, which is largely compatible with most recent
 				container runtimes, but Docker may be needed for older runtimes without
-				OCI support.`),
+				OCI support.`).
+			ArgDoc("sourceDateEpoch",
+				`Make the image reproducible by setting the creation time of its
+				config and clamping the timestamps of the files in its layers to this
+				Unix time (e.g., the SOURCE_DATE_EPOCH of the build).`),
 
 		dagql.Func("import", s.import_).
 			Doc(`Reads the container from an OCI tarball.`).
@@ -422,6 +605,11 @@ func (s *containerSchema) Install() {
 		dagql.Func("imageRef", s.imageRef).
 			Doc(`The unique image reference which can only be retrieved immediately after the 'Container.From' call.`),
 
+		dagql.Func("imageConfig", s.imageConfig).
+			Doc(`Retrieves the OCI image config of this container, serialized as JSON.`,
+				`It includes the environment variables, default arguments, entrypoint,
+				labels, exposed ports, user and working directory set on the image.`),
+
 		dagql.Func("withExposedPort", s.withExposedPort).
 			Doc(`Expose a network port.`,
 				`Exposed ports serve two purposes:`,
@@ -489,6 +677,25 @@ func (s *containerSchema) Install() {
 		guarantees when using this option. It should only be used when
 		absolutely necessary and only with trusted commands.`),
 
+		dagql.Func("withDNS", s.withDNS).
+			Doc(`Retrieves this container with the given DNS configuration for its commands.`,
+				`Nameservers replace the ones of the engine, so hostnames of services
+				bound to the container can only be resolved if they forward to the
+				engine's resolver.`).
+			ArgDoc("nameservers", `IP addresses of the nameservers to use instead of the engine's.`).
+			ArgDoc("searchDomains", `Domains to search before the engine's (e.g., "corp.example.com").`),
+
+		dagql.Func("withExtraHost", s.withExtraHost).
+			Doc(`Retrieves this container with an extra entry in the hosts file of its commands, like docker run --add-host.`,
+				`Any existing entry for the same hostname is replaced.`).
+			ArgDoc("name", `Hostname to resolve (e.g., "internal.example.com").`).
+			ArgDoc("ip", `IP address the hostname resolves to (e.g., "10.0.0.1").`),
+
+		dagql.Func("withDevice", s.withDevice).
+			Doc(`Retrieves this container with a host device exposed to its commands at the same path, e.g. to run VMs or FUSE filesystems.`,
+				`The engine must allow the device, by being started with --allow-device, or else commands fail to run.`).
+			ArgDoc("path", `Path of the device on the engine's host (e.g., "/dev/kvm", "/dev/fuse").`),
+
 		dagql.Func("experimentalWithGPU", s.withGPU).
 			Doc(`EXPERIMENTAL API! Subject to change/removal at any time.`,
 				`Configures the provided list of devices to be accessible to this container.`,
@@ -500,6 +707,23 @@ func (s *containerSchema) Install() {
 				`Configures all available GPUs on the host to be accessible to this container.`,
 				`This currently works for Nvidia devices only.`),
 	}.Install(s.srv)
+
+	s.srv.InstallSubscription(
+		dagql.Subscribe("containerStdout", s.followStdout).
+			Doc(`Streams the standard output of the container's last executed command as it's written.`,
+				`Will execute default command if none is set, or error if there's no default.`,
+				`If the command is cached, its whole output is sent at once instead.`,
+				`Output written faster than it's received is dropped, and replaced by a line saying how many bytes were lost.`).
+			ArgDoc("container", `The container to follow the output of.`),
+	)
+	s.srv.InstallSubscription(
+		dagql.Subscribe("containerStderr", s.followStderr).
+			Doc(`Streams the error output of the container's last executed command as it's written.`,
+				`Will execute default command if none is set, or error if there's no default.`,
+				`If the command is cached, its whole output is sent at once instead.`,
+				`Output written faster than it's received is dropped, and replaced by a line saying how many bytes were lost.`).
+			ArgDoc("container", `The container to follow the output of.`),
+	)
 }
 
 type containerArgs struct {
@@ -526,12 +750,26 @@ func (s *containerSchema) container(ctx context.Context, parent *core.Query, arg
 	return parent.NewContainer(platform), nil
 }
 
+type containerCopyImageArgs struct {
+	Source      string
+	Destination string
+}
+
+func (s *containerSchema) copyImage(ctx context.Context, parent *core.Query, args containerCopyImageArgs) (string, error) {
+	return parent.Buildkit.CopyImage(ctx, args.Source, args.Destination)
+}
+
 type containerFromArgs struct {
-	Address string
+	Address    string
+	PullPolicy core.ImagePullPolicy `default:"IF_NOT_PRESENT"`
 }
 
 func (s *containerSchema) from(ctx context.Context, parent *core.Container, args containerFromArgs) (*core.Container, error) {
-	return parent.From(ctx, args.Address)
+	addr, err := parent.Query.ResolveImageAlias(args.Address)
+	if err != nil {
+		return nil, err
+	}
+	return parent.From(ctx, addr, args.PullPolicy)
 }
 
 type containerBuildArgs struct {
@@ -589,10 +827,26 @@ func (s *containerSchema) rootfs(ctx context.Context, parent *core.Container, ar
 
 type containerExecArgs struct {
 	core.ContainerExecOpts
+
+	ProblemMatchers []dagql.InputObject[core.ProblemMatcher] `default:"[]"`
 }
 
 func (s *containerSchema) withExec(ctx context.Context, parent *core.Container, args containerExecArgs) (*core.Container, error) {
-	return parent.WithExec(ctx, args.ContainerExecOpts)
+	opts := args.ContainerExecOpts
+	opts.ProblemMatchers = collectInputsSlice(args.ProblemMatchers)
+	return parent.WithExec(ctx, opts)
+}
+
+func (s *containerSchema) diagnostics(ctx context.Context, parent *core.Container, _ struct{}) ([]core.Diagnostic, error) {
+	return parent.Diagnostics(ctx)
+}
+
+func (s *containerSchema) exitCode(ctx context.Context, parent *core.Container, _ struct{}) (dagql.Int, error) {
+	exitCode, err := parent.ExitCode(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return dagql.NewInt(exitCode), nil
 }
 
 func (s *containerSchema) stdout(ctx context.Context, parent *core.Container, _ struct{}) (string, error) {
@@ -603,6 +857,42 @@ func (s *containerSchema) stderr(ctx context.Context, parent *core.Container, _
 	return parent.MetaFileContents(ctx, buildkit.MetaMountStderrPath)
 }
 
+type followOutputArgs struct {
+	Container core.ContainerID
+}
+
+func (s *containerSchema) followStdout(ctx context.Context, args followOutputArgs, send func(string) error) error {
+	ctr, err := args.Container.Load(ctx, s.srv)
+	if err != nil {
+		return err
+	}
+	return ctr.Self.FollowOutput(ctx, buildkit.MetaMountStdoutPath, send)
+}
+
+func (s *containerSchema) followStderr(ctx context.Context, args followOutputArgs, send func(string) error) error {
+	ctr, err := args.Container.Load(ctx, s.srv)
+	if err != nil {
+		return err
+	}
+	return ctr.Self.FollowOutput(ctx, buildkit.MetaMountStderrPath, send)
+}
+
+func (s *containerSchema) stdoutBytes(ctx context.Context, parent *core.Container, _ struct{}) (string, error) {
+	content, err := parent.MetaFileBytes(ctx, buildkit.MetaMountStdoutPath)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(content), nil
+}
+
+func (s *containerSchema) stderrBytes(ctx context.Context, parent *core.Container, _ struct{}) (string, error) {
+	content, err := parent.MetaFileBytes(ctx, buildkit.MetaMountStderrPath)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(content), nil
+}
+
 type containerGpuArgs struct {
 	core.ContainerGPUOpts
 }
@@ -739,6 +1029,78 @@ func (s *containerSchema) workdir(ctx context.Context, parent *core.Container, a
 	return cfg.WorkingDir, nil
 }
 
+type containerWithStopSignalArgs struct {
+	Signal string
+}
+
+func (s *containerSchema) withStopSignal(ctx context.Context, parent *core.Container, args containerWithStopSignalArgs) (*core.Container, error) {
+	if _, err := signal.ParseSignal(args.Signal); err != nil {
+		return nil, err
+	}
+	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+		cfg.StopSignal = args.Signal
+		return cfg
+	})
+}
+
+func (s *containerSchema) withoutStopSignal(ctx context.Context, parent *core.Container, _ struct{}) (*core.Container, error) {
+	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+		cfg.StopSignal = ""
+		return cfg
+	})
+}
+
+func (s *containerSchema) stopSignal(ctx context.Context, parent *core.Container, args struct{}) (string, error) {
+	cfg, err := parent.ImageConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.StopSignal, nil
+}
+
+type containerWithVolumeArgs struct {
+	Path string
+}
+
+func (s *containerSchema) withVolume(ctx context.Context, parent *core.Container, args containerWithVolumeArgs) (*core.Container, error) {
+	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+		if cfg.Volumes == nil {
+			cfg.Volumes = map[string]struct{}{}
+		}
+		cfg.Volumes[absPath(cfg.WorkingDir, args.Path)] = struct{}{}
+		return cfg
+	})
+}
+
+type containerWithoutVolumeArgs struct {
+	Path string
+}
+
+func (s *containerSchema) withoutVolume(ctx context.Context, parent *core.Container, args containerWithoutVolumeArgs) (*core.Container, error) {
+	return parent.UpdateImageConfig(ctx, func(cfg specs.ImageConfig) specs.ImageConfig {
+		delete(cfg.Volumes, absPath(cfg.WorkingDir, args.Path))
+		return cfg
+	})
+}
+
+func (s *containerSchema) volumes(ctx context.Context, parent *core.Container, args struct{}) ([]string, error) {
+	cfg, err := parent.ImageConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := make([]string, 0, len(cfg.Volumes))
+	for volume := range cfg.Volumes {
+		volumes = append(volumes, volume)
+	}
+
+	// sort so the order is stable for IDs
+	sort.Strings(volumes)
+
+	return volumes, nil
+}
+
 type containerWithVariableArgs struct {
 	Name   string
 	Value  string
@@ -920,7 +1282,9 @@ type containerPublishArgs struct {
 	Address           dagql.String
 	PlatformVariants  []core.ContainerID `default:"[]"`
 	ForcedCompression dagql.Optional[core.ImageLayerCompression]
+	CompressionLevel  dagql.Optional[dagql.Int]
 	MediaTypes        core.ImageMediaTypes `default:"OCIMediaTypes"`
+	SourceDateEpoch   dagql.Optional[dagql.Int]
 }
 
 func (s *containerSchema) publish(ctx context.Context, parent *core.Container, args containerPublishArgs) (dagql.String, error) {
@@ -933,7 +1297,9 @@ func (s *containerSchema) publish(ctx context.Context, parent *core.Container, a
 		args.Address.String(),
 		variants,
 		args.ForcedCompression.Value,
+		optionalInt(args.CompressionLevel),
 		args.MediaTypes,
+		optionalInt(args.SourceDateEpoch),
 	)
 	if err != nil {
 		return "", err
@@ -956,11 +1322,12 @@ func (s *containerSchema) withMountedFile(ctx context.Context, parent *core.Cont
 }
 
 type containerWithMountedCacheArgs struct {
-	Path    string
-	Cache   core.CacheVolumeID
-	Source  dagql.Optional[core.DirectoryID]
-	Sharing core.CacheSharingMode `default:"SHARED"`
-	Owner   string                `default:""`
+	Path        string
+	Cache       core.CacheVolumeID
+	Source      dagql.Optional[core.DirectoryID]
+	Sharing     core.CacheSharingMode `default:"SHARED"`
+	Owner       string                `default:""`
+	Permissions *int
 }
 
 func (s *containerSchema) withMountedCache(ctx context.Context, parent *core.Container, args containerWithMountedCacheArgs) (*core.Container, error) {
@@ -985,6 +1352,7 @@ func (s *containerSchema) withMountedCache(ctx context.Context, parent *core.Con
 		dir,
 		args.Sharing,
 		args.Owner,
+		args.Permissions,
 	)
 }
 
@@ -1038,6 +1406,23 @@ func (s *containerSchema) withoutLabel(ctx context.Context, parent *core.Contain
 	})
 }
 
+type containerWithAnnotationArgs struct {
+	Name  string
+	Value string
+}
+
+func (s *containerSchema) withAnnotation(ctx context.Context, parent *core.Container, args containerWithAnnotationArgs) (*core.Container, error) {
+	return parent.WithAnnotation(ctx, args.Name, args.Value)
+}
+
+type containerWithoutAnnotationArgs struct {
+	Name string
+}
+
+func (s *containerSchema) withoutAnnotation(ctx context.Context, parent *core.Container, args containerWithoutAnnotationArgs) (*core.Container, error) {
+	return parent.WithoutAnnotation(ctx, args.Name)
+}
+
 type containerDirectoryArgs struct {
 	Path string
 }
@@ -1173,6 +1558,25 @@ func (s *containerSchema) withNewFile(ctx context.Context, parent *core.Containe
 	return parent.WithNewFile(ctx, args.Path, []byte(args.Contents), fs.FileMode(args.Permissions), args.Owner)
 }
 
+type containerWithNewDirectoryArgs struct {
+	Path        string
+	Permissions int    `default:"0755"`
+	Owner       string `default:""`
+}
+
+func (s *containerSchema) withNewDirectory(ctx context.Context, parent *core.Container, args containerWithNewDirectoryArgs) (*core.Container, error) {
+	return parent.WithNewDirectory(ctx, args.Path, fs.FileMode(args.Permissions), args.Owner)
+}
+
+type containerWithSymlinkArgs struct {
+	Target   string
+	LinkName string
+}
+
+func (s *containerSchema) withSymlink(ctx context.Context, parent *core.Container, args containerWithSymlinkArgs) (*core.Container, error) {
+	return parent.WithSymlink(ctx, args.Target, args.LinkName)
+}
+
 type containerWithUnixSocketArgs struct {
 	Path   string
 	Source core.SocketID
@@ -1203,7 +1607,9 @@ type containerExportArgs struct {
 	Path              string
 	PlatformVariants  []core.ContainerID `default:"[]"`
 	ForcedCompression dagql.Optional[core.ImageLayerCompression]
+	CompressionLevel  dagql.Optional[dagql.Int]
 	MediaTypes        core.ImageMediaTypes `default:"OCIMediaTypes"`
+	SourceDateEpoch   dagql.Optional[dagql.Int]
 }
 
 func (s *containerSchema) export(ctx context.Context, parent *core.Container, args containerExportArgs) (dagql.String, error) {
@@ -1216,7 +1622,9 @@ func (s *containerSchema) export(ctx context.Context, parent *core.Container, ar
 		args.Path,
 		variants,
 		args.ForcedCompression.Value,
+		optionalInt(args.CompressionLevel),
 		args.MediaTypes,
+		optionalInt(args.SourceDateEpoch),
 	)
 	if err != nil {
 		return "", err
@@ -1232,7 +1640,9 @@ func (s *containerSchema) export(ctx context.Context, parent *core.Container, ar
 type containerAsTarballArgs struct {
 	PlatformVariants  []core.ContainerID `default:"[]"`
 	ForcedCompression dagql.Optional[core.ImageLayerCompression]
+	CompressionLevel  dagql.Optional[dagql.Int]
 	MediaTypes        core.ImageMediaTypes `default:"OCIMediaTypes"`
+	SourceDateEpoch   dagql.Optional[dagql.Int]
 }
 
 func (s *containerSchema) asTarball(ctx context.Context, parent *core.Container, args containerAsTarballArgs) (*core.File, error) {
@@ -1240,7 +1650,22 @@ func (s *containerSchema) asTarball(ctx context.Context, parent *core.Container,
 	if err != nil {
 		return nil, err
 	}
-	return parent.AsTarball(ctx, variants, args.ForcedCompression.Value, args.MediaTypes)
+	return parent.AsTarball(
+		ctx,
+		variants,
+		args.ForcedCompression.Value,
+		optionalInt(args.CompressionLevel),
+		args.MediaTypes,
+		optionalInt(args.SourceDateEpoch),
+	)
+}
+
+func optionalInt(opt dagql.Optional[dagql.Int]) *int {
+	if !opt.Valid {
+		return nil
+	}
+	v := opt.Value.Int()
+	return &v
 }
 
 type containerImportArgs struct {
@@ -1305,6 +1730,20 @@ func (s *containerSchema) imageRef(ctx context.Context, parent *core.Container,
 	return parent.ImageRefOrErr(ctx)
 }
 
+func (s *containerSchema) imageConfig(ctx context.Context, parent *core.Container, args struct{}) (core.JSON, error) {
+	cfg, err := parent.ImageConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal image config: %w", err)
+	}
+
+	return core.JSON(cfgBytes), nil
+}
+
 type containerWithServiceBindingArgs struct {
 	Alias   string
 	Service core.ServiceID
@@ -1319,6 +1758,32 @@ func (s *containerSchema) withServiceBinding(ctx context.Context, parent *core.C
 	return parent.WithServiceBinding(ctx, svc.ID(), svc.Self, args.Alias)
 }
 
+type containerWithDNSArgs struct {
+	Nameservers   []string `default:"[]"`
+	SearchDomains []string `default:"[]"`
+}
+
+func (s *containerSchema) withDNS(ctx context.Context, parent *core.Container, args containerWithDNSArgs) (*core.Container, error) {
+	return parent.WithDNS(ctx, args.Nameservers, args.SearchDomains)
+}
+
+type containerWithExtraHostArgs struct {
+	Name string
+	IP   string `name:"ip"`
+}
+
+func (s *containerSchema) withExtraHost(ctx context.Context, parent *core.Container, args containerWithExtraHostArgs) (*core.Container, error) {
+	return parent.WithExtraHost(ctx, args.Name, args.IP)
+}
+
+type containerWithDeviceArgs struct {
+	Path string
+}
+
+func (s *containerSchema) withDevice(ctx context.Context, parent *core.Container, args containerWithDeviceArgs) (*core.Container, error) {
+	return parent.WithDevice(ctx, args.Path)
+}
+
 type containerWithExposedPortArgs struct {
 	Port                        int
 	Protocol                    core.NetworkProtocol `default:"TCP"`
@@ -41,7 +41,9 @@ func (m *CoreMod) Install(ctx context.Context, dag *dagql.Server) error {
 		&secretSchema{dag},
 		&serviceSchema{dag},
 		&hostSchema{dag},
+		&engineSchema{dag},
 		&httpSchema{dag},
+		&toolSchema{dag},
 		&platformSchema{dag},
 		&socketSchema{dag},
 		&moduleSchema{dag},
@@ -121,8 +123,13 @@ func (m *CoreMod) TypeDefs(ctx context.Context) ([]*core.TypeDef, error) {
 	}
 	schema := schemaResp.Schema
 
+	subscriptionType := schema.Subscription()
 	typeDefs := make([]*core.TypeDef, 0, len(schema.Types))
 	for _, introspectionType := range schema.Types {
+		if introspectionType == subscriptionType {
+			// subscriptions are only served over websockets, not to modules
+			continue
+		}
 		switch introspectionType.Kind {
 		case introspection.TypeKindObject:
 			typeDef := &core.ObjectTypeDef{
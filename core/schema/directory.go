@@ -4,6 +4,8 @@ import (
 	"context"
 	"io/fs"
 
+	"github.com/moby/patternmatcher"
+
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/dagql"
 )
@@ -23,7 +25,20 @@ func (s *directorySchema) Install() {
 
 	dagql.Fields[*core.Directory]{
 		Syncer[*core.Directory]().
-			Doc(`Force evaluation in the engine.`),
+			Doc(`Force evaluation in the engine.`,
+				`Modifications of the result are built on top of the evaluated
+				directory, so syncing it before branching off divergent
+				modifications evaluates it only once.`),
+		Grapher[*core.Directory]().
+			Doc(`Evaluates the directory and returns the graph of its operations.`,
+				`Each operation is reported with its cache status, so that accidental
+				serialization or cache misses can be spotted.`),
+		CacheChecker[*core.Directory]().
+			Doc(`Returns whether the execs the directory is made of are already cached,
+				without running them.`,
+				`Other operations, like pulling images or loading host directories, are
+				still performed, since the cache keys of the execs depend on their
+				results.`),
 		dagql.Func("pipeline", s.pipeline).
 			Doc(`Creates a named sub-pipeline.`).
 			ArgDoc("name", "Name of the sub-pipeline.").
@@ -31,10 +46,20 @@ func (s *directorySchema) Install() {
 			ArgDoc("labels", "Labels to apply to the sub-pipeline."),
 		dagql.Func("entries", s.entries).
 			Doc(`Returns a list of files and directories at the given path.`).
-			ArgDoc("path", `Location of the directory to look at (e.g., "/src").`),
+			ArgDoc("path", `Location of the directory to look at (e.g., "/src").`).
+			ArgDoc("recursive", `List the entries of subdirectories too, with paths relative to the directory looked at.`).
+			ArgDoc("pattern", `Only list the entries whose path matches the pattern (e.g., "**/*.go").`),
+		dagql.Func("stat", s.stat).
+			Doc(`Returns information about the file or directory at the given path.`).
+			ArgDoc("path", `Location of the file or directory to look at (e.g., "/src/main.go").`),
 		dagql.Func("glob", s.glob).
 			Doc(`Returns a list of files and directories that matche the given pattern.`).
 			ArgDoc("pattern", `Pattern to match (e.g., "*.md").`),
+		dagql.Func("gitVersion", s.gitVersion).
+			Doc(`Computes version information from the git tags and history of the repository in this directory.`,
+				`The directory must include the .git directory, e.g. by querying the repository with keepGitDir.`).
+			ArgDoc("path", `Location of the repository within the directory.`).
+			ArgDoc("prefix", `Prefix of the tags to consider, which must be followed by a semver version.`),
 		dagql.Func("file", s.file).
 			Doc(`Retrieves a file at the given path.`).
 			ArgDoc("path", `Location of the file to retrieve (e.g., "README.md").`),
@@ -56,6 +81,10 @@ func (s *directorySchema) Install() {
 		dagql.Func("withoutFile", s.withoutFile).
 			Doc(`Retrieves this directory with the file at the given path removed.`).
 			ArgDoc("path", `Location of the file to remove (e.g., "/file.txt").`),
+		dagql.Func("withSymlink", s.withSymlink).
+			Doc(`Retrieves this directory plus a new symlink at the given path.`).
+			ArgDoc("target", `Location the symlink points to (e.g., "python3").`).
+			ArgDoc("linkName", `Location of the written symlink (e.g., "/bin/python").`),
 		dagql.Func("directory", s.subdirectory).
 			Doc(`Retrieves a directory at the given path.`).
 			ArgDoc("path", `Location of the directory to retrieve (e.g., "/src").`),
@@ -69,9 +98,23 @@ func (s *directorySchema) Install() {
 			Doc(`Retrieves this directory plus a new directory created at the given path.`).
 			ArgDoc("path", `Location of the directory created (e.g., "/logs").`).
 			ArgDoc("permissions", `Permission granted to the created directory (e.g., 0777).`),
+		dagql.Func("withPermissions", s.withPermissions).
+			Doc(`Retrieves this directory with the permissions of the file or directory at the given path changed, recursively.`).
+			ArgDoc("path", `Location of the file or directory to change (e.g., "bin/").`).
+			ArgDoc("permissions", `Permission given to the file or directory and its contents (e.g., 0755).`),
+		dagql.Func("withOwner", s.withOwner).
+			Doc(`Retrieves this directory with the owner of the file or directory at the given path changed, recursively.`).
+			ArgDoc("path", `Location of the file or directory to change (e.g., "bin/").`).
+			ArgDoc("owner",
+				`A user:group to set for the file or directory and its contents.`,
+				`The user and group must be IDs (1000:1000), unless the directory contains an /etc/passwd and /etc/group to look names up in.`,
+				`If the group is omitted, it defaults to the same as the user.`),
 		dagql.Func("withoutDirectory", s.withoutDirectory).
 			Doc(`Retrieves this directory with the directory at the given path removed.`).
 			ArgDoc("path", `Location of the directory to remove (e.g., ".github/").`),
+		dagql.Func("withoutEntries", s.withoutEntries).
+			Doc(`Retrieves this directory with the entries matching any of the given patterns removed.`).
+			ArgDoc("patterns", `Patterns of the entries to remove (e.g., ["**/*.test", "node_modules/**"]).`),
 		dagql.Func("diff", s.diff).
 			Doc(`Gets the difference between this directory and an another directory.`).
 			ArgDoc("other", `Identifier of the directory to compare.`),
@@ -109,6 +152,8 @@ func (s *directorySchema) Install() {
 			guarantees when using this option. It should only be used when
 			absolutely necessary and only with trusted commands.`),
 	}.Install(s.srv)
+
+	dagql.Fields[core.Stat]{}.Install(s.srv)
 }
 
 type directoryPipelineArgs struct {
@@ -151,7 +196,7 @@ type withNewDirectoryArgs struct {
 }
 
 func (s *directorySchema) withNewDirectory(ctx context.Context, parent *core.Directory, args withNewDirectoryArgs) (*core.Directory, error) {
-	return parent.WithNewDirectory(ctx, args.Path, fs.FileMode(args.Permissions))
+	return parent.WithNewDirectory(ctx, args.Path, fs.FileMode(args.Permissions), nil)
 }
 
 type WithDirectoryArgs struct {
@@ -178,14 +223,49 @@ func (s *directorySchema) withTimestamps(ctx context.Context, parent *core.Direc
 }
 
 type entriesArgs struct {
-	Path dagql.Optional[dagql.String]
+	Path      dagql.Optional[dagql.String]
+	Recursive bool `default:"false"`
+	Pattern   dagql.Optional[dagql.String]
 }
 
 func (s *directorySchema) entries(ctx context.Context, parent *core.Directory, args entriesArgs) (dagql.Array[dagql.String], error) {
+	if args.Recursive {
+		dir := parent
+		if args.Path.Valid {
+			var err error
+			dir, err = parent.Directory(ctx, args.Path.Value.String())
+			if err != nil {
+				return nil, err
+			}
+		}
+		pattern := "**/*"
+		if args.Pattern.Valid {
+			pattern = args.Pattern.Value.String()
+		}
+		ents, err := dir.Glob(ctx, ".", pattern)
+		if err != nil {
+			return nil, err
+		}
+		return dagql.NewStringArray(ents...), nil
+	}
+
 	ents, err := parent.Entries(ctx, args.Path.Value.String())
 	if err != nil {
 		return nil, err
 	}
+	if args.Pattern.Valid {
+		matching := make([]string, 0, len(ents))
+		for _, ent := range ents {
+			match, err := patternmatcher.MatchesOrParentMatches(ent, []string{args.Pattern.Value.String()})
+			if err != nil {
+				return nil, err
+			}
+			if match {
+				matching = append(matching, ent)
+			}
+		}
+		ents = matching
+	}
 	return dagql.NewStringArray(ents...), nil
 }
 
@@ -193,10 +273,35 @@ type globArgs struct {
 	Pattern string
 }
 
+type directoryStatArgs struct {
+	Path string
+}
+
+func (s *directorySchema) stat(ctx context.Context, parent *core.Directory, args directoryStatArgs) (core.Stat, error) {
+	info, err := parent.Stat(ctx, parent.Query.Buildkit, parent.Query.Services, args.Path)
+	if err != nil {
+		return core.Stat{}, err
+	}
+	return core.NewStat(info), nil
+}
+
 func (s *directorySchema) glob(ctx context.Context, parent *core.Directory, args globArgs) ([]string, error) {
 	return parent.Glob(ctx, ".", args.Pattern)
 }
 
+type dirGitVersionArgs struct {
+	Path   string `default:"."`
+	Prefix string `default:"v"`
+}
+
+func (s *directorySchema) gitVersion(ctx context.Context, parent *core.Directory, args dirGitVersionArgs) (core.GitVersion, error) {
+	version, err := parent.GitVersion(ctx, args.Path, args.Prefix)
+	if err != nil {
+		return core.GitVersion{}, err
+	}
+	return *version, nil
+}
+
 type dirFileArgs struct {
 	Path string
 }
@@ -213,6 +318,13 @@ func (s *directorySchema) withNewFile(ctx context.Context, parent *core.Director
 	return parent.WithNewFile(ctx, args.Path, []byte(args.Contents), fs.FileMode(args.Permissions), nil)
 }
 
+func (s *directorySchema) withSymlink(ctx context.Context, parent *core.Directory, args struct {
+	Target   string
+	LinkName string
+}) (*core.Directory, error) {
+	return parent.WithSymlink(ctx, args.Target, args.LinkName)
+}
+
 type WithFileArgs struct {
 	Path        string
 	Source      core.FileID
@@ -263,6 +375,32 @@ func (s *directorySchema) withoutFile(ctx context.Context, parent *core.Director
 	return parent.Without(ctx, args.Path)
 }
 
+type withPermissionsArgs struct {
+	Path        string
+	Permissions int
+}
+
+func (s *directorySchema) withPermissions(ctx context.Context, parent *core.Directory, args withPermissionsArgs) (*core.Directory, error) {
+	return parent.WithPermissions(ctx, args.Path, fs.FileMode(args.Permissions))
+}
+
+type withOwnerArgs struct {
+	Path  string
+	Owner string
+}
+
+func (s *directorySchema) withOwner(ctx context.Context, parent *core.Directory, args withOwnerArgs) (*core.Directory, error) {
+	return parent.WithOwner(ctx, args.Path, args.Owner)
+}
+
+type withoutEntriesArgs struct {
+	Patterns []string
+}
+
+func (s *directorySchema) withoutEntries(ctx context.Context, parent *core.Directory, args withoutEntriesArgs) (*core.Directory, error) {
+	return parent.WithoutEntries(ctx, args.Patterns)
+}
+
 type diffArgs struct {
 	Other core.DirectoryID
 }
@@ -0,0 +1,122 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/engine"
+)
+
+type engineSchema struct {
+	srv *dagql.Server
+}
+
+var _ SchemaResolvers = &engineSchema{}
+
+func (s *engineSchema) Install() {
+	dagql.Fields[*core.Query]{
+		dagql.Func("engine", s.engine).
+			Doc(`Queries the engine serving the current session.`),
+	}.Install(s.srv)
+
+	dagql.Fields[*core.Engine]{
+		dagql.Func("version", s.version).
+			Doc(`The version of the engine.`),
+
+		dagql.Func("buildkitVersion", s.buildkitVersion).
+			Doc(`The version of Buildkit embedded in the engine (e.g., "v0.14.1").`,
+				`The optional features supported by the engine are reported by
+				"capabilities".`),
+
+		dagql.Func("cacheUsage", s.cacheUsage).
+			Impure("Reports the current state of the cache.").
+			Doc(`The disk usage of the engine's cache, by type of cache record, from the largest to the smallest.`),
+
+		dagql.Func("prune", s.prune).
+			Impure("Removes cache records from the engine.").
+			Doc(`Removes cache records that are not in use from the engine, returning the disk space reclaimed.`,
+				`Only admins of the engine can prune its cache, which is shared by every session.`).
+			ArgDoc("filter",
+				`Only prune records matching any of these filters (e.g., "type==exec.cachemount", "type==source.local").`).
+			ArgDoc("keepBytes",
+				`Stop pruning once the matching records use at most this many bytes.`,
+				`0 prunes all of them.`).
+			ArgDoc("keepDuration",
+				`Only prune records that have not been used for this long (e.g., "48h").`).
+			ArgDoc("all",
+				`Also prune records that are shared or internal to the engine.`),
+
+		dagql.Func("sessions", s.sessions).
+			Impure("Reports the current state of the engine.").
+			Doc(`The sessions of the engine the client may manage: every session for admins of the engine, or else those started with the same credentials.`,
+				`Sessions whose client stopped sending heartbeats, e.g. because it crashed, are removed automatically along with their services.`),
+
+		dagql.Func("ping", s.ping).
+			Impure("Reports the current state of the engine.").
+			Doc(`Checks that the engine is healthy, returning an error describing the problem otherwise.`),
+
+		dagql.Func("removeSession", s.removeSession).
+			Impure("Removes a session from the engine.").
+			Doc(`Forcibly removes a session of the engine, stopping its services and releasing its containers.`,
+				`This is meant to clean up the sessions left behind by clients that are no longer running. Only the sessions listed by "sessions" can be removed.`).
+			ArgDoc("sessionID", `The ID of the session to remove. It cannot be the current session.`),
+	}.Install(s.srv)
+
+	dagql.Fields[core.CachePruneResult]{}.Install(s.srv)
+	dagql.Fields[core.CacheUsage]{}.Install(s.srv)
+	dagql.Fields[core.EngineSession]{}.Install(s.srv)
+}
+
+func (s *engineSchema) engine(ctx context.Context, parent *core.Query, args struct{}) (*core.Engine, error) {
+	return parent.NewEngine(), nil
+}
+
+func (s *engineSchema) version(ctx context.Context, parent *core.Engine, args struct{}) (string, error) {
+	return engine.Version, nil
+}
+
+func (s *engineSchema) buildkitVersion(ctx context.Context, parent *core.Engine, args struct{}) (string, error) {
+	return parent.BuildkitVersion(), nil
+}
+
+func (s *engineSchema) cacheUsage(ctx context.Context, parent *core.Engine, args struct{}) ([]core.CacheUsage, error) {
+	return parent.CacheUsage(ctx)
+}
+
+type enginePruneArgs struct {
+	Filter       []string `default:"[]"`
+	KeepBytes    int      `default:"0"`
+	KeepDuration string   `default:""`
+	All          bool     `default:"false"`
+}
+
+func (s *engineSchema) prune(ctx context.Context, parent *core.Engine, args enginePruneArgs) (core.CachePruneResult, error) {
+	var keepDuration time.Duration
+	if args.KeepDuration != "" {
+		var err error
+		keepDuration, err = time.ParseDuration(args.KeepDuration)
+		if err != nil {
+			return core.CachePruneResult{}, fmt.Errorf("invalid keepDuration: %w", err)
+		}
+	}
+	return parent.Prune(ctx, args.Filter, int64(args.KeepBytes), keepDuration, args.All)
+}
+
+func (s *engineSchema) sessions(ctx context.Context, parent *core.Engine, args struct{}) ([]core.EngineSession, error) {
+	return parent.Sessions(ctx)
+}
+
+func (s *engineSchema) ping(ctx context.Context, parent *core.Engine, args struct{}) (dagql.Nullable[core.Void], error) {
+	return dagql.Null[core.Void](), parent.Ping(ctx)
+}
+
+type engineRemoveSessionArgs struct {
+	SessionID string `name:"sessionID"`
+}
+
+func (s *engineSchema) removeSession(ctx context.Context, parent *core.Engine, args engineRemoveSessionArgs) (dagql.Nullable[core.Void], error) {
+	return dagql.Null[core.Void](), parent.RemoveSession(ctx, args.SessionID)
+}
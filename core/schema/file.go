@@ -2,7 +2,9 @@ package schema
 
 import (
 	"context"
+	"io/fs"
 	"path/filepath"
+	"time"
 
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/dagql"
@@ -23,10 +25,27 @@ func (s *fileSchema) Install() {
 	dagql.Fields[*core.File]{
 		Syncer[*core.File]().
 			Doc(`Force evaluation in the engine.`),
+		Grapher[*core.File]().
+			Doc(`Evaluates the file and returns the graph of its operations.`,
+				`Each operation is reported with its cache status, so that accidental
+				serialization or cache misses can be spotted.`),
+		CacheChecker[*core.File]().
+			Doc(`Returns whether the execs the file is made of are already cached,
+				without running them.`,
+				`Other operations, like pulling images or loading host directories, are
+				still performed, since the cache keys of the execs depend on their
+				results.`),
 		dagql.Func("contents", s.contents).
 			Doc(`Retrieves the contents of the file.`),
 		dagql.Func("size", s.size).
 			Doc(`Retrieves the size of the file, in bytes.`),
+		dagql.Func("mode", s.mode).
+			Doc(`Retrieves the permission bits of the file (e.g., 0644).`),
+		dagql.Func("modTime", s.modTime).
+			Doc(`Retrieves the last modification time of the file, in seconds following Unix epoch.`),
+		dagql.Func("digest", s.digest).
+			Doc(`Computes the digest of the contents of the file (e.g., "sha256:...").`).
+			ArgDoc("algorithm", `Hash algorithm to use.`),
 		dagql.Func("name", s.name).
 			Doc(`Retrieves the name of the file.`),
 		dagql.Func("withName", s.withName).
@@ -76,6 +95,37 @@ func (s *fileSchema) size(ctx context.Context, file *core.File, args struct{}) (
 	return dagql.NewInt(int(info.Size_)), nil
 }
 
+func (s *fileSchema) mode(ctx context.Context, file *core.File, args struct{}) (dagql.Int, error) {
+	info, err := file.Stat(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return dagql.NewInt(int(fs.FileMode(info.Mode).Perm())), nil
+}
+
+func (s *fileSchema) modTime(ctx context.Context, file *core.File, args struct{}) (dagql.Int, error) {
+	info, err := file.Stat(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return dagql.NewInt(int(time.Unix(0, info.ModTime).Unix())), nil
+}
+
+type fileDigestArgs struct {
+	Algorithm core.DigestAlgorithm `default:"SHA256"`
+}
+
+func (s *fileSchema) digest(ctx context.Context, file *core.File, args fileDigestArgs) (dagql.String, error) {
+	dgst, err := file.Digest(ctx, args.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	return dagql.NewString(dgst.String()), nil
+}
+
 func (s *fileSchema) name(ctx context.Context, file *core.File, args struct{}) (dagql.String, error) {
 	return dagql.NewString(filepath.Base(file.File)), nil
 }
@@ -51,7 +51,12 @@ func (s *gitSchema) Install() {
 			ArgDoc("id", `Identifier of the commit (e.g., "b6315d8f2810962c601af73f86831f6866ea798b").`),
 		dagql.Func("withAuthToken", s.withAuthToken).
 			Doc(`Token to authenticate the remote with.`).
-			ArgDoc("token", `Secret used to populate the password during basic HTTP Authorization`),
+			ArgDoc("token", `Secret used to populate the password during basic HTTP Authorization`).
+			ArgDoc("username",
+				`Username to send along with the token during basic HTTP Authorization.`,
+				`Defaults to "x-access-token", which is accepted by GitHub. Other
+				remotes may require a specific value (e.g. "gitlab-ci-token" for
+				GitLab CI job tokens, or "x-token-auth" for Bitbucket).`),
 		dagql.Func("withAuthHeader", s.withAuthHeader).
 			Doc(`Header to authenticate the remote with.`).
 			ArgDoc("header", `Secret used to populate the Authorization HTTP header`),
@@ -60,6 +65,12 @@ func (s *gitSchema) Install() {
 	dagql.Fields[*core.GitRef]{
 		dagql.Func("tree", s.tree).
 			Doc(`The filesystem tree at this ref.`).
+			ArgDoc("depth",
+				`The number of commits of history to fetch.`,
+				`Only has an effect when the repository was queried with keepGitDir.
+				Tags are fetched along with the history when set to anything other
+				than 1, so that tools like "git describe" work. Set to -1 to fetch
+				the full history.`).
 			ArgDeprecated("sshKnownHosts", "This option should be passed to `git` instead.").
 			ArgDeprecated("sshAuthSocket", "This option should be passed to `git` instead."),
 		dagql.Func("commit", s.fetchCommit).
@@ -168,7 +179,8 @@ func (s *gitSchema) tag(ctx context.Context, parent *core.GitRepository, args ta
 }
 
 type withAuthTokenArgs struct {
-	Token core.SecretID
+	Token    core.SecretID
+	Username string `default:""`
 }
 
 func (s *gitSchema) withAuthToken(ctx context.Context, parent *core.GitRepository, args withAuthTokenArgs) (*core.GitRepository, error) {
@@ -178,6 +190,7 @@ func (s *gitSchema) withAuthToken(ctx context.Context, parent *core.GitRepositor
 	}
 	repo := *parent
 	repo.AuthToken = token.Self
+	repo.AuthUsername = args.Username
 	return &repo, nil
 }
 
@@ -198,6 +211,7 @@ func (s *gitSchema) withAuthHeader(ctx context.Context, parent *core.GitReposito
 type treeArgs struct {
 	SSHKnownHosts dagql.Optional[dagql.String]  `name:"sshKnownHosts"`
 	SSHAuthSocket dagql.Optional[core.SocketID] `name:"sshAuthSocket"`
+	Depth         int                           `default:"1"`
 }
 
 func (s *gitSchema) tree(ctx context.Context, parent *core.GitRef, args treeArgs) (*core.Directory, error) {
@@ -217,7 +231,15 @@ func (s *gitSchema) tree(ctx context.Context, parent *core.GitRef, args treeArgs
 		cp.SSHAuthSocket = authSock
 		res.Repo = &cp
 	}
-	return res.Tree(ctx)
+	depth := args.Depth
+	switch {
+	case depth == -1:
+		// full history
+		depth = 0
+	case depth < 1:
+		return nil, fmt.Errorf("depth must be positive or -1 for the full history, got %d", depth)
+	}
+	return res.Tree(ctx, depth)
 }
 
 func (s *gitSchema) fetchCommit(ctx context.Context, parent *core.GitRef, _ struct{}) (dagql.String, error) {
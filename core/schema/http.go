@@ -2,6 +2,9 @@ package schema
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"path"
 
 	"github.com/moby/buildkit/client/llb"
 	"github.com/opencontainers/go-digest"
@@ -23,21 +26,27 @@ func (s *httpSchema) Install() {
 		dagql.Func("http", s.http).
 			Doc(`Returns a file containing an http remote url content.`).
 			ArgDoc("url", `HTTP url to get the content from (e.g., "https://docs.dagger.io").`).
+			ArgDoc("checksum", `Expected digest of the content (e.g., "sha256:...").`,
+				`If the downloaded content does not match, the query fails.`).
+			ArgDoc("headers", `Additional headers to send with the request.`).
+			ArgDoc("authHeader", `Secret used to populate the Authorization header of the request.`).
 			ArgDoc("experimentalServiceHost", `A service which must be started before the URL is fetched.`),
 	}.Install(s.srv)
 }
 
 type httpArgs struct {
 	URL                     string
+	Checksum                string                               `default:""`
+	Headers                 []dagql.InputObject[core.HTTPHeader] `default:"[]"`
+	AuthHeader              dagql.Optional[core.SecretID]
 	ExperimentalServiceHost dagql.Optional[core.ServiceID]
 }
 
 func (s *httpSchema) http(ctx context.Context, parent *core.Query, args httpArgs) (*core.File, error) {
-	// Use a filename that is set to the URL. Buildkit internally stores some cache metadata of etags
-	// and http checksums using an id based on this name, so setting it to the URL maximizes our chances
-	// of following more optimized cache codepaths.
-	// Do a hash encode to prevent conflicts with use of `/` in the URL while also not hitting max filename limits
-	filename := digest.FromString(args.URL).Encoded()
+	filename, err := httpFilename(args.URL)
+	if err != nil {
+		return nil, err
+	}
 
 	svcs := core.ServiceBindings{}
 	if args.ExperimentalServiceHost.Valid {
@@ -59,6 +68,23 @@ func (s *httpSchema) http(ctx context.Context, parent *core.Query, args httpArgs
 	opts := []llb.HTTPOption{
 		llb.Filename(filename),
 	}
+	if args.Checksum != "" {
+		dgst, err := digest.Parse(args.Checksum)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum %q: %w", args.Checksum, err)
+		}
+		opts = append(opts, llb.Checksum(dgst))
+	}
+	for _, h := range collectInputsSlice(args.Headers) {
+		opts = append(opts, httpdns.Header{Name: h.Name, Value: h.Value})
+	}
+	if args.AuthHeader.Valid {
+		secret, err := args.AuthHeader.Value.Load(ctx, s.srv)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, httpdns.AuthHeaderSecret(secret.Self.Accessor))
+	}
 
 	clientMetadata, err := engine.ClientMetadataFromContext(ctx)
 	if err != nil {
@@ -68,3 +94,26 @@ func (s *httpSchema) http(ctx context.Context, parent *core.Query, args httpArgs
 	st := httpdns.HTTP(args.URL, clientMetadata.SessionID, opts...)
 	return core.NewFileSt(ctx, parent, st, filename, parent.Platform, svcs)
 }
+
+// httpFilename returns the name of the file downloaded from the given URL,
+// which is the last element of its path, e.g. "foo.tar.gz" for
+// "https://example.com/releases/foo.tar.gz".
+//
+// If the URL has no usable last path element, or it's too long to be a file
+// name, the digest of the URL is used instead, which can't contain a `/` and
+// stays within file name length limits.
+func httpFilename(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	name := path.Base(u.Path)
+	switch name {
+	case "", ".", "/", "..":
+		return digest.FromString(rawURL).Encoded(), nil
+	}
+	if len(name) > 255 {
+		return digest.FromString(rawURL).Encoded(), nil
+	}
+	return name, nil
+}
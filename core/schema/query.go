@@ -24,18 +24,29 @@ func (s *querySchema) Install() {
 	core.NetworkProtocols.Install(s.srv)
 	core.ImageLayerCompressions.Install(s.srv)
 	core.ImageMediaTypesEnum.Install(s.srv)
+	core.ImagePullPolicies.Install(s.srv)
+	core.GraphFormats.Install(s.srv)
 	core.CacheSharingModes.Install(s.srv)
 	core.TypeDefKinds.Install(s.srv)
 	core.ModuleSourceKindEnum.Install(s.srv)
+	core.EngineCapabilities.Install(s.srv)
+	core.DigestAlgorithms.Install(s.srv)
+	core.FileTypes.Install(s.srv)
 
 	dagql.MustInputSpec(PipelineLabel{}).Install(s.srv)
 	dagql.MustInputSpec(core.PortForward{}).Install(s.srv)
 	dagql.MustInputSpec(core.BuildArg{}).Install(s.srv)
+	dagql.MustInputSpec(core.HTTPHeader{}).Install(s.srv)
+	dagql.MustInputSpec(core.ProblemMatcher{}).Install(s.srv)
 
 	dagql.Fields[EnvVariable]{}.Install(s.srv)
 
 	dagql.Fields[core.Port]{}.Install(s.srv)
 
+	dagql.Fields[core.GitVersion]{}.Install(s.srv)
+	dagql.Fields[core.Diagnostic]{}.Install(s.srv)
+	dagql.Fields[core.Capability]{}.Install(s.srv)
+
 	dagql.Fields[Label]{}.Install(s.srv)
 
 	dagql.Fields[*core.Query]{
@@ -47,6 +58,20 @@ func (s *querySchema) Install() {
 
 		dagql.Func("version", s.version).
 			Doc(`Get the current Dagger Engine version.`),
+
+		dagql.Func("capabilities", s.capabilities).
+			Doc(`Reports whether the engine supports each of its optional features.`),
+
+		dagql.Func("capability", s.capability).
+			Doc(`Reports whether the engine supports an optional feature.`,
+				`Operations using an unsupported feature fail with an error whose
+				"_type" extension is "UNSUPPORTED".`).
+			ArgDoc("name", `The name of the feature.`),
+
+		dagql.Func("defaultValue", s.defaultValue).
+			Doc(`Retrieves a named default value configured for the project (e.g., an
+				APK mirror), or null if it isn't set.`).
+			ArgDoc("name", `The name of the default value.`),
 	}.Install(s.srv)
 }
 
@@ -63,3 +88,26 @@ func (s *querySchema) pipeline(ctx context.Context, parent *core.Query, args pip
 func (s *querySchema) version(_ context.Context, _ *core.Query, args struct{}) (string, error) {
 	return engine.Version, nil
 }
+
+func (s *querySchema) capabilities(ctx context.Context, parent *core.Query, args struct{}) ([]core.Capability, error) {
+	return parent.Capabilities(ctx)
+}
+
+type capabilityArgs struct {
+	Name core.EngineCapability
+}
+
+func (s *querySchema) capability(ctx context.Context, parent *core.Query, args capabilityArgs) (core.Capability, error) {
+	return parent.Capability(ctx, args.Name)
+}
+
+type defaultValueArgs struct {
+	Name string
+}
+
+func (s *querySchema) defaultValue(_ context.Context, parent *core.Query, args defaultValueArgs) (dagql.Nullable[dagql.String], error) {
+	if val, ok := parent.Defaults.Values[args.Name]; ok {
+		return dagql.NonNull(dagql.NewString(val)), nil
+	}
+	return dagql.Null[dagql.String](), nil
+}
@@ -114,6 +114,7 @@ func (s *moduleSchema) newModuleSDK(
 ) (*moduleSDK, error) {
 	dag := dagql.NewServer(root)
 	dag.Cache = root.Cache
+	dag.Observe(root.Observer)
 	if err := sdkModMeta.Self.Install(ctx, dag); err != nil {
 		return nil, fmt.Errorf("failed to install sdk module %s: %w", sdkModMeta.Self.Name(), err)
 	}
@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/dagql"
+)
+
+var _ SchemaResolvers = &toolSchema{}
+
+type toolSchema struct {
+	srv *dagql.Server
+}
+
+func (s *toolSchema) Install() {
+	dagql.Fields[*core.Query]{
+		dagql.Func("tool", s.tool).
+			Doc(`Returns the executable of a pinned release of a well-known tool
+				(e.g., "kubectl", "helm", "golangci-lint").`,
+				`The release is downloaded from the tool's official location and
+				verified against its checksum, pinned by the engine.`).
+			ArgDoc("name", `The name of the tool (e.g., "kubectl").`).
+			ArgDoc("version", `The version of the tool (e.g., "1.30.2").`).
+			ArgDoc("platform", `Platform of the executable. Defaults to the platform of the engine.`).
+			ArgDoc("checksum", `Expected digest of the release artifact (e.g., "sha256:...").`,
+				`Defaults to the checksum pinned by the engine, which is required if the engine doesn't pin the release.`),
+	}.Install(s.srv)
+}
+
+type toolArgs struct {
+	Name     string
+	Version  string
+	Platform dagql.Optional[core.Platform]
+	Checksum string `default:""`
+}
+
+func (s *toolSchema) tool(ctx context.Context, parent *core.Query, args toolArgs) (*core.File, error) {
+	platform := parent.Platform
+	if args.Platform.Valid {
+		platform = args.Platform.Value
+	}
+	release, err := core.ResolveTool(args.Name, args.Version, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := args.Checksum
+	if checksum == "" {
+		if release.Checksum == "" {
+			return nil, fmt.Errorf("%s %s for %s is not pinned by the engine, its checksum must be passed explicitly",
+				args.Name, args.Version, platform.Format())
+		}
+		checksum = release.Checksum.String()
+	} else if _, err := digest.Parse(checksum); err != nil {
+		return nil, fmt.Errorf("invalid checksum %q: %w", checksum, err)
+	}
+
+	var artifact dagql.Instance[*core.File]
+	if err := s.srv.Select(ctx, s.srv.Root(), &artifact, dagql.Selector{
+		Field: "http",
+		Args: []dagql.NamedInput{
+			{Name: "url", Value: dagql.NewString(release.URL)},
+			{Name: "checksum", Value: dagql.NewString(checksum)},
+		},
+	}); err != nil {
+		return nil, err
+	}
+	return core.ToolBinary(ctx, artifact.Self, release.Path)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/iancoleman/strcase"
 
+	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/dagql"
 	"github.com/dagger/dagger/dagql/introspection"
 	"github.com/dagger/dagger/engine/buildkit"
@@ -32,6 +33,43 @@ func Syncer[T Evaluatable]() dagql.Field[T] {
 	})
 }
 
+type Graphable interface {
+	dagql.Typed
+	Graph(context.Context, core.GraphFormat) (string, error)
+}
+
+type graphArgs struct {
+	Format core.GraphFormat `default:"JSON"`
+}
+
+func Grapher[T Graphable]() dagql.Field[T] {
+	return dagql.Func("graph", func(ctx context.Context, self T, args graphArgs) (dagql.String, error) {
+		graph, err := self.Graph(ctx, args.Format)
+		if err != nil {
+			return "", err
+		}
+		return dagql.NewString(graph), nil
+	}).
+		Impure("Reports the cache status of the operations at the time of the call.").
+		ArgDoc("format", "The format to export the graph in.")
+}
+
+type Cacheable interface {
+	dagql.Typed
+	IsCached(context.Context) (bool, error)
+}
+
+func CacheChecker[T Cacheable]() dagql.Field[T] {
+	return dagql.Func("cached", func(ctx context.Context, self T, _ struct{}) (dagql.Boolean, error) {
+		cached, err := self.IsCached(ctx)
+		if err != nil {
+			return false, err
+		}
+		return dagql.NewBoolean(cached), nil
+	}).
+		Impure("Reports the state of the cache at the time of the call.")
+}
+
 func collectInputsSlice[T dagql.Type](inputs []dagql.InputObject[T]) []T {
 	ts := make([]T, len(inputs))
 	for i, input := range inputs {
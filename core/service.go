@@ -328,6 +328,7 @@ func (svc *Service) startContainer(
 	gc, err := bk.NewContainer(ctx, buildkit.NewContainerRequest{
 		Mounts:            mounts,
 		Hostname:          fullHost,
+		ExtraHosts:        execOp.Meta.ExtraHosts,
 		Platform:          &pbPlatform,
 		ExecutionMetadata: *execMD,
 	})
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -279,6 +280,20 @@ func (ss *Services) Stop(ctx context.Context, id *call.ID, kill bool) error {
 	}
 }
 
+// Running returns the services that are running, sorted by host.
+func (ss *Services) Running() []*RunningService {
+	ss.l.Lock()
+	running := make([]*RunningService, 0, len(ss.running))
+	for _, svc := range ss.running {
+		running = append(running, svc)
+	}
+	ss.l.Unlock()
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].Host < running[j].Host
+	})
+	return running
+}
+
 // StopSessionServices stops all of the services being run by the given server.
 // It is called when a server is closing.
 func (ss *Services) StopSessionServices(ctx context.Context, sessionID string) error {
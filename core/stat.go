@@ -0,0 +1,91 @@
+package core
+
+import (
+	"io/fs"
+	"path"
+	"time"
+
+	fstypes "github.com/tonistiigi/fsutil/types"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/dagql/call"
+)
+
+type Stat struct {
+	Name          string   `field:"true" doc:"The name of the entry."`
+	FileType      FileType `field:"true" name:"type" doc:"The type of the entry."`
+	Size          int      `field:"true" doc:"The size of the entry, in bytes."`
+	Mode          int      `field:"true" doc:"The permission bits of the entry (e.g., 0644)."`
+	UID           int      `field:"true" name:"uid" doc:"The user ID owning the entry."`
+	GID           int      `field:"true" name:"gid" doc:"The group ID owning the entry."`
+	ModTime       int      `field:"true" doc:"The last modification time of the entry, in seconds following Unix epoch."`
+	SymlinkTarget string   `field:"true" doc:"The location the entry points to if it is a symlink, or an empty string otherwise."`
+}
+
+func NewStat(st *fstypes.Stat) Stat {
+	mode := fs.FileMode(st.Mode)
+
+	fileType := FileTypeOther
+	switch {
+	case mode.IsRegular():
+		fileType = FileTypeRegular
+	case mode.IsDir():
+		fileType = FileTypeDir
+	case mode&fs.ModeSymlink != 0:
+		fileType = FileTypeSymlink
+	}
+
+	return Stat{
+		Name:          path.Base(st.Path),
+		FileType:      fileType,
+		Size:          int(st.Size_),
+		Mode:          int(mode.Perm()),
+		UID:           int(st.Uid),
+		GID:           int(st.Gid),
+		ModTime:       int(time.Unix(0, st.ModTime).Unix()),
+		SymlinkTarget: st.Linkname,
+	}
+}
+
+func (Stat) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "Stat",
+		NonNull:   true,
+	}
+}
+
+func (Stat) TypeDescription() string {
+	return "Information about a file or directory."
+}
+
+// FileType is a GraphQL enum type.
+type FileType string
+
+var FileTypes = dagql.NewEnum[FileType]()
+
+var (
+	FileTypeRegular = FileTypes.Register("REGULAR")
+	FileTypeDir     = FileTypes.Register("DIR")
+	FileTypeSymlink = FileTypes.Register("SYMLINK")
+	FileTypeOther   = FileTypes.Register("OTHER")
+)
+
+func (typ FileType) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "FileType",
+		NonNull:   true,
+	}
+}
+
+func (typ FileType) TypeDescription() string {
+	return "The type of a file or directory."
+}
+
+func (typ FileType) Decoder() dagql.InputDecoder {
+	return FileTypes
+}
+
+func (typ FileType) ToLiteral() call.Literal {
+	return FileTypes.Literal(typ)
+}
@@ -166,7 +166,7 @@ func (dir *Directory) Terminal(
 		if err != nil {
 			return fmt.Errorf("failed to create terminal container: %w", err)
 		}
-		ctr, err = ctr.From(ctx, distconsts.AlpineImage)
+		ctr, err = ctr.From(ctx, distconsts.AlpineImage, PullPolicyIfNotPresent)
 		if err != nil {
 			return fmt.Errorf("failed to create terminal container: %w", err)
 		}
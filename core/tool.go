@@ -0,0 +1,218 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/moby/buildkit/client/llb"
+	"github.com/opencontainers/go-digest"
+
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+// ToolRelease describes where to download a release of a tool for a
+// platform.
+type ToolRelease struct {
+	// URL of the release artifact, either the binary itself or a tar archive
+	// containing it.
+	URL string
+
+	// URL of a file containing the SHA-256 digest of the artifact, either
+	// alone or in the format of sha256sum. It's served by the same origin as
+	// the artifact, so it's only used to pin new releases in
+	// tool_checksums.txt.
+	ChecksumURL string
+
+	// Checksum is the pinned digest of the artifact, or empty if the release
+	// isn't pinned.
+	Checksum digest.Digest
+
+	// Path of the binary in the artifact if it is an archive, or empty if the
+	// artifact is the binary.
+	Path string
+}
+
+// ToolProvider resolves the releases of a tool.
+type ToolProvider interface {
+	Release(version string, platform Platform) (ToolRelease, error)
+}
+
+// ToolProviderFunc is a ToolProvider implemented by a function.
+type ToolProviderFunc func(version string, platform Platform) (ToolRelease, error)
+
+func (f ToolProviderFunc) Release(version string, platform Platform) (ToolRelease, error) {
+	return f(version, platform)
+}
+
+var (
+	toolProviders   = map[string]ToolProvider{}
+	toolProvidersMu sync.RWMutex
+)
+
+// RegisterTool makes a tool available to the tool API under the given name,
+// replacing any tool previously registered with that name.
+func RegisterTool(name string, provider ToolProvider) {
+	toolProvidersMu.Lock()
+	defer toolProvidersMu.Unlock()
+	toolProviders[name] = provider
+}
+
+// ResolveTool returns where to download the given release of a registered
+// tool.
+func ResolveTool(name, version string, platform Platform) (ToolRelease, error) {
+	toolProvidersMu.RLock()
+	provider, ok := toolProviders[name]
+	known := make([]string, 0, len(toolProviders))
+	for name := range toolProviders {
+		known = append(known, name)
+	}
+	toolProvidersMu.RUnlock()
+	if !ok {
+		sort.Strings(known)
+		return ToolRelease{}, fmt.Errorf("unknown tool %q, known tools are: %s", name, strings.Join(known, ", "))
+	}
+	version = strings.TrimPrefix(version, "v")
+	if version == "" {
+		return ToolRelease{}, fmt.Errorf("a version of %s is required", name)
+	}
+	release, err := provider.Release(version, platform)
+	if err != nil {
+		return ToolRelease{}, fmt.Errorf("%s %s: %w", name, version, err)
+	}
+	release.Checksum = pinnedToolChecksums()[release.URL]
+	return release, nil
+}
+
+//go:embed tool_checksums.txt
+var toolChecksumsFile string
+
+// pinnedToolChecksums returns the digests of the pinned tool releases, by URL
+// of their artifact.
+var pinnedToolChecksums = sync.OnceValue(func() map[string]digest.Digest {
+	return parsePinnedToolChecksums(toolChecksumsFile)
+})
+
+func parsePinnedToolChecksums(contents string) map[string]digest.Digest {
+	checksums := map[string]digest.Digest{}
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		checksums[fields[1]] = digest.NewDigestFromEncoded(digest.SHA256, strings.ToLower(fields[0]))
+	}
+	return checksums
+}
+
+func init() {
+	RegisterTool("kubectl", ToolProviderFunc(func(version string, platform Platform) (ToolRelease, error) {
+		bin := "kubectl"
+		if platform.OS == "windows" {
+			bin += ".exe"
+		}
+		url := fmt.Sprintf("https://dl.k8s.io/release/v%s/bin/%s/%s/%s", version, platform.OS, platform.Architecture, bin)
+		return ToolRelease{
+			URL:         url,
+			ChecksumURL: url + ".sha256",
+		}, nil
+	}))
+
+	RegisterTool("helm", ToolProviderFunc(func(version string, platform Platform) (ToolRelease, error) {
+		if platform.OS == "windows" {
+			return ToolRelease{}, fmt.Errorf("unsupported platform %s", platform.Format())
+		}
+		url := fmt.Sprintf("https://get.helm.sh/helm-v%s-%s-%s.tar.gz", version, platform.OS, platform.Architecture)
+		return ToolRelease{
+			URL:         url,
+			ChecksumURL: url + ".sha256sum",
+			Path:        fmt.Sprintf("%s-%s/helm", platform.OS, platform.Architecture),
+		}, nil
+	}))
+
+	RegisterTool("golangci-lint", ToolProviderFunc(func(version string, platform Platform) (ToolRelease, error) {
+		if platform.OS == "windows" {
+			return ToolRelease{}, fmt.Errorf("unsupported platform %s", platform.Format())
+		}
+		arch := platform.Architecture
+		if arch == "arm" {
+			variant := platform.Variant
+			if variant == "" {
+				variant = "v7"
+			}
+			arch = "arm" + variant
+		}
+		name := fmt.Sprintf("golangci-lint-%s-%s-%s", version, platform.OS, arch)
+		base := fmt.Sprintf("https://github.com/golangci/golangci-lint/releases/download/v%s/", version)
+		return ToolRelease{
+			URL:         base + name + ".tar.gz",
+			ChecksumURL: base + fmt.Sprintf("golangci-lint-%s-checksums.txt", version),
+			Path:        name + "/golangci-lint",
+		}, nil
+	}))
+}
+
+// ParseToolChecksum returns the digest of the artifact named filename from
+// the contents of a checksum file, which either contains only a SHA-256
+// digest or lists one per file in the format of sha256sum.
+func ParseToolChecksum(contents, filename string) (digest.Digest, error) {
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	var lines [][]string
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	var encoded string
+	switch {
+	case len(lines) == 1 && len(lines[0]) == 1:
+		encoded = lines[0][0]
+	default:
+		for _, fields := range lines {
+			if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == filename {
+				encoded = fields[0]
+				break
+			}
+		}
+	}
+	if encoded == "" {
+		return "", fmt.Errorf("no checksum found for %s", filename)
+	}
+	dgst := digest.NewDigestFromEncoded(digest.SHA256, strings.ToLower(encoded))
+	if err := dgst.Validate(); err != nil {
+		return "", fmt.Errorf("invalid checksum for %s: %w", filename, err)
+	}
+	return dgst, nil
+}
+
+// ToolBinary returns the executable of a tool from its downloaded release
+// artifact, unpacking it from the artifact if binPath is set.
+func ToolBinary(ctx context.Context, artifact *File, binPath string) (*File, error) {
+	st, err := artifact.State()
+	if err != nil {
+		return nil, err
+	}
+	srcSt, srcPath := st, artifact.File
+	if binPath != "" {
+		srcSt = llb.Scratch().File(
+			llb.Copy(st, artifact.File, "/", &llb.CopyInfo{AttemptUnpack: true}),
+			llb.WithCustomName(buildkit.InternalPrefix+"unpack "+path.Base(artifact.File)),
+		)
+		srcPath = path.Join("/", binPath)
+	}
+
+	dest := path.Join("/", path.Base(srcPath))
+	mode := fs.FileMode(0o755)
+	binSt := llb.Scratch().File(llb.Copy(srcSt, srcPath, dest, &llb.CopyInfo{Mode: &mode}))
+	return NewFileSt(ctx, artifact.Query, binSt, dest, artifact.Platform, artifact.Services)
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveTool(t *testing.T) {
+	linuxArm64 := Platform{OS: "linux", Architecture: "arm64"}
+
+	release, err := ResolveTool("kubectl", "v1.30.2", linuxArm64)
+	require.NoError(t, err)
+	require.Equal(t, ToolRelease{
+		URL:         "https://dl.k8s.io/release/v1.30.2/bin/linux/arm64/kubectl",
+		ChecksumURL: "https://dl.k8s.io/release/v1.30.2/bin/linux/arm64/kubectl.sha256",
+	}, release)
+
+	release, err = ResolveTool("helm", "3.15.2", linuxArm64)
+	require.NoError(t, err)
+	require.Equal(t, "linux-arm64/helm", release.Path)
+
+	release, err = ResolveTool("golangci-lint", "1.59.1", Platform{OS: "linux", Architecture: "arm"})
+	require.NoError(t, err)
+	require.Equal(t, "golangci-lint-1.59.1-linux-armv7/golangci-lint", release.Path)
+
+	_, err = ResolveTool("helm", "3.15.2", Platform{OS: "windows", Architecture: "amd64"})
+	require.ErrorContains(t, err, "unsupported platform")
+
+	_, err = ResolveTool("kubectl", "", linuxArm64)
+	require.ErrorContains(t, err, "a version of kubectl is required")
+
+	_, err = ResolveTool("nope", "1.0.0", linuxArm64)
+	require.ErrorContains(t, err, `unknown tool "nope"`)
+}
+
+func TestPinnedToolChecksums(t *testing.T) {
+	const sum = "0f12dc1f5b2a3c1e36f2d3b8a8c1a4b0e3c2d1f0a9b8c7d6e5f4a3b2c1d0e9f8"
+
+	checksums := parsePinnedToolChecksums("# comment\n\n" +
+		sum + "  https://dl.k8s.io/release/v1.30.2/bin/linux/arm64/kubectl\n")
+	require.Equal(t, map[string]digest.Digest{
+		"https://dl.k8s.io/release/v1.30.2/bin/linux/arm64/kubectl": "sha256:" + sum,
+	}, checksums)
+
+	for url, dgst := range pinnedToolChecksums() {
+		require.NoError(t, dgst.Validate(), url)
+	}
+}
+
+func TestParseToolChecksum(t *testing.T) {
+	const sum = "0f12dc1f5b2a3c1e36f2d3b8a8c1a4b0e3c2d1f0a9b8c7d6e5f4a3b2c1d0e9f8"
+
+	dgst, err := ParseToolChecksum(sum+"\n", "kubectl")
+	require.NoError(t, err)
+	require.Equal(t, "sha256:"+sum, dgst.String())
+
+	dgst, err = ParseToolChecksum(
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff  tool-linux-amd64.tar.gz\n"+
+			sum+" *tool-linux-arm64.tar.gz\n",
+		"tool-linux-arm64.tar.gz",
+	)
+	require.NoError(t, err)
+	require.Equal(t, "sha256:"+sum, dgst.String())
+
+	_, err = ParseToolChecksum(sum+"  tool-linux-amd64.tar.gz\n", "tool-darwin-amd64.tar.gz")
+	require.ErrorContains(t, err, "no checksum found")
+
+	_, err = ParseToolChecksum("not-a-digest\n", "kubectl")
+	require.ErrorContains(t, err, "invalid checksum")
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -839,6 +842,178 @@ func TestImpureIDsReEvaluate(t *testing.T) {
 	assert.Equal(t, called, 2)
 }
 
+func TestImpureArgsReEvaluate(t *testing.T) {
+	srv := dagql.NewServer(Query{})
+	points.Install[Query](srv)
+
+	var impure func() bool
+	gqlSrv := handler.NewDefaultServer(srv)
+	gql := client.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx context.Context
+		ctx, impure = dagql.WithImpurityTracking(r.Context())
+		gqlSrv.ServeHTTP(w, r.WithContext(ctx))
+	}))
+
+	called := 0
+	dagql.Fields[*points.Point]{
+		dagql.Func("snitch", func(ctx context.Context, self *points.Point, _ struct {
+			Fresh bool `default:"false"`
+		}) (*points.Point, error) {
+			called++
+			return self, nil
+		}).ArgImpure("fresh", "Increments internal state on each call."),
+	}.Install(srv)
+
+	snitch := func(fresh bool) {
+		var res struct {
+			Point struct {
+				Snitch struct {
+					ID string
+				}
+			}
+		}
+		req(t, gql, fmt.Sprintf(`query {
+			point(x: 6, y: 7) {
+				snitch(fresh: %t) {
+					id
+				}
+			}
+		}`, fresh), &res)
+	}
+
+	snitch(false)
+	assert.Assert(t, !impure())
+	snitch(false)
+	assert.Equal(t, called, 1)
+
+	snitch(true)
+	assert.Assert(t, impure())
+	snitch(true)
+	assert.Equal(t, called, 3)
+}
+
+func TestObserve(t *testing.T) {
+	srv := dagql.NewServer(Query{})
+	points.Install[Query](srv)
+
+	type observed struct {
+		Field  string
+		Cached bool
+	}
+	var mu sync.Mutex
+	var calls []observed
+	srv.Observe(func(ctx context.Context, id *call.ID, cached bool, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, observed{id.Field(), cached})
+	})
+
+	gql := client.New(handler.NewDefaultServer(srv))
+	var res struct {
+		Point struct {
+			ShiftLeft struct {
+				X int
+			}
+		}
+	}
+	req(t, gql, `query { point(x: 6, y: 7) { shiftLeft { x } } }`, &res)
+	assert.DeepEqual(t, []observed{
+		{"point", false},
+		{"shiftLeft", false},
+		{"x", false},
+	}, calls)
+
+	// cached selections are observed too
+	calls = nil
+	req(t, gql, `query { point(x: 6, y: 7) { shiftLeft { x } } }`, &res)
+	assert.DeepEqual(t, []observed{
+		{"point", true},
+		{"shiftLeft", true},
+		{"x", true},
+	}, calls)
+}
+
+func TestImpurityTracking(t *testing.T) {
+	srv := dagql.NewServer(Query{})
+	points.Install[Query](srv)
+
+	dagql.Fields[*points.Point]{
+		dagql.Func("snitch", func(ctx context.Context, self *points.Point, _ struct{}) (*points.Point, error) {
+			return self, nil
+		}).Impure("Increments internal state on each call."),
+	}.Install(srv)
+
+	var impure func() bool
+	gqlSrv := handler.NewDefaultServer(srv)
+	gql := client.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx context.Context
+		ctx, impure = dagql.WithImpurityTracking(r.Context())
+		gqlSrv.ServeHTTP(w, r.WithContext(ctx))
+	}))
+
+	var res struct {
+		Point struct {
+			ShiftLeft struct {
+				ID string
+				X  int
+			}
+			Snitch struct {
+				ID string
+				X  int
+			}
+		}
+	}
+	req(t, gql, `query {
+		point(x: 6, y: 7) {
+			shiftLeft {
+				id
+				x
+			}
+		}
+	}`, &res)
+	assert.Assert(t, !impure())
+
+	req(t, gql, `query {
+		point(x: 6, y: 7) {
+			snitch {
+				id
+				x
+			}
+		}
+	}`, &res)
+	assert.Assert(t, impure())
+
+	t.Run("loading pure IDs is pure", func(t *testing.T) {
+		var loaded struct {
+			LoadPointFromID struct {
+				X int
+			}
+		}
+		req(t, gql, `query {
+			loadPointFromID(id: "`+res.Point.ShiftLeft.ID+`") {
+				x
+			}
+		}`, &loaded)
+		assert.Equal(t, loaded.LoadPointFromID.X, 5)
+		assert.Assert(t, !impure())
+	})
+
+	t.Run("loading impure IDs is impure", func(t *testing.T) {
+		var loaded struct {
+			LoadPointFromID struct {
+				X int
+			}
+		}
+		req(t, gql, `query {
+			loadPointFromID(id: "`+res.Point.Snitch.ID+`") {
+				x
+			}
+		}`, &loaded)
+		assert.Equal(t, loaded.LoadPointFromID.X, 6)
+		assert.Assert(t, impure())
+	})
+}
+
 func TestPassingObjectsAround(t *testing.T) {
 	srv := dagql.NewServer(Query{})
 	points.Install[Query](srv)
@@ -1631,6 +1806,68 @@ func TestIntrospection(t *testing.T) {
 	golden.Assert(t, buf.String(), "introspection.json")
 }
 
+func TestStrictValidation(t *testing.T) {
+	srv := dagql.NewServer(Query{})
+
+	dagql.Fields[Query]{
+		dagql.Func("supportedField", func(ctx context.Context, self Query, args struct {
+			Foo string `default:""`
+		}) (string, error) {
+			return args.Foo, nil
+		}),
+
+		dagql.Func("deprecatedField", func(ctx context.Context, self Query, args struct {
+			Foo string `default:""`
+		}) (string, error) {
+			return args.Foo, nil
+		}).Deprecated("Use `supportedField` instead."),
+
+		dagql.Func("deprecatedArg", func(ctx context.Context, self Query, args struct {
+			Foo string `default:""`
+			Bar string `default:""`
+		}) (string, error) {
+			return args.Foo + args.Bar, nil
+		}).ArgDeprecated("bar", "Use `foo` instead."),
+	}.Install(srv)
+
+	t.Run("lenient by default", func(t *testing.T) {
+		ctx := context.Background()
+		res, err := srv.Query(ctx, `{deprecatedField(foo: "a"), deprecatedArg(bar: "b")}`, nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, res, map[string]any{
+			"deprecatedField": dagql.String("a"),
+			"deprecatedArg":   dagql.String("b"),
+		})
+	})
+
+	t.Run("strict allows supported fields", func(t *testing.T) {
+		ctx := dagql.WithStrictValidation(context.Background())
+		res, err := srv.Query(ctx, `{supportedField(foo: "a"), deprecatedArg(foo: "b")}`, nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, res, map[string]any{
+			"supportedField": dagql.String("a"),
+			"deprecatedArg":  dagql.String("b"),
+		})
+	})
+
+	t.Run("strict rejects deprecated fields", func(t *testing.T) {
+		ctx := dagql.WithStrictValidation(context.Background())
+		_, err := srv.Query(ctx, `{deprecatedField(foo: "a")}`, nil)
+		assert.ErrorContains(t, err, "Query.deprecatedField is deprecated")
+		assert.ErrorContains(t, err, "Use `supportedField` instead.")
+		var deprErr *dagql.DeprecationError
+		assert.Check(t, errors.As(err, &deprErr))
+		assert.Equal(t, deprErr.Field, "deprecatedField")
+	})
+
+	t.Run("strict rejects deprecated args", func(t *testing.T) {
+		ctx := dagql.WithStrictValidation(context.Background())
+		_, err := srv.Query(ctx, `{deprecatedArg(bar: "b")}`, nil)
+		assert.ErrorContains(t, err, `argument "bar" of Query.deprecatedArg is deprecated`)
+		assert.ErrorContains(t, err, "Use `foo` instead.")
+	})
+}
+
 func TestIDFormat(t *testing.T) {
 	ctx := context.Background()
 	srv := dagql.NewServer(Query{})
@@ -1743,6 +1980,80 @@ func TestIDFormat(t *testing.T) {
 	assert.Equal(t, lineAArg.Value.GetCallDigest(), pointADgst.String())
 }
 
+func TestSubscriptions(t *testing.T) {
+	srv := dagql.NewServer(Query{})
+	introspection.Install[Query](srv)
+	points.Install[Query](srv)
+	srv.InstallSubscription(
+		dagql.Subscribe("countdown", func(ctx context.Context, args struct {
+			From int
+		}, send func(int) error) error {
+			for i := args.From; i > 0; i-- {
+				if err := send(i); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	)
+	srv.InstallSubscription(
+		dagql.Subscribe("diagonal", func(ctx context.Context, args struct {
+			Length int `default:"2"`
+		}, send func(*points.Point) error) error {
+			for i := 0; i < args.Length; i++ {
+				if err := send(&points.Point{X: i, Y: i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	)
+
+	gql := client.New(handler.NewDefaultServer(srv))
+
+	t.Run("scalar results", func(t *testing.T) {
+		sub := gql.Websocket(`subscription { countdown(from: 3) }`)
+		defer sub.Close()
+		for _, expected := range []int{3, 2, 1} {
+			var res struct {
+				Countdown int
+			}
+			assert.NilError(t, sub.Next(&res))
+			assert.Equal(t, expected, res.Countdown)
+		}
+		var res struct{}
+		assert.ErrorContains(t, sub.Next(&res), "complete")
+	})
+
+	t.Run("object results", func(t *testing.T) {
+		sub := gql.Websocket(`subscription { line: diagonal { x, y } }`)
+		defer sub.Close()
+		for _, expected := range []int{0, 1} {
+			var res struct {
+				Line struct {
+					X int
+					Y int
+				}
+			}
+			assert.NilError(t, sub.Next(&res))
+			assert.Equal(t, expected, res.Line.X)
+			assert.Equal(t, expected, res.Line.Y)
+		}
+	})
+
+	t.Run("introspection", func(t *testing.T) {
+		var res struct {
+			Schema struct {
+				SubscriptionType struct {
+					Name string
+				}
+			} `json:"__schema"`
+		}
+		req(t, gql, `{ __schema { subscriptionType { name } } }`, &res)
+		assert.Equal(t, "Subscription", res.Schema.SubscriptionType.Name)
+	})
+}
+
 func eqIDs(t *testing.T, actual, expected string) {
 	debugID(t, "actual  : %s", actual)
 	debugID(t, "expected: %s", expected)
@@ -8,6 +8,7 @@ import (
 
 	"dagger.io/dagger/telemetry"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 
@@ -33,6 +34,8 @@ type DB struct {
 
 	Effects    map[string]*Span
 	EffectSite map[string]*Span
+
+	Progress map[trace.SpanID]*Progress
 }
 
 func NewDB() *DB {
@@ -51,6 +54,7 @@ func NewDB() *DB {
 		Intervals:  make(map[string]map[time.Time]*Span),
 		Effects:    make(map[string]*Span),
 		EffectSite: make(map[string]*Span),
+		Progress:   make(map[trace.SpanID]*Progress),
 	}
 }
 
@@ -111,6 +115,10 @@ type DBLogExporter struct {
 
 func (db DBLogExporter) Export(ctx context.Context, logs []sdklog.Record) error {
 	for _, log := range logs {
+		if current, total, ok := logProgress(log); ok {
+			db.recordProgress(log.SpanID(), log.Timestamp(), current, total, "")
+			continue
+		}
 		if log.Body().AsString() == "" {
 			// eof; ignore
 			continue
@@ -123,6 +131,50 @@ func (db DBLogExporter) Export(ctx context.Context, logs []sdklog.Record) error
 	return nil
 }
 
+// logProgress returns the progress reported by a log record, if any.
+func logProgress(rec sdklog.Record) (current, total int64, ok bool) {
+	rec.WalkAttributes(func(kv log.KeyValue) bool {
+		switch kv.Key {
+		case telemetry.ProgressCurrentAttr:
+			current = kv.Value.AsInt64()
+			ok = true
+		case telemetry.ProgressTotalAttr:
+			total = kv.Value.AsInt64()
+		}
+		return true
+	})
+	return current, total, ok
+}
+
+// recordProgress updates the progress of a span. A zero total or empty units
+// leave the previous ones as is.
+func (db *DB) recordProgress(spanID trace.SpanID, ts time.Time, current, total int64, units string) {
+	progress, found := db.Progress[spanID]
+	if !found {
+		progress = &Progress{
+			Started: ts,
+			Initial: current,
+		}
+		if span, ok := db.Spans[spanID]; ok {
+			progress.Started = span.StartTime()
+			progress.Initial = 0
+		}
+		db.Progress[spanID] = progress
+	}
+	if ts.Before(progress.Updated) {
+		// out of order
+		return
+	}
+	progress.Current = current
+	progress.Updated = ts
+	if total > 0 {
+		progress.Total = total
+	}
+	if units != "" {
+		progress.Units = units
+	}
+}
+
 func (db *DB) Shutdown(ctx context.Context) error {
 	return nil // noop
 }
@@ -215,6 +267,7 @@ func (db *DB) maybeRecordSpan(traceData *Trace, span sdktrace.ReadOnlySpan) { //
 		db.Intervals[digest][span.StartTime()] = spanData
 	}
 
+	var progressRecorded bool
 	for _, attr := range attrs {
 		switch attr.Key {
 		case telemetry.DagCallAttr:
@@ -268,6 +321,12 @@ func (db *DB) maybeRecordSpan(traceData *Trace, span sdktrace.ReadOnlySpan) { //
 		case telemetry.DagInputsAttr:
 			spanData.Inputs = attr.Value.AsStringSlice()
 
+		case telemetry.ProgressTotalAttr, telemetry.ProgressCurrentAttr, telemetry.ProgressUnitsAttr:
+			if !progressRecorded {
+				db.recordSpanProgress(span)
+				progressRecorded = true
+			}
+
 		case telemetry.EffectIDsAttr:
 			spanData.Effects = attr.Value.AsStringSlice()
 			for _, digest := range spanData.Effects {
@@ -328,6 +387,35 @@ func (db *DB) maybeRecordSpan(traceData *Trace, span sdktrace.ReadOnlySpan) { //
 	}
 }
 
+// recordSpanProgress records the progress attributes of a span, which are
+// final once it completes.
+func (db *DB) recordSpanProgress(span sdktrace.ReadOnlySpan) {
+	var current, total int64
+	var units string
+	var hasCurrent bool
+	for _, attr := range span.Attributes() {
+		switch attr.Key {
+		case telemetry.ProgressCurrentAttr:
+			current = attr.Value.AsInt64()
+			hasCurrent = true
+		case telemetry.ProgressTotalAttr:
+			total = attr.Value.AsInt64()
+		case telemetry.ProgressUnitsAttr:
+			units = attr.Value.AsString()
+		}
+	}
+	ts := span.StartTime()
+	if span.EndTime().After(ts) {
+		ts = span.EndTime()
+	}
+	if !hasCurrent {
+		if progress, found := db.Progress[span.SpanContext().SpanID()]; found {
+			current = progress.Current
+		}
+	}
+	db.recordProgress(span.SpanContext().SpanID(), ts, current, total, units)
+}
+
 func (db *DB) HighLevelSpan(call *callpbv1.Call) *Span {
 	return db.MostInterestingSpan(db.Simplify(call, false).Digest)
 }
@@ -248,6 +248,7 @@ func (r renderer) renderSpan(
 	if span != nil {
 		// TODO: when a span has child spans that have progress, do 2-d progress
 		// fe.renderVertexTasks(out, span, depth)
+		r.renderProgress(out, span)
 		r.renderDuration(out, span)
 	}
 
@@ -328,6 +329,29 @@ func (r renderer) renderStatus(out *termenv.Output, span *Span, focused bool) {
 	}
 }
 
+func (r renderer) renderProgress(out *termenv.Output, span *Span) {
+	progress := span.Progress()
+	if progress == nil {
+		return
+	}
+	fmt.Fprint(out, " ")
+	amount := progress.Format(float64(progress.Current))
+	if progress.Total > 0 {
+		amount += "/" + progress.Format(float64(progress.Total))
+	}
+	if span.IsRunning() {
+		if rate := progress.Rate(); rate > 0 {
+			amount += " " + progress.Format(rate) + "/s"
+		}
+		if eta, ok := progress.ETA(); ok {
+			amount += " ETA " + fmtDuration(eta)
+		}
+		fmt.Fprint(out, out.String(amount).Foreground(termenv.ANSICyan))
+	} else {
+		fmt.Fprint(out, out.String(amount).Faint())
+	}
+}
+
 func (r renderer) renderDuration(out *termenv.Output, span *Span) {
 	fmt.Fprint(out, " ")
 	duration := out.String(fmtDuration(span.Duration()))
@@ -0,0 +1,247 @@
+package idtui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"dagger.io/dagger/telemetry"
+)
+
+// JSON event types emitted by the json frontend.
+const (
+	JSONEventEngine    = "engine"
+	JSONEventCloud     = "cloud"
+	JSONEventStarted   = "started"
+	JSONEventCached    = "cached"
+	JSONEventCompleted = "completed"
+	JSONEventFailed    = "failed"
+	JSONEventCanceled  = "canceled"
+	JSONEventLog       = "log"
+)
+
+// JSONEvent is a single line of the json frontend's output.
+type JSONEvent struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+
+	// Span fields, set for all events except engine and cloud.
+	SpanID   string `json:"span,omitempty"`
+	ParentID string `json:"parent,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Digest   string `json:"digest,omitempty"`
+	Internal bool   `json:"internal,omitempty"`
+
+	// Duration is the duration of the span in seconds, set once it ended.
+	Duration float64 `json:"duration,omitempty"`
+	// Error is the error the span failed with.
+	Error string `json:"error,omitempty"`
+
+	// Data is a chunk of log output, and Stream the stdio stream it was
+	// written to (1 for stdout, 2 for stderr), if any.
+	Data   string `json:"data,omitempty"`
+	Stream int64  `json:"stream,omitempty"`
+
+	// Fields set for engine and cloud events.
+	Engine   string `json:"engine,omitempty"`
+	Version  string `json:"version,omitempty"`
+	ClientID string `json:"client,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+type frontendJSON struct {
+	FrontendOpts
+
+	// db stores info about all the spans
+	db *DB
+
+	// started and ended track which events were already emitted for each
+	// span, since spans are exported again every time they change
+	started map[trace.SpanID]bool
+	ended   map[trace.SpanID]bool
+
+	enc *json.Encoder
+
+	mu sync.Mutex
+}
+
+// NewJSON returns a frontend that writes a stream of newline-delimited
+// JSON events to stderr, for consumption by other programs.
+func NewJSON() Frontend {
+	return &frontendJSON{
+		db:      NewDB(),
+		started: make(map[trace.SpanID]bool),
+		ended:   make(map[trace.SpanID]bool),
+		enc:     json.NewEncoder(os.Stderr),
+	}
+}
+
+func (fe *frontendJSON) Run(ctx context.Context, opts FrontendOpts, run func(context.Context) error) error {
+	fe.FrontendOpts = opts
+	return run(ctx)
+}
+
+func (fe *frontendJSON) SetPrimary(spanID trace.SpanID) {
+	fe.mu.Lock()
+	fe.db.PrimarySpan = spanID
+	fe.mu.Unlock()
+}
+
+func (fe *frontendJSON) Background(cmd tea.ExecCommand) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (fe *frontendJSON) ConnectedToEngine(ctx context.Context, name string, version string, clientID string) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	fe.emit(JSONEvent{
+		Type:     JSONEventEngine,
+		Time:     time.Now(),
+		Engine:   name,
+		Version:  version,
+		ClientID: clientID,
+	})
+}
+
+func (fe *frontendJSON) ConnectedToCloud(ctx context.Context, url string, msg string) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	fe.emit(JSONEvent{
+		Type: JSONEventCloud,
+		Time: time.Now(),
+		URL:  url,
+	})
+}
+
+func (fe *frontendJSON) emit(ev JSONEvent) {
+	if fe.Silent {
+		return
+	}
+	// nothing sensible to do with a write error; the consumer went away
+	_ = fe.enc.Encode(ev)
+}
+
+func (fe *frontendJSON) spanEvent(typ string, span *Span) JSONEvent {
+	ev := JSONEvent{
+		Type:     typ,
+		SpanID:   span.ID.String(),
+		Name:     span.Name(),
+		Digest:   span.Digest,
+		Internal: span.Internal,
+	}
+	if parent := span.Parent().SpanID(); parent.IsValid() {
+		ev.ParentID = parent.String()
+	}
+	return ev
+}
+
+func (fe *frontendJSON) Shutdown(ctx context.Context) error {
+	return fe.db.Shutdown(ctx)
+}
+
+func (fe *frontendJSON) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (fe *frontendJSON) SpanExporter() sdktrace.SpanExporter {
+	return jsonSpanExporter{fe}
+}
+
+type jsonSpanExporter struct {
+	*frontendJSON
+}
+
+func (fe jsonSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	if err := fe.db.ExportSpans(ctx, spans); err != nil {
+		return err
+	}
+
+	for _, s := range spans {
+		spanID := s.SpanContext().SpanID()
+		span := fe.db.Spans[spanID]
+		if span == nil {
+			continue
+		}
+		if !fe.started[spanID] {
+			fe.started[spanID] = true
+			ev := fe.spanEvent(JSONEventStarted, span)
+			ev.Time = s.StartTime()
+			fe.emit(ev)
+		}
+		if fe.ended[spanID] || s.EndTime().Before(s.StartTime()) {
+			continue
+		}
+		fe.ended[spanID] = true
+
+		var ev JSONEvent
+		switch {
+		case span.Canceled:
+			ev = fe.spanEvent(JSONEventCanceled, span)
+		case span.Err() != nil:
+			ev = fe.spanEvent(JSONEventFailed, span)
+			ev.Error = span.Err().Error()
+		case span.Cached:
+			ev = fe.spanEvent(JSONEventCached, span)
+		default:
+			ev = fe.spanEvent(JSONEventCompleted, span)
+		}
+		ev.Time = s.EndTime()
+		ev.Duration = s.EndTime().Sub(s.StartTime()).Seconds()
+		fe.emit(ev)
+	}
+	return nil
+}
+
+func (fe *frontendJSON) LogExporter() sdklog.Exporter {
+	return jsonLogExporter{fe}
+}
+
+type jsonLogExporter struct {
+	*frontendJSON
+}
+
+func (fe jsonLogExporter) Export(ctx context.Context, logs []sdklog.Record) error {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	if err := fe.db.LogExporter().Export(ctx, logs); err != nil {
+		return err
+	}
+
+	for _, rec := range logs {
+		body := rec.Body().AsString()
+		if body == "" {
+			// likely just indicates EOF
+			continue
+		}
+		ev := JSONEvent{
+			Type: JSONEventLog,
+			Time: rec.Timestamp(),
+			Data: body,
+		}
+		if rec.SpanID().IsValid() {
+			ev.SpanID = rec.SpanID().String()
+		}
+		rec.WalkAttributes(func(kv log.KeyValue) bool {
+			if kv.Key == telemetry.StdioStreamAttr {
+				ev.Stream = kv.Value.AsInt64()
+				return false
+			}
+			return true
+		})
+		fe.emit(ev)
+	}
+	return nil
+}
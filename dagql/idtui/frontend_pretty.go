@@ -886,6 +886,11 @@ func newPrettyLogs() *prettyLogs {
 
 func (l *prettyLogs) Export(ctx context.Context, logs []sdklog.Record) error {
 	for _, log := range logs {
+		if _, _, ok := logProgress(log); ok {
+			// rendered with the span instead
+			continue
+		}
+
 		slog.Debug("exporting log", "span", log.SpanID, "body", log.Body().AsString())
 
 		// render vterm for TUI
@@ -3,6 +3,7 @@ package idtui
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -129,6 +130,11 @@ func (span *Span) EndTime() time.Time {
 	return span.ReadOnlySpan.EndTime()
 }
 
+// Progress returns the progress reported for the span, if any.
+func (span *Span) Progress() *Progress {
+	return span.db.Progress[span.ID]
+}
+
 func (span *Span) IsBefore(other *Span) bool {
 	return span.StartTime().Before(other.StartTime())
 }
@@ -196,6 +202,59 @@ func (span *Span) Classes() string {
 	return strings.Join(classes, " ")
 }
 
+// Progress is the progress of a span towards a total, e.g. the bytes of an
+// image layer pulled so far.
+type Progress struct {
+	Current int64
+	Total   int64
+	Units   string
+
+	// Initial is the value of Current at Started, which with the latest value
+	// at Updated gives the rate of progress.
+	Initial          int64
+	Started, Updated time.Time
+}
+
+// Rate returns the average progress per second.
+func (p *Progress) Rate() float64 {
+	elapsed := p.Updated.Sub(p.Started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Current-p.Initial) / elapsed
+}
+
+// ETA returns the estimated time remaining until the total is reached, if
+// the total is known and progress is being made.
+func (p *Progress) ETA() (time.Duration, bool) {
+	rate := p.Rate()
+	if p.Total <= 0 || p.Current >= p.Total || rate <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(p.Total-p.Current) / rate * float64(time.Second)), true
+}
+
+// Format formats an amount of progress in its units.
+func (p *Progress) Format(n float64) string {
+	if p.Units == "bytes" {
+		return fmtBytes(n)
+	}
+	return strconv.FormatFloat(n, 'f', 0, 64)
+}
+
+func fmtBytes(n float64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%.0f B", n)
+	}
+	exp := 0
+	for n >= unit*unit && exp < 4 {
+		n /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", n/unit, "KMGTP"[exp])
+}
+
 func fmtDuration(d time.Duration) string {
 	days := int64(d.Hours()) / 24
 	hours := int64(d.Hours()) % 24
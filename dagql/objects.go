@@ -88,6 +88,14 @@ func (class Class[T]) Field(name string) (Field[T], bool) {
 	return *field, ok
 }
 
+func (class Class[T]) FieldSpec(name string) (FieldSpec, bool) {
+	field, ok := class.Field(name)
+	if !ok {
+		return FieldSpec{}, false
+	}
+	return field.Spec, true
+}
+
 func (class Class[T]) Install(fields ...Field[T]) {
 	class.fieldsL.Lock()
 	defer class.fieldsL.Unlock()
@@ -414,6 +422,9 @@ type InputSpec struct {
 	// Sensitive indicates that the value of this arg is sensitive and should be
 	// omitted from telemetry.
 	Sensitive bool
+	// ImpurityReason indicates that the field's result may change over time
+	// when this boolean arg is true.
+	ImpurityReason string
 }
 
 type InputSpecs []InputSpec
@@ -562,6 +573,18 @@ func (field Field[T]) ArgSensitive(name string) Field[T] {
 	panic(fmt.Sprintf("field %s has no such argument: %q", field.Spec.Name, name))
 }
 
+// ArgImpure marks the field as "impure" when the given boolean arg is true,
+// like Impure.
+func (field Field[T]) ArgImpure(name string, reason string, paras ...string) Field[T] {
+	for i, arg := range field.Spec.Args {
+		if arg.Name == name {
+			field.Spec.Args[i].ImpurityReason = FormatDescription(append([]string{reason}, paras...)...)
+			return field
+		}
+	}
+	panic(fmt.Sprintf("field %s has no such argument: %q", field.Spec.Name, name))
+}
+
 func (field Field[T]) ArgDeprecated(name string, paras ...string) Field[T] {
 	for i, arg := range field.Spec.Args {
 		if arg.Name == name {
@@ -8,7 +8,9 @@ import (
 	"reflect"
 	"runtime/debug"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/iancoleman/strcase"
@@ -27,12 +29,15 @@ import (
 type Server struct {
 	root        Object
 	telemetry   AroundFunc
+	observer    ObserveFunc
 	objects     map[string]ObjectType
 	scalars     map[string]ScalarType
 	typeDefs    map[string]TypeDef
 	directives  map[string]DirectiveSpec
 	installLock *sync.Mutex
 
+	subscriptions map[string]Subscription
+
 	// Cache is the inner cache used by the server. It can be replicated to
 	// another *Server to inherit and share caches.
 	//
@@ -50,6 +55,10 @@ type AroundFunc func(
 	*call.ID,
 ) (context.Context, func(res Typed, cached bool, err error))
 
+// ObserveFunc is a function that is called after every selection, whether its
+// result was cached or not.
+type ObserveFunc func(ctx context.Context, id *call.ID, cached bool, err error)
+
 // Cache stores results of pure selections against Server.
 type Cache interface {
 	GetOrInitialize(
@@ -85,6 +94,8 @@ func NewServer[T Typed](root T) *Server {
 		typeDefs:    map[string]TypeDef{},
 		directives:  map[string]DirectiveSpec{},
 		installLock: &sync.Mutex{},
+
+		subscriptions: map[string]Subscription{},
 	}
 	srv.InstallObject(rootClass)
 	for _, scalar := range coreScalars {
@@ -265,6 +276,12 @@ func (s *Server) Around(rec AroundFunc) {
 	s.telemetry = rec
 }
 
+// Observe installs a function to be called after every selection, including
+// cached ones.
+func (s *Server) Observe(obs ObserveFunc) {
+	s.observer = obs
+}
+
 // Query is a convenience method for executing a query against the server
 // without having to go through HTTP. This can be useful for introspection, for
 // example.
@@ -297,6 +314,10 @@ func (s *Server) Schema() *ast.Schema { // TODO: change this to be updated whene
 	for _, t := range s.typeDefs {
 		schema.AddTypes(t.TypeDefinition())
 	}
+	if def := s.subscriptionDefinition(); def != nil {
+		schema.Subscription = def
+		schema.AddTypes(def)
+	}
 	schema.Directives = map[string]*ast.DirectiveDefinition{}
 	for n, d := range s.directives {
 		schema.Directives[n] = d.DirectiveDefinition()
@@ -318,6 +339,9 @@ type ExtendedError interface {
 
 // Exec implements graphql.ExecutableSchema.
 func (s *Server) Exec(ctx1 context.Context) graphql.ResponseHandler {
+	if gqlOp := graphql.GetOperationContext(ctx1); gqlOp.Operation != nil && gqlOp.Operation.Operation == ast.Subscription {
+		return s.execSubscription(ctx1, gqlOp)
+	}
 	return func(ctx context.Context) (res *graphql.Response) {
 		gqlOp := graphql.GetOperationContext(ctx)
 
@@ -586,10 +610,14 @@ func (s *Server) cachedSelect(ctx context.Context, self Object, sel Selector) (r
 		}
 		return self.Select(ctx, sel)
 	}
+	var cached bool
 	if chainedID.IsTainted() {
 		val, err = doSelect(ctx)
 	} else {
-		val, _, err = s.Cache.GetOrInitialize(ctx, dig, doSelect)
+		val, cached, err = s.Cache.GetOrInitialize(ctx, dig, doSelect)
+	}
+	if s.observer != nil {
+		s.observer(ctx, chainedID, cached, err)
 	}
 	if err != nil {
 		return nil, nil, err
@@ -652,6 +680,9 @@ func (s *Server) resolvePath(ctx context.Context, self Object, sel Selection) (r
 	if err != nil {
 		return nil, fmt.Errorf("resolve: %w", err)
 	}
+	if impure, ok := ctx.Value(impurityKey{}).(*atomic.Bool); ok && resultImpure(self, sel.Selector, chainedID, val) {
+		impure.Store(true)
+	}
 
 	if val == nil {
 		// a nil value ignores all sub-selections
@@ -708,6 +739,30 @@ func (s *Server) resolvePath(ctx context.Context, self Object, sel Selection) (r
 	return s.Resolve(ctx, node, sel.Subselections...)
 }
 
+type impurityKey struct{}
+
+// WithImpurityTracking returns a context recording whether the results of the
+// queries resolved with it may change if they're queried again, because any
+// of their selections is impure or selected on an impure object. The returned
+// function reports it once the queries are resolved.
+func WithImpurityTracking(ctx context.Context) (context.Context, func() bool) {
+	impure := new(atomic.Bool)
+	return context.WithValue(ctx, impurityKey{}, impure), impure.Load
+}
+
+// resultImpure returns whether the result of a selection may change if it's
+// selected again. Module functions are only cached for the lifetime of a
+// session, so results depending on them count as impure too. Loading an
+// object from its ID is only as impure as the ID.
+func resultImpure(self Object, sel Selector, chainedID *call.ID, val Typed) bool {
+	id := chainedID
+	if obj, ok := val.(Object); ok && self.ID() == nil &&
+		strings.HasPrefix(sel.Field, "load") && strings.HasSuffix(sel.Field, "FromID") {
+		id = obj.ID()
+	}
+	return id.IsTainted() || len(id.Modules()) > 0
+}
+
 func (s *Server) toSelectable(chainedID *call.ID, val Typed) (Object, error) {
 	if sel, ok := val.(Object); ok {
 		// We always support returning something that's already Selectable, e.g. an
@@ -737,6 +792,13 @@ func (s *Server) parseASTSelections(ctx context.Context, gqlOp *graphql.Operatio
 			if err != nil {
 				return nil, fmt.Errorf("parse field %q: %w", x.Name, err)
 			}
+			if IsStrictValidation(ctx) {
+				if spec, ok := class.FieldSpec(x.Name); ok {
+					if err := checkDeprecations(class.TypeName(), spec, x); err != nil {
+						return nil, err
+					}
+				}
+			}
 			var subsels []Selection
 			if len(x.SelectionSet) > 0 {
 				subsels, err = s.parseASTSelections(ctx, gqlOp, resType, x.SelectionSet)
@@ -819,13 +881,15 @@ func (sel Selector) AppendTo(id *call.ID, spec FieldSpec) *call.ID {
 			// we don't include null arguments, since they would needlessly bust caches
 			continue
 		}
-		if arg, found := spec.Args.Lookup(arg.Name); found && arg.Sensitive {
+		argSpec, found := spec.Args.Lookup(arg.Name)
+		if found && argSpec.Sensitive {
 			continue
 		}
-		idArgs = append(idArgs, call.NewArgument(
-			arg.Name,
-			arg.Value.ToLiteral(),
-		))
+		lit := arg.Value.ToLiteral()
+		if b, ok := lit.(*call.LiteralBool); ok && argSpec.ImpurityReason != "" && b.Value() {
+			tainted = true
+		}
+		idArgs = append(idArgs, call.NewArgument(arg.Name, lit))
 	}
 	// TODO: it's better DX if it matches schema order
 	sort.Slice(idArgs, func(i, j int) bool {
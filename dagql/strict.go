@@ -0,0 +1,90 @@
+package dagql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+type strictKey struct{}
+
+// WithStrictValidation returns a new context with strict validation enabled.
+//
+// In strict mode, queries that select deprecated fields or pass deprecated
+// arguments are rejected at parse time instead of being resolved. Unknown
+// fields and arguments are always rejected by schema validation, which
+// already suggests the closest matches.
+func WithStrictValidation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictKey{}, true)
+}
+
+// IsStrictValidation returns whether strict validation is enabled in the
+// context.
+func IsStrictValidation(ctx context.Context) bool {
+	if val := ctx.Value(strictKey{}); val != nil {
+		return val.(bool)
+	}
+	return false
+}
+
+// DeprecationError is returned in strict mode when a query uses a deprecated
+// field or argument.
+type DeprecationError struct {
+	// TypeName is the name of the type the field belongs to.
+	TypeName string
+	// Field is the name of the deprecated field, or the field whose argument
+	// is deprecated.
+	Field string
+	// Arg is the name of the deprecated argument, if any.
+	Arg string
+	// Reason is the deprecation reason, typically suggesting a replacement.
+	Reason string
+}
+
+var _ ExtendedError = (*DeprecationError)(nil)
+
+func (err *DeprecationError) Error() string {
+	if err.Arg != "" {
+		return fmt.Sprintf("argument %q of %s.%s is deprecated: %s", err.Arg, err.TypeName, err.Field, err.Reason)
+	}
+	return fmt.Sprintf("%s.%s is deprecated: %s", err.TypeName, err.Field, err.Reason)
+}
+
+func (err *DeprecationError) Extensions() map[string]any {
+	ext := map[string]any{
+		"_type":  "DEPRECATION_ERROR",
+		"type":   err.TypeName,
+		"field":  err.Field,
+		"reason": err.Reason,
+	}
+	if err.Arg != "" {
+		ext["arg"] = err.Arg
+	}
+	return ext
+}
+
+// checkDeprecations returns a *DeprecationError if the given field selection
+// uses a deprecated field or argument.
+func checkDeprecations(typeName string, spec FieldSpec, astField *ast.Field) error {
+	if spec.DeprecatedReason != "" {
+		return &DeprecationError{
+			TypeName: typeName,
+			Field:    spec.Name,
+			Reason:   spec.DeprecatedReason,
+		}
+	}
+	for _, arg := range astField.Arguments {
+		argSpec, ok := spec.Args.Lookup(arg.Name)
+		if !ok || argSpec.DeprecatedReason == "" {
+			continue
+		}
+		return &DeprecationError{
+			TypeName: typeName,
+			Field:    spec.Name,
+			Arg:      arg.Name,
+			Reason:   argSpec.DeprecatedReason,
+		}
+	}
+	return nil
+}
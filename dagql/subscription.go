@@ -0,0 +1,219 @@
+package dagql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/dagger/dagger/dagql/call"
+	"github.com/dagger/dagger/engine/slog"
+)
+
+// SubscriptionTypeName is the name of the root type of subscriptions.
+const SubscriptionTypeName = "Subscription"
+
+// Subscription is a field of the Subscription root type. Unlike other fields,
+// it yields any number of results, which are sent to the client as they come.
+type Subscription struct {
+	Spec FieldSpec
+	// Func sends each result of the subscription, returning once there are no
+	// more or the context is canceled.
+	Func func(ctx context.Context, args map[string]Input, send func(Typed) error) error
+}
+
+// Subscribe is like Func, but for a subscription: the given function sends
+// each result as it comes and returns once there are no more.
+func Subscribe[A any, R any](name string, fn func(ctx context.Context, args A, send func(R) error) error) Subscription {
+	var zeroArgs A
+	inputs, argsErr := inputSpecsForType(zeroArgs, true)
+	if argsErr != nil {
+		slog.Error("failed to parse args", "type", SubscriptionTypeName, "field", name, "error", argsErr)
+	}
+	var zeroRet R
+	ret, err := builtinOrTyped(zeroRet)
+	if err != nil {
+		slog.Error("failed to parse return type", "type", SubscriptionTypeName, "field", name, "error", err)
+	}
+	return Subscription{
+		Spec: FieldSpec{
+			Name: name,
+			Args: inputs,
+			Type: ret,
+		},
+		Func: func(ctx context.Context, argVals map[string]Input, send func(Typed) error) error {
+			if argsErr != nil {
+				return argsErr
+			}
+			var args A
+			if err := setInputFields(inputs, argVals, &args); err != nil {
+				return err
+			}
+			return fn(ctx, args, func(res R) error {
+				val, err := builtinOrTyped(res)
+				if err != nil {
+					return err
+				}
+				return send(val)
+			})
+		},
+	}
+}
+
+// Doc sets the description of the subscription. Each argument is joined by
+// two empty lines.
+func (sub Subscription) Doc(paras ...string) Subscription {
+	sub.Spec.Description = FormatDescription(paras...)
+	return sub
+}
+
+func (sub Subscription) ArgDoc(name string, paras ...string) Subscription {
+	for i, arg := range sub.Spec.Args {
+		if arg.Name == name {
+			sub.Spec.Args[i].Description = FormatDescription(paras...)
+			return sub
+		}
+	}
+	panic(fmt.Sprintf("subscription %s has no such argument: %q", sub.Spec.Name, name))
+}
+
+// InstallSubscription installs the given subscription into the schema.
+func (s *Server) InstallSubscription(sub Subscription) {
+	s.installLock.Lock()
+	defer s.installLock.Unlock()
+	s.subscriptions[sub.Spec.Name] = sub
+}
+
+// subscriptionDefinition returns the definition of the Subscription root
+// type, or nil if there are no subscriptions.
+func (s *Server) subscriptionDefinition() *ast.Definition {
+	if len(s.subscriptions) == 0 {
+		return nil
+	}
+	def := &ast.Definition{
+		Kind:        ast.Object,
+		Name:        SubscriptionTypeName,
+		Description: "The root of all subscriptions, whose results are streamed to the client as they come.",
+	}
+	for _, sub := range s.subscriptions {
+		def.Fields = append(def.Fields, sub.Spec.FieldDefinition())
+	}
+	sort.Slice(def.Fields, func(i, j int) bool {
+		return def.Fields[i].Name < def.Fields[j].Name
+	})
+	return def
+}
+
+// execSubscription starts the subscription selected by the operation, and
+// returns a handler yielding a response for each of its results.
+func (s *Server) execSubscription(ctx context.Context, gqlOp *graphql.OperationContext) graphql.ResponseHandler {
+	if err := gqlOp.Validate(ctx); err != nil {
+		return graphql.OneShot(graphql.ErrorResponse(ctx, "validate: %s", err))
+	}
+	if len(gqlOp.Operation.SelectionSet) != 1 {
+		return graphql.OneShot(graphql.ErrorResponse(ctx, "subscriptions must select exactly one field"))
+	}
+	astField, ok := gqlOp.Operation.SelectionSet[0].(*ast.Field)
+	if !ok {
+		return graphql.OneShot(graphql.ErrorResponse(ctx, "subscriptions must select a field, got %T", gqlOp.Operation.SelectionSet[0]))
+	}
+
+	s.installLock.Lock()
+	sub, ok := s.subscriptions[astField.Name]
+	s.installLock.Unlock()
+	if !ok {
+		return graphql.OneShot(graphql.ErrorResponse(ctx, "%s has no such field: %q", SubscriptionTypeName, astField.Name))
+	}
+
+	var inputs Inputs
+	for _, arg := range astField.Arguments {
+		argSpec, ok := sub.Spec.Args.Lookup(arg.Name)
+		if !ok {
+			return graphql.OneShot(graphql.ErrorResponse(ctx, "%s.%s has no such argument: %q", SubscriptionTypeName, sub.Spec.Name, arg.Name))
+		}
+		val, err := arg.Value.Value(gqlOp.Variables)
+		if err != nil {
+			return graphql.OneShot(&graphql.Response{Errors: gqlErrs(err)})
+		}
+		if val == nil {
+			continue
+		}
+		input, err := argSpec.Type.Decoder().DecodeInput(val)
+		if err != nil {
+			return graphql.OneShot(graphql.ErrorResponse(ctx, "init arg %q value as %T (%s) using %T: %s", arg.Name, argSpec.Type, argSpec.Type.Type(), argSpec.Type.Decoder(), err))
+		}
+		inputs = append(inputs, NamedInput{Name: arg.Name, Value: input})
+	}
+	args, err := applyDefaults(sub.Spec, inputs)
+	if err != nil {
+		return graphql.OneShot(&graphql.Response{Errors: gqlErrs(err)})
+	}
+
+	var subsels []Selection
+	if len(astField.SelectionSet) > 0 {
+		subsels, err = s.parseASTSelections(ctx, gqlOp, sub.Spec.Type.Type(), astField.SelectionSet)
+		if err != nil {
+			return graphql.OneShot(&graphql.Response{Errors: gqlErrs(err)})
+		}
+	}
+	name := astField.Alias
+	if name == "" {
+		name = astField.Name
+	}
+
+	// Results are selected from under an ID for the subscription that's
+	// tainted, so that each result is resolved rather than reusing the fields
+	// cached for the first.
+	idSpec := sub.Spec
+	idSpec.ImpurityReason = "Subscription results are different each time."
+	resultID := Selector{Field: sub.Spec.Name, Args: inputs}.AppendTo(call.New(), idSpec)
+
+	responses := make(chan *graphql.Response)
+	respond := func(res *graphql.Response) error {
+		select {
+		case responses <- res:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	go func() {
+		defer close(responses)
+		err := sub.Func(ctx, args, func(val Typed) error {
+			var res any = val
+			if len(subsels) > 0 {
+				node, err := s.toSelectable(resultID, val)
+				if err != nil {
+					return fmt.Errorf("instantiate: %w", err)
+				}
+				res, err = s.Resolve(ctx, node, subsels...)
+				if err != nil {
+					return err
+				}
+			}
+			data, err := json.Marshal(map[string]any{name: res})
+			if err != nil {
+				return fmt.Errorf("marshal: %w", err)
+			}
+			return respond(&graphql.Response{Data: json.RawMessage(data)})
+		})
+		if err != nil && ctx.Err() == nil {
+			respond(&graphql.Response{Errors: gqlErrs(err)})
+		}
+	}()
+
+	return func(ctx context.Context) *graphql.Response {
+		select {
+		case res, ok := <-responses:
+			if !ok {
+				return nil
+			}
+			return res
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
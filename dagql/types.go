@@ -43,6 +43,8 @@ type ObjectType interface {
 	// ParseField parses the given field and returns a Selector and an expected
 	// return type.
 	ParseField(context.Context, *ast.Field, map[string]any) (Selector, *ast.Type, error)
+	// FieldSpec returns the spec of the field with the given name, if it exists.
+	FieldSpec(string) (FieldSpec, bool)
 	// Extend registers an additional field onto the type.
 	//
 	// Unlike natively added fields, the extended func is limited to the external
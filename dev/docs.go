@@ -2,6 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 
 	"golang.org/x/sync/errgroup"
 
@@ -16,6 +24,8 @@ type Docs struct {
 const (
 	generatedSchemaPath = "docs/docs-graphql/schema.graphqls"
 	generatedCliZenPath = "docs/current_docs/reference/cli.mdx"
+
+	examplesPath = "docs/current_docs"
 )
 
 const cliZenFrontmatter = `---
@@ -139,3 +149,234 @@ func (d Docs) GenerateCli() *Directory {
 		File("cli.mdx")
 	return dag.Directory().WithFile(generatedCliZenPath, generated)
 }
+
+// Run the Go and GraphQL examples of the docs against a dev engine
+//
+// Go examples are the Go modules in a "go" directory, run with the
+// "dagger call" commands shown after them in the docs pages, or loaded with
+// "dagger functions" if there are none that can run unattended. GraphQL
+// examples are the .gql and .graphql files, and the "dagger query" commands
+// of the docs pages. Every example is run, and the failures of each are
+// reported together.
+func (d Docs) TestExamples(
+	ctx context.Context,
+	// Directory to discover examples in, instead of the docs
+	// +optional
+	source *Directory,
+	// Only run the examples whose path contains this string
+	// +optional
+	filter string,
+	// Maximum number of examples to run concurrently, or 0 for the number of
+	// CPUs
+	// +optional
+	// +default=8
+	parallel int,
+) error {
+	if parallel < 0 {
+		return fmt.Errorf("invalid parallel %d: must not be negative", parallel)
+	}
+	if parallel == 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	if source == nil {
+		source = d.Dagger.Source.Directory(examplesPath)
+	}
+
+	examples, err := discoverExamples(ctx, source, filter)
+	if err != nil {
+		return err
+	}
+	if len(examples) == 0 {
+		return fmt.Errorf("no examples found")
+	}
+
+	ctr, err := d.Dagger.Dev(ctx, source, false)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var failures []error
+	eg := errgroup.Group{}
+	eg.SetLimit(parallel)
+	for _, example := range examples {
+		example := example
+		eg.Go(func() error {
+			_, err := example.run(ctr).Sync(ctx)
+			if err != nil {
+				mu.Lock()
+				failures = append(failures, fmt.Errorf("example %s: %w", example.Path, err))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	if len(failures) > 0 {
+		sort.Slice(failures, func(i, j int) bool {
+			return failures[i].Error() < failures[j].Error()
+		})
+		return fmt.Errorf("%d of %d examples failed:\n%w", len(failures), len(examples), errors.Join(failures...))
+	}
+	return nil
+}
+
+type docsExample struct {
+	// Path of the module directory or query file, or the page and line of
+	// a query shown in a page
+	Path string
+	// Whether the example is a GraphQL query rather than a Go module
+	Query bool
+	// The query, if it's shown in a page rather than in a file
+	QueryText string
+	// The "dagger call" commands shown for a Go module
+	Calls []string
+}
+
+func discoverExamples(ctx context.Context, source *Directory, filter string) ([]docsExample, error) {
+	modules := map[string]*docsExample{}
+
+	moduleConfigs, err := source.Glob(ctx, "**/go/dagger.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range moduleConfigs {
+		dir := path.Dir(config)
+		modules[dir] = &docsExample{Path: dir}
+	}
+
+	var examples []docsExample
+	for _, pattern := range []string{"**/*.gql", "**/*.graphql"} {
+		queries, err := source.Glob(ctx, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, query := range queries {
+			examples = append(examples, docsExample{Path: query, Query: true})
+		}
+	}
+
+	pages, err := source.Glob(ctx, "**/*.mdx")
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		contents, err := source.File(page).Contents(ctx)
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, parseDocsPage(page, contents, modules)...)
+	}
+
+	for _, module := range modules {
+		examples = append(examples, *module)
+	}
+
+	filtered := examples[:0]
+	for _, example := range examples {
+		if strings.Contains(example.Path, filter) {
+			filtered = append(filtered, example)
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].Path < filtered[j].Path
+	})
+	return filtered, nil
+}
+
+var (
+	snippetRef     = regexp.MustCompile("^```\\w+ file=(\\S+)")
+	heredocQuery   = regexp.MustCompile(`^dagger query.*<<\s*'?(\w+)'?$`)
+	hereStrQuery   = regexp.MustCompile(`^dagger query.*<<<\s*'(.*)'$`)
+	unattendedCall = regexp.MustCompile(`\b(up|terminal)\b|\b(env|file|cmd):|tcp://`)
+)
+
+// parseDocsPage adds the "dagger call" commands that follow the Go modules
+// referenced by the page to them, and returns the "dagger query" commands of
+// the page as examples.
+func parseDocsPage(page, contents string, modules map[string]*docsExample) []docsExample {
+	var queries []docsExample
+
+	// the modules referenced since the last section or shell block, which
+	// the calls of the next shell block apply to
+	var referenced []*docsExample
+	var inShell, afterShell bool
+
+	lines := strings.Split(contents, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case inShell && line == "```":
+			inShell = false
+			afterShell = true
+		case inShell:
+			switch {
+			case strings.HasPrefix(line, "dagger call "):
+				for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+					i++
+					line = strings.TrimSuffix(line, "\\") + strings.TrimSpace(lines[i])
+				}
+				if unattendedCall.MatchString(line) {
+					continue
+				}
+				for _, module := range referenced {
+					module.Calls = append(module.Calls, line)
+				}
+			case heredocQuery.MatchString(line):
+				delim := heredocQuery.FindStringSubmatch(line)[1]
+				start := i
+				var query []string
+				for i++; i < len(lines) && strings.TrimSpace(lines[i]) != delim; i++ {
+					query = append(query, lines[i])
+				}
+				queries = append(queries, docsExample{
+					Path:      fmt.Sprintf("%s:%d", page, start+1),
+					Query:     true,
+					QueryText: strings.Join(query, "\n"),
+				})
+			case hereStrQuery.MatchString(line):
+				queries = append(queries, docsExample{
+					Path:      fmt.Sprintf("%s:%d", page, i+1),
+					Query:     true,
+					QueryText: hereStrQuery.FindStringSubmatch(line)[1],
+				})
+			}
+		case line == "```shell":
+			inShell = true
+		case strings.HasPrefix(line, "#"):
+			referenced = nil
+		case snippetRef.MatchString(line):
+			if afterShell {
+				referenced = nil
+				afterShell = false
+			}
+			file := path.Join(path.Dir(page), snippetRef.FindStringSubmatch(line)[1])
+			if module, ok := modules[path.Dir(file)]; ok {
+				referenced = append(referenced, module)
+			}
+		}
+	}
+	return queries
+}
+
+func (example docsExample) run(ctr *Container) *Container {
+	switch {
+	case example.QueryText != "":
+		return ctr.WithExec([]string{"dagger", "query"}, dagger.ContainerWithExecOpts{
+			Stdin: example.QueryText,
+		})
+	case example.Query:
+		return ctr.WithExec([]string{"dagger", "query", "--doc", example.Path})
+	}
+	ctr = ctr.WithWorkdir(example.Path)
+	if len(example.Calls) == 0 {
+		// loading the functions builds the module and registers its API
+		return ctr.WithExec([]string{"dagger", "functions"})
+	}
+	for _, call := range example.Calls {
+		ctr = ctr.WithExec([]string{"sh", "-c", call})
+	}
+	return ctr
+}
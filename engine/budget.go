@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	// BudgetMaxPullBytesEnv sets Budget.MaxPullBytes for sessions started by
+	// the client.
+	BudgetMaxPullBytesEnv = "DAGGER_BUDGET_MAX_PULL_BYTES"
+	// BudgetMaxExportBytesEnv sets Budget.MaxExportBytes for sessions started
+	// by the client.
+	BudgetMaxExportBytesEnv = "DAGGER_BUDGET_MAX_EXPORT_BYTES"
+	// BudgetMaxExecOutputBytesEnv sets Budget.MaxExecOutputBytes for sessions
+	// started by the client.
+	BudgetMaxExecOutputBytesEnv = "DAGGER_BUDGET_MAX_EXEC_OUTPUT_BYTES"
+	// BudgetMaxParallelismEnv sets Budget.MaxParallelism for sessions started
+	// by the client.
+	BudgetMaxParallelismEnv = "DAGGER_BUDGET_MAX_PARALLELISM"
+)
+
+// Budget limits the resources that a session may consume. Zero values mean
+// unlimited.
+type Budget struct {
+	// MaxPullBytes is the maximum number of compressed image bytes that may be
+	// pulled from registries.
+	MaxPullBytes int64 `json:"max_pull_bytes,omitempty"`
+
+	// MaxExportBytes is the maximum number of bytes that may be exported to the
+	// client's filesystem.
+	MaxExportBytes int64 `json:"max_export_bytes,omitempty"`
+
+	// MaxExecOutputBytes is the maximum number of bytes of stdout and stderr
+	// captured of each exec that doesn't set its own limit.
+	MaxExecOutputBytes int64 `json:"max_exec_output_bytes,omitempty"`
+
+	// MaxParallelism is the maximum number of execs and image pulls that may
+	// run at the same time.
+	MaxParallelism int64 `json:"max_parallelism,omitempty"`
+}
+
+// Within returns the budget with each of its limits lowered to the one of
+// the given budget, if that one is lower or the budget is unlimited.
+func (b Budget) Within(limit Budget) Budget {
+	lower := func(v, limit int64) int64 {
+		if limit > 0 && (v == 0 || v > limit) {
+			return limit
+		}
+		return v
+	}
+	return Budget{
+		MaxPullBytes:       lower(b.MaxPullBytes, limit.MaxPullBytes),
+		MaxExportBytes:     lower(b.MaxExportBytes, limit.MaxExportBytes),
+		MaxExecOutputBytes: lower(b.MaxExecOutputBytes, limit.MaxExecOutputBytes),
+		MaxParallelism:     lower(b.MaxParallelism, limit.MaxParallelism),
+	}
+}
+
+// BudgetFromEnv returns the budget configured in the environment.
+func BudgetFromEnv() (Budget, error) {
+	var budget Budget
+	for env, dest := range map[string]*int64{
+		BudgetMaxPullBytesEnv:       &budget.MaxPullBytes,
+		BudgetMaxExportBytesEnv:     &budget.MaxExportBytes,
+		BudgetMaxExecOutputBytesEnv: &budget.MaxExecOutputBytes,
+		BudgetMaxParallelismEnv:     &budget.MaxParallelism,
+	} {
+		v, ok := os.LookupEnv(env)
+		if !ok || v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return Budget{}, fmt.Errorf("invalid %s %q: must be a non-negative number", env, v)
+		}
+		*dest = n
+	}
+	return budget, nil
+}
@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetWithin(t *testing.T) {
+	declared := Budget{
+		MaxPullBytes:   1000,
+		MaxExportBytes: 10,
+		MaxParallelism: 8,
+	}
+	limit := Budget{
+		MaxPullBytes:       100,
+		MaxExportBytes:     100,
+		MaxExecOutputBytes: 100,
+	}
+	require.Equal(t, Budget{
+		// lowered to the limit
+		MaxPullBytes: 100,
+		// already within the limit
+		MaxExportBytes: 10,
+		// unlimited by the client
+		MaxExecOutputBytes: 100,
+		// unlimited by the engine
+		MaxParallelism: 8,
+	}, declared.Within(limit))
+
+	require.Equal(t, declared, declared.Within(Budget{}))
+}
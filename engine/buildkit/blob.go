@@ -1,16 +1,22 @@
 package buildkit
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"fmt"
 	"io/fs"
 
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/labels"
 	cacheconfig "github.com/moby/buildkit/cache/config"
 	"github.com/moby/buildkit/client/llb"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
 	bksolverpb "github.com/moby/buildkit/solver/pb"
 	"github.com/moby/buildkit/util/compression"
+	"github.com/moby/buildkit/util/leaseutil"
 	bkworker "github.com/moby/buildkit/worker"
+	"github.com/opencontainers/go-digest"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 
 	"github.com/dagger/dagger/engine/sources/blob"
@@ -43,7 +49,7 @@ func (c *Client) DefToBlob(
 	}
 	cachedRes, err := resultProxy.Result(ctx)
 	if err != nil {
-		return nil, desc, wrapError(ctx, err, c.ID())
+		return nil, desc, wrapError(ctx, err, c)
 	}
 	workerRef, ok := cachedRes.Sys().(*bkworker.WorkerRef)
 	if !ok {
@@ -90,7 +96,7 @@ func (c *Client) DefToBlob(
 		Evaluate:   true,
 	})
 	if err != nil {
-		return nil, desc, fmt.Errorf("failed to solve blobsource: %w", wrapError(ctx, err, c.ID()))
+		return nil, desc, fmt.Errorf("failed to solve blobsource: %w", wrapError(ctx, err, c))
 	}
 
 	return blobPB, desc, nil
@@ -111,3 +117,70 @@ func (c *Client) BytesToBlob(
 	}
 	return c.DefToBlob(ctx, def.ToPB(), compressionType)
 }
+
+// SymlinkToBlob creates a content addressed blob holding a single symlink named
+// linkName pointing to target, valid for the duration of the current session.
+// LLB has no file action for creating symlinks, so they're copied from this
+// blob instead.
+func (c *Client) SymlinkToBlob(
+	ctx context.Context,
+	linkName string,
+	target string,
+) (_ *bksolverpb.Definition, desc specs.Descriptor, _ error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     linkName,
+		Linkname: target,
+		Mode:     0o777,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return nil, desc, fmt.Errorf("failed to write symlink header: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, desc, fmt.Errorf("failed to close tar writer: %w", err)
+	}
+
+	dgst := digest.FromBytes(buf.Bytes())
+	desc = specs.Descriptor{
+		MediaType: specs.MediaTypeImageLayer,
+		Digest:    dgst,
+		Size:      int64(buf.Len()),
+		Annotations: map[string]string{
+			// uncompressed label is required to be set by buildkit's GetByBlob
+			// implementation
+			labels.LabelUncompressed: dgst.String(),
+		},
+	}
+
+	// hold the blob with a temporary lease until the blob source below has
+	// taken its own reference to it
+	ctx, done, err := leaseutil.WithLease(ctx, c.Worker.LeaseManager(), leaseutil.MakeTemporary)
+	if err != nil {
+		return nil, desc, fmt.Errorf("failed to create lease: %w", err)
+	}
+	defer done(context.WithoutCancel(ctx))
+
+	err = content.WriteBlob(ctx, c.Worker.ContentStore(), dgst.String(), bytes.NewReader(buf.Bytes()), desc)
+	if err != nil {
+		return nil, desc, fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	blobDef, err := blob.LLB(desc).Marshal(ctx)
+	if err != nil {
+		return nil, desc, fmt.Errorf("failed to marshal blob source: %w", err)
+	}
+	blobPB := blobDef.ToPB()
+
+	_, err = c.Solve(ctx, bkgw.SolveRequest{
+		Definition: blobPB,
+		Evaluate:   true,
+	})
+	if err != nil {
+		return nil, desc, fmt.Errorf("failed to solve blobsource: %w", wrapError(ctx, err, c))
+	}
+
+	return blobPB, desc, nil
+}
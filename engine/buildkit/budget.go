@@ -0,0 +1,283 @@
+package buildkit
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/platforms"
+	bksession "github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/snapshot"
+	bksolverpb "github.com/moby/buildkit/solver/pb"
+	srctypes "github.com/moby/buildkit/source/types"
+	"github.com/moby/buildkit/util/contentutil"
+	"github.com/moby/buildkit/util/resolver"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/dagger/dagger/engine"
+)
+
+const (
+	BudgetResourcePull   = "pull"
+	BudgetResourceExport = "export"
+)
+
+// BudgetTracker enforces an engine.Budget across all the clients of a
+// session.
+type BudgetTracker struct {
+	budget engine.Budget
+
+	// limits the execs and image pulls running at once, if set
+	parallelism *semaphore.Weighted
+
+	mu       sync.Mutex
+	pulled   int64
+	exported int64
+	// the images already charged as pulled, which are only charged once
+	pulledImages map[string]struct{}
+}
+
+func NewBudgetTracker(budget engine.Budget) *BudgetTracker {
+	t := &BudgetTracker{budget: budget}
+	if budget.MaxParallelism > 0 {
+		t.parallelism = semaphore.NewWeighted(budget.MaxParallelism)
+	}
+	return t
+}
+
+// LimitsPulls reports whether the budget limits registry pulls, so callers
+// can skip computing pull sizes otherwise.
+func (t *BudgetTracker) LimitsPulls() bool {
+	return t != nil && t.budget.MaxPullBytes > 0
+}
+
+// MaxExecOutputBytes returns the default limit of the stdout and stderr
+// captured of each exec, or 0 if unlimited.
+func (t *BudgetTracker) MaxExecOutputBytes() int64 {
+	if t == nil {
+		return 0
+	}
+	return t.budget.MaxExecOutputBytes
+}
+
+// LimitsParallelism reports whether the budget limits the number of execs
+// and image pulls running at once.
+func (t *BudgetTracker) LimitsParallelism() bool {
+	return t != nil && t.parallelism != nil
+}
+
+// AcquireParallelism waits until fewer execs and image pulls than the budget
+// allows are running, returning a function to call once the caller is done
+// running.
+func (t *BudgetTracker) AcquireParallelism(ctx context.Context) (func(), error) {
+	if !t.LimitsParallelism() {
+		return func() {}, nil
+	}
+	if err := t.parallelism.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	return func() { t.parallelism.Release(1) }, nil
+}
+
+// LimitsExports reports whether the budget limits exports.
+func (t *BudgetTracker) LimitsExports() bool {
+	return t != nil && t.budget.MaxExportBytes > 0
+}
+
+// AddPull records size bytes pulled for the image subject, returning a
+// *BudgetViolationError without recording them if that would exceed the
+// budget. An image is only recorded the first time it's pulled.
+func (t *BudgetTracker) AddPull(subject string, size int64) error {
+	if !t.LimitsPulls() || t.PullRecorded(subject) {
+		return nil
+	}
+	if err := t.add(BudgetResourcePull, &t.pulled, t.budget.MaxPullBytes, subject, size); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pulledImages == nil {
+		t.pulledImages = map[string]struct{}{}
+	}
+	t.pulledImages[subject] = struct{}{}
+	return nil
+}
+
+// PullRecorded reports whether the pull of the image subject was already
+// recorded, so callers can skip computing its size.
+func (t *BudgetTracker) PullRecorded(subject string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.pulledImages[subject]
+	return ok
+}
+
+// AddExport records size bytes exported for subject, returning a
+// *BudgetViolationError without recording them if that would exceed the
+// budget.
+func (t *BudgetTracker) AddExport(subject string, size int64) error {
+	if !t.LimitsExports() {
+		return nil
+	}
+	return t.add(BudgetResourceExport, &t.exported, t.budget.MaxExportBytes, subject, size)
+}
+
+func (t *BudgetTracker) add(resource string, used *int64, limit int64, subject string, size int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if *used+size > limit {
+		return &BudgetViolationError{
+			Resource:  resource,
+			Subject:   subject,
+			Limit:     limit,
+			Used:      *used,
+			Requested: size,
+		}
+	}
+	*used += size
+	return nil
+}
+
+// BudgetViolationError is returned when an operation would exceed the
+// session's budget.
+type BudgetViolationError struct {
+	// Resource is the budgeted resource, e.g. "pull" or "export".
+	Resource string
+	// Subject is what was being pulled or exported.
+	Subject string
+	// Limit is the budget for the resource, in bytes.
+	Limit int64
+	// Used is the number of bytes consumed before the operation.
+	Used int64
+	// Requested is the number of bytes the operation needed.
+	Requested int64
+}
+
+func (e *BudgetViolationError) Error() string {
+	return fmt.Sprintf("%s of %s exceeds budget: %d bytes requested, %d of %d bytes used",
+		e.Resource, e.Subject, e.Requested, e.Used, e.Limit)
+}
+
+func (e *BudgetViolationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"_type":     "BUDGET_VIOLATION",
+		"resource":  e.Resource,
+		"subject":   e.Subject,
+		"limit":     e.Limit,
+		"used":      e.Used,
+		"requested": e.Requested,
+	}
+}
+
+// ImagePullSize returns the number of compressed bytes that pulling the image
+// ref for the given platform would fetch, i.e. the size of its layers that
+// aren't already in the local content store.
+func (c *Client) ImagePullSize(ctx context.Context, ref string, platform specs.Platform) (int64, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	r := resolver.DefaultPool.GetResolver(c.Worker.RegistryHosts, ref, "pull", c.SessionManager, bksession.NewGroup(c.ID()))
+	_, desc, err := r.Resolve(ctx, ref)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	fetcher, err := r.Fetcher(ctx, ref)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create fetcher for %s: %w", ref, err)
+	}
+	manifest, err := images.Manifest(ctx, contentutil.FromFetcher(fetcher), desc, platforms.OnlyStrict(platform))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get manifest of %s: %w", ref, err)
+	}
+
+	store := c.Worker.ContentStore()
+	var size int64
+	for _, layer := range manifest.Layers {
+		if _, err := store.Info(ctx, layer.Digest); err == nil {
+			continue
+		}
+		size += layer.Size
+	}
+	return size, nil
+}
+
+// chargeImagePulls records the pulls of the images in the definition against
+// the budget, so that every pull made on behalf of the session is charged,
+// whether it comes from Container.from, a Dockerfile or any other frontend.
+func (c *Client) chargeImagePulls(ctx context.Context, def *bksolverpb.Definition) error {
+	if !c.Budget.LimitsPulls() || def == nil || len(def.Def) == 0 {
+		return nil
+	}
+	dag, err := DefToDAG(def)
+	if err != nil {
+		return err
+	}
+	return dag.Walk(func(dag *OpDAG) error {
+		img, ok := dag.AsImage()
+		if !ok {
+			return nil
+		}
+		ref := strings.TrimPrefix(img.Identifier, srctypes.DockerImageScheme+"://")
+		if c.Budget.PullRecorded(ref) {
+			return nil
+		}
+		platform := platforms.Normalize(platforms.DefaultSpec())
+		if p := dag.GetPlatform(); p != nil {
+			platform = specs.Platform{
+				OS:           p.OS,
+				Architecture: p.Architecture,
+				Variant:      p.Variant,
+			}
+		}
+		size, err := c.ImagePullSize(ctx, ref, platform)
+		if err != nil {
+			return err
+		}
+		return c.Budget.AddPull(ref, size)
+	})
+}
+
+// exportSize returns the total size of the regular files in the ref.
+func exportSize(ctx context.Context, r *ref) (int64, error) {
+	mnt, err := r.getMountable(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if mnt == nil {
+		return 0, nil
+	}
+	lm := snapshot.LocalMounter(mnt)
+	root, err := lm.Mount()
+	if err != nil {
+		return 0, fmt.Errorf("failed to mount: %w", err)
+	}
+	defer lm.Unmount()
+
+	var size int64
+	err = filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,74 @@
+package buildkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/engine"
+)
+
+func TestBudgetTracker(t *testing.T) {
+	tracker := NewBudgetTracker(engine.Budget{MaxPullBytes: 100})
+
+	require.True(t, tracker.LimitsPulls())
+	require.False(t, tracker.LimitsExports())
+
+	require.NoError(t, tracker.AddPull("a", 60))
+	require.NoError(t, tracker.AddExport("b", 1000))
+
+	err := tracker.AddPull("c", 50)
+	var violation *BudgetViolationError
+	require.True(t, errors.As(err, &violation))
+	require.Equal(t, BudgetResourcePull, violation.Resource)
+	require.Equal(t, "c", violation.Subject)
+	require.EqualValues(t, 100, violation.Limit)
+	require.EqualValues(t, 60, violation.Used)
+	require.EqualValues(t, 50, violation.Requested)
+	require.Equal(t, "BUDGET_VIOLATION", violation.Extensions()["_type"])
+
+	// rejected pulls aren't recorded
+	require.NoError(t, tracker.AddPull("d", 40))
+	require.False(t, tracker.PullRecorded("c"))
+
+	// and images are only charged the first time they're pulled
+	require.True(t, tracker.PullRecorded("a"))
+	require.NoError(t, tracker.AddPull("a", 60))
+}
+
+func TestBudgetTrackerNil(t *testing.T) {
+	var tracker *BudgetTracker
+	require.False(t, tracker.LimitsPulls())
+	require.NoError(t, tracker.AddPull("a", 1<<40))
+	require.NoError(t, tracker.AddExport("b", 1<<40))
+	require.False(t, tracker.LimitsParallelism())
+	release, err := tracker.AcquireParallelism(context.Background())
+	require.NoError(t, err)
+	release()
+}
+
+func TestBudgetTrackerParallelism(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewBudgetTracker(engine.Budget{MaxParallelism: 2})
+	require.True(t, tracker.LimitsParallelism())
+
+	release1, err := tracker.AcquireParallelism(ctx)
+	require.NoError(t, err)
+	release2, err := tracker.AcquireParallelism(ctx)
+	require.NoError(t, err)
+
+	// a third has to wait for one of the others to be done
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = tracker.AcquireParallelism(waitCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release1()
+	release3, err := tracker.AcquireParallelism(ctx)
+	require.NoError(t, err)
+	release2()
+	release3()
+}
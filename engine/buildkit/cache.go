@@ -0,0 +1,48 @@
+package buildkit
+
+import (
+	"context"
+
+	bkclient "github.com/moby/buildkit/client"
+	"golang.org/x/sync/errgroup"
+)
+
+// PruneCache removes the cache records matching info that are not in use,
+// returning the removed records.
+func (c *Client) PruneCache(ctx context.Context, info bkclient.PruneInfo) ([]bkclient.UsageInfo, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	ch := make(chan bkclient.UsageInfo, 32)
+	var pruned []bkclient.UsageInfo
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		defer close(ch)
+		return c.Worker.Prune(egCtx, ch, info)
+	})
+	eg.Go(func() error {
+		for record := range ch {
+			pruned = append(pruned, record)
+		}
+		return nil
+	})
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return pruned, nil
+}
+
+// CacheUsage returns the cache records of the engine, with their disk usage.
+func (c *Client) CacheUsage(ctx context.Context) ([]*bkclient.UsageInfo, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return c.Worker.DiskUsage(ctx, bkclient.DiskUsageInfo{})
+}
@@ -0,0 +1,93 @@
+package buildkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/moby/buildkit/identity"
+	"github.com/moby/buildkit/solver/pb"
+)
+
+// ErrCacheMiss is the error of execs solved by Client.IsCached that aren't
+// cached.
+var ErrCacheMiss = errors.New("exec is not cached")
+
+// IsCached reports whether all the execs of the definition are already
+// cached, without running any of them.
+//
+// The other operations of the definition, like pulling images or loading
+// host directories, are still performed, since the cache keys of the execs
+// depend on their results.
+func (c *Client) IsCached(ctx context.Context, def *pb.Definition) (bool, error) {
+	if def == nil || len(def.Def) == 0 {
+		return true, nil
+	}
+	dag, err := DefToDAG(def)
+	if err != nil {
+		return false, err
+	}
+
+	probeID := identity.NewID()
+	err = dag.Walk(func(dag *OpDAG) error {
+		execOp, ok := dag.AsExec()
+		if !ok {
+			return nil
+		}
+		execMD, ok, err := ExecutionMetadataFromDescription(execOp.Metadata.Description)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			execMD = &ExecutionMetadata{}
+		}
+		execMD.CacheProbe = true
+		bs, err := json.Marshal(execMD)
+		if err != nil {
+			return err
+		}
+		desc := maps.Clone(execOp.Metadata.Description)
+		if desc == nil {
+			desc = map[string]string{}
+		}
+		desc[executionMetadataKey] = string(bs)
+		execOp.Metadata.Description = desc
+
+		// The solver shares vertexes with the same digest between all the
+		// clients of the engine, so the probe must not be loaded as the same
+		// vertex as the real exec, or it could fail it for everyone. The
+		// proxy env isn't part of the cache key, so it's used to change the
+		// digest of the vertex without changing what it's cached as; it
+		// doesn't matter otherwise since the probe never runs.
+		meta := *execOp.Meta
+		proxyEnv := pb.ProxyEnv{}
+		if meta.ProxyEnv != nil {
+			proxyEnv = *meta.ProxyEnv
+		}
+		proxyEnv.AllProxy = "dagger-cache-probe-" + probeID
+		meta.ProxyEnv = &proxyEnv
+		execOp.Meta = &meta
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	probeDef, err := dag.Marshal()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = c.Solve(ctx, bkgw.SolveRequest{
+		Definition: probeDef,
+		Evaluate:   true,
+	})
+	if errors.Is(err, ErrCacheMiss) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -7,12 +7,14 @@ import (
 	"io"
 	"net"
 	"sync"
+	"time"
 
 	bkcache "github.com/moby/buildkit/cache"
 	bkcacheconfig "github.com/moby/buildkit/cache/config"
 	"github.com/moby/buildkit/cache/remotecache"
 	"github.com/moby/buildkit/client/llb"
 	"github.com/moby/buildkit/client/llb/sourceresolver"
+	bkexecutor "github.com/moby/buildkit/executor"
 	bkfrontend "github.com/moby/buildkit/frontend"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
 	bkcontainer "github.com/moby/buildkit/frontend/gateway/container"
@@ -21,12 +23,14 @@ import (
 	bksecrets "github.com/moby/buildkit/session/secrets"
 	bksolver "github.com/moby/buildkit/solver"
 	"github.com/moby/buildkit/solver/llbsolver"
+	llberror "github.com/moby/buildkit/solver/llbsolver/errdefs"
 	bksolverpb "github.com/moby/buildkit/solver/pb"
 	solverresult "github.com/moby/buildkit/solver/result"
 	"github.com/moby/buildkit/util/bklog"
 	"github.com/moby/buildkit/util/entitlements"
 	bkworker "github.com/moby/buildkit/worker"
 	"github.com/opencontainers/go-digest"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/metadata"
@@ -68,6 +72,9 @@ type Opts struct {
 	UpstreamCacheImporters map[string]remotecache.ResolveCacheImporterFunc
 	UpstreamCacheImports   []bkgw.CacheOptionsEntry
 	Frontends              map[string]bkfrontend.Frontend
+	Budget                 *BudgetTracker
+	SolveDuration          prometheus.Observer
+	Interactive            bool
 
 	Refs         map[Reference]struct{}
 	RefsMu       *sync.Mutex
@@ -86,6 +93,16 @@ type Client struct {
 	closeCtx context.Context
 	cancel   context.CancelFunc
 	closeMu  sync.RWMutex
+
+	// status of the vertexes solved by the client, see TrackVertexes
+	vertexes          map[digest.Digest]*trackedVertex
+	completedVertexes []digest.Digest
+	vertexesMu        sync.Mutex
+
+	// exec errors being debugged, closed and removed once their terminal
+	// exits
+	execDebugs   map[*llberror.ExecError]chan struct{}
+	execDebugsMu sync.Mutex
 }
 
 func NewClient(ctx context.Context, opts *Opts) (*Client, error) {
@@ -95,6 +112,7 @@ func NewClient(ctx context.Context, opts *Opts) (*Client, error) {
 		Opts:     opts,
 		closeCtx: ctx,
 		cancel:   cancel,
+		vertexes: map[digest.Digest]*trackedVertex{},
 	}
 
 	return client, nil
@@ -131,6 +149,13 @@ func (c *Client) Solve(ctx context.Context, req bkgw.SolveRequest) (_ *Result, r
 	defer cancel()
 	ctx = withOutgoingContext(ctx)
 
+	if c.SolveDuration != nil {
+		start := time.Now()
+		defer func() {
+			c.SolveDuration.Observe(time.Since(start).Seconds())
+		}()
+	}
+
 	// include upstream cache imports, if any
 	req.CacheImports = c.UpstreamCacheImports
 
@@ -143,7 +168,7 @@ func (c *Client) Solve(ctx context.Context, req bkgw.SolveRequest) (_ *Result, r
 	if err != nil {
 		// writing log w/ %+v so that we can see stack traces embedded in err by buildkit's usage of pkg/errors
 		bklog.G(ctx).Errorf("solve error: %+v", err)
-		return nil, wrapError(ctx, err, c.ID())
+		return nil, wrapError(ctx, err, c)
 	}
 
 	res, err := solverresult.ConvertResult(llbRes, func(rp bksolver.ResultProxy) (*ref, error) {
@@ -191,9 +216,10 @@ func (c *Client) ResolveSourceMetadata(ctx context.Context, op *bksolverpb.Sourc
 }
 
 type NewContainerRequest struct {
-	Mounts   []bkgw.Mount
-	Platform *bksolverpb.Platform
-	Hostname string
+	Mounts     []bkgw.Mount
+	Platform   *bksolverpb.Platform
+	Hostname   string
+	ExtraHosts []*bksolverpb.HostIP
 	ExecutionMetadata
 }
 
@@ -221,6 +247,13 @@ func (c *Client) NewContainer(ctx context.Context, req NewContainerRequest) (*Co
 		Hostname:    req.Hostname,
 		Mounts:      make([]bkcontainer.Mount, len(req.Mounts)),
 	}
+	for _, h := range req.ExtraHosts {
+		ip := net.ParseIP(h.IP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP %q for extra host %q", h.IP, h.Host)
+		}
+		ctrReq.ExtraHosts = append(ctrReq.ExtraHosts, bkexecutor.HostIP{Host: h.Host, IP: ip})
+	}
 
 	// get the input mounts in parallel in case they need to be evaluated, which can be expensive
 	eg, egctx := errgroup.WithContext(ctx)
@@ -264,11 +297,21 @@ func (c *Client) NewContainer(ctx context.Context, req NewContainerRequest) (*Co
 		return nil, fmt.Errorf("wait: %w", err)
 	}
 
+	return c.newContainer(ctx, ctrReq, req.ExecutionMetadata)
+}
+
+// newContainer creates a container from the given request, tracking it so
+// that it's released when the client closes.
+func (c *Client) newContainer(
+	ctx context.Context,
+	ctrReq bkcontainer.NewContainerRequest,
+	execMD ExecutionMetadata,
+) (*Container, error) {
 	// using context.Background so it continues running until exit or when c.Close() is called
 	ctr, err := bkcontainer.NewContainer(
 		context.WithoutCancel(ctx),
 		c.Worker.CacheManager(),
-		c.Worker.withExecMD(req.ExecutionMetadata), // also implements Executor
+		c.Worker.withExecMD(execMD), // also implements Executor
 		c.SessionManager,
 		bksession.NewGroup(c.ID()),
 		ctrReq,
@@ -289,7 +332,7 @@ func (c *Client) NewContainer(ctx context.Context, req NewContainerRequest) (*Co
 
 	return &Container{
 		Container: ctr,
-		id:        containerID,
+		id:        ctrReq.ContainerID,
 	}, nil
 }
 
@@ -736,6 +779,10 @@ func newFilterGateway(client *Client, req bkgw.SolveRequest) *filteringGateway {
 func (gw *filteringGateway) Solve(ctx context.Context, req bkfrontend.SolveRequest, sid string) (*bkfrontend.Result, error) {
 	switch {
 	case req.Definition != nil && req.Definition.Def != nil:
+		if err := gw.client.chargeImagePulls(ctx, req.Definition); err != nil {
+			return nil, err
+		}
+
 		if gw.secretTranslator != nil {
 			dag, err := DefToDAG(req.Definition)
 			if err != nil {
@@ -0,0 +1,162 @@
+package buildkit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/opencontainers/go-digest"
+)
+
+// ExecOutput is a chunk of the output of a running exec.
+type ExecOutput struct {
+	// Fd is the file descriptor the chunk was written to: 1 for stdout and 2
+	// for stderr.
+	Fd   int
+	Data []byte
+	// Lost is the number of bytes written to Fd before Data that the
+	// subscriber didn't receive because it fell behind.
+	Lost int
+}
+
+// execOutputs lets clients follow the output of execs as it's written, by
+// the digest of their vertex.
+type execOutputs struct {
+	subs map[digest.Digest]map[*ExecOutputSubscription]struct{}
+	mu   sync.Mutex
+}
+
+// ExecOutputSubscription receives the output of an exec as it's written.
+type ExecOutputSubscription struct {
+	ch chan ExecOutput
+
+	// bytes dropped by fd since the last chunk received
+	lost   map[int]int
+	lostMu sync.Mutex
+
+	close func()
+}
+
+func newExecOutputs() *execOutputs {
+	return &execOutputs{
+		subs: map[digest.Digest]map[*ExecOutputSubscription]struct{}{},
+	}
+}
+
+func (outputs *execOutputs) subscribe(dgst digest.Digest) *ExecOutputSubscription {
+	sub := &ExecOutputSubscription{
+		ch:   make(chan ExecOutput, 256),
+		lost: map[int]int{},
+	}
+	outputs.mu.Lock()
+	if outputs.subs[dgst] == nil {
+		outputs.subs[dgst] = map[*ExecOutputSubscription]struct{}{}
+	}
+	outputs.subs[dgst][sub] = struct{}{}
+	outputs.mu.Unlock()
+
+	var once sync.Once
+	sub.close = func() {
+		once.Do(func() {
+			outputs.mu.Lock()
+			delete(outputs.subs[dgst], sub)
+			if len(outputs.subs[dgst]) == 0 {
+				delete(outputs.subs, dgst)
+			}
+			outputs.mu.Unlock()
+		})
+	}
+	return sub
+}
+
+// Output returns the channel receiving the chunks of output.
+func (sub *ExecOutputSubscription) Output() <-chan ExecOutput {
+	return sub.ch
+}
+
+// Lost returns the number of bytes written to fd since the last chunk
+// received that were dropped because the subscriber fell behind.
+func (sub *ExecOutputSubscription) Lost(fd int) int {
+	sub.lostMu.Lock()
+	defer sub.lostMu.Unlock()
+	return sub.lost[fd]
+}
+
+// Close stops receiving output.
+func (sub *ExecOutputSubscription) Close() {
+	sub.close()
+}
+
+// send delivers a chunk without blocking, so that a slow subscriber can't
+// hold up the exec, counting it as lost if the subscriber's buffer is full.
+func (sub *ExecOutputSubscription) send(out ExecOutput) {
+	sub.lostMu.Lock()
+	defer sub.lostMu.Unlock()
+	out.Lost = sub.lost[out.Fd]
+	select {
+	case sub.ch <- out:
+		sub.lost[out.Fd] = 0
+	default:
+		sub.lost[out.Fd] += len(out.Data)
+	}
+}
+
+// writer returns a writer publishing everything written to it to the
+// subscribers of the exec.
+func (outputs *execOutputs) writer(dgst digest.Digest, fd int) io.Writer {
+	return execOutputWriter{outputs: outputs, dgst: dgst, fd: fd}
+}
+
+type execOutputWriter struct {
+	outputs *execOutputs
+	dgst    digest.Digest
+	fd      int
+}
+
+// Write never fails nor blocks, so that subscribers can't fail or slow down
+// the exec.
+func (w execOutputWriter) Write(p []byte) (int, error) {
+	w.outputs.mu.Lock()
+	subs := make([]*ExecOutputSubscription, 0, len(w.outputs.subs[w.dgst]))
+	for sub := range w.outputs.subs[w.dgst] {
+		subs = append(subs, sub)
+	}
+	w.outputs.mu.Unlock()
+	if len(subs) == 0 {
+		return len(p), nil
+	}
+
+	out := ExecOutput{Fd: w.fd, Data: bytes.Clone(p)}
+	for _, sub := range subs {
+		sub.send(out)
+	}
+	return len(p), nil
+}
+
+// FollowExecOutput subscribes to the output of the exec with the given
+// vertex digest as it's written, until the subscription is closed.
+//
+// Only execs that actually run have output to follow; nothing is sent for
+// cached ones.
+func (c *Client) FollowExecOutput(dgst digest.Digest) *ExecOutputSubscription {
+	return c.Worker.execOutputs.subscribe(dgst)
+}
+
+// ExecDigest returns the digest of the exec op whose output is the result of
+// the given definition.
+func ExecDigest(def *pb.Definition) (digest.Digest, error) {
+	dag, err := DefToDAG(def)
+	if err != nil {
+		return "", err
+	}
+	if dag.Op.Op == nil && len(dag.Inputs) == 1 {
+		// skip the terminal op that only selects the result
+		dag = dag.Inputs[0]
+	}
+	if _, ok := dag.AsExec(); !ok {
+		return "", fmt.Errorf("definition is not the result of an exec")
+	}
+	return *dag.OpDigest, nil
+}
@@ -0,0 +1,48 @@
+package buildkit
+
+import (
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecOutputs(t *testing.T) {
+	outputs := newExecOutputs()
+	dgst := digest.FromString("exec")
+	sub := outputs.subscribe(dgst)
+
+	stdout := outputs.writer(dgst, 1)
+	stderr := outputs.writer(dgst, 2)
+
+	// writes never block, even once the subscriber falls behind
+	for i := 0; i < cap(sub.ch); i++ {
+		_, err := stdout.Write([]byte("a"))
+		require.NoError(t, err)
+	}
+	n, err := stdout.Write([]byte("lost"))
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, 4, sub.Lost(1))
+	require.Equal(t, 0, sub.Lost(2))
+
+	for i := 0; i < cap(sub.ch); i++ {
+		out := <-sub.Output()
+		require.Equal(t, ExecOutput{Fd: 1, Data: []byte("a")}, out)
+	}
+
+	// the next chunk of the same fd reports what was lost before it
+	_, err = stderr.Write([]byte("err"))
+	require.NoError(t, err)
+	_, err = stdout.Write([]byte("out"))
+	require.NoError(t, err)
+	require.Equal(t, ExecOutput{Fd: 2, Data: []byte("err")}, <-sub.Output())
+	require.Equal(t, ExecOutput{Fd: 1, Data: []byte("out"), Lost: 4}, <-sub.Output())
+	require.Equal(t, 0, sub.Lost(1))
+
+	sub.Close()
+	require.Empty(t, outputs.subs)
+	_, err = stdout.Write([]byte("closed"))
+	require.NoError(t, err)
+	require.Empty(t, sub.Output())
+}
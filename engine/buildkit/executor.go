@@ -51,6 +51,10 @@ type ExecutionMetadata struct {
 
 	CachePerSession bool
 
+	// if set, a non-zero exit code of the command doesn't fail the exec; the
+	// exit code is recorded in the meta mount alongside stdout and stderr
+	AllowFailure bool
+
 	// hostname -> list of aliases
 	HostAliases map[string][]string
 
@@ -64,6 +68,29 @@ type ExecutionMetadata struct {
 
 	EnabledGPUs []string
 
+	// paths of host devices to expose, which must be allowed by the engine
+	Devices []string
+
+	// resource limits of the command, enforced by its cgroup if non-zero
+	CPUs        float64
+	MemoryBytes int64
+
+	// if set, replace the nameservers of the engine in resolv.conf
+	DNSNameservers []string
+	// prepended to the search domains of the engine in resolv.conf
+	DNSSearchDomains []string
+
+	// if set, the root filesystem is mounted read-only
+	ReadOnlyRootfs bool
+
+	// if non-zero, caps the stdout and stderr captured of the command, along
+	// with the engine's own limit
+	MaxOutputBytes int64
+
+	// if set, the exec fails with ErrCacheMiss instead of running; see
+	// Client.IsCached
+	CacheProbe bool
+
 	SpanContext propagation.MapCarrier
 }
 
@@ -115,6 +142,10 @@ func (w *Worker) Run(
 		id = randid.NewID()
 	}
 
+	if w.execMD != nil && w.execMD.CacheProbe {
+		return nil, ErrCacheMiss
+	}
+
 	if err := w.validateEntitlements(procInfo.Meta); err != nil {
 		return nil, err
 	}
@@ -133,6 +164,8 @@ func (w *Worker) Run(
 		w.setupSecretScrubbing,
 		w.setProxyEnvs,
 		w.enableGPU,
+		w.addDevices,
+		w.setResourceLimits,
 		w.createCWD,
 		w.setupNestedClient,
 		w.installCACerts,
@@ -217,6 +250,13 @@ func (w *Worker) run(
 		return err
 	}
 	err = exitError(ctx, state.exitCodePath, w.callWithIO(ctx, state.procInfo, startedCallback, killer, runcCall))
+	if err != nil && w.execMD != nil && w.execMD.AllowFailure && ctx.Err() == nil {
+		var exitErr *gatewayapi.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode != gatewayapi.UnknownExitStatus {
+			// the command ran to completion, keep its outputs
+			err = nil
+		}
+	}
 	if err != nil {
 		w.runc.Delete(context.TODO(), state.id, &runc.DeleteOpts{})
 		return err
@@ -13,6 +13,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -54,6 +55,12 @@ const (
 	DaggerRedirectStdoutEnv  = "_DAGGER_REDIRECT_STDOUT"
 	DaggerRedirectStderrEnv  = "_DAGGER_REDIRECT_STDERR"
 	DaggerHostnameAliasesEnv = "_DAGGER_HOSTNAME_ALIASES"
+	DaggerCacheBusterEnv     = "_DAGGER_CACHE_BUSTER"
+	DaggerAllowFailureEnv    = "_DAGGER_ALLOW_FAILURE"
+	DaggerReadOnlyRootfsEnv  = "_DAGGER_READ_ONLY_ROOTFS"
+	DaggerMaxOutputBytesEnv  = "_DAGGER_MAX_OUTPUT_BYTES"
+	DaggerDNSEnv             = "_DAGGER_DNS"
+	DaggerDevicesEnv         = "_DAGGER_DEVICES"
 
 	DaggerSessionPortEnv  = "DAGGER_SESSION_PORT"
 	DaggerSessionTokenEnv = "DAGGER_SESSION_TOKEN"
@@ -82,6 +89,12 @@ var removeEnvs = map[string]struct{}{
 	DaggerRedirectStdoutEnv:  {},
 	DaggerRedirectStderrEnv:  {},
 	DaggerHostnameAliasesEnv: {},
+	DaggerCacheBusterEnv:     {},
+	DaggerAllowFailureEnv:    {},
+	DaggerReadOnlyRootfsEnv:  {},
+	DaggerMaxOutputBytesEnv:  {},
+	DaggerDNSEnv:             {},
+	DaggerDevicesEnv:         {},
 }
 
 type execState struct {
@@ -195,10 +208,19 @@ func (w *Worker) setupNetwork(ctx context.Context, state *execState) error {
 		return fmt.Errorf("chmod resolv.conf: %w", err)
 	}
 
+	for _, ns := range w.execMD.DNSNameservers {
+		if _, err := fmt.Fprintln(ctrResolvFile, "nameserver", ns); err != nil {
+			return fmt.Errorf("write resolv.conf: %w", err)
+		}
+	}
+
 	scanner := bufio.NewScanner(baseResolvFile)
 	var replaced bool
 	for scanner.Scan() {
 		line := scanner.Text()
+		if len(w.execMD.DNSNameservers) > 0 && strings.HasPrefix(line, "nameserver") {
+			continue
+		}
 		if !strings.HasPrefix(line, "search") {
 			if _, err := fmt.Fprintln(ctrResolvFile, line); err != nil {
 				return fmt.Errorf("write resolv.conf: %w", err)
@@ -206,7 +228,7 @@ func (w *Worker) setupNetwork(ctx context.Context, state *execState) error {
 			continue
 		}
 
-		domains := strings.Fields(line)[1:]
+		domains := append(slices.Clone(w.execMD.DNSSearchDomains), strings.Fields(line)[1:]...)
 		domains = append(domains, extraSearchDomain)
 		if _, err := fmt.Fprintln(ctrResolvFile, "search", strings.Join(domains, " ")); err != nil {
 			return fmt.Errorf("write resolv.conf: %w", err)
@@ -217,7 +239,8 @@ func (w *Worker) setupNetwork(ctx context.Context, state *execState) error {
 		return fmt.Errorf("read resolv.conf: %w", err)
 	}
 	if !replaced {
-		if _, err := fmt.Fprintln(ctrResolvFile, "search", extraSearchDomain); err != nil {
+		domains := append(slices.Clone(w.execMD.DNSSearchDomains), extraSearchDomain)
+		if _, err := fmt.Fprintln(ctrResolvFile, "search", strings.Join(domains, " ")); err != nil {
 			return fmt.Errorf("write resolv.conf: %w", err)
 		}
 	}
@@ -389,6 +412,11 @@ func (w *Worker) setupRootfs(ctx context.Context, state *execState) error {
 		return os.RemoveAll(state.rootfsPath)
 	})
 	state.spec.Root.Path = state.rootfsPath
+	if w.execMD != nil && w.execMD.ReadOnlyRootfs {
+		// runc remounts the root read-only once everything is mounted beneath
+		// it, so mounts stay writable unless they're read-only themselves
+		state.spec.Root.Readonly = true
+	}
 
 	rootMountable, err := state.rootMount.Src.Mount(ctx, false)
 	if err != nil {
@@ -546,9 +574,11 @@ func (w *Worker) setupStdio(_ context.Context, state *execState) error {
 		return fmt.Errorf("open stdin file: %w", err)
 	}
 
+	// everything but the redirect files is captured or streamed to the client,
+	// so is capped at the exec's output limit
 	var stdoutWriters []io.Writer
 	if state.procInfo.Stdout != nil {
-		stdoutWriters = append(stdoutWriters, state.procInfo.Stdout)
+		stdoutWriters = append(stdoutWriters, w.limitOutput(state, "stdout progress", state.procInfo.Stdout))
 	}
 	stdoutPath := filepath.Join(state.metaMount.Source, MetaMountStdoutPath)
 	stdoutFile, err := os.OpenFile(stdoutPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
@@ -556,11 +586,14 @@ func (w *Worker) setupStdio(_ context.Context, state *execState) error {
 		return fmt.Errorf("open stdout file: %w", err)
 	}
 	state.cleanups.Add("close container stdout file", stdoutFile.Close)
-	stdoutWriters = append(stdoutWriters, stdoutFile)
+	stdoutWriters = append(stdoutWriters, w.limitOutput(state, "stdout", stdoutFile))
+	if w.execDigest != "" {
+		stdoutWriters = append(stdoutWriters, w.limitOutput(state, "followed stdout", w.execOutputs.writer(w.execDigest, 1)))
+	}
 
 	var stderrWriters []io.Writer
 	if state.procInfo.Stderr != nil {
-		stderrWriters = append(stderrWriters, state.procInfo.Stderr)
+		stderrWriters = append(stderrWriters, w.limitOutput(state, "stderr progress", state.procInfo.Stderr))
 	}
 	stderrPath := filepath.Join(state.metaMount.Source, MetaMountStderrPath)
 	stderrFile, err := os.OpenFile(stderrPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
@@ -568,7 +601,10 @@ func (w *Worker) setupStdio(_ context.Context, state *execState) error {
 		return fmt.Errorf("open stderr file: %w", err)
 	}
 	state.cleanups.Add("close container stderr file", stderrFile.Close)
-	stderrWriters = append(stderrWriters, stderrFile)
+	stderrWriters = append(stderrWriters, w.limitOutput(state, "stderr", stderrFile))
+	if w.execDigest != "" {
+		stderrWriters = append(stderrWriters, w.limitOutput(state, "followed stderr", w.execOutputs.writer(w.execDigest, 2)))
+	}
 
 	if w.execMD != nil && (w.execMD.RedirectStdoutPath != "" || w.execMD.RedirectStderrPath != "") {
 		ctrFS, err := containerfs.NewContainerFS(state.spec, nil)
@@ -642,8 +678,9 @@ func (w *Worker) setupOTel(ctx context.Context, state *execState) error {
 	stdio := telemetry.SpanStdio(ctx, InstrumentationLibrary, logAttrs...)
 	state.cleanups.Add("close logs", stdio.Close)
 
-	state.procInfo.Stdout = nopCloser{io.MultiWriter(stdio.Stdout, state.procInfo.Stdout)}
-	state.procInfo.Stderr = nopCloser{io.MultiWriter(stdio.Stderr, state.procInfo.Stderr)}
+	// added after the logs are closed, so that the limiters are finished first
+	state.procInfo.Stdout = nopCloser{io.MultiWriter(w.limitOutput(state, "stdout logs", stdio.Stdout), state.procInfo.Stdout)}
+	state.procInfo.Stderr = nopCloser{io.MultiWriter(w.limitOutput(state, "stderr logs", stdio.Stderr), state.procInfo.Stderr)}
 
 	listener, err := runInNetNS(ctx, state, func() (net.Listener, error) {
 		return net.Listen("tcp", "127.0.0.1:0")
@@ -843,6 +880,81 @@ func (w *Worker) enableGPU(_ context.Context, state *execState) error {
 	return nil
 }
 
+func (w *Worker) addDevices(_ context.Context, state *execState) error {
+	if w.execMD == nil {
+		return nil
+	}
+	if len(w.execMD.Devices) == 0 {
+		return nil
+	}
+
+	if state.spec.Linux == nil {
+		state.spec.Linux = &specs.Linux{}
+	}
+	if state.spec.Linux.Resources == nil {
+		state.spec.Linux.Resources = &specs.LinuxResources{}
+	}
+	for _, devicePath := range w.execMD.Devices {
+		if !slices.Contains(w.allowedDevices, devicePath) {
+			return fmt.Errorf("device %s is not allowed, start the engine with --allow-device=%s", devicePath, devicePath)
+		}
+		dev, err := ctdoci.DeviceFromPath(devicePath)
+		if err != nil {
+			return fmt.Errorf("device %s: %w", devicePath, err)
+		}
+		state.spec.Linux.Devices = append(state.spec.Linux.Devices, *dev)
+		state.spec.Linux.Resources.Devices = append(state.spec.Linux.Resources.Devices, specs.LinuxDeviceCgroup{
+			Allow:  true,
+			Type:   dev.Type,
+			Major:  &dev.Major,
+			Minor:  &dev.Minor,
+			Access: "rwm",
+		})
+	}
+
+	return nil
+}
+
+func (w *Worker) setResourceLimits(_ context.Context, state *execState) error {
+	if w.execMD == nil {
+		return nil
+	}
+	if w.execMD.CPUs == 0 && w.execMD.MemoryBytes == 0 {
+		return nil
+	}
+
+	if state.spec.Linux == nil {
+		state.spec.Linux = &specs.Linux{}
+	}
+	if state.spec.Linux.Resources == nil {
+		state.spec.Linux.Resources = &specs.LinuxResources{}
+	}
+	resources := state.spec.Linux.Resources
+
+	if w.execMD.CPUs > 0 {
+		// same as docker's --cpus: the quota of CPU time per scheduling period
+		period := uint64(100000)
+		quota := int64(w.execMD.CPUs * float64(period))
+		if resources.CPU == nil {
+			resources.CPU = &specs.LinuxCPU{}
+		}
+		resources.CPU.Period = &period
+		resources.CPU.Quota = &quota
+	}
+
+	if w.execMD.MemoryBytes > 0 {
+		limit := w.execMD.MemoryBytes
+		if resources.Memory == nil {
+			resources.Memory = &specs.LinuxMemory{}
+		}
+		resources.Memory.Limit = &limit
+		// don't let the command exceed its limit by swapping
+		resources.Memory.Swap = &limit
+	}
+
+	return nil
+}
+
 func (w *Worker) createCWD(_ context.Context, state *execState) error {
 	newp, err := fs.RootPath(state.rootfsPath, state.procInfo.Meta.Cwd)
 	if err != nil {
@@ -185,6 +185,19 @@ func (c *Client) LocalDirExport(
 	if err != nil {
 		return fmt.Errorf("failed to solve for local export: %w", err)
 	}
+	if c.Budget.LimitsExports() {
+		ref, err := res.SingleRef()
+		if err != nil {
+			return fmt.Errorf("failed to get single ref: %w", err)
+		}
+		size, err := exportSize(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("failed to compute export size: %w", err)
+		}
+		if err := c.Budget.AddExport(destPath, size); err != nil {
+			return err
+		}
+	}
 	cacheRes, err := ConvertToWorkerCacheResult(ctx, res)
 	if err != nil {
 		return fmt.Errorf("failed to convert result: %w", err)
@@ -284,6 +297,9 @@ func (c *Client) LocalFileExport(
 	if err != nil {
 		return fmt.Errorf("failed to stat file: %w", err)
 	}
+	if err := c.Budget.AddExport(destPath, stat.Size()); err != nil {
+		return err
+	}
 
 	ctx = engine.LocalExportOpts{
 		Path:               destPath,
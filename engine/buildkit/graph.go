@@ -0,0 +1,228 @@
+package buildkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	bkclient "github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/opencontainers/go-digest"
+)
+
+// VertexStatus is the state of a vertex as last reported by the solver.
+type VertexStatus string
+
+const (
+	// VertexUnknown is the status of a vertex that wasn't solved in the
+	// session.
+	VertexUnknown VertexStatus = "unknown"
+	// VertexRunning is the status of a vertex that is still being solved.
+	VertexRunning VertexStatus = "running"
+	// VertexCached is the status of a vertex that was loaded from the cache.
+	VertexCached VertexStatus = "cached"
+	// VertexExecuted is the status of a vertex that had to be executed.
+	VertexExecuted VertexStatus = "executed"
+	// VertexFailed is the status of a vertex that failed.
+	VertexFailed VertexStatus = "failed"
+)
+
+// OpGraph is the graph of the LLB operations of a definition.
+type OpGraph struct {
+	// Vertexes are sorted so that inputs come before the vertexes using them.
+	Vertexes []*OpGraphVertex `json:"vertexes"`
+}
+
+type OpGraphVertex struct {
+	Digest string       `json:"digest"`
+	Kind   string       `json:"kind"`
+	Name   string       `json:"name"`
+	Inputs []string     `json:"inputs,omitempty"`
+	Status VertexStatus `json:"status"`
+}
+
+// maxCompletedVertexes is the number of completed vertexes whose status is
+// kept for OpGraph, beyond which the oldest ones are evicted.
+const maxCompletedVertexes = 10000
+
+type trackedVertex struct {
+	name   string
+	status VertexStatus
+}
+
+// TrackVertexes records the status of the vertexes reported on statusCh, so
+// that it can be reported by OpGraph, and passes the statuses on to the
+// returned channel, so that the subscription to the progress of the job can
+// be shared.
+func (c *Client) TrackVertexes(statusCh <-chan *bkclient.SolveStatus) <-chan *bkclient.SolveStatus {
+	out := make(chan *bkclient.SolveStatus, 8)
+	go func() {
+		defer close(out)
+		for status := range statusCh {
+			c.trackVertexes(status.Vertexes)
+			out <- status
+		}
+	}()
+	return out
+}
+
+func (c *Client) trackVertexes(vertexes []*bkclient.Vertex) {
+	c.vertexesMu.Lock()
+	defer c.vertexesMu.Unlock()
+	for _, vtx := range vertexes {
+		tracked := &trackedVertex{
+			name:   vtx.Name,
+			status: vertexStatus(vtx),
+		}
+		prev, found := c.vertexes[vtx.Digest]
+		c.vertexes[vtx.Digest] = tracked
+		if tracked.status == VertexRunning || (found && prev.status != VertexRunning) {
+			continue
+		}
+		c.completedVertexes = append(c.completedVertexes, vtx.Digest)
+		for len(c.completedVertexes) > maxCompletedVertexes {
+			oldest := c.completedVertexes[0]
+			c.completedVertexes = c.completedVertexes[1:]
+			// vertexes solved again since are queued again once completed
+			if v, ok := c.vertexes[oldest]; ok && v.status != VertexRunning {
+				delete(c.vertexes, oldest)
+			}
+		}
+	}
+}
+
+func vertexStatus(vtx *bkclient.Vertex) VertexStatus {
+	switch {
+	case vtx.Error != "":
+		return VertexFailed
+	case vtx.Cached:
+		return VertexCached
+	case vtx.Completed != nil:
+		return VertexExecuted
+	default:
+		return VertexRunning
+	}
+}
+
+// OpGraph returns the graph of the operations of the given definition, with
+// the status of each of them as last reported by the solver.
+func (c *Client) OpGraph(ctx context.Context, def *pb.Definition) (*OpGraph, error) {
+	graph := &OpGraph{}
+	if def == nil || len(def.Def) == 0 {
+		return graph, nil
+	}
+	dag, err := DefToDAG(def)
+	if err != nil {
+		return nil, err
+	}
+	if dag.Op.Op == nil && len(dag.Inputs) == 1 {
+		// skip the terminal op that only selects the result
+		dag = dag.Inputs[0]
+	}
+
+	c.vertexesMu.Lock()
+	defer c.vertexesMu.Unlock()
+
+	seen := map[digest.Digest]bool{}
+	var visit func(*OpDAG)
+	visit = func(dag *OpDAG) {
+		if seen[*dag.OpDigest] {
+			return
+		}
+		seen[*dag.OpDigest] = true
+
+		vtx := &OpGraphVertex{
+			Digest: dag.OpDigest.String(),
+			Kind:   opKind(dag.Op),
+			Name:   opName(dag),
+			Status: VertexUnknown,
+		}
+		for _, input := range dag.Inputs {
+			visit(input)
+			vtx.Inputs = append(vtx.Inputs, input.OpDigest.String())
+		}
+		if solved, ok := c.vertexes[*dag.OpDigest]; ok {
+			if solved.name != "" {
+				vtx.Name = solved.name
+			}
+			vtx.Status = solved.status
+		}
+		graph.Vertexes = append(graph.Vertexes, vtx)
+	}
+	visit(dag)
+	return graph, nil
+}
+
+// DOT renders the graph in the Graphviz DOT language.
+func (graph *OpGraph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph {\n")
+	for _, vtx := range graph.Vertexes {
+		fmt.Fprintf(&sb, "  %q [label=%q, tooltip=%q, style=filled, fillcolor=%q];\n",
+			vtx.Digest,
+			vtx.Name+"\n"+string(vtx.Status),
+			vtx.Digest,
+			vertexColor(vtx.Status),
+		)
+	}
+	for _, vtx := range graph.Vertexes {
+		inputs := append([]string{}, vtx.Inputs...)
+		sort.Strings(inputs)
+		for _, input := range inputs {
+			fmt.Fprintf(&sb, "  %q -> %q;\n", input, vtx.Digest)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func vertexColor(status VertexStatus) string {
+	switch status {
+	case VertexCached:
+		return "lightblue"
+	case VertexExecuted:
+		return "palegreen"
+	case VertexFailed:
+		return "salmon"
+	case VertexRunning:
+		return "khaki"
+	default:
+		return "white"
+	}
+}
+
+func opKind(op *pb.Op) string {
+	switch op.Op.(type) {
+	case *pb.Op_Exec:
+		return "exec"
+	case *pb.Op_File:
+		return "file"
+	case *pb.Op_Source:
+		return "source"
+	case *pb.Op_Merge:
+		return "merge"
+	case *pb.Op_Diff:
+		return "diff"
+	case *pb.Op_Build:
+		return "build"
+	default:
+		return "unknown"
+	}
+}
+
+func opName(dag *OpDAG) string {
+	if dag.Metadata != nil {
+		if name := dag.Metadata.Description["llb.customname"]; name != "" {
+			return name
+		}
+	}
+	switch op := dag.Op.Op.(type) {
+	case *pb.Op_Exec:
+		return strings.Join(op.Exec.Meta.Args, " ")
+	case *pb.Op_Source:
+		return op.Source.Identifier
+	default:
+		return opKind(dag.Op)
+	}
+}
@@ -0,0 +1,39 @@
+package buildkit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	bkclient "github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackVertexes(t *testing.T) {
+	c := &Client{vertexes: map[digest.Digest]*trackedVertex{}}
+
+	statusCh := make(chan *bkclient.SolveStatus)
+	out := c.TrackVertexes(statusCh)
+
+	now := time.Now()
+	send := func(vtxs ...*bkclient.Vertex) {
+		status := &bkclient.SolveStatus{Vertexes: vtxs}
+		statusCh <- status
+		require.Same(t, status, <-out)
+	}
+
+	running := digest.FromString("running")
+	send(&bkclient.Vertex{Digest: running, Name: "exec"})
+	for i := range maxCompletedVertexes + 1 {
+		send(&bkclient.Vertex{Digest: digest.FromString(fmt.Sprint(i)), Completed: &now})
+	}
+	close(statusCh)
+	_, open := <-out
+	require.False(t, open)
+
+	require.Len(t, c.vertexes, maxCompletedVertexes+1)
+	require.Equal(t, VertexRunning, c.vertexes[running].status)
+	require.NotContains(t, c.vertexes, digest.FromString(fmt.Sprint(0)))
+	require.Equal(t, VertexExecuted, c.vertexes[digest.FromString(fmt.Sprint(1))].status)
+}
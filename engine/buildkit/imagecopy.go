@@ -0,0 +1,180 @@
+package buildkit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/labels"
+	"github.com/containerd/containerd/remotes"
+	"github.com/distribution/reference"
+	bksession "github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/util/contentutil"
+	"github.com/moby/buildkit/util/resolver"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxParallelBlobCopies is the number of blobs of an image copied
+// concurrently by CopyImage.
+const maxParallelBlobCopies = 4
+
+// CopyImage copies the image src, including all of its platforms, to dest
+// using only registry APIs, returning the fully qualified reference of the
+// copied image.
+//
+// Nothing is stored in the engine: blobs are mounted across repositories of
+// the same registry, and otherwise streamed from src to dest.
+func (c *Client) CopyImage(ctx context.Context, src, dest string) (string, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	srcRef, err := reference.ParseNormalizedNamed(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse source %q: %w", src, err)
+	}
+	srcRef = reference.TagNameOnly(srcRef)
+	destRef, err := reference.ParseNormalizedNamed(dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse destination %q: %w", dest, err)
+	}
+	destRef = reference.TagNameOnly(destRef)
+
+	g := bksession.NewGroup(c.ID())
+
+	srcResolver := resolver.DefaultPool.GetResolver(c.Worker.RegistryHosts, srcRef.String(), "pull", c.SessionManager, g)
+	_, desc, err := srcResolver.Resolve(ctx, srcRef.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", srcRef, err)
+	}
+	fetcher, err := srcResolver.Fetcher(ctx, srcRef.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to create fetcher for %s: %w", srcRef, err)
+	}
+
+	// pushing to name:tag@digest makes the pusher tag the root manifest and
+	// push any child manifests by digest
+	pushRef := destRef.String() + "@" + desc.Digest.String()
+	destResolver := resolver.DefaultPool.GetResolver(c.Worker.RegistryHosts, destRef.String(), "push", c.SessionManager, g)
+	pusher, err := destResolver.Pusher(ctx, pushRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to create pusher for %s: %w", destRef, err)
+	}
+
+	cp := &imageCopier{
+		provider: contentutil.FromFetcher(fetcher),
+		pusher:   pusher,
+		// lets the pusher mount blobs from the source repository instead of
+		// uploading them, if both are on the same registry
+		sourceLabel: labels.LabelDistributionSource + "." + reference.Domain(srcRef),
+		sourceRepo:  reference.Path(srcRef),
+		sem:         make(chan struct{}, maxParallelBlobCopies),
+		pushes:      map[digest.Digest]*blobPush{},
+	}
+	if err := cp.copy(ctx, desc); err != nil {
+		return "", fmt.Errorf("failed to copy %s to %s: %w", srcRef, destRef, err)
+	}
+
+	digested, err := reference.WithDigest(destRef, desc.Digest)
+	if err != nil {
+		return "", err
+	}
+	return digested.String(), nil
+}
+
+type imageCopier struct {
+	provider    content.Provider
+	pusher      remotes.Pusher
+	sourceLabel string
+	sourceRepo  string
+
+	sem chan struct{}
+
+	mu     sync.Mutex
+	pushes map[digest.Digest]*blobPush
+}
+
+type blobPush struct {
+	once sync.Once
+	err  error
+}
+
+// copy pushes desc after all of its children, so that the destination never
+// references content it doesn't have.
+func (cp *imageCopier) copy(ctx context.Context, desc specs.Descriptor) error {
+	children, err := images.Children(ctx, cp.provider, desc)
+	if err != nil {
+		return err
+	}
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, child := range children {
+		child := child
+		eg.Go(func() error {
+			return cp.copy(egCtx, child)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return cp.push(ctx, desc)
+}
+
+// push uploads a single descriptor, once per copy.
+func (cp *imageCopier) push(ctx context.Context, desc specs.Descriptor) error {
+	cp.mu.Lock()
+	push, ok := cp.pushes[desc.Digest]
+	if !ok {
+		push = &blobPush{}
+		cp.pushes[desc.Digest] = push
+	}
+	cp.mu.Unlock()
+
+	push.once.Do(func() {
+		select {
+		case cp.sem <- struct{}{}:
+		case <-ctx.Done():
+			push.err = ctx.Err()
+			return
+		}
+		defer func() { <-cp.sem }()
+		push.err = cp.pushBlob(ctx, desc)
+	})
+	return push.err
+}
+
+func (cp *imageCopier) pushBlob(ctx context.Context, desc specs.Descriptor) error {
+	desc.Annotations = cloneAnnotations(desc.Annotations)
+	desc.Annotations[cp.sourceLabel] = cp.sourceRepo
+
+	cw, err := cp.pusher.Push(ctx, desc)
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			// already present, or mounted from the source repository
+			return nil
+		}
+		return err
+	}
+	defer cw.Close()
+
+	ra, err := cp.provider.ReaderAt(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer ra.Close()
+	return content.Copy(ctx, cw, content.NewReader(ra), desc.Size, desc.Digest)
+}
+
+func cloneAnnotations(annotations map[string]string) map[string]string {
+	cp := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		cp[k] = v
+	}
+	return cp
+}
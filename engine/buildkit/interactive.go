@@ -0,0 +1,157 @@
+package buildkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	bkexecutor "github.com/moby/buildkit/executor"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	bkcontainer "github.com/moby/buildkit/frontend/gateway/container"
+	bkgwpb "github.com/moby/buildkit/frontend/gateway/pb"
+	"github.com/moby/buildkit/identity"
+	llberror "github.com/moby/buildkit/solver/llbsolver/errdefs"
+	bksolverpb "github.com/moby/buildkit/solver/pb"
+	bkworker "github.com/moby/buildkit/worker"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/dagger/dagger/engine"
+)
+
+// debugExecError opens a terminal for the main client in a container with the
+// state of a failed exec: its mounts as the exec left them, and its env,
+// working directory and user. It returns once the terminal exits.
+func (c *Client) debugExecError(
+	ctx context.Context,
+	execOp *bksolverpb.ExecOp,
+	execErr *llberror.ExecError,
+	wrappedErr *ExecError,
+) error {
+	// the same error is returned to everything waiting on the failed exec, so
+	// only debug it once, holding the others until the terminal exits so
+	// they don't release its mounts in the meantime; it's forgotten once the
+	// terminal exits, so that it and its mounts can be released
+	c.execDebugsMu.Lock()
+	if c.execDebugs == nil {
+		c.execDebugs = map[*llberror.ExecError]chan struct{}{}
+	}
+	done, debugging := c.execDebugs[execErr]
+	if !debugging {
+		done = make(chan struct{})
+		c.execDebugs[execErr] = done
+	}
+	c.execDebugsMu.Unlock()
+	if debugging {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+	defer func() {
+		c.execDebugsMu.Lock()
+		delete(c.execDebugs, execErr)
+		c.execDebugsMu.Unlock()
+		close(done)
+	}()
+
+	var execMD ExecutionMetadata
+	if clientMetadata, err := engine.ClientMetadataFromContext(ctx); err == nil {
+		execMD.SessionID = clientMetadata.SessionID
+	}
+
+	ctrReq := bkcontainer.NewContainerRequest{
+		ContainerID: identity.NewID(),
+		NetMode:     execOp.Network,
+		Mounts:      make([]bkcontainer.Mount, len(execOp.Mounts)),
+	}
+	for _, h := range execOp.Meta.ExtraHosts {
+		ip := net.ParseIP(h.IP)
+		if ip == nil {
+			return fmt.Errorf("invalid IP %q for extra host %q", h.IP, h.Host)
+		}
+		ctrReq.ExtraHosts = append(ctrReq.ExtraHosts, bkexecutor.HostIP{Host: h.Host, IP: ip})
+	}
+	for i, m := range execOp.Mounts {
+		var workerRef *bkworker.WorkerRef
+		if i < len(execErr.Mounts) && execErr.Mounts[i] != nil {
+			var ok bool
+			workerRef, ok = execErr.Mounts[i].Sys().(*bkworker.WorkerRef)
+			if !ok {
+				return fmt.Errorf("invalid ref type: %T", execErr.Mounts[i].Sys())
+			}
+		}
+		ctrReq.Mounts[i] = bkcontainer.Mount{
+			WorkerRef: workerRef,
+			Mount:     m,
+		}
+	}
+
+	term, err := c.OpenTerminal(ctx)
+	if err != nil {
+		return fmt.Errorf("open terminal: %w", err)
+	}
+
+	ctr, err := c.newContainer(ctx, ctrReq, execMD)
+	if err != nil {
+		term.Close(1)
+		return fmt.Errorf("new container: %w", err)
+	}
+	defer ctr.Release(context.WithoutCancel(ctx))
+
+	fmt.Fprintf(term.Stderr, "Exec failed with exit code %d, attaching terminal to its container: %s\r\n\n",
+		wrappedErr.ExitCode,
+		strings.Join(execOp.Meta.Args, " "))
+
+	proc, err := ctr.Start(ctx, bkgw.StartRequest{
+		Args:         []string{"sh"},
+		Env:          execOp.Meta.Env,
+		Cwd:          execOp.Meta.Cwd,
+		User:         execOp.Meta.User,
+		SecretEnv:    execOp.Secretenv,
+		Tty:          true,
+		Stdin:        term.Stdin,
+		Stdout:       term.Stdout,
+		Stderr:       term.Stderr,
+		SecurityMode: execOp.Security,
+	})
+	if err != nil {
+		term.Close(1)
+		return fmt.Errorf("start container: %w", err)
+	}
+
+	eg, egctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		for resize := range term.ResizeCh {
+			if err := proc.Resize(egctx, resize); err != nil {
+				return fmt.Errorf("failed to resize terminal: %w", err)
+			}
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		if err := <-term.ErrCh; err != nil {
+			proc.Signal(egctx, syscall.SIGKILL)
+			return fmt.Errorf("terminal session failed: %w", err)
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		exitCode := 0
+		if err := proc.Wait(); err != nil {
+			exitCode = 1
+			var exitErr *bkgwpb.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = int(exitErr.ExitCode)
+			}
+		}
+		if err := term.Close(exitCode); err != nil {
+			return fmt.Errorf("failed to forward exit code: %w", err)
+		}
+		return nil
+	})
+	return eg.Wait()
+}
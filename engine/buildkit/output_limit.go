@@ -0,0 +1,87 @@
+package buildkit
+
+import (
+	"fmt"
+	"io"
+)
+
+// OutputLimitTruncationMessage is appended to the captured output of an exec
+// that exceeded its output limit.
+const OutputLimitTruncationMessage = "\n[output truncated: omitted %d bytes beyond the limit of %d bytes]\n"
+
+// outputLimiter writes up to limit bytes to w and drops the rest, so that a
+// chatty command can't grow its captured output without bounds.
+type outputLimiter struct {
+	w       io.Writer
+	limit   int64
+	written int64
+	omitted int64
+}
+
+// Write never reports dropped bytes as an error, so the command isn't
+// interrupted once it reaches the limit.
+func (l *outputLimiter) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := l.limit - l.written; int64(len(p)) > room {
+		l.omitted += int64(len(p)) - room
+		p = p[:room]
+	}
+	if len(p) > 0 {
+		written, err := l.w.Write(p)
+		l.written += int64(written)
+		if err != nil {
+			return written, err
+		}
+	}
+	return n, nil
+}
+
+// Close appends the truncation message if any output was dropped.
+func (l *outputLimiter) Close() error {
+	if l.omitted == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(l.w, OutputLimitTruncationMessage, l.omitted, l.limit)
+	return err
+}
+
+// lowestLimit returns the lowest of the given limits that's non-zero, or 0 if
+// they're all unlimited.
+func lowestLimit(limits ...int64) int64 {
+	var lowest int64
+	for _, limit := range limits {
+		if limit > 0 && (lowest == 0 || limit < lowest) {
+			lowest = limit
+		}
+	}
+	return lowest
+}
+
+// ExecOutputLimit returns the limit of the output of an exec given its own
+// limit: the lowest of it, the session's budget and the engine's limit, or 0
+// if they're all unlimited.
+func (c *Client) ExecOutputLimit(limit int64) int64 {
+	return lowestLimit(limit, c.Budget.MaxExecOutputBytes(), c.Worker.maxExecOutputBytes)
+}
+
+// maxOutputBytes returns the limit of the output of the exec: the lower of
+// its own limit and the engine's, or 0 if unlimited.
+func (w *Worker) maxOutputBytes() int64 {
+	var limit int64
+	if w.execMD != nil {
+		limit = w.execMD.MaxOutputBytes
+	}
+	return lowestLimit(limit, w.maxExecOutputBytes)
+}
+
+// limitOutput caps the output written to out at the exec's limit, if any.
+func (w *Worker) limitOutput(state *execState, name string, out io.Writer) io.Writer {
+	limit := w.maxOutputBytes()
+	if limit == 0 {
+		return out
+	}
+	l := &outputLimiter{w: out, limit: limit}
+	// cleanups run in reverse, so this is written before the file is closed
+	state.cleanups.Add("finish container "+name+" output", l.Close)
+	return l
+}
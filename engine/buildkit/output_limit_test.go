@@ -0,0 +1,45 @@
+package buildkit
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputLimiter(t *testing.T) {
+	var buf bytes.Buffer
+	l := &outputLimiter{w: &buf, limit: 5}
+
+	n, err := l.Write([]byte("abc"))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	n, err = l.Write([]byte("defgh"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+
+	n, err = l.Write([]byte("ij"))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	require.NoError(t, l.Close())
+	require.Equal(t, "abcde"+fmt.Sprintf(OutputLimitTruncationMessage, 5, 5), buf.String())
+
+	t.Run("within limit", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := &outputLimiter{w: &buf, limit: 5}
+		_, err := l.Write([]byte("abcde"))
+		require.NoError(t, err)
+		require.NoError(t, l.Close())
+		require.Equal(t, "abcde", buf.String())
+	})
+}
+
+func TestLowestLimit(t *testing.T) {
+	require.Equal(t, int64(0), lowestLimit())
+	require.Equal(t, int64(0), lowestLimit(0, 0))
+	require.Equal(t, int64(10), lowestLimit(0, 10, 20))
+	require.Equal(t, int64(10), lowestLimit(20, 0, 10))
+}
@@ -0,0 +1,76 @@
+package buildkit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/moby/buildkit/client/llb"
+	"github.com/moby/buildkit/solver"
+	"github.com/moby/buildkit/solver/pb"
+	srctypes "github.com/moby/buildkit/source/types"
+)
+
+const sessionIDDescriptionKey = "dagger.sessionID"
+
+// WithSessionID marks an op as run on behalf of the given session, subjecting
+// it to the session's parallelism budget. Execs carry their session in their
+// ExecutionMetadata instead.
+func WithSessionID(sessionID string) llb.ConstraintsOpt {
+	return llb.WithDescription(map[string]string{
+		sessionIDDescriptionKey: sessionID,
+	})
+}
+
+// sessionLimitedOp waits for one of its session's parallel slots before
+// acquiring the resources of the op it wraps, e.g. a slot of the engine's own
+// parallelism limit, so that waiting on the session doesn't hold up other
+// sessions.
+type sessionLimitedOp struct {
+	solver.Op
+	budget *BudgetTracker
+}
+
+func (op sessionLimitedOp) Acquire(ctx context.Context) (solver.ReleaseFunc, error) {
+	release, err := op.budget.AcquireParallelism(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opRelease, err := op.Op.Acquire(ctx)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return func() {
+		opRelease()
+		release()
+	}, nil
+}
+
+// limitSessionParallelism subjects the op of the vertex to the parallelism
+// budget of its session, if it's an exec or an image pull and the session has
+// one.
+func (w *Worker) limitSessionParallelism(vtx solver.Vertex, op solver.Op) solver.Op {
+	var sessionID string
+	if w.execMD != nil {
+		// nested clients, like function calls, wait on the execs they run, so
+		// they can't hold a slot themselves without risking a deadlock
+		if w.execMD.ClientID != "" {
+			return op
+		}
+		sessionID = w.execMD.SessionID
+	} else if baseOp, ok := vtx.Sys().(*pb.Op); ok {
+		src, ok := baseOp.Op.(*pb.Op_Source)
+		if !ok || !strings.HasPrefix(src.Source.Identifier, srctypes.DockerImageScheme+"://") {
+			return op
+		}
+		sessionID = vtx.Options().Description[sessionIDDescriptionKey]
+	}
+	if sessionID == "" || w.sessionHandler == nil {
+		return op
+	}
+	budget := w.sessionHandler.SessionBudget(sessionID)
+	if !budget.LimitsParallelism() {
+		return op
+	}
+	return sessionLimitedOp{Op: op, budget: budget}
+}
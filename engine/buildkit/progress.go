@@ -0,0 +1,121 @@
+package buildkit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	bkclient "github.com/moby/buildkit/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/trace"
+
+	"dagger.io/dagger/telemetry"
+)
+
+// TransferProgressInterval is how often the progress of a transfer is
+// reported while it's running.
+const TransferProgressInterval = time.Second
+
+// vertexSpans maps the digests of the vertices being solved to the contexts
+// of their spans, so that the progress of their transfers can be reported
+// beneath them.
+var vertexSpans sync.Map
+
+type transferKey struct {
+	vertex string
+	id     string
+}
+
+type transfer struct {
+	ctx      context.Context
+	span     trace.Span
+	reported time.Time
+}
+
+// ForwardTransferProgress reports the statuses of a solve that count bytes,
+// like pulling the layers of an image or transferring a local directory, as
+// spans beneath the span of their vertex, or the span of ctx if it isn't
+// part of the same trace.
+//
+// The span of a transfer has the progress.units and progress.total
+// attributes, if the total is known. The progress.current attribute is
+// emitted as log records without a body while it's running and set on the
+// span once it completes, so that frontends can render rates and ETAs.
+func ForwardTransferProgress(ctx context.Context, clientID string, statusCh <-chan *bkclient.SolveStatus) {
+	tracer := otel.Tracer(InstrumentationLibrary)
+	logger := telemetry.Logger(InstrumentationLibrary)
+	parentCtx := trace.SpanContextFromContext(ctx)
+
+	transfers := map[transferKey]*transfer{}
+	defer func() {
+		// the solve is done; don't leave spans running
+		for _, t := range transfers {
+			t.span.End()
+		}
+	}()
+
+	for st := range statusCh {
+		for _, vs := range st.Statuses {
+			if vs.Total == 0 && vs.Current == 0 {
+				// not counting bytes, e.g. "resolve" or "extract" statuses
+				continue
+			}
+			key := transferKey{vertex: vs.Vertex.String(), id: vs.ID}
+			t, found := transfers[key]
+			if !found {
+				if vs.Completed != nil && vs.Started == nil {
+					continue
+				}
+				spanCtx := ctx
+				if vtxSpan, ok := vertexSpans.Load(key.vertex); ok {
+					if sc := vtxSpan.(trace.SpanContext); sc.TraceID() == parentCtx.TraceID() {
+						spanCtx = trace.ContextWithSpanContext(ctx, sc)
+					}
+				}
+				startOpts := []trace.SpanStartOption{
+					trace.WithAttributes(
+						attribute.String(telemetry.ProgressUnitsAttr, "bytes"),
+						attribute.String(telemetry.ClientIDAttr, clientID),
+					),
+				}
+				if vs.Started != nil {
+					startOpts = append(startOpts, trace.WithTimestamp(*vs.Started))
+				}
+				if vs.Total > 0 {
+					startOpts = append(startOpts, trace.WithAttributes(
+						attribute.Int64(telemetry.ProgressTotalAttr, vs.Total)))
+				}
+				spanCtx, span := tracer.Start(spanCtx, vs.ID, startOpts...)
+				t = &transfer{ctx: spanCtx, span: span}
+				transfers[key] = t
+			}
+
+			if vs.Completed != nil {
+				t.span.SetAttributes(
+					attribute.Int64(telemetry.ProgressCurrentAttr, vs.Current),
+					attribute.Int64(telemetry.ProgressTotalAttr, max(vs.Total, vs.Current)),
+				)
+				t.span.End(trace.WithTimestamp(*vs.Completed))
+				delete(transfers, key)
+				continue
+			}
+
+			if vs.Timestamp.Sub(t.reported) < TransferProgressInterval {
+				continue
+			}
+			t.reported = vs.Timestamp
+			rec := log.Record{}
+			rec.SetTimestamp(vs.Timestamp)
+			rec.AddAttributes(
+				log.Int64(telemetry.ProgressCurrentAttr, vs.Current),
+				log.String(telemetry.ClientIDAttr, clientID),
+			)
+			if vs.Total > 0 {
+				rec.AddAttributes(log.Int64(telemetry.ProgressTotalAttr, vs.Total))
+			}
+			logger.Emit(t.ctx, rec)
+		}
+	}
+}
@@ -173,6 +173,26 @@ func (r *ref) AddDependencyBlobs(ctx context.Context, blobs map[digest.Digest]*o
 	return nil
 }
 
+// WithLocalMount mounts the ref read-only on the engine's filesystem and
+// calls fn with the path to the mount, unmounting it once fn returns.
+func (r *ref) WithLocalMount(ctx context.Context, fn func(root string) error) error {
+	ctx = withOutgoingContext(ctx)
+	mnt, err := r.getMountable(ctx)
+	if err != nil {
+		return err
+	}
+	if mnt == nil {
+		return errors.New("cannot mount empty ref")
+	}
+	lm := snapshot.LocalMounter(mnt)
+	root, err := lm.Mount()
+	if err != nil {
+		return fmt.Errorf("failed to mount: %w", err)
+	}
+	defer lm.Unmount()
+	return fn(root)
+}
+
 func (r *ref) getMountable(ctx context.Context) (snapshot.Mountable, error) {
 	if r == nil {
 		return nil, nil
@@ -201,7 +221,7 @@ func (r *ref) Result(ctx context.Context) (bksolver.CachedResult, error) {
 	ctx = withOutgoingContext(ctx)
 	res, err := r.resultProxy.Result(ctx)
 	if err != nil {
-		return nil, wrapError(ctx, err, r.c.ID())
+		return nil, wrapError(ctx, err, r.c)
 	}
 	return res, nil
 }
@@ -254,7 +274,7 @@ func ConvertToWorkerCacheResult(ctx context.Context, res *solverresult.Result[*r
 	})
 }
 
-func wrapError(ctx context.Context, baseErr error, sessionID string) error {
+func wrapError(ctx context.Context, baseErr error, c *Client) error {
 	var slowCacheErr *bksolver.SlowCacheError
 	if errors.As(baseErr, &slowCacheErr) {
 		if slowCacheErr.Result != nil {
@@ -313,7 +333,7 @@ func wrapError(ctx context.Context, baseErr error, sessionID string) error {
 	if !ok {
 		return errors.Join(baseErr, fmt.Errorf("invalid ref type: %T", metaMountResult.Sys()))
 	}
-	mntable, err := workerRef.ImmutableRef.Mount(ctx, true, bksession.NewGroup(sessionID))
+	mntable, err := workerRef.ImmutableRef.Mount(ctx, true, bksession.NewGroup(c.ID()))
 	if err != nil {
 		return errors.Join(err, baseErr)
 	}
@@ -339,13 +359,22 @@ func wrapError(ctx context.Context, baseErr error, sessionID string) error {
 		}
 	}
 
-	return &ExecError{
+	wrappedErr := &ExecError{
 		original: baseErr,
 		Cmd:      execOp.Exec.Meta.Args,
 		ExitCode: exitCode,
 		Stdout:   strings.TrimSpace(string(stdoutBytes)),
 		Stderr:   strings.TrimSpace(string(stderrBytes)),
 	}
+
+	if c.Interactive && !errors.Is(baseErr, ErrCacheMiss) && ctx.Err() == nil {
+		// the mounts are only released once the terminal exits
+		if err := c.debugExecError(ctx, execOp.Exec, execErr, wrappedErr); err != nil {
+			bklog.G(ctx).WithError(err).Warn("failed to debug exec error")
+		}
+	}
+
+	return wrappedErr
 }
 
 func getExecMetaFile(ctx context.Context, mntable snapshot.Mountable, fileName string) ([]byte, error) {
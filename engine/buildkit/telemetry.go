@@ -115,6 +115,7 @@ func (sp SpanProcessor) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan
 	// remap vertex attr to standard effect ID attr
 	if vertex != "" {
 		attrs = append(attrs, attribute.String(telemetry.EffectIDAttr, vertex))
+		vertexSpans.Store(vertex, span.SpanContext())
 	}
 
 	if len(attrs) > 0 {
@@ -122,6 +123,14 @@ func (sp SpanProcessor) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan
 	}
 }
 
-func (sp SpanProcessor) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (sp SpanProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == "vertex" {
+			vertexSpans.CompareAndDelete(attr.Value.AsString(), span.SpanContext())
+			return
+		}
+	}
+}
+
 func (sp SpanProcessor) ForceFlush(context.Context) error { return nil }
 func (sp SpanProcessor) Shutdown(context.Context) error   { return nil }
@@ -18,6 +18,7 @@ import (
 	"github.com/moby/buildkit/util/network"
 	"github.com/moby/buildkit/worker"
 	"github.com/moby/buildkit/worker/base"
+	"github.com/opencontainers/go-digest"
 	"golang.org/x/sync/semaphore"
 
 	"github.com/dagger/dagger/engine/telemetry"
@@ -36,6 +37,10 @@ here). For simplicity, this Worker struct also implements that Executor interfac
 type Worker struct {
 	*sharedWorkerState
 	execMD *ExecutionMetadata
+
+	// digest of the vertex of the exec, if any, to publish its output to
+	// followers
+	execDigest digest.Digest
 }
 
 type sharedWorkerState struct {
@@ -55,15 +60,23 @@ type sharedWorkerState struct {
 	apparmorProfile  string
 	selinux          bool
 	entitlements     entitlements.Set
+	allowedDevices   []string
 	parallelismSem   *semaphore.Weighted
 	workerCache      bkcache.Manager
 
+	// caps the stdout and stderr captured of each exec, if non-zero
+	maxExecOutputBytes int64
+
 	running map[string]*execState
 	mu      sync.RWMutex
+
+	execOutputs *execOutputs
 }
 
 type sessionHandler interface {
 	ServeHTTPToNestedClient(http.ResponseWriter, *http.Request, *ExecutionMetadata)
+	// SessionBudget returns the budget of the session, or nil if it's gone.
+	SessionBudget(sessionID string) *BudgetTracker
 }
 
 type NewWorkerOpts struct {
@@ -82,9 +95,11 @@ type NewWorkerOpts struct {
 	ApparmorProfile     string
 	SELinux             bool
 	Entitlements        entitlements.Set
+	AllowedDevices      []string
 	NetworkProviders    map[pb.NetMode]network.Provider
 	ParallelismSem      *semaphore.Weighted
 	WorkerCache         bkcache.Manager
+	MaxExecOutputBytes  int64
 }
 
 func NewWorker(opts *NewWorkerOpts) *Worker {
@@ -105,13 +120,31 @@ func NewWorker(opts *NewWorkerOpts) *Worker {
 		apparmorProfile:  opts.ApparmorProfile,
 		selinux:          opts.SELinux,
 		entitlements:     opts.Entitlements,
+		allowedDevices:   opts.AllowedDevices,
 		parallelismSem:   opts.ParallelismSem,
 		workerCache:      opts.WorkerCache,
 
+		maxExecOutputBytes: opts.MaxExecOutputBytes,
+
 		running: make(map[string]*execState),
+
+		execOutputs: newExecOutputs(),
 	}}
 }
 
+// AllowedDevices returns the paths of the host devices that can be exposed to
+// containers.
+func (w *Worker) AllowedDevices() []string {
+	return w.allowedDevices
+}
+
+// RunningExecs returns the number of container processes currently running.
+func (w *Worker) RunningExecs() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return len(w.running)
+}
+
 func (w *Worker) Executor() executor.Executor {
 	return w
 }
@@ -127,7 +160,8 @@ func (w *Worker) ResolveOp(vtx solver.Vertex, s frontend.FrontendLLBBridge, sm *
 			if ok {
 				w = w.withExecMD(*execMD)
 			}
-			return ops.NewExecOp(
+			w = w.withExecDigest(vtx.Digest())
+			op, err := ops.NewExecOp(
 				vtx,
 				execOp,
 				baseOp.Platform,
@@ -137,17 +171,29 @@ func (w *Worker) ResolveOp(vtx solver.Vertex, s frontend.FrontendLLBBridge, sm *
 				w, // executor
 				w,
 			)
+			if err != nil {
+				return nil, err
+			}
+			return w.limitSessionParallelism(vtx, op), nil
 		}
 	}
 
 	// otherwise, just use the default base.Worker's ResolveOp
-	return w.Worker.ResolveOp(vtx, s, sm)
+	op, err := w.Worker.ResolveOp(vtx, s, sm)
+	if err != nil {
+		return nil, err
+	}
+	return w.limitSessionParallelism(vtx, op), nil
 }
 
 func (w *Worker) withExecMD(execMD ExecutionMetadata) *Worker {
 	return &Worker{sharedWorkerState: w.sharedWorkerState, execMD: &execMD}
 }
 
+func (w *Worker) withExecDigest(dgst digest.Digest) *Worker {
+	return &Worker{sharedWorkerState: w.sharedWorkerState, execMD: w.execMD, execDigest: dgst}
+}
+
 /*
 Buildkit's worker.Controller is a bit odd; it exists to manage multiple workers because that was
 a planned feature years ago, but it never got implemented. So it exists to manage a single worker,
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+)
+
+// ParseCacheConfigs parses remote cache configs in the form
+// k1=v1,k2=v2;k3=v3... with ';' used to separate multiple cache configs.
+// Any value that itself needs ';' can use '\;' to escape it.
+//
+// Each config must have a "type" key naming the cache backend (e.g.
+// "registry", "s3", "gha"); the other keys are the backend's attributes.
+func ParseCacheConfigs(val string) ([]*controlapi.CacheOptionsEntry, error) {
+	configKVs := strings.Split(val, ";")
+	// handle '\;' as an escape in case ';' needs to be used in a cache config setting rather than as
+	// a delimiter between multiple cache configs
+	for i := len(configKVs) - 2; i >= 0; i-- {
+		if strings.HasSuffix(configKVs[i], `\`) {
+			configKVs[i] = configKVs[i][:len(configKVs[i])-1] + ";" + configKVs[i+1]
+			configKVs = append(configKVs[:i+1], configKVs[i+2:]...)
+		}
+	}
+
+	cacheConfigs := make([]*controlapi.CacheOptionsEntry, 0, len(configKVs))
+	for _, kvsStr := range configKVs {
+		kvs := strings.Split(kvsStr, ",")
+		if len(kvs) == 0 {
+			continue
+		}
+		attrs := make(map[string]string)
+		for _, kv := range kvs {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid form for cache config %q", kv)
+			}
+			attrs[parts[0]] = parts[1]
+		}
+		typeVal, ok := attrs["type"]
+		if !ok {
+			return nil, fmt.Errorf("missing type in cache config: %q", val)
+		}
+		delete(attrs, "type")
+		cacheConfigs = append(cacheConfigs, &controlapi.CacheOptionsEntry{
+			Type:  typeVal,
+			Attrs: attrs,
+		})
+	}
+	return cacheConfigs, nil
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"testing"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheConfigs(t *testing.T) {
+	cfgs, err := ParseCacheConfigs(`type=s3,bucket=cache,endpoint_url=http://minio:9000,use_path_style=true;type=registry,ref=registry:5000/cache\;v1`)
+	require.NoError(t, err)
+	require.Equal(t, []*controlapi.CacheOptionsEntry{
+		{
+			Type: "s3",
+			Attrs: map[string]string{
+				"bucket":         "cache",
+				"endpoint_url":   "http://minio:9000",
+				"use_path_style": "true",
+			},
+		},
+		{
+			Type:  "registry",
+			Attrs: map[string]string{"ref": "registry:5000/cache;v1"},
+		},
+	}, cfgs)
+
+	_, err = ParseCacheConfigs("bucket=cache")
+	require.ErrorContains(t, err, "missing type")
+
+	_, err = ParseCacheConfigs("type=s3,bucket")
+	require.ErrorContains(t, err, "invalid form")
+}
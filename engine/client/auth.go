@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/dagger/dagger/engine"
+)
+
+const (
+	// a token authenticating to the engine, e.g. an ID token issued by an
+	// organization's identity provider
+	engineTokenEnvName = "DAGGER_ENGINE_TOKEN"
+
+	// a file holding a token authenticating to the engine, read again for
+	// each connection so that it can be rotated
+	engineTokenFileEnvName = "DAGGER_ENGINE_TOKEN_FILE"
+
+	// the audience of the ID token to request from GitHub Actions to
+	// authenticate to the engine
+	engineTokenAudienceEnvName = "DAGGER_ENGINE_TOKEN_AUDIENCE"
+
+	// how long before its expiry a token is requested again
+	engineTokenExpiryLeeway = time.Minute
+)
+
+// engineTokenSource provides the token authenticating the client to the
+// engine, if configured.
+type engineTokenSource struct {
+	static   string
+	file     string
+	audience string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+var _ credentials.PerRPCCredentials = (*engineTokenSource)(nil)
+
+// engineTokenSourceFromEnv returns the source of the token authenticating to
+// the engine, or nil if none is configured.
+func engineTokenSourceFromEnv() (*engineTokenSource, error) {
+	src := &engineTokenSource{
+		static:   os.Getenv(engineTokenEnvName),
+		file:     os.Getenv(engineTokenFileEnvName),
+		audience: os.Getenv(engineTokenAudienceEnvName),
+	}
+	switch {
+	case src.static != "":
+	case src.file != "":
+	case src.audience != "":
+		if os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") == "" || os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN") == "" {
+			return nil, errors.New(engineTokenAudienceEnvName + " requires a GitHub Actions job with the id-token: write permission")
+		}
+	default:
+		return nil, nil
+	}
+	return src, nil
+}
+
+// Token returns the token authenticating to the engine.
+func (src *engineTokenSource) Token(ctx context.Context) (string, error) {
+	if src.static != "" {
+		return src.static, nil
+	}
+	if src.file != "" {
+		bs, err := os.ReadFile(src.file)
+		if err != nil {
+			return "", fmt.Errorf("read engine token: %w", err)
+		}
+		return strings.TrimSpace(string(bs)), nil
+	}
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if src.token != "" && time.Until(src.expiry) > engineTokenExpiryLeeway {
+		return src.token, nil
+	}
+	token, err := fetchGitHubActionsIDToken(ctx, src.audience)
+	if err != nil {
+		return "", fmt.Errorf("fetch engine token: %w", err)
+	}
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return "", fmt.Errorf("parse engine token: %w", err)
+	}
+	src.token = token
+	src.expiry = time.Time{}
+	if claims.ExpiresAt != nil {
+		src.expiry = claims.ExpiresAt.Time
+	}
+	return token, nil
+}
+
+// SetHeader sets the token on the headers of a request to the engine. It's a
+// no-op if src is nil.
+func (src *engineTokenSource) SetHeader(ctx context.Context, headers http.Header) error {
+	if src == nil {
+		return nil
+	}
+	token, err := src.Token(ctx)
+	if err != nil {
+		return err
+	}
+	headers.Set(engine.EngineAuthMetaKey, "Bearer "+token)
+	return nil
+}
+
+func (src *engineTokenSource) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := src.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		strings.ToLower(engine.EngineAuthMetaKey): "Bearer " + token,
+	}, nil
+}
+
+func (src *engineTokenSource) RequireTransportSecurity() bool {
+	// the engine is commonly reached through a transport that's secured
+	// outside of gRPC, e.g. a kube port-forward or an SSH tunnel
+	return false
+}
+
+// fetchGitHubActionsIDToken requests an ID token for the audience from the
+// GitHub Actions OIDC provider.
+func fetchGitHubActionsIDToken(ctx context.Context, audience string) (string, error) {
+	u, err := url.Parse(os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"))
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("audience", audience)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var body struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Value == "" {
+		return "", errors.New("empty token")
+	}
+	return body.Value, nil
+}
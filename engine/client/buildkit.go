@@ -21,7 +21,7 @@ const (
 	envDaggerCloudCachetoken = "_EXPERIMENTAL_DAGGER_CACHESERVICE_TOKEN"
 )
 
-func newBuildkitClient(ctx context.Context, remote *url.URL, connector drivers.Connector) (_ *bkclient.Client, _ *bkclient.Info, rerr error) {
+func newBuildkitClient(ctx context.Context, remote *url.URL, connector drivers.Connector, engineToken *engineTokenSource) (_ *bkclient.Client, _ *bkclient.Info, rerr error) {
 	backoffConfig := backoff.DefaultConfig
 	backoffConfig.MaxDelay = 30 * time.Second
 	opts := []bkclient.ClientOpt{
@@ -34,6 +34,9 @@ func newBuildkitClient(ctx context.Context, remote *url.URL, connector drivers.C
 			MinConnectTimeout: 1 * time.Second,
 		})),
 	}
+	if engineToken != nil {
+		opts = append(opts, bkclient.WithGRPCDialOption(grpc.WithPerRPCCredentials(engineToken)))
+	}
 
 	c, err := bkclient.New(ctx, remote.String(), opts...)
 	if err != nil {
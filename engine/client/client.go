@@ -2,6 +2,7 @@ package client
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -15,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Khan/genqlient/graphql"
@@ -29,6 +31,8 @@ import (
 	"github.com/moby/buildkit/session/auth/authprovider"
 	"github.com/moby/buildkit/util/grpcerrors"
 	"github.com/moby/buildkit/util/tracing"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
@@ -78,6 +82,10 @@ type Params struct {
 	LogLevel slog.Level
 
 	WithTerminal session.WithTerminalFunc
+
+	// If set, a failed exec opens a terminal in its container as it was when
+	// it failed, so it can be debugged, instead of failing right away.
+	Interactive bool
 }
 
 type Client struct {
@@ -87,7 +95,8 @@ type Client struct {
 
 	eg *errgroup.Group
 
-	connector drivers.Connector
+	connector   drivers.Connector
+	connectorMu sync.RWMutex
 
 	internalCtx    context.Context
 	internalCancel context.CancelFunc
@@ -99,10 +108,18 @@ type Client struct {
 	telemetry     *errgroup.Group
 	telemetryConn *grpc.ClientConn
 
-	httpClient *httpClient
-	bkClient   *bkclient.Client
-	bkVersion  string
-	sessionSrv *BuildkitSessionServer
+	httpClient  *httpClient
+	bkClient    *bkclient.Client
+	bkVersion   string
+	sessionSrv  *BuildkitSessionServer
+	sessionDone <-chan struct{}
+
+	// guards the connection to the engine, i.e. bkClient, sessionSrv,
+	// sessionDone, telemetry and telemetryConn, which resume swaps
+	resumeMu sync.Mutex
+	// queries that set secrets, sent again when resuming the session since
+	// the engine only keeps secrets in memory
+	secretQueries [][]byte
 
 	// A client for the dagger API that is directly hooked up to this engine client.
 	// Currently used for the dagger CLI so it can avoid making a subprocess of itself...
@@ -111,9 +128,16 @@ type Client struct {
 	upstreamCacheImportOptions []*controlapi.CacheOptionsEntry
 	upstreamCacheExportOptions []*controlapi.CacheOptionsEntry
 
+	budget   engine.Budget
+	defaults engine.Defaults
+
+	// authenticates to the engine, if configured
+	engineToken *engineTokenSource
+
 	hostname string
 
-	nestedSessionPort int
+	nestedSessionPort   int
+	nestedSessionSocket string
 
 	labels enginetel.Labels
 }
@@ -161,13 +185,18 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 	}
 	c.hostname = hostname
 
-	nestedSessionPortVal, isNestedSession := os.LookupEnv("DAGGER_SESSION_PORT")
-	if isNestedSession {
-		nestedSessionPort, err := strconv.Atoi(nestedSessionPortVal)
-		if err != nil {
-			return nil, nil, fmt.Errorf("parse DAGGER_SESSION_PORT: %w", err)
+	nestedSessionSocket, isNestedSocketSession := os.LookupEnv("DAGGER_SESSION_SOCKET")
+	nestedSessionPortVal, isNestedPortSession := os.LookupEnv("DAGGER_SESSION_PORT")
+	if isNestedSocketSession || isNestedPortSession {
+		if isNestedSocketSession {
+			c.nestedSessionSocket = nestedSessionSocket
+		} else {
+			nestedSessionPort, err := strconv.Atoi(nestedSessionPortVal)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parse DAGGER_SESSION_PORT: %w", err)
+			}
+			c.nestedSessionPort = nestedSessionPort
 		}
-		c.nestedSessionPort = nestedSessionPort
 		c.SecretToken = os.Getenv("DAGGER_SESSION_TOKEN")
 		c.httpClient = c.newHTTPClient()
 		if err := c.daggerConnect(ctx); err != nil {
@@ -184,6 +213,21 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 		return nil, nil, fmt.Errorf("cache config from env: %w", err)
 	}
 
+	c.budget, err = engine.BudgetFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.defaults, err = engine.DefaultsFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.engineToken, err = engineTokenSourceFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	connectSpanOpts := []trace.SpanStartOption{}
 	if configuredSessionID != "" {
 		// infer that this is not a main client caller, server ID is never set for those currently
@@ -194,7 +238,8 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 	connectCtx, span := Tracer().Start(ctx, "connect", connectSpanOpts...)
 	defer telemetry.End(span, func() error { return rerr })
 
-	if err := c.startEngine(connectCtx); err != nil {
+	bkInfo, err := c.startEngine(connectCtx)
+	if err != nil {
 		return nil, nil, fmt.Errorf("start engine: %w", err)
 	}
 	err = engine.CheckVersionCompatibility(c.bkVersion, engine.MinimumEngineVersion)
@@ -202,6 +247,15 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 		return nil, nil, fmt.Errorf("incompatible engine version: %w", err)
 	}
 
+	if c.EngineCallback != nil {
+		c.EngineCallback(connectCtx, bkInfo.BuildkitVersion.Revision, bkInfo.BuildkitVersion.Version, c.ID)
+	}
+	if c.CloudCallback != nil {
+		if url, msg, ok := enginetel.URLForTrace(connectCtx); ok {
+			c.CloudCallback(connectCtx, url, msg)
+		}
+	}
+
 	defer func() {
 		if rerr != nil {
 			c.bkClient.Close()
@@ -211,6 +265,8 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 	if err := c.startSession(connectCtx); err != nil {
 		return nil, nil, fmt.Errorf("start session: %w", err)
 	}
+	c.httpClient.session = c
+	go c.sendHeartbeats(c.internalCtx)
 
 	defer func() {
 		if rerr != nil {
@@ -225,15 +281,15 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 	return c, ctx, nil
 }
 
-func (c *Client) startEngine(ctx context.Context) (rerr error) {
+func (c *Client) startEngine(ctx context.Context) (_ *bkclient.Info, rerr error) {
 	remote, err := url.Parse(c.RunnerHost)
 	if err != nil {
-		return fmt.Errorf("parse runner host: %w", err)
+		return nil, fmt.Errorf("parse runner host: %w", err)
 	}
 
 	driver, err := drivers.GetDriver(remote.Scheme)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var cloudToken string
@@ -245,16 +301,20 @@ func (c *Client) startEngine(ctx context.Context) (rerr error) {
 
 	provisionCtx, provisionSpan := Tracer().Start(ctx, "starting engine")
 	provisionCtx, provisionCancel := context.WithTimeout(provisionCtx, 10*time.Minute)
-	c.connector, err = driver.Provision(provisionCtx, remote, &drivers.DriverOpts{
+	connector, err := driver.Provision(provisionCtx, remote, &drivers.DriverOpts{
 		UserAgent:        c.UserAgent,
 		DaggerCloudToken: cloudToken,
 		GPUSupport:       os.Getenv(drivers.EnvGPUSupport),
+		TLS:              drivers.TLSOptsFromEnv(),
 	})
 	provisionCancel()
 	telemetry.End(provisionSpan, func() error { return err })
 	if err != nil {
-		return err
+		return nil, err
 	}
+	c.connectorMu.Lock()
+	c.connector = connector
+	c.connectorMu.Unlock()
 
 	ctx, span := Tracer().Start(ctx, "connecting to engine")
 	defer telemetry.End(span, func() error { return rerr })
@@ -263,21 +323,24 @@ func (c *Client) startEngine(ctx context.Context) (rerr error) {
 
 	slog.Debug("connecting", "runner", c.RunnerHost, "client", c.ID)
 
-	bkClient, bkInfo, err := newBuildkitClient(ctx, remote, c.connector)
+	bkClient, bkInfo, err := newBuildkitClient(ctx, remote, connector, c.engineToken)
 	if err != nil {
-		return fmt.Errorf("new client: %w", err)
+		return nil, fmt.Errorf("new client: %w", err)
 	}
 	c.bkClient = bkClient
 	c.bkVersion = bkInfo.BuildkitVersion.Version
 
+	if err := checkEngineHealth(ctx, connector, c.engineToken); err != nil {
+		return nil, err
+	}
+
 	if err := retry(ctx, 10*time.Millisecond, func(elapsed time.Duration, ctx context.Context) error {
 		slog.Debug("subscribing to telemetry", "remote", c.RunnerHost)
 
 		// Open a separate connection for telemetry.
-		telemetryConn, err := grpc.NewClient(
-			"passthrough:"+c.RunnerHost,
+		telemetryOpts := []grpc.DialOption{
 			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
-				return c.connector.Connect(c.internalCtx)
+				return connector.Connect(c.internalCtx)
 			}),
 			// Propagate the session ID to the server (via baggage).
 			grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
@@ -288,7 +351,12 @@ func (c *Client) startEngine(ctx context.Context) (rerr error) {
 			// Uncomment to measure telemetry traffic.
 			// grpc.WithUnaryInterceptor(telemetry.MeasuringUnaryClientInterceptor()),
 			// grpc.WithStreamInterceptor(telemetry.MeasuringStreamClientInterceptor()),
-			grpc.WithTransportCredentials(insecure.NewCredentials()))
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}
+		if c.engineToken != nil {
+			telemetryOpts = append(telemetryOpts, grpc.WithPerRPCCredentials(c.engineToken))
+		}
+		telemetryConn, err := grpc.NewClient("passthrough:"+c.RunnerHost, telemetryOpts...)
 		if err != nil {
 			slog.Error("failed to dial telemetry", "error", err, "elapsed", elapsed)
 			return fmt.Errorf("telemetry grpc dial: %w", err)
@@ -314,19 +382,10 @@ func (c *Client) startEngine(ctx context.Context) (rerr error) {
 
 		return nil
 	}); err != nil {
-		return fmt.Errorf("attach to telemetry: %w", err)
-	}
-
-	if c.EngineCallback != nil {
-		c.EngineCallback(ctx, bkInfo.BuildkitVersion.Revision, bkInfo.BuildkitVersion.Version, c.ID)
-	}
-	if c.CloudCallback != nil {
-		if url, msg, ok := enginetel.URLForTrace(ctx); ok {
-			c.CloudCallback(ctx, url, msg)
-		}
+		return nil, fmt.Errorf("attach to telemetry: %w", err)
 	}
 
-	return nil
+	return bkInfo, nil
 }
 
 func (c *Client) startSession(ctx context.Context) (rerr error) {
@@ -365,16 +424,24 @@ func (c *Client) startSession(ctx context.Context) (rerr error) {
 		}
 	}()
 
+	sessionHeaders := c.AppendHTTPRequestHeaders(http.Header{})
+	if err := c.engineToken.SetHeader(ctx, sessionHeaders); err != nil {
+		return err
+	}
 	c.sessionSrv, err = ConnectBuildkitSession(ctx,
 		sessionConn,
-		c.AppendHTTPRequestHeaders(http.Header{}),
+		sessionHeaders,
 		attachables...,
 	)
 	if err != nil {
 		return fmt.Errorf("connect buildkit session: %w", err)
 	}
 
+	sessionSrv := c.sessionSrv
+	sessionDone := make(chan struct{})
+	c.sessionDone = sessionDone
 	c.eg.Go(func() error {
+		defer close(sessionDone)
 		ctx, cancel, err := c.withClientCloseCancel(ctx)
 		if err != nil {
 			return err
@@ -383,14 +450,91 @@ func (c *Client) startSession(ctx context.Context) (rerr error) {
 			<-ctx.Done()
 			cancel()
 		}()
-		c.sessionSrv.Run(ctx)
+		sessionSrv.Run(ctx)
 		return nil
 	})
 
-	c.httpClient = c.newHTTPClient()
+	if c.httpClient == nil {
+		c.httpClient = c.newHTTPClient()
+	}
+	return nil
+}
+
+// currentSession returns a channel identifying the current session, closed
+// when its connection to the engine ends.
+func (c *Client) currentSession() <-chan struct{} {
+	c.resumeMu.Lock()
+	defer c.resumeMu.Unlock()
+	return c.sessionDone
+}
+
+// resume reconnects to the engine after it went away mid-session, e.g. after
+// running out of memory or being upgraded, provisioning it again if needed.
+//
+// The session is re-established with the same ID, so results that completed
+// before the restart are reused from the engine's persistent cache, and the
+// session attachables serving host sockets and files are attached again.
+// Secrets set with setSecret are restored by sending the queries setting them
+// again. Other state only held in the engine's memory, like running
+// services, is lost.
+func (c *Client) resume(ctx context.Context, lost <-chan struct{}) (rerr error) {
+	c.resumeMu.Lock()
+	defer c.resumeMu.Unlock()
+
+	if c.sessionDone != lost {
+		// another request already resumed the session
+		return nil
+	}
+	if c.closeCtx.Err() != nil {
+		return errors.New("client closed")
+	}
+
+	ctx, span := Tracer().Start(ctx, "resuming session")
+	defer telemetry.End(span, func() error { return rerr })
+
+	// tear down everything connected to the previous engine
+	c.sessionSrv.Stop()
+	if c.bkClient != nil {
+		c.bkClient.Close()
+	}
+	if c.telemetryConn != nil {
+		c.telemetryConn.Close()
+	}
+	if c.telemetry != nil {
+		if err := c.telemetry.Wait(); err != nil {
+			slog.Debug("stopped exporting telemetry from previous engine", "error", err)
+		}
+	}
+	c.httpClient.inner.CloseIdleConnections()
+
+	if _, err := c.startEngine(ctx); err != nil {
+		return fmt.Errorf("start engine: %w", err)
+	}
+	if err := c.startSession(ctx); err != nil {
+		return fmt.Errorf("start session: %w", err)
+	}
+
+	for _, query := range c.secretQueries {
+		if err := c.httpClient.replay(ctx, query); err != nil {
+			return fmt.Errorf("restore secrets: %w", err)
+		}
+	}
 	return nil
 }
 
+// recordSecretQuery keeps a query setting a secret so that it can be sent
+// again when resuming the session.
+func (c *Client) recordSecretQuery(query []byte) {
+	c.resumeMu.Lock()
+	defer c.resumeMu.Unlock()
+	for _, q := range c.secretQueries {
+		if bytes.Equal(q, query) {
+			return
+		}
+	}
+	c.secretQueries = append(c.secretQueries, query)
+}
+
 func ConnectBuildkitSession(
 	ctx context.Context,
 	conn net.Conn,
@@ -498,6 +642,45 @@ func (srv *BuildkitSessionServer) Run(ctx context.Context) {
 	}
 }
 
+// checkEngineHealth fails fast with the diagnosis of the engine when it
+// reports being unhealthy, rather than letting the session fail later on.
+//
+// Engines that can't be probed, e.g. because they predate the health
+// endpoint, are assumed to be healthy.
+func checkEngineHealth(ctx context.Context, connector drivers.Connector, engineToken *engineTokenSource) error {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return connector.Connect(ctx)
+		},
+	}
+	defer transport.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://dagger"+engine.HealthEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	if err := engineToken.SetHeader(ctx, req.Header); err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		slog.Debug("failed to check engine health", "error", err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+
+	var health struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil || health.Error == "" {
+		return errors.New("engine is unhealthy")
+	}
+	return fmt.Errorf("engine is unhealthy: %s", health.Error)
+}
+
 func retry(ctx context.Context, initialInterval time.Duration, fn func(time.Duration, context.Context) error) error {
 	bo := backoff.NewExponentialBackOff()
 	bo.InitialInterval = initialInterval
@@ -557,6 +740,10 @@ func (c *Client) Close() (rerr error) {
 		c.internalCancel()
 	}
 
+	// wait for any resume in progress, which is interrupted by closeRequests
+	c.resumeMu.Lock()
+	defer c.resumeMu.Unlock()
+
 	if c.daggerClient != nil {
 		c.eg.Go(c.daggerClient.Close)
 	}
@@ -697,6 +884,44 @@ func (c *Client) shutdownServer() error {
 	return resp.Body.Close()
 }
 
+// sendHeartbeats renews the lease of the session until the client is closed,
+// so that the engine can tell it apart from the session of a crashed client.
+func (c *Client) sendHeartbeats(ctx context.Context) {
+	ticker := time.NewTicker(engine.SessionHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		if err := c.heartbeat(ctx); err != nil && ctx.Err() == nil {
+			slog.Debug("failed to send heartbeat", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) heartbeat(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, engine.SessionHeartbeatInterval)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://dagger"+engine.HeartbeatEndpoint, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do heartbeat: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+func (c *Client) getConnector() drivers.Connector {
+	c.connectorMu.RLock()
+	defer c.connectorMu.RUnlock()
+	return c.connector
+}
+
 func (c *Client) withClientCloseCancel(ctx context.Context) (context.Context, context.CancelFunc, error) {
 	c.closeMu.RLock()
 	defer c.closeMu.RUnlock()
@@ -722,16 +947,20 @@ func (c *Client) DialContext(ctx context.Context, _, _ string) (conn net.Conn, e
 		return nil, err
 	}
 
-	isNestedSession := c.nestedSessionPort != 0
-	if isNestedSession {
+	switch {
+	case c.nestedSessionSocket != "":
+		conn, err = (&net.Dialer{
+			Cancel: ctx.Done(),
+		}).Dial("unix", c.nestedSessionSocket)
+	case c.nestedSessionPort != 0:
 		conn, err = (&net.Dialer{
 			Cancel: ctx.Done(),
 		}).Dial("tcp", "127.0.0.1:"+strconv.Itoa(c.nestedSessionPort))
-	} else {
-		conn, err = c.connector.Connect(ctx)
+	default:
+		conn, err = c.getConnector().Connect(ctx)
 	}
 	if err != nil {
-		return nil, err
+		return nil, &engineDialError{err}
 	}
 
 	go func() {
@@ -830,6 +1059,9 @@ func (c *Client) serveHijackedHTTP(ctx context.Context, cancel context.CancelFun
 
 	// send the initial client http upgrade request to the server
 	r.Header = c.AppendHTTPRequestHeaders(r.Header)
+	if err := c.engineToken.SetHeader(ctx, r.Header); err != nil {
+		panic(fmt.Errorf("set engine token: %w", err))
+	}
 	if err := r.Write(serverConn); err != nil {
 		panic(fmt.Errorf("write upgrade request: %w", err))
 	}
@@ -926,48 +1158,14 @@ const (
 	cacheExportsConfigEnvName = "_EXPERIMENTAL_DAGGER_CACHE_EXPORT_CONFIG"
 )
 
-// env is in form k1=v1,k2=v2;k3=v3... with ';' used to separate multiple cache configs.
-// any value that itself needs ';' can use '\;' to escape it.
+// cacheConfigFromEnv parses the cache configs set in the given env var, in
+// the form accepted by engine.ParseCacheConfigs.
 func cacheConfigFromEnv(envName string) ([]*controlapi.CacheOptionsEntry, error) {
 	envVal, ok := os.LookupEnv(envName)
 	if !ok {
 		return nil, nil
 	}
-	configKVs := strings.Split(envVal, ";")
-	// handle '\;' as an escape in case ';' needs to be used in a cache config setting rather than as
-	// a delimiter between multiple cache configs
-	for i := len(configKVs) - 2; i >= 0; i-- {
-		if strings.HasSuffix(configKVs[i], `\`) {
-			configKVs[i] = configKVs[i][:len(configKVs[i])-1] + ";" + configKVs[i+1]
-			configKVs = append(configKVs[:i+1], configKVs[i+2:]...)
-		}
-	}
-
-	cacheConfigs := make([]*controlapi.CacheOptionsEntry, 0, len(configKVs))
-	for _, kvsStr := range configKVs {
-		kvs := strings.Split(kvsStr, ",")
-		if len(kvs) == 0 {
-			continue
-		}
-		attrs := make(map[string]string)
-		for _, kv := range kvs {
-			parts := strings.SplitN(kv, "=", 2)
-			if len(parts) != 2 {
-				return nil, fmt.Errorf("invalid form for cache config %q", kv)
-			}
-			attrs[parts[0]] = parts[1]
-		}
-		typeVal, ok := attrs["type"]
-		if !ok {
-			return nil, fmt.Errorf("missing type in cache config: %q", envVal)
-		}
-		delete(attrs, "type")
-		cacheConfigs = append(cacheConfigs, &controlapi.CacheOptionsEntry{
-			Type:  typeVal,
-			Attrs: attrs,
-		})
-	}
-	return cacheConfigs, nil
+	return engine.ParseCacheConfigs(envVal)
 }
 
 func allCacheConfigsFromEnv() (cacheImportConfigs []*controlapi.CacheOptionsEntry, cacheExportConfigs []*controlapi.CacheOptionsEntry, rerr error) {
@@ -993,6 +1191,15 @@ func allCacheConfigsFromEnv() (cacheImportConfigs []*controlapi.CacheOptionsEntr
 		cacheExportConfigs = append(cacheExportConfigs, cfg)
 	}
 
+	ghaCacheConfig, err := ghaCacheConfigFromEnv()
+	if err != nil {
+		return nil, nil, fmt.Errorf("gha cache config from env: %w", err)
+	}
+	if ghaCacheConfig != nil {
+		cacheImportConfigs = append(cacheImportConfigs, ghaCacheConfig)
+		cacheExportConfigs = append(cacheExportConfigs, ghaCacheConfig)
+	}
+
 	return cacheImportConfigs, cacheExportConfigs, nil
 }
 
@@ -1008,6 +1215,9 @@ func (c *Client) clientMetadata() engine.ClientMetadata {
 		Labels:                    c.labels,
 		CloudToken:                os.Getenv("DAGGER_CLOUD_TOKEN"),
 		DoNotTrack:                analytics.DoNotTrack(),
+		Budget:                    c.budget,
+		Defaults:                  c.defaults,
+		Interactive:               c.Interactive,
 	}
 }
 
@@ -1027,6 +1237,7 @@ func (c *Client) newHTTPClient() *httpClient {
 		},
 		headers:     c.AppendHTTPRequestHeaders(http.Header{}),
 		secretToken: c.SecretToken,
+		engineToken: c.engineToken,
 	}
 }
 
@@ -1034,22 +1245,171 @@ type httpClient struct {
 	inner       *http.Client
 	headers     http.Header
 	secretToken string
+	engineToken *engineTokenSource
+
+	// if set, queries that fail because the engine went away are retried once
+	// after resuming the session
+	session *Client
 }
 
 func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
+	if err := c.prepare(req); err != nil {
+		return nil, err
+	}
+
+	if c.session == nil || req.URL.Path != engine.QueryEndpoint || req.Body == nil {
+		return c.inner.Do(req)
+	}
+
+	session := c.session.currentSession()
+	select {
+	case <-session:
+		// the connection to the engine was lost since the last query
+		slog.Warn("lost connection to engine, resuming session")
+		if err := c.session.resume(req.Context(), session); err != nil {
+			return nil, fmt.Errorf("resume session: %w", err)
+		}
+		session = c.session.currentSession()
+	default:
+	}
+
+	// buffer the query so that it can be sent again
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := c.inner.Do(req)
+	if err == nil {
+		if resp.StatusCode == http.StatusOK && setsSecret(body) {
+			c.session.recordSecretQuery(body)
+		}
+		return resp, nil
+	}
+	if !isEngineGone(err) || req.Context().Err() != nil {
+		return nil, err
+	}
+
+	slog.Warn("lost connection to engine, resuming session", "error", err)
+	if resumeErr := c.session.resume(req.Context(), session); resumeErr != nil {
+		return nil, errors.Join(err, fmt.Errorf("resume session: %w", resumeErr))
+	}
+	// only send the query again if it can't have been run twice
+	var dialErr *engineDialError
+	if !errors.As(err, &dialErr) && !isReplayable(req) {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return c.inner.Do(req)
+}
+
+func (c *httpClient) prepare(req *http.Request) error {
 	for k, v := range c.headers {
 		req.Header[k] = v
 	}
 	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
 	req.SetBasicAuth(c.secretToken, "")
+	if err := c.engineToken.SetHeader(req.Context(), req.Header); err != nil {
+		return err
+	}
 
 	// We're making a request to the engine HTTP/2 server, but these headers are not
 	// allowed in HTTP 2+, so unset them in case they came from an HTTP/1 client that
 	// we are proxying a request for.
 	req.Header.Del("Connection")
 	req.Header.Del("Keep-Alive")
+	return nil
+}
 
-	return c.inner.Do(req)
+// replay sends a query to the engine again, bypassing session resumption.
+func (c *httpClient) replay(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://dagger"+engine.QueryEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := c.prepare(req); err != nil {
+		return err
+	}
+	resp, err := c.inner.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// engineDialError is returned when a connection to the engine can't be
+// established, so the engine can't have received the request.
+type engineDialError struct {
+	err error
+}
+
+func (e *engineDialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *engineDialError) Unwrap() error {
+	return e.err
+}
+
+// isEngineGone returns whether a request failed because the connection to the
+// engine was lost or refused, rather than because of the request itself.
+func isEngineGone(err error) bool {
+	var opErr *net.OpError
+	var goAway http2.GoAwayError
+	var dialErr *engineDialError
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &opErr) ||
+		errors.As(err, &goAway) ||
+		errors.As(err, &dialErr) ||
+		// not exported by x/net/http2
+		strings.Contains(err.Error(), "http2: client connection lost")
+}
+
+// isReplayable returns whether a request may be sent again after it possibly
+// reached the engine, following the conventions of net/http: its method is
+// idempotent, or the caller marked it as such with an idempotency key.
+func isReplayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	_, ok := req.Header["X-Idempotency-Key"]
+	return ok
+}
+
+// setsSecret returns whether a GraphQL request body calls setSecret.
+func setsSecret(body []byte) bool {
+	var params struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		return false
+	}
+	doc, err := parser.ParseQuery(&ast.Source{Input: params.Query})
+	if err != nil {
+		return false
+	}
+	for _, op := range doc.Operations {
+		for _, sel := range op.SelectionSet {
+			if field, ok := sel.(*ast.Field); ok && field.Name == "setSecret" {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (c *httpClient) Close() error {
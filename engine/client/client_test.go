@@ -0,0 +1,34 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeRetries(t *testing.T) {
+	post := func(header http.Header) *http.Request {
+		return &http.Request{Method: http.MethodPost, Header: header}
+	}
+	require.False(t, isReplayable(post(http.Header{})))
+	require.True(t, isReplayable(post(http.Header{"Idempotency-Key": {"1"}})))
+	require.True(t, isReplayable(&http.Request{Method: http.MethodGet, Header: http.Header{}}))
+
+	refused := &engineDialError{syscall.ECONNREFUSED}
+	require.True(t, isEngineGone(refused))
+	var dialErr *engineDialError
+	require.True(t, errors.As(errors.Join(errors.New("post"), refused), &dialErr))
+	require.True(t, isEngineGone(syscall.ECONNRESET))
+	require.False(t, isEngineGone(errors.New("bad request")))
+}
+
+func TestSetsSecret(t *testing.T) {
+	require.True(t, setsSecret([]byte(`{"query":"query{setSecret(name:\"foo\",plaintext:\"bar\"){id}}"}`)))
+	require.True(t, setsSecret([]byte(`{"query":"query Q($p:String!){s:setSecret(name:\"foo\",plaintext:$p){id}}","variables":{"p":"bar"}}`)))
+	require.False(t, setsSecret([]byte(`{"query":"query{secret(name:\"foo\"){id}}"}`)))
+	require.False(t, setsSecret([]byte(`{"query":"query{container{withSecretVariable(name:\"setSecret\"){id}}}"}`)))
+	require.False(t, setsSecret([]byte(`not json`)))
+}
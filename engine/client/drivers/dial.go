@@ -2,6 +2,7 @@ package drivers
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"net/url"
 	"strings"
@@ -28,18 +29,36 @@ type dialDriver struct {
 	fn func(*url.URL) (*connh.ConnectionHelper, error)
 }
 
-func (d *dialDriver) Provision(ctx context.Context, target *url.URL, _ *DriverOpts) (Connector, error) {
-	return dialConnector{dialDriver: d, target: target}, nil
+func (d *dialDriver) Provision(ctx context.Context, target *url.URL, opts *DriverOpts) (Connector, error) {
+	connector := dialConnector{dialDriver: d, target: target}
+	if target.Scheme == "tcp" && opts != nil {
+		tlsConfig, err := opts.TLS.Config(target.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		connector.tlsConfig = tlsConfig
+	}
+	return connector, nil
 }
 
 type dialConnector struct {
 	*dialDriver
-	target *url.URL
+	target    *url.URL
+	tlsConfig *tls.Config
 }
 
 func (d dialConnector) Connect(ctx context.Context) (_ net.Conn, rerr error) {
 	if d.fn == nil {
-		return defaultDialer(ctx, d.target.String())
+		conn, err := defaultDialer(ctx, d.target.String())
+		if err != nil || d.tlsConfig == nil {
+			return conn, err
+		}
+		tlsConn := tls.Client(conn, d.tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
 	}
 
 	helper, err := d.fn(d.target)
@@ -17,6 +17,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	connh "github.com/moby/buildkit/client/connhelper"
 	connhDocker "github.com/moby/buildkit/client/connhelper/dockercontainer"
+	connhPodman "github.com/moby/buildkit/client/connhelper/podmancontainer"
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
@@ -26,11 +27,31 @@ import (
 )
 
 func init() {
-	register("docker-image", &dockerDriver{})
+	register("docker-image", &dockerDriver{
+		cli:           "docker",
+		containerConn: connhDocker.Helper,
+		nameFilter:    "name=^/" + containerNamePrefix,
+	})
+	register("podman-image", &dockerDriver{
+		cli:           "podman",
+		containerConn: connhPodman.Helper,
+		nameFilter:    "name=^" + containerNamePrefix,
+	})
 }
 
 // dockerDriver creates and manages a container, then connects to it
-type dockerDriver struct{}
+//
+// It works with any CLI compatible with docker's, such as podman, both when
+// running as root and rootless.
+type dockerDriver struct {
+	// cli is the name of the CLI used to manage containers.
+	cli string
+	// containerConn connects to a container managed by cli.
+	containerConn func(*url.URL) (*connh.ConnectionHelper, error)
+	// nameFilter is the filter matching engine containers in cli's listings,
+	// since docker prefixes container names with a slash and podman doesn't.
+	nameFilter string
+}
 
 func (d *dockerDriver) Provision(ctx context.Context, target *url.URL, opts *DriverOpts) (Connector, error) {
 	helper, err := d.create(ctx, target.Host+target.Path, opts)
@@ -94,7 +115,7 @@ func (d *dockerDriver) create(ctx context.Context, imageRef string, opts *Driver
 
 	// We collect leftover engine anyway since we garbage collect them at the end
 	// And check if we are in a fallback case then perform fallback to most recent engine
-	leftoverEngines, err := collectLeftoverEngines(ctx)
+	leftoverEngines, err := d.collectLeftoverEngines(ctx)
 	if err != nil {
 		slog.Warn("failed to list containers", "error", err)
 		leftoverEngines = []string{}
@@ -106,15 +127,15 @@ func (d *dockerDriver) create(ctx context.Context, imageRef string, opts *Driver
 
 		// the first leftover engine may not be running, so make sure to start it
 		firstEngine := leftoverEngines[0]
-		cmd := exec.CommandContext(ctx, "docker", "start", firstEngine)
+		cmd := exec.CommandContext(ctx, d.cli, "start", firstEngine)
 		if output, err := traceExec(ctx, cmd); err != nil {
 			return nil, errors.Wrapf(err, "failed to start container: %s", output)
 		}
 
-		garbageCollectEngines(ctx, slog, leftoverEngines[1:])
+		d.garbageCollectEngines(ctx, slog, leftoverEngines[1:])
 
-		return connhDocker.Helper(&url.URL{
-			Scheme: "docker-container",
+		return d.containerConn(&url.URL{
+			Scheme: d.cli + "-container",
 			Host:   firstEngine,
 		})
 	}
@@ -131,27 +152,27 @@ func (d *dockerDriver) create(ctx context.Context, imageRef string, opts *Driver
 	for i, leftoverEngine := range leftoverEngines {
 		// if we already have a container with that name, attempt to start it
 		if leftoverEngine == containerName {
-			cmd := exec.CommandContext(ctx, "docker", "start", leftoverEngine)
+			cmd := exec.CommandContext(ctx, d.cli, "start", leftoverEngine)
 			if output, err := traceExec(ctx, cmd); err != nil {
 				return nil, errors.Wrapf(err, "failed to start container: %s", output)
 			}
-			garbageCollectEngines(ctx, slog, append(leftoverEngines[:i], leftoverEngines[i+1:]...))
-			return connhDocker.Helper(&url.URL{
-				Scheme: "docker-container",
+			d.garbageCollectEngines(ctx, slog, append(leftoverEngines[:i], leftoverEngines[i+1:]...))
+			return d.containerConn(&url.URL{
+				Scheme: d.cli + "-container",
 				Host:   containerName,
 			})
 		}
 	}
 
 	// ensure the image is pulled
-	if _, err := traceExec(ctx, exec.CommandContext(ctx, "docker", "inspect", "--type=image", imageRef), telemetry.Encapsulated()); err != nil {
-		if _, err := traceExec(ctx, exec.CommandContext(ctx, "docker", "pull", imageRef)); err != nil {
+	if _, err := traceExec(ctx, exec.CommandContext(ctx, d.cli, "inspect", "--type=image", imageRef), telemetry.Encapsulated()); err != nil {
+		if _, err := traceExec(ctx, exec.CommandContext(ctx, d.cli, "pull", imageRef)); err != nil {
 			return nil, errors.Wrapf(err, "failed to pull image")
 		}
 	}
 
 	cmd := exec.CommandContext(ctx,
-		"docker",
+		d.cli,
 		"run",
 		"--name", containerName,
 		"-d",
@@ -168,7 +189,13 @@ func (d *dockerDriver) create(ctx context.Context, imageRef string, opts *Driver
 	}
 	if opts.GPUSupport != "" {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", EnvGPUSupport, opts.GPUSupport))
-		cmd.Args = append(cmd.Args, "-e", EnvGPUSupport, "--gpus", "all")
+		cmd.Args = append(cmd.Args, "-e", EnvGPUSupport)
+		if d.cli == "podman" {
+			// podman exposes GPUs through CDI rather than --gpus
+			cmd.Args = append(cmd.Args, "--device", "nvidia.com/gpu=all")
+		} else {
+			cmd.Args = append(cmd.Args, "--gpus", "all")
+		}
 	}
 
 	cmd.Args = append(cmd.Args, imageRef, "--debug")
@@ -182,21 +209,21 @@ func (d *dockerDriver) create(ctx context.Context, imageRef string, opts *Driver
 	// garbage collect any other containers with the same name pattern, which
 	// we assume to be leftover from previous runs of the engine using an older
 	// version
-	garbageCollectEngines(ctx, slog, leftoverEngines)
+	d.garbageCollectEngines(ctx, slog, leftoverEngines)
 
-	return connhDocker.Helper(&url.URL{
-		Scheme: "docker-container",
+	return d.containerConn(&url.URL{
+		Scheme: d.cli + "-container",
 		Host:   containerName,
 	})
 }
 
-func garbageCollectEngines(ctx context.Context, log *slog.Logger, engines []string) {
+func (d *dockerDriver) garbageCollectEngines(ctx context.Context, log *slog.Logger, engines []string) {
 	for _, engine := range engines {
 		if engine == "" {
 			continue
 		}
 		if output, err := traceExec(ctx, exec.CommandContext(ctx,
-			"docker", "rm", "-fv", engine,
+			d.cli, "rm", "-fv", engine,
 		)); err != nil {
 			if !strings.Contains(output, "already in progress") {
 				log.Warn("failed to remove old container", "container", engine, "error", err)
@@ -219,12 +246,12 @@ func traceExec(ctx context.Context, cmd *exec.Cmd, opts ...trace.SpanStartOption
 	return outBuf.String(), nil
 }
 
-func collectLeftoverEngines(ctx context.Context) ([]string, error) {
+func (d *dockerDriver) collectLeftoverEngines(ctx context.Context) ([]string, error) {
 	output, err := exec.CommandContext(ctx,
-		"docker", "ps",
+		d.cli, "ps",
 		"-a",
 		"--no-trunc",
-		"--filter", "name=^/"+containerNamePrefix,
+		"--filter", d.nameFilter,
 		"--format", "{{.Names}}",
 	).CombinedOutput()
 	output = bytes.TrimSpace(output)
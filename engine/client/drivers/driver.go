@@ -27,6 +27,9 @@ type DriverOpts struct {
 
 	DaggerCloudToken string
 	GPUSupport       string
+
+	// TLS configures TLS for engines reached over TCP.
+	TLS TLSOpts
 }
 
 const (
@@ -0,0 +1,77 @@
+package drivers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+const (
+	// the CA certificate to verify the engine's certificate with, enabling
+	// TLS for tcp:// engines
+	EnvTLSCACert = "DAGGER_ENGINE_TLS_CACERT"
+
+	// the client certificate and key to present to the engine, for engines
+	// requiring mutual TLS
+	EnvTLSCert = "DAGGER_ENGINE_TLS_CERT"
+	EnvTLSKey  = "DAGGER_ENGINE_TLS_KEY"
+
+	// the name to verify the engine's certificate against, if it's not the
+	// host of the engine's address
+	EnvTLSServerName = "DAGGER_ENGINE_TLS_SERVER_NAME"
+)
+
+// TLSOpts configures TLS for connecting to an engine over TCP.
+type TLSOpts struct {
+	CACert     string
+	Cert       string
+	Key        string
+	ServerName string
+}
+
+// TLSOptsFromEnv returns the TLS options set in the environment.
+func TLSOptsFromEnv() TLSOpts {
+	return TLSOpts{
+		CACert:     os.Getenv(EnvTLSCACert),
+		Cert:       os.Getenv(EnvTLSCert),
+		Key:        os.Getenv(EnvTLSKey),
+		ServerName: os.Getenv(EnvTLSServerName),
+	}
+}
+
+// Config returns the TLS configuration for connecting to the given host, or
+// nil if TLS isn't enabled.
+func (opts TLSOpts) Config(host string) (*tls.Config, error) {
+	if opts.CACert == "" && opts.Cert == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if opts.ServerName != "" {
+		cfg.ServerName = opts.ServerName
+	}
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("read TLS CA cert: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CACert)
+		}
+	}
+	if opts.Cert != "" {
+		if opts.Key == "" {
+			return nil, fmt.Errorf("%s requires %s", EnvTLSCert, EnvTLSKey)
+		}
+		cert, err := tls.LoadX509KeyPair(opts.Cert, opts.Key)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS client cert: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
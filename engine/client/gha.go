@@ -0,0 +1,59 @@
+package client
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+)
+
+const (
+	// enables caching to the GitHub Actions cache service, set to "true" or
+	// to the scope of the cache
+	ghaCacheEnvName = "_EXPERIMENTAL_DAGGER_GHA_CACHE"
+
+	ghaCacheDefaultScope = "dagger"
+)
+
+// ghaCacheConfigFromEnv returns a cache config importing from and exporting to
+// the GitHub Actions cache service if enabled, or nil otherwise.
+//
+// The cache service is only exposed to actions, so a previous step of the job
+// must export ACTIONS_CACHE_URL and ACTIONS_RUNTIME_TOKEN for it to be used
+// from a run step.
+func ghaCacheConfigFromEnv() (*controlapi.CacheOptionsEntry, error) {
+	scope, ok := os.LookupEnv(ghaCacheEnvName)
+	if !ok || scope == "" || scope == "false" {
+		return nil, nil
+	}
+	if scope == "true" {
+		scope = ghaCacheDefaultScope
+	}
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return nil, errors.New(ghaCacheEnvName + " is only supported in GitHub Actions")
+	}
+
+	var missing []string
+	url := os.Getenv("ACTIONS_CACHE_URL")
+	if url == "" {
+		missing = append(missing, "ACTIONS_CACHE_URL")
+	}
+	token := os.Getenv("ACTIONS_RUNTIME_TOKEN")
+	if token == "" {
+		missing = append(missing, "ACTIONS_RUNTIME_TOKEN")
+	}
+	if len(missing) > 0 {
+		return nil, errors.New(ghaCacheEnvName + " requires " + strings.Join(missing, " and ") +
+			" to be exported by a previous step of the job")
+	}
+
+	return &controlapi.CacheOptionsEntry{
+		Type: "gha",
+		Attrs: map[string]string{
+			"url":   url,
+			"token": token,
+			"scope": scope,
+		},
+	}, nil
+}
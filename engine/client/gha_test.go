@@ -0,0 +1,74 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGHACacheConfigFromEnv(t *testing.T) {
+	setEnv := func(t *testing.T, env map[string]string) {
+		for _, name := range []string{ghaCacheEnvName, "GITHUB_ACTIONS", "ACTIONS_CACHE_URL", "ACTIONS_RUNTIME_TOKEN"} {
+			t.Setenv(name, env[name])
+		}
+	}
+	actions := map[string]string{
+		"GITHUB_ACTIONS":        "true",
+		"ACTIONS_CACHE_URL":     "https://cache.example/",
+		"ACTIONS_RUNTIME_TOKEN": "s3cr3t",
+	}
+	with := func(name, value string) map[string]string {
+		env := map[string]string{name: value}
+		for k, v := range actions {
+			if k != name {
+				env[k] = v
+			}
+		}
+		return env
+	}
+
+	for _, value := range []string{"", "false"} {
+		t.Run("disabled "+value, func(t *testing.T) {
+			setEnv(t, with(ghaCacheEnvName, value))
+			cfg, err := ghaCacheConfigFromEnv()
+			require.NoError(t, err)
+			require.Nil(t, cfg)
+		})
+	}
+
+	t.Run("default scope", func(t *testing.T) {
+		setEnv(t, with(ghaCacheEnvName, "true"))
+		cfg, err := ghaCacheConfigFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, "gha", cfg.Type)
+		require.Equal(t, map[string]string{
+			"url":   "https://cache.example/",
+			"token": "s3cr3t",
+			"scope": "dagger",
+		}, cfg.Attrs)
+	})
+
+	t.Run("custom scope", func(t *testing.T) {
+		setEnv(t, with(ghaCacheEnvName, "lint"))
+		cfg, err := ghaCacheConfigFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, "lint", cfg.Attrs["scope"])
+	})
+
+	t.Run("outside actions", func(t *testing.T) {
+		env := with(ghaCacheEnvName, "true")
+		env["GITHUB_ACTIONS"] = ""
+		setEnv(t, env)
+		_, err := ghaCacheConfigFromEnv()
+		require.ErrorContains(t, err, "only supported in GitHub Actions")
+	})
+
+	t.Run("cache service not exported", func(t *testing.T) {
+		setEnv(t, map[string]string{
+			ghaCacheEnvName:  "true",
+			"GITHUB_ACTIONS": "true",
+		})
+		_, err := ghaCacheConfigFromEnv()
+		require.ErrorContains(t, err, "requires ACTIONS_CACHE_URL and ACTIONS_RUNTIME_TOKEN")
+	})
+}
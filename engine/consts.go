@@ -3,6 +3,8 @@ package engine
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"time"
 )
 
 const (
@@ -16,18 +18,38 @@ const (
 
 	GPUSupportEnv = "_EXPERIMENTAL_DAGGER_GPU_SUPPORT"
 	RunnerHostEnv = "_EXPERIMENTAL_DAGGER_RUNNER_HOST"
+
+	// DaggerHostEnv is the stable name of RunnerHostEnv, taking precedence
+	// over it when both are set.
+	DaggerHostEnv = "DAGGER_HOST"
 )
 
+// RunnerHost returns the connection string of the engine to connect to, e.g.
+// tcp://, unix://, ssh://, docker-container:// or kube-pod://, defaulting to
+// provisioning the engine image matching this version in docker, or in podman
+// if only podman is installed. Setting either variable to an empty value is
+// an error rather than a request for the default.
 func RunnerHost() (string, error) {
-	if v, ok := os.LookupEnv(RunnerHostEnv); ok {
-		return v, nil
+	for _, env := range []string{DaggerHostEnv, RunnerHostEnv} {
+		if v, ok := os.LookupEnv(env); ok {
+			if v == "" {
+				return "", fmt.Errorf("%s is set but empty", env)
+			}
+			return v, nil
+		}
 	}
 
 	tag := Version
 	if os.Getenv(GPUSupportEnv) != "" {
 		tag += "-gpu"
 	}
-	return fmt.Sprintf("docker-image://%s:%s", EngineImageRepo, tag), nil
+	scheme := "docker-image"
+	if _, err := exec.LookPath("docker"); err != nil {
+		if _, err := exec.LookPath("podman"); err == nil {
+			scheme = "podman-image"
+		}
+	}
+	return fmt.Sprintf("%s://%s:%s", scheme, EngineImageRepo, tag), nil
 }
 
 const (
@@ -48,6 +70,9 @@ const (
 	SessionAttachablesEndpoint = "/sessionAttachables"
 	QueryEndpoint              = "/query"
 	ShutdownEndpoint           = "/shutdown"
+	HeartbeatEndpoint          = "/heartbeat"
+	HealthEndpoint             = "/healthz"
+	MetricsEndpoint            = "/metrics"
 
 	// Buildkit-interpreted session keys, can't change
 	SessionIDMetaKey         = "X-Docker-Expose-Session-Uuid"
@@ -56,6 +81,10 @@ const (
 	SessionMethodNameMetaKey = "X-Docker-Expose-Session-Grpc-Method"
 )
 
+// SessionHeartbeatInterval is how often clients send a heartbeat to the
+// engine, which removes the sessions of clients that stop sending them.
+const SessionHeartbeatInterval = 10 * time.Second
+
 var ProxyEnvNames = []string{
 	HTTPProxyEnvName,
 	HTTPSProxyEnvName,
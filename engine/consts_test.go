@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerHost(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		env      map[string]string
+		expected string
+		err      string
+	}{
+		{
+			name:     "dagger host",
+			env:      map[string]string{DaggerHostEnv: "tcp://stable:1234"},
+			expected: "tcp://stable:1234",
+		},
+		{
+			name:     "legacy",
+			env:      map[string]string{RunnerHostEnv: "tcp://legacy:1234"},
+			expected: "tcp://legacy:1234",
+		},
+		{
+			name:     "both",
+			env:      map[string]string{DaggerHostEnv: "tcp://stable:1234", RunnerHostEnv: "tcp://legacy:1234"},
+			expected: "tcp://stable:1234",
+		},
+		{
+			name: "empty dagger host",
+			env:  map[string]string{DaggerHostEnv: "", RunnerHostEnv: "tcp://legacy:1234"},
+			err:  DaggerHostEnv + " is set but empty",
+		},
+		{
+			name: "empty legacy",
+			env:  map[string]string{RunnerHostEnv: ""},
+			err:  RunnerHostEnv + " is set but empty",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, env := range []string{DaggerHostEnv, RunnerHostEnv} {
+				if v, ok := tc.env[env]; ok {
+					t.Setenv(env, v)
+				} else {
+					unsetenv(t, env)
+				}
+			}
+			host, err := RunnerHost()
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, host)
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		unsetenv(t, DaggerHostEnv)
+		unsetenv(t, RunnerHostEnv)
+		host, err := RunnerHost()
+		require.NoError(t, err)
+		require.Contains(t, host, "-image://"+EngineImageRepo+":"+Version)
+	})
+}
+
+// unsetenv unsets an environment variable for the duration of the test.
+func unsetenv(t *testing.T, name string) {
+	t.Helper()
+	// registers restoring the previous value
+	t.Setenv(name, "")
+	os.Unsetenv(name)
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DefaultsFileEnv sets the path of the file configuring the Defaults of
+	// sessions started by the client.
+	DefaultsFileEnv = "DAGGER_DEFAULTS_FILE"
+
+	// DefaultsFileName is the name of the file configuring the Defaults of a
+	// project, looked up from the client's working directory up to the root
+	// if DefaultsFileEnv isn't set.
+	DefaultsFileName = "dagger.defaults.json"
+
+	// BaseImageScheme prefixes the address of an image alias from
+	// Defaults.Images, e.g. base://go.
+	BaseImageScheme = "base://"
+)
+
+// Defaults are values shared by all the pipelines of a project, so that they
+// stay consistent without being repeated in every pipeline.
+type Defaults struct {
+	// Platform is the default platform of the session, overriding the
+	// engine's.
+	Platform string `json:"platform,omitempty"`
+
+	// Images maps aliases to image addresses, used in place of an address as
+	// base://<alias>.
+	Images map[string]string `json:"images,omitempty"`
+
+	// Values are arbitrary named defaults, e.g. an APK mirror.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// DefaultsFromEnv returns the defaults configured for the client, from the
+// file set in DefaultsFileEnv or else the nearest DefaultsFileName.
+func DefaultsFromEnv() (Defaults, error) {
+	path := os.Getenv(DefaultsFileEnv)
+	if path == "" {
+		var err error
+		path, err = findDefaultsFile()
+		if err != nil {
+			return Defaults{}, err
+		}
+		if path == "" {
+			return Defaults{}, nil
+		}
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return Defaults{}, fmt.Errorf("read defaults: %w", err)
+	}
+	var defaults Defaults
+	if err := json.Unmarshal(bs, &defaults); err != nil {
+		return Defaults{}, fmt.Errorf("parse defaults %s: %w", path, err)
+	}
+	return defaults, nil
+}
+
+func findDefaultsFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, DefaultsFileName)
+		_, err := os.Stat(path)
+		switch {
+		case err == nil:
+			return path, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultsFromEnv(t *testing.T) {
+	const contents = `{"platform": "linux/arm64", "images": {"go": "golang:1.22-alpine"}, "values": {"apkMirror": "https://mirror.example.com"}}`
+	expected := Defaults{
+		Platform: "linux/arm64",
+		Images:   map[string]string{"go": "golang:1.22-alpine"},
+		Values:   map[string]string{"apkMirror": "https://mirror.example.com"},
+	}
+
+	t.Run("env", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "defaults.json")
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+		t.Setenv(DefaultsFileEnv, path)
+
+		defaults, err := DefaultsFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, expected, defaults)
+	})
+
+	t.Run("discovered", func(t *testing.T) {
+		t.Setenv(DefaultsFileEnv, "")
+		root := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(root, DefaultsFileName), []byte(contents), 0o600))
+		sub := filepath.Join(root, "a", "b")
+		require.NoError(t, os.MkdirAll(sub, 0o755))
+		chdir(t, sub)
+
+		defaults, err := DefaultsFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, expected, defaults)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "defaults.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"images": []}`), 0o600))
+		t.Setenv(DefaultsFileEnv, path)
+
+		_, err := DefaultsFromEnv()
+		require.Error(t, err)
+	})
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() {
+		require.NoError(t, os.Chdir(wd))
+	})
+}
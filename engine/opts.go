@@ -19,6 +19,14 @@ import (
 const (
 	EngineVersionMetaKey = "X-Dagger-Engine"
 
+	// EngineAuthMetaKey holds the token authenticating a client to the
+	// engine, in the form "Bearer <token>".
+	EngineAuthMetaKey = "X-Dagger-Engine-Authorization"
+
+	// QueryPureMetaKey is set to "true" on responses to queries whose results
+	// won't change if they're queried again, letting clients cache them.
+	QueryPureMetaKey = "X-Dagger-Query-Pure"
+
 	ClientMetadataMetaKey  = "X-Dagger-Client-Metadata"
 	localImportOptsMetaKey = "X-Dagger-Local-Import-Opts"
 	localExportOptsMetaKey = "X-Dagger-Local-Export-Opts"
@@ -68,6 +76,16 @@ type ClientMetadata struct {
 
 	// Disable analytics
 	DoNotTrack bool
+
+	// Resource budget for the session
+	Budget Budget
+
+	// Project defaults for the session
+	Defaults Defaults `json:"defaults,omitempty"`
+
+	// If set, a failed exec opens a terminal in its container as it was when
+	// it failed, instead of failing right away
+	Interactive bool `json:"interactive,omitempty"`
 }
 
 type clientMetadataCtxKey struct{}
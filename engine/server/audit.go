@@ -0,0 +1,267 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/dagger/dagger/dagql/call"
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/slog"
+)
+
+// auditLog records who did what on the engine, for compliance in regulated
+// environments: the queries clients run, the images they pull and push, the
+// host paths they read and the secrets they reference.
+//
+// Each event is written as a line of JSON. Sensitive values, like secret
+// plaintexts, are never recorded.
+type auditLog struct {
+	w  io.Writer
+	mu sync.Mutex
+
+	// clientFromContext returns the client making a call, to attribute events
+	// recorded outside of a client's own handlers
+	clientFromContext func(context.Context) (*daggerClient, error)
+}
+
+const (
+	auditActionQuery       = "query"
+	auditActionImagePull   = "image.pull"
+	auditActionImagePush   = "image.push"
+	auditActionImageBuild  = "image.build"
+	auditActionImageImport = "image.import"
+	auditActionHostRead    = "host.read"
+	auditActionSecret      = "secret"
+)
+
+type auditEvent struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+
+	// who did it
+	SessionID      string `json:"session_id"`
+	ClientID       string `json:"client_id"`
+	Principal      string `json:"principal,omitempty"`
+	ClientHostname string `json:"client_hostname,omitempty"`
+	ClientVersion  string `json:"client_version,omitempty"`
+	// the module whose function is the client, if any
+	Module string `json:"module,omitempty"`
+
+	// the operation and root fields of a query
+	Operation string   `json:"operation,omitempty"`
+	Fields    []string `json:"fields,omitempty"`
+
+	// the call an action was made with and what it applies to: an image
+	// address, a host path or a secret name
+	Call   string `json:"call,omitempty"`
+	Target string `json:"target,omitempty"`
+	// whether the call's result was cached from an earlier one in the session
+	Cached bool `json:"cached,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// auditedCall is the action recorded for a field, and the argument naming
+// what it applies to, if any.
+type auditedCall struct {
+	action string
+	arg    string
+}
+
+// auditedCalls are the fields recorded in the audit log, by type and name.
+//
+// Images pulled by other means, like the base images of a Dockerfile or the
+// images of module SDKs, are recorded by the registry requests made for their
+// manifests.
+var auditedCalls = map[string]auditedCall{
+	"Container.from":        {auditActionImagePull, "address"},
+	"Container.publish":     {auditActionImagePush, "address"},
+	"Container.build":       {auditActionImageBuild, "dockerfile"},
+	"Directory.dockerBuild": {auditActionImageBuild, "dockerfile"},
+	"Container.import":      {auditActionImageImport, "tag"},
+	"Host.directory":        {auditActionHostRead, "path"},
+	"Host.file":             {auditActionHostRead, "path"},
+	"Host.unixSocket":       {auditActionHostRead, "path"},
+	"Host.setSecretFile":    {auditActionHostRead, "path"},
+	"Query.setSecret":       {auditActionSecret, "name"},
+	"Query.secret":          {auditActionSecret, "name"},
+}
+
+// record writes the event, made by the given client or, if it's nil, by the
+// client of the context.
+func (log *auditLog) record(ctx context.Context, client *daggerClient, ev auditEvent) {
+	ev.Time = time.Now().UTC()
+	if client == nil && log.clientFromContext != nil {
+		client, _ = log.clientFromContext(ctx)
+	}
+	if md, err := engine.ClientMetadataFromContext(ctx); err == nil {
+		ev.SessionID = md.SessionID
+		ev.ClientID = md.ClientID
+		ev.ClientHostname = md.ClientHostname
+		ev.ClientVersion = md.ClientVersion
+	}
+	if client != nil {
+		ev.SessionID = client.daggerSession.sessionID
+		ev.ClientID = client.clientID
+		ev.Principal = client.daggerSession.principal
+		if client.mod != nil {
+			ev.Module = client.mod.Name()
+		}
+	}
+
+	bs, err := json.Marshal(ev)
+	if err != nil {
+		slog.Error("failed to encode audit event", "action", ev.Action, "error", err)
+		return
+	}
+	bs = append(bs, '\n')
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if _, err := log.w.Write(bs); err != nil {
+		slog.Error("failed to write audit event", "action", ev.Action, "error", err)
+	}
+}
+
+// observe records the audited calls of every client once they complete,
+// including those whose result is cached.
+func (log *auditLog) observe(ctx context.Context, id *call.ID, cached bool, err error) {
+	base := "Query"
+	if id.Base() != nil {
+		base = id.Base().Type().ToAST().Name()
+	}
+	audited, ok := auditedCalls[base+"."+id.Field()]
+	if !ok {
+		return
+	}
+
+	ev := auditEvent{
+		Action: audited.action,
+		// sensitive args are never part of an ID
+		Call:   id.DisplaySelf(),
+		Cached: cached,
+	}
+	for _, arg := range id.Args() {
+		if audited.arg != "" && arg.Name() == audited.arg {
+			ev.Target = fmt.Sprint(arg.Value().ToInput())
+		}
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	log.record(ctx, nil, ev)
+}
+
+// wrapRegistryHosts returns a RegistryHosts whose requests for manifests are
+// recorded as image pulls and pushes, whatever made them.
+func (log *auditLog) wrapRegistryHosts(hosts docker.RegistryHosts) docker.RegistryHosts {
+	return func(host string) ([]docker.RegistryHost, error) {
+		regHosts, err := hosts(host)
+		if err != nil {
+			return nil, err
+		}
+		wrapped := make([]docker.RegistryHost, len(regHosts))
+		for i, regHost := range regHosts {
+			client := http.DefaultClient
+			if regHost.Client != nil {
+				client = regHost.Client
+			}
+			clientCopy := *client
+			base := clientCopy.Transport
+			if base == nil {
+				base = http.DefaultTransport
+			}
+			clientCopy.Transport = &auditedTransport{log: log, base: base}
+			regHost.Client = &clientCopy
+			wrapped[i] = regHost
+		}
+		return wrapped, nil
+	}
+}
+
+type auditedTransport struct {
+	log  *auditLog
+	base http.RoundTripper
+}
+
+func (t *auditedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	action, target, ok := auditedRegistryRequest(req)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	resp, err := t.base.RoundTrip(req)
+	ev := auditEvent{
+		Action: action,
+		Call:   req.Method + " " + req.URL.Redacted(),
+		Target: target,
+	}
+	switch {
+	case err != nil:
+		ev.Error = err.Error()
+	case resp.StatusCode >= 400:
+		ev.Error = resp.Status
+	}
+	t.log.record(req.Context(), nil, ev)
+	return resp, err
+}
+
+// auditedRegistryRequest returns the action and image address of a request
+// for a manifest, or false if it's any other request.
+func auditedRegistryRequest(req *http.Request) (string, string, bool) {
+	name, ref, ok := strings.Cut(strings.TrimPrefix(req.URL.Path, "/v2/"), "/manifests/")
+	if !ok || name == "" || ref == "" {
+		return "", "", false
+	}
+	var action string
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		action = auditActionImagePull
+	case http.MethodPut:
+		action = auditActionImagePush
+	default:
+		return "", "", false
+	}
+	target := req.URL.Host + "/" + name
+	if strings.Contains(ref, ":") {
+		target += "@" + ref
+	} else {
+		target += ":" + ref
+	}
+	return action, target, true
+}
+
+// aroundOperations records every operation run by the client.
+//
+// Only the root fields are recorded, not the raw query, which may have
+// sensitive values inlined as arguments.
+func (log *auditLog) aroundOperations(client *daggerClient) graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		oc := graphql.GetOperationContext(ctx)
+		if oc.Operation != nil {
+			ev := auditEvent{
+				Action:    auditActionQuery,
+				Operation: string(oc.Operation.Operation),
+			}
+			if oc.OperationName != "" {
+				ev.Operation += " " + oc.OperationName
+			}
+			for _, sel := range oc.Operation.SelectionSet {
+				if field, ok := sel.(*ast.Field); ok {
+					ev.Fields = append(ev.Fields, field.Name)
+				}
+			}
+			log.record(ctx, client, ev)
+		}
+		return next(ctx)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/dagger/dagger/dagql/call"
+)
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	client := &daggerClient{
+		clientID:      "client",
+		daggerSession: &daggerSession{sessionID: "session", principal: "oidc:alice"},
+	}
+	log := &auditLog{
+		w: &buf,
+		clientFromContext: func(context.Context) (*daggerClient, error) {
+			return client, nil
+		},
+	}
+
+	ctrType := &ast.Type{NamedType: "Container", NonNull: true}
+	secretType := &ast.Type{NamedType: "Secret", NonNull: true}
+	ctr := call.New().Append(ctrType, "container", nil, false, 0)
+	from := ctr.Append(ctrType, "from", nil, false, 0,
+		call.NewArgument("address", call.NewLiteralString("alpine:3.20")))
+
+	ctx := context.Background()
+	log.observe(ctx, ctr, false, nil)
+	log.observe(ctx, from, false, nil)
+	log.observe(ctx, from, true, nil)
+	log.observe(ctx, ctr.Append(ctrType, "publish", nil, false, 0,
+		call.NewArgument("address", call.NewLiteralString("registry.example.com/app"))), false, errors.New("denied"))
+	log.observe(ctx, call.New().Append(secretType, "setSecret", nil, true, 0,
+		call.NewArgument("name", call.NewLiteralString("token"))), false, nil)
+
+	events := decodeAuditEvents(t, &buf)
+	require.Len(t, events, 4)
+	for _, ev := range events {
+		require.Equal(t, "session", ev.SessionID)
+		require.Equal(t, "client", ev.ClientID)
+		require.Equal(t, "oidc:alice", ev.Principal)
+		require.NotZero(t, ev.Time)
+	}
+
+	require.Equal(t, auditActionImagePull, events[0].Action)
+	require.Equal(t, "alpine:3.20", events[0].Target)
+	require.Equal(t, `from(address: "alpine:3.20")`, events[0].Call)
+	require.False(t, events[0].Cached)
+	require.Empty(t, events[0].Error)
+
+	// calls are recorded again when they're cached
+	require.Equal(t, auditActionImagePull, events[1].Action)
+	require.True(t, events[1].Cached)
+
+	require.Equal(t, auditActionImagePush, events[2].Action)
+	require.Equal(t, "registry.example.com/app", events[2].Target)
+	require.Equal(t, "denied", events[2].Error)
+
+	require.Equal(t, auditActionSecret, events[3].Action)
+	require.Equal(t, "token", events[3].Target)
+}
+
+func TestAuditLogRegistry(t *testing.T) {
+	var buf bytes.Buffer
+	client := &daggerClient{
+		clientID:      "client",
+		daggerSession: &daggerSession{sessionID: "session"},
+	}
+	log := &auditLog{
+		w: &buf,
+		clientFromContext: func(context.Context) (*daggerClient, error) {
+			return client, nil
+		},
+	}
+
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/denied/manifests/latest" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer registry.Close()
+
+	hosts := log.wrapRegistryHosts(func(host string) ([]docker.RegistryHost, error) {
+		return []docker.RegistryHost{{Host: host}}, nil
+	})
+	regHosts, err := hosts(registry.Listener.Addr().String())
+	require.NoError(t, err)
+	httpClient := regHosts[0].Client
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodHead, "/v2/library/alpine/manifests/3.20"},
+		{http.MethodGet, "/v2/library/alpine/manifests/sha256:abcd"},
+		{http.MethodGet, "/v2/library/alpine/blobs/sha256:abcd"},
+		{http.MethodPut, "/v2/app/manifests/v1"},
+		{http.MethodGet, "/v2/denied/manifests/latest"},
+	} {
+		r, err := http.NewRequest(req.method, registry.URL+req.path, nil)
+		require.NoError(t, err)
+		resp, err := httpClient.Do(r)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	host := registry.Listener.Addr().String()
+	events := decodeAuditEvents(t, &buf)
+	require.Len(t, events, 4)
+	require.Equal(t, auditActionImagePull, events[0].Action)
+	require.Equal(t, host+"/library/alpine:3.20", events[0].Target)
+	require.Equal(t, "client", events[0].ClientID)
+	require.Equal(t, auditActionImagePull, events[1].Action)
+	require.Equal(t, host+"/library/alpine@sha256:abcd", events[1].Target)
+	require.Equal(t, auditActionImagePush, events[2].Action)
+	require.Equal(t, host+"/app:v1", events[2].Target)
+	require.Equal(t, host+"/denied:latest", events[3].Target)
+	require.Equal(t, "401 Unauthorized", events[3].Error)
+}
+
+func decodeAuditEvents(t *testing.T, buf *bytes.Buffer) []auditEvent {
+	t.Helper()
+	var events []auditEvent
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var ev auditEvent
+		require.NoError(t, dec.Decode(&ev))
+		events = append(events, ev)
+	}
+	return events
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxBatchSize is the maximum number of operations accepted in a single
+	// batched request.
+	maxBatchSize = 1000
+
+	// maxBatchConcurrency is the maximum number of operations of a batch
+	// run at the same time.
+	maxBatchConcurrency = 32
+
+	// maxQueryBytes is the maximum size of the body of a query, batched or
+	// not, since it's buffered in memory.
+	maxQueryBytes = 64 << 20
+)
+
+// readBatch reads the body of a POST request and returns the operations it
+// contains if it's a batch, i.e. a JSON array of GraphQL requests. If it isn't
+// a batch, the body is left to be read again by the regular handler. Errors
+// are httpErrors with the status to respond with.
+func readBatch(w http.ResponseWriter, r *http.Request) ([]json.RawMessage, bool, error) {
+	if r.Method != http.MethodPost || r.Body == nil {
+		return nil, false, nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxQueryBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, false, httpErr(fmt.Errorf("query exceeds the maximum of %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		}
+		return nil, false, httpErr(err, http.StatusBadRequest)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return nil, false, nil
+	}
+	var ops []json.RawMessage
+	if err := json.Unmarshal(trimmed, &ops); err != nil {
+		return nil, false, httpErr(fmt.Errorf("decode batch: %w", err), http.StatusBadRequest)
+	}
+	if len(ops) > maxBatchSize {
+		return nil, false, httpErr(fmt.Errorf("batch of %d operations exceeds the maximum of %d", len(ops), maxBatchSize), http.StatusBadRequest)
+	}
+	return ops, true, nil
+}
+
+// serveBatch runs the operations of a batch concurrently against the handler,
+// as if they were sent in separate requests, and writes the JSON array of
+// their responses in the same order. An operation that fails without a
+// GraphQL response gets one with the error, so it doesn't fail the others.
+func serveBatch(w http.ResponseWriter, r *http.Request, h http.Handler, ops []json.RawMessage) error {
+	results := make([]json.RawMessage, len(ops))
+	var eg errgroup.Group
+	eg.SetLimit(maxBatchConcurrency)
+	for i, op := range ops {
+		eg.Go(func() error {
+			req := r.Clone(r.Context())
+			req.Body = io.NopCloser(bytes.NewReader(op))
+			req.ContentLength = int64(len(op))
+			resp := &bufferedResponse{header: http.Header{}}
+			h.ServeHTTP(resp, req)
+			if !json.Valid(resp.body.Bytes()) {
+				results[i] = batchErrorResponse(fmt.Errorf("invalid response (status %d): %s", resp.status, bytes.TrimSpace(resp.body.Bytes())))
+				return nil
+			}
+			results[i] = resp.body.Bytes()
+			return nil
+		})
+	}
+	eg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(results)
+}
+
+func batchErrorResponse(err error) json.RawMessage {
+	resp, _ := json.Marshal(graphql.Response{
+		Errors: gqlerror.List{gqlerror.Wrap(err)},
+	})
+	return resp
+}
+
+// bufferedResponse is an http.ResponseWriter that keeps the response in
+// memory.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (resp *bufferedResponse) Header() http.Header {
+	return resp.header
+}
+
+func (resp *bufferedResponse) WriteHeader(status int) {
+	if resp.status == 0 {
+		resp.status = status
+	}
+}
+
+func (resp *bufferedResponse) Write(p []byte) (int, error) {
+	if resp.status == 0 {
+		resp.status = http.StatusOK
+	}
+	return resp.body.Write(p)
+}
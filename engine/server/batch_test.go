@@ -0,0 +1,79 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	t.Run("not a batch", func(t *testing.T) {
+		body := `{"query":"{__typename}"}`
+		r := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+		_, isBatch, err := readBatch(httptest.NewRecorder(), r)
+		require.NoError(t, err)
+		require.False(t, isBatch)
+		// left to be read again
+		rest, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, body, string(rest))
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/query", bytes.NewReader(make([]byte, maxQueryBytes+1)))
+		_, _, err := readBatch(httptest.NewRecorder(), r)
+		var httpErr httpError
+		require.True(t, errors.As(err, &httpErr))
+		require.Equal(t, http.StatusRequestEntityTooLarge, httpErr.code)
+	})
+
+	t.Run("errors per operation", func(t *testing.T) {
+		var running, maxRunning atomic.Int64
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			body, _ := io.ReadAll(r.Body)
+			if string(body) == `"fail"` {
+				http.Error(w, "boom", http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"data":{}}`))
+		})
+
+		ops := make([]json.RawMessage, 100)
+		for i := range ops {
+			ops[i] = json.RawMessage(`"ok"`)
+		}
+		ops[1] = json.RawMessage(`"fail"`)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/query", nil)
+		require.NoError(t, serveBatch(w, r, h, ops))
+		require.LessOrEqual(t, maxRunning.Load(), int64(maxBatchConcurrency))
+
+		var results []struct {
+			Data   any
+			Errors []struct{ Message string }
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+		require.Len(t, results, len(ops))
+		require.NotNil(t, results[0].Data)
+		require.Empty(t, results[0].Errors)
+		require.Len(t, results[1].Errors, 1)
+		require.Contains(t, results[1].Errors[0].Message, "boom")
+	})
+}
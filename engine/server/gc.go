@@ -2,6 +2,9 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	controlapi "github.com/moby/buildkit/api/services/control"
@@ -183,3 +186,43 @@ func defaultGCPolicy(keep config.DiskSpace) []config.GCPolicy {
 }
 
 const DiskSpacePercentage int64 = 75
+
+// ParseGCPolicy parses a single GC rule in the form k1=v1,k2=v2,...
+//
+// Supported keys are "keepBytes" (e.g. "10GB" or "50%"), "keepDuration"
+// (e.g. "48h"), "all" (whether to also prune internal and shared records)
+// and "filter", which may be repeated; a record is pruned by the rule if it
+// matches any of its filters, e.g. "filter=type==source.local".
+func ParseGCPolicy(val string) (config.GCPolicy, error) {
+	var rule config.GCPolicy
+	for _, kv := range strings.Split(val, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return config.GCPolicy{}, fmt.Errorf("invalid form for gc policy %q", kv)
+		}
+		switch k {
+		case "keepBytes":
+			if err := rule.KeepBytes.UnmarshalText([]byte(v)); err != nil {
+				return config.GCPolicy{}, fmt.Errorf("invalid keepBytes %q: %w", v, err)
+			}
+		case "keepDuration":
+			if err := rule.KeepDuration.UnmarshalText([]byte(v)); err != nil {
+				return config.GCPolicy{}, fmt.Errorf("invalid keepDuration %q: %w", v, err)
+			}
+		case "all":
+			all, err := strconv.ParseBool(v)
+			if err != nil {
+				return config.GCPolicy{}, fmt.Errorf("invalid all %q: %w", v, err)
+			}
+			rule.All = all
+		case "filter":
+			rule.Filters = append(rule.Filters, v)
+		default:
+			return config.GCPolicy{}, fmt.Errorf("unknown gc policy key %q", k)
+		}
+	}
+	if rule.KeepBytes == (config.DiskSpace{}) && rule.KeepDuration.Duration == 0 {
+		return config.GCPolicy{}, fmt.Errorf("gc policy %q must set keepBytes or keepDuration", val)
+	}
+	return rule, nil
+}
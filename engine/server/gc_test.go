@@ -0,0 +1,38 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/cmd/buildkitd/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGCPolicy(t *testing.T) {
+	rule, err := ParseGCPolicy("keepBytes=512MB,keepDuration=48h,filter=type==source.local,filter=type==exec.cachemount")
+	require.NoError(t, err)
+	require.Equal(t, config.GCPolicy{
+		Filters:      []string{"type==source.local", "type==exec.cachemount"},
+		KeepBytes:    config.DiskSpace{Bytes: 512 * 1024 * 1024},
+		KeepDuration: config.Duration{Duration: 48 * time.Hour},
+	}, rule)
+
+	rule, err = ParseGCPolicy("keepBytes=80%,all=true")
+	require.NoError(t, err)
+	require.Equal(t, config.GCPolicy{
+		All:       true,
+		KeepBytes: config.DiskSpace{Percentage: 80},
+	}, rule)
+
+	_, err = ParseGCPolicy("all=true")
+	require.ErrorContains(t, err, "must set keepBytes or keepDuration")
+
+	_, err = ParseGCPolicy("keepBytes")
+	require.ErrorContains(t, err, "invalid form")
+
+	_, err = ParseGCPolicy("keepBytes=1GB,foo=bar")
+	require.ErrorContains(t, err, "unknown gc policy key")
+
+	_, err = ParseGCPolicy("keepDuration=forever")
+	require.ErrorContains(t, err, "invalid keepDuration")
+}
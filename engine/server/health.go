@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/moby/buildkit/util/bklog"
+
+	"github.com/dagger/dagger/engine"
+)
+
+// EngineHealth checks that the engine is able to serve sessions, returning
+// an error describing the problem if it isn't.
+//
+// It's cheap enough to be used by liveness and readiness probes: it only
+// checks that the state directory is still writable, which is the first
+// thing to go when the engine's volume is full or was unmounted.
+func (srv *Server) EngineHealth(ctx context.Context) error {
+	f, err := os.CreateTemp(srv.rootDir, ".health-*")
+	if err != nil {
+		return fmt.Errorf("state directory %s is not writable: %w", srv.rootDir, err)
+	}
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return fmt.Errorf("state directory %s is not writable: %w", srv.rootDir, err)
+	}
+	return nil
+}
+
+type healthResponse struct {
+	Version string `json:"version"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ServeHealth serves the health endpoint of the engine, which responds with
+// 200 when the engine is healthy and 503 otherwise.
+//
+// It doesn't require a session, so it can be probed by orchestrators.
+func (srv *Server) ServeHealth(w http.ResponseWriter, r *http.Request) {
+	resp := healthResponse{Version: engine.Version}
+	status := http.StatusOK
+	if err := srv.EngineHealth(r.Context()); err != nil {
+		bklog.G(r.Context()).WithError(err).Warn("engine is unhealthy")
+		resp.Error = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/moby/buildkit/util/bklog"
+
+	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/engine"
+)
+
+// SessionLeaseTTL is how long a session is kept after its main client caller
+// last sent a heartbeat, after which the client is assumed to have crashed and
+// the session is removed along with its services and containers.
+//
+// Sessions of clients that never send heartbeats are only removed once all of
+// their connections are closed.
+const SessionLeaseTTL = 6 * engine.SessionHeartbeatInterval
+
+// serveHeartbeat renews the lease of the client's session.
+func (srv *Server) serveHeartbeat(w http.ResponseWriter, r *http.Request, client *daggerClient) error {
+	if client.clientID == client.daggerSession.mainClientCallerID {
+		client.daggerSession.lastHeartbeat.Store(time.Now().UnixNano())
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// leaseExpired returns whether the session's main client caller sent
+// heartbeats and stopped.
+func (sess *daggerSession) leaseExpired(now time.Time) bool {
+	last := sess.lastHeartbeat.Load()
+	return last != 0 && now.Sub(time.Unix(0, last)) > SessionLeaseTTL
+}
+
+// reapStaleSessions periodically removes the sessions whose lease expired,
+// until the server is closed.
+func (srv *Server) reapStaleSessions() {
+	ticker := time.NewTicker(engine.SessionHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-srv.closed:
+			return
+		case now := <-ticker.C:
+			srv.daggerSessionsMu.RLock()
+			var stale []*daggerSession
+			for _, sess := range srv.daggerSessions {
+				if sess.leaseExpired(now) {
+					stale = append(stale, sess)
+				}
+			}
+			srv.daggerSessionsMu.RUnlock()
+
+			for _, sess := range stale {
+				ctx := context.Background()
+				bklog.G(ctx).
+					WithField("session_id", sess.sessionID).
+					WithField("client_hostname", sess.clientHostname).
+					Warn("session lease expired; removing stale session")
+				if err := srv.forceRemoveDaggerSession(ctx, sess); err != nil {
+					bklog.G(ctx).WithError(err).Error("failed to remove stale session")
+				}
+			}
+		}
+	}
+}
+
+// forceRemoveDaggerSession removes a session even if its clients are still
+// connected, stopping its services and releasing its containers.
+func (srv *Server) forceRemoveDaggerSession(ctx context.Context, sess *daggerSession) error {
+	sess.stateMu.Lock()
+	defer sess.stateMu.Unlock()
+	if sess.state != sessionStateInitialized {
+		return nil
+	}
+	return srv.removeDaggerSession(ctx, sess)
+}
+
+// canManageSession returns whether the client may see and remove the
+// session. Admins can manage every session, other clients only those started
+// by the same principal. Nested clients, like module functions, can only see
+// their own session.
+func (srv *Server) canManageSession(client *daggerClient, sess *daggerSession) bool {
+	current := client.daggerSession
+	if sess == current {
+		return true
+	}
+	if client.clientID != current.mainClientCallerID {
+		return false
+	}
+	return srv.isAdmin(current.principal) || sess.principal == current.principal
+}
+
+// isEngineAdmin returns whether the client may administer the whole engine,
+// e.g. prune its cache. Nested clients, like module functions, never may.
+func (srv *Server) isEngineAdmin(client *daggerClient) bool {
+	sess := client.daggerSession
+	return client.clientID == sess.mainClientCallerID && srv.isAdmin(sess.principal)
+}
+
+// CheckEngineAdmin returns an error unless the client may administer the
+// whole engine.
+func (srv *Server) CheckEngineAdmin(ctx context.Context) error {
+	client, err := srv.clientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !srv.isEngineAdmin(client) {
+		return errors.New("only admins of the engine can do this")
+	}
+	return nil
+}
+
+// EngineSessions lists the sessions of the engine the client may manage, for
+// administering shared engines.
+func (srv *Server) EngineSessions(ctx context.Context) ([]core.EngineSession, error) {
+	current, err := srv.clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.daggerSessionsMu.RLock()
+	sessions := make([]*daggerSession, 0, len(srv.daggerSessions))
+	for _, sess := range srv.daggerSessions {
+		sessions = append(sessions, sess)
+	}
+	srv.daggerSessionsMu.RUnlock()
+
+	now := time.Now()
+	result := make([]core.EngineSession, 0, len(sessions))
+	for _, sess := range sessions {
+		sess.stateMu.RLock()
+		initialized := sess.state == sessionStateInitialized
+		sess.stateMu.RUnlock()
+		if !initialized || !srv.canManageSession(current, sess) {
+			continue
+		}
+		info := core.EngineSession{
+			SessionID:      sess.sessionID,
+			ClientHostname: sess.clientHostname,
+			Current:        sess == current.daggerSession,
+			Stale:          sess.leaseExpired(now),
+			Services:       []string{},
+		}
+		if last := sess.lastHeartbeat.Load(); last != 0 {
+			info.LastHeartbeat = time.Unix(0, last).UTC().Format(time.RFC3339)
+		}
+		for _, svc := range sess.services.Running() {
+			info.Services = append(info.Services, svc.Host)
+		}
+		result = append(result, info)
+	}
+	slices.SortFunc(result, func(a, b core.EngineSession) int {
+		return strings.Compare(a.SessionID, b.SessionID)
+	})
+	return result, nil
+}
+
+// RemoveEngineSession forcibly removes another session of the engine, e.g.
+// one left behind by a crashed client.
+func (srv *Server) RemoveEngineSession(ctx context.Context, sessionID string) error {
+	current, err := srv.clientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if sessionID == current.daggerSession.sessionID {
+		return errors.New("cannot remove the current session")
+	}
+
+	srv.daggerSessionsMu.RLock()
+	sess, ok := srv.daggerSessions[sessionID]
+	srv.daggerSessionsMu.RUnlock()
+	// don't reveal the sessions the client can't manage
+	if !ok || !srv.canManageSession(current, sess) {
+		return fmt.Errorf("session %q not found", sessionID)
+	}
+
+	bklog.G(ctx).
+		WithField("session_id", sess.sessionID).
+		WithField("client_hostname", sess.clientHostname).
+		Warn("removing session on request")
+	return srv.forceRemoveDaggerSession(context.WithoutCancel(ctx), sess)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanManageSession(t *testing.T) {
+	newSession := func(principal string) *daggerSession {
+		return &daggerSession{mainClientCallerID: "main", principal: principal}
+	}
+	mainClient := func(sess *daggerSession) *daggerClient {
+		return &daggerClient{daggerSession: sess, clientID: "main"}
+	}
+	nestedClient := func(sess *daggerSession) *daggerClient {
+		return &daggerClient{daggerSession: sess, clientID: "nested"}
+	}
+
+	srv := &Server{
+		authenticator:   AnyAuthenticator{},
+		adminPrincipals: []string{"oidc:repo:my-org/infra:*"},
+	}
+	alice := newSession("oidc:repo:my-org/app:ref:refs/heads/main")
+	aliceAgain := newSession("oidc:repo:my-org/app:ref:refs/heads/main")
+	bob := newSession("oidc:repo:other-org/app:ref:refs/heads/main")
+	admin := newSession("oidc:repo:my-org/infra:ref:refs/heads/main")
+
+	require.True(t, srv.canManageSession(mainClient(alice), alice))
+	require.True(t, srv.canManageSession(mainClient(alice), aliceAgain))
+	require.False(t, srv.canManageSession(mainClient(alice), bob))
+	require.False(t, srv.canManageSession(mainClient(bob), alice))
+	require.False(t, srv.canManageSession(mainClient(alice), admin))
+
+	require.True(t, srv.canManageSession(mainClient(admin), alice))
+	require.True(t, srv.canManageSession(mainClient(admin), bob))
+
+	// nested clients, like module functions, don't act for the principal
+	require.True(t, srv.canManageSession(nestedClient(admin), admin))
+	require.False(t, srv.canManageSession(nestedClient(admin), alice))
+	require.False(t, srv.canManageSession(nestedClient(alice), aliceAgain))
+
+	require.True(t, srv.isEngineAdmin(mainClient(admin)))
+	require.False(t, srv.isEngineAdmin(nestedClient(admin)))
+	require.False(t, srv.isEngineAdmin(mainClient(alice)))
+
+	t.Run("unauthenticated engine", func(t *testing.T) {
+		srv := &Server{}
+		one, other := newSession(""), newSession("")
+		require.True(t, srv.canManageSession(mainClient(one), other))
+		require.False(t, srv.canManageSession(nestedClient(one), other))
+		require.True(t, srv.isEngineAdmin(mainClient(one)))
+		require.False(t, srv.isEngineAdmin(nestedClient(one)))
+	})
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	bkclient "github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/util/bklog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/dagger/dagger/engine"
+)
+
+const metricsNamespace = "dagger_engine"
+
+// cacheSizeInterval is how often the size of the cache is summed up for the
+// metrics, which walks every record so it's too expensive to do on scrape.
+const cacheSizeInterval = time.Minute
+
+// cacheSizeTimeout bounds how long summing up the size of the cache may take.
+const cacheSizeTimeout = 10 * time.Second
+
+// engineMetrics holds the prometheus collectors exposed on the metrics
+// endpoint. Most of them are read from existing engine state at scrape time
+// rather than being updated on every change.
+type engineMetrics struct {
+	registry *prometheus.Registry
+
+	solveDuration prometheus.Histogram
+
+	// last known size of the cache, refreshed by refreshCacheSize
+	cacheSize atomic.Int64
+}
+
+func newEngineMetrics(srv *Server) *engineMetrics {
+	m := &engineMetrics{
+		registry: prometheus.NewRegistry(),
+		solveDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "solve_duration_seconds",
+			Help:      "Duration of LLB solves made by clients of the engine.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.solveDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "sessions_active",
+			Help:      "Number of dagger sessions currently connected to the engine.",
+		}, func() float64 {
+			srv.daggerSessionsMu.RLock()
+			defer srv.daggerSessionsMu.RUnlock()
+			return float64(len(srv.daggerSessions))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "execs_inflight",
+			Help:      "Number of container processes currently running.",
+		}, func() float64 {
+			if srv.worker == nil {
+				return 0
+			}
+			return float64(srv.worker.RunningExecs())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_size_bytes",
+			Help:      "Total size of the engine's local cache, as of the last minute.",
+		}, func() float64 {
+			return float64(m.cacheSize.Load())
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "registry_requests_total",
+			Help:      "Number of requests made to registries.",
+		}, func() float64 {
+			return float64(srv.registryLimiter.requests.Load())
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "registry_requests_deduped_total",
+			Help:      "Number of registry requests served by an identical in-flight request.",
+		}, func() float64 {
+			return float64(srv.registryLimiter.deduped.Load())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "registry_requests_inflight",
+			Help:      "Number of registry requests currently in flight.",
+		}, func() float64 {
+			return float64(srv.registryLimiter.inflight.Load())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "registry_requests_waiting",
+			Help:      "Number of registry requests waiting for a per-host slot.",
+		}, func() float64 {
+			return float64(srv.registryLimiter.waiting.Load())
+		}),
+	)
+	return m
+}
+
+// refreshCacheSize updates the cache size gauge with size every interval
+// until done is closed.
+func (m *engineMetrics) refreshCacheSize(done <-chan struct{}, interval time.Duration, size func(context.Context) (int64, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), cacheSizeTimeout)
+		if n, err := size(ctx); err != nil {
+			bklog.G(ctx).WithError(err).Warn("failed to get cache disk usage for metrics")
+		} else {
+			m.cacheSize.Store(n)
+		}
+		cancel()
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (srv *Server) cacheSize(ctx context.Context) (int64, error) {
+	du, err := srv.baseWorker.DiskUsage(ctx, bkclient.DiskUsageInfo{})
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, r := range du {
+		if r.Size > 0 {
+			size += r.Size
+		}
+	}
+	return size, nil
+}
+
+// ServeMetrics serves the engine's metrics in the prometheus exposition
+// format.
+func (srv *Server) ServeMetrics(w http.ResponseWriter, r *http.Request) {
+	promhttp.HandlerFor(srv.metrics.registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// MonitoringHandler serves the metrics and health endpoints, and nothing
+// else, for a listener dedicated to scrapers and probes that don't have
+// credentials.
+func (srv *Server) MonitoringHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(engine.MetricsEndpoint, srv.ServeMetrics)
+	mux.HandleFunc(engine.HealthEndpoint, srv.ServeHealth)
+	return mux
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/engine"
+)
+
+func TestMetrics(t *testing.T) {
+	srv := &Server{registryLimiter: newRegistryLimiter(0)}
+	srv.metrics = newEngineMetrics(srv)
+
+	var calls atomic.Int64
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		srv.metrics.refreshCacheSize(done, 10*time.Millisecond, func(context.Context) (int64, error) {
+			return 42 * calls.Add(1), nil
+		})
+	}()
+	require.Eventually(t, func() bool {
+		return srv.metrics.cacheSize.Load() >= 84
+	}, 5*time.Second, 10*time.Millisecond)
+	close(done)
+	<-stopped
+
+	t.Run("scrape", func(t *testing.T) {
+		before := calls.Load()
+		resp := httptest.NewRecorder()
+		srv.MonitoringHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, engine.MetricsEndpoint, nil))
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), "dagger_engine_cache_size_bytes ")
+		require.Contains(t, resp.Body.String(), "dagger_engine_sessions_active 0")
+		// the size of the cache isn't summed up on scrape
+		require.Equal(t, before, calls.Load())
+	})
+
+	t.Run("monitoring only", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		srv.MonitoringHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodPost, engine.QueryEndpoint, nil))
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
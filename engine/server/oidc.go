@@ -0,0 +1,325 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/dagger/dagger/engine"
+)
+
+// Authenticator authenticates the clients connecting to the engine, e.g. to
+// gate an engine shared over the network.
+type Authenticator interface {
+	// Authenticate returns the principal the request is made by, e.g.
+	// "oidc:repo:my-org/my-repo:ref:refs/heads/main", or an error if the
+	// request is not allowed.
+	Authenticate(r *http.Request) (string, error)
+}
+
+// Authenticate checks the request of a client connecting to the engine
+// against the engine's Authenticator, if any, returning the request with its
+// principal attached.
+func (srv *Server) Authenticate(r *http.Request) (*http.Request, error) {
+	if srv.authenticator == nil {
+		return r, nil
+	}
+	principal, err := srv.authenticator.Authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)), nil
+}
+
+type principalKey struct{}
+
+// principalFromContext returns the principal of an authenticated request, or
+// an empty string if the engine doesn't authenticate its clients.
+func principalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// isAdmin returns whether the principal may administer the whole engine, e.g.
+// prune its cache or remove the sessions of others. Engines that don't
+// authenticate their clients trust anyone who can reach them.
+func (srv *Server) isAdmin(principal string) bool {
+	if srv.authenticator == nil {
+		return true
+	}
+	for _, pattern := range srv.adminPrincipals {
+		if matchSubject(pattern, principal) {
+			return true
+		}
+	}
+	return false
+}
+
+// OIDCConfig configures the authentication of clients with OpenID Connect ID
+// tokens, e.g. issued by an organization's identity provider or a CI system.
+type OIDCConfig struct {
+	// Issuer is the URL of the identity provider, which must serve its
+	// discovery document at /.well-known/openid-configuration.
+	Issuer string
+
+	// Audience is the audience that tokens must be issued for.
+	Audience string
+
+	// Subjects are the patterns of the subjects allowed to connect, where *
+	// matches any sequence of characters, e.g. repo:my-org/*. If empty, any
+	// subject is allowed.
+	Subjects []string
+}
+
+// oidcKeysRefreshInterval is the minimum interval between two fetches of the
+// identity provider's keys, which are fetched again when a token is signed by
+// an unknown key.
+const oidcKeysRefreshInterval = time.Minute
+
+var oidcSigningMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+}
+
+// OIDCAuthenticator authenticates clients with short-lived ID tokens signed
+// by an OpenID Connect identity provider.
+type OIDCAuthenticator struct {
+	OIDCConfig
+
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	jwksURI   string
+	refreshed time.Time
+}
+
+var _ Authenticator = (*OIDCAuthenticator)(nil)
+
+func NewOIDCAuthenticator(cfg OIDCConfig) (*OIDCAuthenticator, error) {
+	if cfg.Issuer == "" {
+		return nil, errors.New("OIDC issuer must be set")
+	}
+	if cfg.Audience == "" {
+		return nil, errors.New("OIDC audience must be set")
+	}
+	return &OIDCAuthenticator{
+		OIDCConfig: cfg,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (a *OIDCAuthenticator) Authenticate(r *http.Request) (string, error) {
+	raw, ok := strings.CutPrefix(r.Header.Get(engine.EngineAuthMetaKey), "Bearer ")
+	if !ok || raw == "" {
+		return "", errors.New("missing engine token")
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return a.key(r.Context(), kid)
+		},
+		jwt.WithIssuer(a.Issuer),
+		jwt.WithAudience(a.Audience),
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return "", fmt.Errorf("invalid engine token: %w", err)
+	}
+
+	principal := "oidc:" + claims.Subject
+	if len(a.Subjects) == 0 {
+		return principal, nil
+	}
+	for _, pattern := range a.Subjects {
+		if matchSubject(pattern, claims.Subject) {
+			return principal, nil
+		}
+	}
+	return "", fmt.Errorf("subject %q is not allowed to connect", claims.Subject)
+}
+
+// key returns the identity provider's key with the given ID, fetching its
+// keys again if it's unknown.
+func (a *OIDCAuthenticator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(a.refreshed) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := a.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (a *OIDCAuthenticator) refreshKeys(ctx context.Context) error {
+	a.refreshed = time.Now()
+
+	if a.jwksURI == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.getJSON(ctx, strings.TrimSuffix(a.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return fmt.Errorf("discover OIDC provider: %w", err)
+		}
+		if discovery.Issuer != a.Issuer {
+			return fmt.Errorf("OIDC provider issuer %q does not match %q", discovery.Issuer, a.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return errors.New("OIDC provider has no jwks_uri")
+		}
+		a.jwksURI = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := a.getJSON(ctx, a.jwksURI, &jwks); err != nil {
+		return fmt.Errorf("fetch OIDC provider keys: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// ignore keys we can't use rather than failing all tokens
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	a.keys = keys
+	return nil
+}
+
+func (a *OIDCAuthenticator) getJSON(ctx context.Context, url string, dest any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
+
+// jsonWebKey is a public key in the JWK format, as served by the jwks_uri of
+// an identity provider.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeJWKInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bs), nil
+}
+
+// matchSubject returns whether a subject matches a pattern in which *
+// matches any sequence of characters, including /.
+func matchSubject(pattern, subject string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == subject
+	}
+	if !strings.HasPrefix(subject, parts[0]) {
+		return false
+	}
+	subject = subject[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(subject, part)
+		if i < 0 {
+			return false
+		}
+		subject = subject[i+len(part):]
+	}
+	return len(subject) >= len(last) && strings.HasSuffix(subject, last)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/engine"
+)
+
+func TestOIDCAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": issuer + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	provider := httptest.NewServer(mux)
+	defer provider.Close()
+	issuer = provider.URL
+
+	auth, err := NewOIDCAuthenticator(OIDCConfig{
+		Issuer:   issuer,
+		Audience: "dagger-engine",
+		Subjects: []string{"repo:my-org/*:ref:refs/heads/main"},
+	})
+	require.NoError(t, err)
+
+	var authenticateAs func(jwt.RegisteredClaims) (string, error)
+	authenticate := func(claims jwt.RegisteredClaims) error {
+		_, err := authenticateAs(claims)
+		return err
+	}
+	authenticateAs = func(claims jwt.RegisteredClaims) (string, error) {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		r := httptest.NewRequest(http.MethodPost, "/query", nil)
+		r.Header.Set(engine.EngineAuthMetaKey, "Bearer "+signed)
+		return auth.Authenticate(r)
+	}
+	valid := jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   "repo:my-org/my-repo:ref:refs/heads/main",
+		Audience:  jwt.ClaimStrings{"dagger-engine"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+	}
+
+	principal, err := authenticateAs(valid)
+	require.NoError(t, err)
+	require.Equal(t, "oidc:repo:my-org/my-repo:ref:refs/heads/main", principal)
+
+	claims := valid
+	claims.Audience = jwt.ClaimStrings{"something-else"}
+	require.ErrorContains(t, authenticate(claims), "invalid engine token")
+
+	claims = valid
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-5 * time.Minute))
+	require.ErrorContains(t, authenticate(claims), "invalid engine token")
+
+	claims = valid
+	claims.ExpiresAt = nil
+	require.ErrorContains(t, authenticate(claims), "invalid engine token")
+
+	claims = valid
+	claims.Subject = "repo:other-org/my-repo:ref:refs/heads/main"
+	require.ErrorContains(t, authenticate(claims), "is not allowed to connect")
+
+	r := httptest.NewRequest(http.MethodPost, "/query", nil)
+	_, err = auth.Authenticate(r)
+	require.ErrorContains(t, err, "missing engine token")
+}
+
+func TestMatchSubject(t *testing.T) {
+	require.True(t, matchSubject("repo:my-org/my-repo", "repo:my-org/my-repo"))
+	require.False(t, matchSubject("repo:my-org/my-repo", "repo:my-org/my-repo2"))
+	require.True(t, matchSubject("repo:my-org/*", "repo:my-org/my-repo:ref:refs/heads/main"))
+	require.True(t, matchSubject("repo:*:ref:refs/heads/main", "repo:my-org/my-repo:ref:refs/heads/main"))
+	require.False(t, matchSubject("repo:*:ref:refs/heads/main", "repo:my-org/my-repo:ref:refs/heads/dev"))
+	require.True(t, matchSubject("*", "anything"))
+	require.False(t, matchSubject("a*b*c", "acb"))
+	require.True(t, matchSubject("a*b*c", "abbc"))
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
+)
+
+// maxDedupedResponseSize is the largest response body that will be buffered
+// in memory and shared between deduplicated requests. Manifests and indexes
+// are well below this; anything larger is not shared.
+const maxDedupedResponseSize = 4 << 20
+
+// registryLimiter caps the number of concurrent requests made to each
+// registry host and collapses identical in-flight manifest requests into a
+// single upstream request.
+type registryLimiter struct {
+	// maxPerHost is the maximum number of concurrent requests per registry
+	// host; 0 means unlimited
+	maxPerHost int64
+
+	semsMu sync.Mutex
+	sems   map[string]*semaphore.Weighted
+
+	flight singleflight.Group
+
+	// metrics
+	inflight atomic.Int64
+	waiting  atomic.Int64
+	requests atomic.Int64
+	deduped  atomic.Int64
+}
+
+func newRegistryLimiter(maxPerHost int) *registryLimiter {
+	return &registryLimiter{
+		maxPerHost: int64(maxPerHost),
+		sems:       map[string]*semaphore.Weighted{},
+	}
+}
+
+// Wrap returns a RegistryHosts that routes all requests made to the resolved
+// hosts through the limiter.
+func (l *registryLimiter) Wrap(hosts docker.RegistryHosts) docker.RegistryHosts {
+	return func(host string) ([]docker.RegistryHost, error) {
+		regHosts, err := hosts(host)
+		if err != nil {
+			return nil, err
+		}
+		wrapped := make([]docker.RegistryHost, len(regHosts))
+		for i, regHost := range regHosts {
+			client := http.DefaultClient
+			if regHost.Client != nil {
+				client = regHost.Client
+			}
+			clientCopy := *client
+			base := clientCopy.Transport
+			if base == nil {
+				base = http.DefaultTransport
+			}
+			clientCopy.Transport = &limitedTransport{limiter: l, base: base}
+			regHost.Client = &clientCopy
+			wrapped[i] = regHost
+		}
+		return wrapped, nil
+	}
+}
+
+func (l *registryLimiter) LogMetrics(entry *logrus.Entry) *logrus.Entry {
+	return entry.
+		WithField("registry-requests-inflight", l.inflight.Load()).
+		WithField("registry-requests-waiting", l.waiting.Load()).
+		WithField("registry-requests-total", l.requests.Load()).
+		WithField("registry-requests-deduped", l.deduped.Load())
+}
+
+func (l *registryLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.requests.Add(1)
+	if l.maxPerHost <= 0 {
+		l.inflight.Add(1)
+		return func() { l.inflight.Add(-1) }, nil
+	}
+
+	l.semsMu.Lock()
+	sem, ok := l.sems[host]
+	if !ok {
+		sem = semaphore.NewWeighted(l.maxPerHost)
+		l.sems[host] = sem
+	}
+	l.semsMu.Unlock()
+
+	l.waiting.Add(1)
+	err := sem.Acquire(ctx, 1)
+	l.waiting.Add(-1)
+	if err != nil {
+		return nil, err
+	}
+	l.inflight.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.inflight.Add(-1)
+			sem.Release(1)
+		})
+	}, nil
+}
+
+type limitedTransport struct {
+	limiter *registryLimiter
+	base    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isDedupable(req) {
+		return t.roundTripDeduped(req)
+	}
+	return t.roundTrip(req)
+}
+
+func (t *limitedTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// hold the slot until the body has been consumed, so that blob downloads
+	// count against the limit for their whole duration
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type sharedResponse struct {
+	resp *http.Response
+	body []byte
+}
+
+func (t *limitedTransport) roundTripDeduped(req *http.Request) (*http.Response, error) {
+	// requests are only shared between callers presenting the same
+	// credentials and asking for the same representation
+	key := strings.Join([]string{
+		req.Method,
+		req.URL.String(),
+		req.Header.Get("Authorization"),
+		strings.Join(req.Header.Values("Accept"), ","),
+	}, "\x00")
+
+	// the shared request is made without the first caller's cancellation, so
+	// that it isn't failed for every caller when that one goes away; each
+	// caller stops waiting on its own context instead
+	sharedReq := req.Clone(context.WithoutCancel(req.Context()))
+	ch := t.limiter.flight.DoChan(key, func() (any, error) {
+		resp, err := t.roundTrip(sharedReq)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.ContentLength > maxDedupedResponseSize {
+			return nil, &tooLargeError{}
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxDedupedResponseSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > maxDedupedResponseSize {
+			return nil, &tooLargeError{}
+		}
+		return &sharedResponse{resp: resp, body: body}, nil
+	})
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-req.Context().Done():
+		return nil, context.Cause(req.Context())
+	}
+	v, err, shared := res.Val, res.Err, res.Shared
+	if _, ok := err.(*tooLargeError); ok {
+		// too large to buffer, let each caller stream it independently
+		return t.roundTrip(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sr := v.(*sharedResponse)
+	if shared {
+		t.limiter.deduped.Add(1)
+	}
+
+	resp := new(http.Response)
+	*resp = *sr.resp
+	resp.Header = sr.resp.Header.Clone()
+	resp.Request = req
+	resp.Body = io.NopCloser(bytes.NewReader(sr.body))
+	return resp, nil
+}
+
+// isDedupable reports whether the request is a read of a manifest, which is
+// small and commonly requested by many concurrent pulls of the same image.
+func isDedupable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	return strings.Contains(req.URL.Path, "/manifests/")
+}
+
+type tooLargeError struct{}
+
+func (*tooLargeError) Error() string {
+	return "registry response too large to share"
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
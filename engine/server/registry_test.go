@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// blockingRegistry serves manifests once unblocked, counting the requests
+// that reach it.
+type blockingRegistry struct {
+	hits    atomic.Int64
+	arrived chan struct{}
+	unblock chan struct{}
+}
+
+func newBlockingRegistry(t *testing.T) (*blockingRegistry, *httptest.Server) {
+	reg := &blockingRegistry{
+		arrived: make(chan struct{}, 10),
+		unblock: make(chan struct{}),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.hits.Add(1)
+		reg.arrived <- struct{}{}
+		<-reg.unblock
+		w.Write([]byte("manifest"))
+	}))
+	t.Cleanup(srv.Close)
+	return reg, srv
+}
+
+func getManifest(ctx context.Context, transport http.RoundTripper, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/v2/foo/manifests/latest", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestRegistryLimiterDedupe(t *testing.T) {
+	reg, srv := newBlockingRegistry(t)
+	limiter := newRegistryLimiter(0)
+	transport := &limitedTransport{limiter: limiter, base: http.DefaultTransport}
+
+	var wg sync.WaitGroup
+	results := make([]string, 3)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := getManifest(context.Background(), transport, srv.URL)
+			require.NoError(t, err)
+			results[i] = body
+		}()
+	}
+
+	<-reg.arrived
+	// give the others time to join the in-flight request
+	time.Sleep(100 * time.Millisecond)
+	close(reg.unblock)
+	wg.Wait()
+
+	require.EqualValues(t, 1, reg.hits.Load())
+	require.Equal(t, []string{"manifest", "manifest", "manifest"}, results)
+}
+
+func TestRegistryLimiterDedupeCancel(t *testing.T) {
+	reg, srv := newBlockingRegistry(t)
+	limiter := newRegistryLimiter(0)
+	transport := &limitedTransport{limiter: limiter, base: http.DefaultTransport}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := getManifest(firstCtx, transport, srv.URL)
+		firstErr <- err
+	}()
+	<-reg.arrived
+
+	secondBody := make(chan string, 1)
+	go func() {
+		body, err := getManifest(context.Background(), transport, srv.URL)
+		require.NoError(t, err)
+		secondBody <- body
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// the first caller going away fails only its own request
+	cancelFirst()
+	require.ErrorIs(t, <-firstErr, context.Canceled)
+
+	close(reg.unblock)
+	require.Equal(t, "manifest", <-secondBody)
+	require.EqualValues(t, 1, reg.hits.Load())
+}
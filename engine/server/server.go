@@ -133,6 +133,10 @@ type Server struct {
 	cacheExporters map[string]remotecache.ResolveCacheExporterFunc
 	cacheImporters map[string]remotecache.ResolveCacheImporterFunc
 
+	// remote caches configured on the engine for every session
+	cacheImportCfgs []*controlapi.CacheOptionsEntry
+	cacheExportCfgs []*controlapi.CacheOptionsEntry
+
 	//
 	// worker/executor-specific config+state
 	//
@@ -149,6 +153,8 @@ type Server struct {
 	enabledPlatforms []ocispecs.Platform
 	defaultPlatform  ocispecs.Platform
 	registryHosts    docker.RegistryHosts
+	registryLimiter  *registryLimiter
+	metrics          *engineMetrics
 
 	//
 	// telemetry config+state
@@ -157,6 +163,25 @@ type Server struct {
 	telemetryPubSub *enginetel.PubSub
 	buildkitLogSink io.Writer
 
+	//
+	// schema config
+	//
+
+	strictSchema bool
+
+	//
+	// auth
+	//
+
+	authenticator   Authenticator
+	adminPrincipals []string
+
+	// records who did what on the engine, if configured
+	auditLog *auditLog
+
+	// caps the budget declared by each session's client
+	sessionBudget engine.Budget
+
 	//
 	// gc related
 	//
@@ -168,6 +193,9 @@ type Server struct {
 	//
 	daggerSessions   map[string]*daggerSession // session id -> session state
 	daggerSessionsMu sync.RWMutex
+
+	// closed when the server is closed, to stop background tasks
+	closed chan struct{}
 }
 
 type NewServerOpts struct {
@@ -175,6 +203,47 @@ type NewServerOpts struct {
 	Name   string
 
 	TelemetryPubSub *enginetel.PubSub
+
+	// StrictSchema rejects queries that select deprecated fields or pass
+	// deprecated arguments.
+	StrictSchema bool
+
+	// RegistryMaxConcurrentRequests caps the number of concurrent requests
+	// made to any single registry host. 0 means unlimited.
+	RegistryMaxConcurrentRequests int
+
+	// CacheImportConfigs and CacheExportConfigs configure remote caches (e.g.
+	// an S3 bucket) used by every session, in addition to any configured by
+	// the client.
+	CacheImportConfigs []*controlapi.CacheOptionsEntry
+	CacheExportConfigs []*controlapi.CacheOptionsEntry
+
+	// Authenticator authenticates the clients connecting to the engine. If
+	// nil, any client is allowed to connect.
+	Authenticator Authenticator
+
+	// AdminPrincipals are the patterns of the principals returned by the
+	// Authenticator that may administer the whole engine, where * matches any
+	// sequence of characters. Other clients can only see and remove the
+	// sessions of their own principal, and can't prune the cache. Without an
+	// Authenticator, every client is an admin.
+	AdminPrincipals []string
+
+	// AllowedDevices are the paths of the host devices that clients can
+	// expose to their containers.
+	AllowedDevices []string
+
+	// AuditLog receives a line of JSON for each audited operation of every
+	// client. If nil, nothing is audited.
+	AuditLog io.Writer
+
+	// MaxExecOutputBytes caps the stdout and stderr captured of each exec,
+	// overriding any higher limit set by the client. 0 means unlimited.
+	MaxExecOutputBytes int64
+
+	// SessionBudget caps the budget of every session, overriding any higher
+	// limit declared by the client. Zero values mean unlimited.
+	SessionBudget engine.Budget
 }
 
 //nolint:gocyclo
@@ -202,7 +271,19 @@ func NewServer(ctx context.Context, opts *NewServerOpts) (*Server, error) {
 
 		telemetryPubSub: opts.TelemetryPubSub,
 
+		strictSchema: opts.StrictSchema,
+
+		authenticator:   opts.Authenticator,
+		adminPrincipals: opts.AdminPrincipals,
+
+		sessionBudget: opts.SessionBudget,
+
 		daggerSessions: make(map[string]*daggerSession),
+
+		closed: make(chan struct{}),
+	}
+	if opts.AuditLog != nil {
+		srv.auditLog = &auditLog{w: opts.AuditLog, clientFromContext: srv.clientFromContext}
 	}
 
 	//
@@ -262,7 +343,12 @@ func NewServer(ctx context.Context, opts *NewServerOpts) (*Server, error) {
 		srv.enabledPlatforms = []ocispecs.Platform{srv.defaultPlatform}
 	}
 
-	srv.registryHosts = resolver.NewRegistryConfig(cfg.Registries)
+	srv.registryLimiter = newRegistryLimiter(opts.RegistryMaxConcurrentRequests)
+	srv.registryHosts = srv.registryLimiter.Wrap(resolver.NewRegistryConfig(cfg.Registries))
+	if srv.auditLog != nil {
+		srv.registryHosts = srv.auditLog.wrapRegistryHosts(srv.registryHosts)
+	}
+	srv.metrics = newEngineMetrics(srv)
 
 	if slog.Default().Enabled(ctx, slog.LevelExtraDebug) {
 		srv.buildkitLogSink = os.Stderr
@@ -448,9 +534,11 @@ func NewServer(ctx context.Context, opts *NewServerOpts) (*Server, error) {
 		ApparmorProfile:     srv.apparmorProfile,
 		SELinux:             srv.selinux,
 		Entitlements:        srv.entitlements,
+		AllowedDevices:      opts.AllowedDevices,
 		NetworkProviders:    srv.networkProviders,
 		ParallelismSem:      srv.parallelismSem,
 		WorkerCache:         srv.workerCache,
+		MaxExecOutputBytes:  opts.MaxExecOutputBytes,
 	})
 
 	//
@@ -512,6 +600,18 @@ func NewServer(ctx context.Context, opts *NewServerOpts) (*Server, error) {
 		"s3":       s3remotecache.ResolveCacheImporterFunc(),
 		"azblob":   azblob.ResolveCacheImporterFunc(),
 	}
+	for _, cacheImportCfg := range opts.CacheImportConfigs {
+		if _, ok := srv.cacheImporters[cacheImportCfg.Type]; !ok {
+			return nil, fmt.Errorf("unknown cache importer type %q", cacheImportCfg.Type)
+		}
+	}
+	for _, cacheExportCfg := range opts.CacheExportConfigs {
+		if _, ok := srv.cacheExporters[cacheExportCfg.Type]; !ok {
+			return nil, fmt.Errorf("unknown cache exporter type %q", cacheExportCfg.Type)
+		}
+	}
+	srv.cacheImportCfgs = opts.CacheImportConfigs
+	srv.cacheExportCfgs = opts.CacheExportConfigs
 
 	srv.solver = solver.NewSolver(solver.SolverOpt{
 		ResolveOpFunc: func(vtx solver.Vertex, builder solver.Builder) (solver.Op, error) {
@@ -527,10 +627,14 @@ func NewServer(ctx context.Context, opts *NewServerOpts) (*Server, error) {
 		time.AfterFunc(time.Second, srv.throttledGC)
 	}()
 
+	go srv.reapStaleSessions()
+	go srv.metrics.refreshCacheSize(srv.closed, cacheSizeInterval, srv.cacheSize)
+
 	return srv, nil
 }
 
 func (srv *Server) Close() error {
+	close(srv.closed)
 	err := srv.baseWorker.Close()
 
 	// note this *could* cause a panic in Session if it was still running, so
@@ -573,6 +677,7 @@ func (srv *Server) LogMetrics(l *logrus.Entry) *logrus.Entry {
 	srv.daggerSessionsMu.RLock()
 	defer srv.daggerSessionsMu.RUnlock()
 	l = l.WithField("dagger-session-count", len(srv.daggerSessions))
+	l = srv.registryLimiter.LogMetrics(l)
 	/* TODO: FIX
 	for _, s := range srv.daggerSessions {
 		l = s.LogMetrics(l)
@@ -9,12 +9,16 @@ import (
 	"net/http"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"dagger.io/dagger/telemetry"
+	gqlgen "github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/Khan/genqlient/graphql"
+	"github.com/containerd/containerd/platforms"
 	"github.com/koron-go/prefixw"
 	"github.com/moby/buildkit/cache/remotecache"
 	bkclient "github.com/moby/buildkit/client"
@@ -48,6 +52,15 @@ import (
 type daggerSession struct {
 	sessionID          string
 	mainClientCallerID string
+	clientHostname     string
+
+	// the principal the main client caller authenticated as, or empty if the
+	// engine doesn't authenticate its clients
+	principal string
+
+	// the last time the main client caller sent a heartbeat, in unix
+	// nanoseconds, or 0 if it has never sent one
+	lastHeartbeat atomic.Int64
 
 	state   daggerSessionState
 	stateMu sync.RWMutex
@@ -69,6 +82,14 @@ type daggerSession struct {
 	secretStore  *core.SecretStore
 	authProvider *auth.RegistryAuthProvider
 
+	budget *buildkit.BudgetTracker
+
+	defaults        engine.Defaults
+	defaultPlatform core.Platform
+
+	// whether failed execs open a terminal for the main client to debug them
+	interactive bool
+
 	cacheExporterCfgs []bkgw.CacheOptionsEntry
 	cacheImporterCfgs []bkgw.CacheOptionsEntry
 
@@ -132,6 +153,7 @@ const (
 
 // requires that sess.stateMu is held
 func (srv *Server) initializeDaggerSession(
+	ctx context.Context,
 	clientMetadata *engine.ClientMetadata,
 	sess *daggerSession,
 	failureCleanups *buildkit.Cleanups,
@@ -141,6 +163,8 @@ func (srv *Server) initializeDaggerSession(
 
 	sess.sessionID = clientMetadata.SessionID
 	sess.mainClientCallerID = clientMetadata.ClientID
+	sess.clientHostname = clientMetadata.ClientHostname
+	sess.principal = principalFromContext(ctx)
 	sess.clients = map[string]*daggerClient{}
 	sess.endpoints = map[string]http.Handler{}
 	sess.services = core.NewServices()
@@ -151,6 +175,17 @@ func (srv *Server) initializeDaggerSession(
 	sess.dagqlCache = dagql.NewCache()
 	sess.telemetryPubSub = srv.telemetryPubSub
 
+	sess.defaults = clientMetadata.Defaults
+	sess.interactive = clientMetadata.Interactive
+	sess.defaultPlatform = core.Platform(srv.defaultPlatform)
+	if sess.defaults.Platform != "" {
+		platform, err := platforms.Parse(sess.defaults.Platform)
+		if err != nil {
+			return fmt.Errorf("invalid default platform %q: %w", sess.defaults.Platform, err)
+		}
+		sess.defaultPlatform = core.Platform(platforms.Normalize(platform))
+	}
+
 	sess.analytics = analytics.New(analytics.Config{
 		DoNotTrack: clientMetadata.DoNotTrack || analytics.DoNotTrack(),
 		Labels: enginetel.Labels(clientMetadata.Labels).
@@ -165,7 +200,7 @@ func (srv *Server) initializeDaggerSession(
 	})
 	failureCleanups.Add("close session analytics", sess.analytics.Close)
 
-	for _, cacheImportCfg := range clientMetadata.UpstreamCacheImportConfig {
+	for _, cacheImportCfg := range slices.Concat(srv.cacheImportCfgs, clientMetadata.UpstreamCacheImportConfig) {
 		_, ok := srv.cacheImporters[cacheImportCfg.Type]
 		if !ok {
 			return fmt.Errorf("unknown cache importer type %q", cacheImportCfg.Type)
@@ -175,7 +210,7 @@ func (srv *Server) initializeDaggerSession(
 			Attrs: cacheImportCfg.Attrs,
 		})
 	}
-	for _, cacheExportCfg := range clientMetadata.UpstreamCacheExportConfig {
+	for _, cacheExportCfg := range slices.Concat(srv.cacheExportCfgs, clientMetadata.UpstreamCacheExportConfig) {
 		_, ok := srv.cacheExporters[cacheExportCfg.Type]
 		if !ok {
 			return fmt.Errorf("unknown cache exporter type %q", cacheExportCfg.Type)
@@ -192,6 +227,12 @@ func (srv *Server) initializeDaggerSession(
 
 // requires that sess.stateMu is held
 func (srv *Server) removeDaggerSession(ctx context.Context, sess *daggerSession) error {
+	if sess.state == sessionStateRemoved {
+		// already removed, e.g. because its lease expired
+		return nil
+	}
+	sess.state = sessionStateRemoved
+
 	slog.ExtraDebug("session closing; stopping client services and flushing",
 		"session", sess.sessionID,
 	)
@@ -391,6 +432,9 @@ func (srv *Server) initializeDaggerClient(
 		UpstreamCacheImporters: srv.cacheImporters,
 		UpstreamCacheImports:   client.daggerSession.cacheImporterCfgs,
 		Frontends:              srv.frontends,
+		Budget:                 client.daggerSession.budget,
+		SolveDuration:          srv.metrics.solveDuration,
+		Interactive:            client.daggerSession.interactive,
 
 		Refs:         client.daggerSession.refs,
 		RefsMu:       &client.daggerSession.refsMu,
@@ -403,8 +447,22 @@ func (srv *Server) initializeDaggerClient(
 		return fmt.Errorf("failed to create buildkit client: %w", err)
 	}
 
+	// keep the status of vertexes for graph, and report the progress of
+	// transfers, like pulls, as spans
+	statusCh := make(chan *bkclient.SolveStatus, 8)
+	go client.job.Status(ctx, statusCh)
+	go buildkit.ForwardTransferProgress(
+		trace.ContextWithSpanContext(ctx, client.spanCtx),
+		client.clientID,
+		client.bkClient.TrackVertexes(statusCh),
+	)
+
 	// setup the graphql server + module/function state for the client
 
+	var observer dagql.ObserveFunc
+	if srv.auditLog != nil {
+		observer = srv.auditLog.observe
+	}
 	client.dagqlRoot = core.NewRoot(core.QueryOpts{
 		Server:             srv,
 		Services:           client.daggerSession.services,
@@ -412,15 +470,18 @@ func (srv *Server) initializeDaggerClient(
 		Auth:               client.daggerSession.authProvider,
 		OCIStore:           srv.contentStore,
 		LeaseManager:       srv.worker.LeaseManager(),
-		Platform:           core.Platform(srv.defaultPlatform),
+		Platform:           client.daggerSession.defaultPlatform,
+		Defaults:           client.daggerSession.defaults,
 		Cache:              client.daggerSession.dagqlCache,
 		Buildkit:           client.bkClient,
 		MainClientCallerID: client.daggerSession.mainClientCallerID,
+		Observer:           observer,
 	})
 
 	dag := dagql.NewServer(client.dagqlRoot)
 	dag.Cache = client.daggerSession.dagqlCache
 	dag.Around(core.AroundFunc)
+	dag.Observe(client.dagqlRoot.Observer)
 	coreMod := &schema.CoreMod{Dag: dag}
 	if err := coreMod.Install(ctx, dag); err != nil {
 		return fmt.Errorf("failed to install core module: %w", err)
@@ -517,6 +578,9 @@ func (srv *Server) getOrInitClient(
 	if !sessionExists {
 		sess = &daggerSession{
 			state: sessionStateUninitialized,
+			// set before the session is shared, since the worker reads it
+			// without waiting for the session to be initialized
+			budget: buildkit.NewBudgetTracker(opts.ClientMetadata.Budget.Within(srv.sessionBudget)),
 		}
 		srv.daggerSessions[sessionID] = sess
 
@@ -533,7 +597,7 @@ func (srv *Server) getOrInitClient(
 	defer sess.stateMu.Unlock()
 	switch sess.state {
 	case sessionStateUninitialized:
-		if err := srv.initializeDaggerSession(opts.ClientMetadata, sess, failureCleanups); err != nil {
+		if err := srv.initializeDaggerSession(ctx, opts.ClientMetadata, sess, failureCleanups); err != nil {
 			return nil, nil, fmt.Errorf("initialize session: %w", err)
 		}
 	case sessionStateInitialized:
@@ -600,6 +664,9 @@ func (srv *Server) getOrInitClient(
 		switch sess.state {
 		case sessionStateInitialized:
 			return srv.removeDaggerSession(ctx, sess)
+		case sessionStateRemoved:
+			// already removed, e.g. because its lease expired
+			return nil
 		default:
 			// this should never happen unless there's a bug
 			slog.Error("session state being removed not in initialized state",
@@ -647,6 +714,17 @@ func (srv *Server) ServeHTTPToNestedClient(w http.ResponseWriter, r *http.Reques
 	}).ServeHTTP(w, r)
 }
 
+// SessionBudget returns the budget of the session, or nil if it's gone.
+func (srv *Server) SessionBudget(sessionID string) *buildkit.BudgetTracker {
+	srv.daggerSessionsMu.RLock()
+	defer srv.daggerSessionsMu.RUnlock()
+	sess, ok := srv.daggerSessions[sessionID]
+	if !ok {
+		return nil
+	}
+	return sess.budget
+}
+
 func (srv *Server) serveHTTPToClient(w http.ResponseWriter, r *http.Request, opts *ClientInitOpts) (rerr error) {
 	ctx := r.Context()
 	ctx, cancel := context.WithCancel(ctx)
@@ -693,6 +771,7 @@ func (srv *Server) serveHTTPToClient(w http.ResponseWriter, r *http.Request, opt
 	mux.Handle(engine.SessionAttachablesEndpoint, httpHandlerFunc(srv.serveSessionAttachables, client))
 	mux.Handle(engine.QueryEndpoint, httpHandlerFunc(srv.serveQuery, client))
 	mux.Handle(engine.ShutdownEndpoint, httpHandlerFunc(srv.serveShutdown, client))
+	mux.Handle(engine.HeartbeatEndpoint, httpHandlerFunc(srv.serveHeartbeat, client))
 	sess.endpointMu.RLock()
 	for path, handler := range sess.endpoints {
 		mux.Handle(path, handler)
@@ -782,7 +861,7 @@ func (srv *Server) serveQuery(w http.ResponseWriter, r *http.Request, client *da
 
 	gqlSrv := handler.NewDefaultServer(schema)
 	// NB: break glass when needed:
-	// gqlSrv.AroundResponses(func(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	// gqlSrv.AroundResponses(func(ctx context.Context, next gqlgen.ResponseHandler) *gqlgen.Response {
 	// 	res := next(ctx)
 	// 	pl, err := json.Marshal(res)
 	// 	slog.Debug("graphql response", "response", string(pl), "error", err)
@@ -820,6 +899,35 @@ func (srv *Server) serveQuery(w http.ResponseWriter, r *http.Request, client *da
 		}
 	}()
 
+	if srv.strictSchema {
+		r = r.WithContext(dagql.WithStrictValidation(ctx))
+	}
+
+	if srv.auditLog != nil {
+		gqlSrv.AroundOperations(srv.auditLog.aroundOperations(client))
+	}
+
+	// a JSON array of operations is served as a batch, saving clients with many
+	// small queries a round trip per operation
+	ops, isBatch, err := readBatch(w, r)
+	if err != nil {
+		return err
+	}
+	if isBatch {
+		return serveBatch(w, r, gqlSrv, ops)
+	}
+
+	// let clients know whether they may cache the result
+	ctx, impure := dagql.WithImpurityTracking(r.Context())
+	r = r.WithContext(ctx)
+	gqlSrv.AroundResponses(func(ctx context.Context, next gqlgen.ResponseHandler) *gqlgen.Response {
+		res := next(ctx)
+		if res != nil && len(res.Errors) == 0 && !impure() {
+			w.Header().Set(engine.QueryPureMetaKey, "true")
+		}
+		return res
+	})
+
 	gqlSrv.ServeHTTP(w, r)
 	return nil
 }
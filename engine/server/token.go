@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dagger/dagger/engine"
+)
+
+// TokenAuthenticator authenticates clients with a static token shared with
+// the engine, e.g. for a small team's remote engine without an identity
+// provider.
+type TokenAuthenticator struct {
+	token []byte
+}
+
+var _ Authenticator = (*TokenAuthenticator)(nil)
+
+// NewTokenAuthenticator returns an authenticator accepting the token read from
+// the given file.
+func NewTokenAuthenticator(path string) (*TokenAuthenticator, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read auth token: %w", err)
+	}
+	token := strings.TrimSpace(string(bs))
+	if token == "" {
+		return nil, fmt.Errorf("auth token file %s is empty", path)
+	}
+	return &TokenAuthenticator{token: []byte(token)}, nil
+}
+
+// Authenticate returns the "token" principal, shared by all the clients that
+// have the token.
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (string, error) {
+	raw, ok := strings.CutPrefix(r.Header.Get(engine.EngineAuthMetaKey), "Bearer ")
+	if !ok || raw == "" {
+		return "", errors.New("missing engine token")
+	}
+	if subtle.ConstantTimeCompare([]byte(raw), a.token) != 1 {
+		return "", errors.New("invalid engine token")
+	}
+	return "token", nil
+}
+
+// AnyAuthenticator accepts the clients accepted by any of its authenticators.
+type AnyAuthenticator []Authenticator
+
+var _ Authenticator = AnyAuthenticator(nil)
+
+func (as AnyAuthenticator) Authenticate(r *http.Request) (string, error) {
+	var errs []error
+	for _, a := range as {
+		principal, err := a.Authenticate(r)
+		if err == nil {
+			return principal, nil
+		}
+		errs = append(errs, err)
+	}
+	return "", errors.Join(errs...)
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/engine"
+)
+
+func TestTokenAuthenticator(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("s3cr3t\n"), 0o600))
+
+	auth, err := NewTokenAuthenticator(tokenPath)
+	require.NoError(t, err)
+
+	request := func(header string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set(engine.EngineAuthMetaKey, header)
+		}
+		return r
+	}
+
+	authenticate := func(a Authenticator, header string) error {
+		_, err := a.Authenticate(request(header))
+		return err
+	}
+
+	principal, err := auth.Authenticate(request("Bearer s3cr3t"))
+	require.NoError(t, err)
+	require.Equal(t, "token", principal)
+	require.ErrorContains(t, authenticate(auth, ""), "missing engine token")
+	require.ErrorContains(t, authenticate(auth, "s3cr3t"), "missing engine token")
+	require.ErrorContains(t, authenticate(auth, "Bearer nope"), "invalid engine token")
+
+	t.Run("empty file", func(t *testing.T) {
+		emptyPath := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(emptyPath, []byte("\n"), 0o600))
+		_, err := NewTokenAuthenticator(emptyPath)
+		require.ErrorContains(t, err, "is empty")
+	})
+
+	t.Run("any", func(t *testing.T) {
+		otherPath := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(otherPath, []byte("other"), 0o600))
+		other, err := NewTokenAuthenticator(otherPath)
+		require.NoError(t, err)
+
+		auths := AnyAuthenticator{auth, other}
+		require.NoError(t, authenticate(auths, "Bearer s3cr3t"))
+		require.NoError(t, authenticate(auths, "Bearer other"))
+		require.ErrorContains(t, authenticate(auths, "Bearer nope"), "invalid engine token")
+	})
+}
@@ -4,10 +4,31 @@ import (
 	bkgit "github.com/moby/buildkit/source/git"
 )
 
-const AttrDNSNamespace = "dagger.dns.namespace"
+const (
+	AttrDNSNamespace = "dagger.dns.namespace"
+	AttrAuthUsername = "dagger.git.authusername"
+	AttrDepth        = "dagger.git.depth"
+)
 
 type GitIdentifier struct {
 	bkgit.GitIdentifier
 
 	Namespace string
+
+	// AuthUsername is the username sent alongside the auth token secret during
+	// basic HTTP authorization. Defaults to "x-access-token" if unset.
+	AuthUsername string
+
+	// Depth is the number of commits of history to fetch when keeping the .git
+	// directory. Zero fetches the full history. Defaults to 1 if unset.
+	Depth *int
+}
+
+// FetchDepth returns the configured history depth, defaulting to a shallow
+// clone of a single commit.
+func (id *GitIdentifier) FetchDepth() int {
+	if id.Depth == nil {
+		return 1
+	}
+	return *id.Depth
 }
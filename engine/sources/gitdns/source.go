@@ -38,6 +38,10 @@ import (
 var validHex = regexp.MustCompile(`^[a-f0-9]{40}$`)
 var defaultBranch = regexp.MustCompile(`refs/heads/(\S+)`)
 
+// defaultAuthUsername is the username used for token authentication when none
+// is configured, as understood by GitHub.
+const defaultAuthUsername = "x-access-token"
+
 type Opt struct {
 	srcgit.Opt
 	BaseDNSConfig *oci.DNSConfig
@@ -81,6 +85,16 @@ func (gs *gitSource) Identifier(scheme, ref string, attrs map[string]string, pla
 	if v, ok := attrs[AttrDNSNamespace]; ok {
 		id.Namespace = v
 	}
+	if v, ok := attrs[AttrAuthUsername]; ok {
+		id.AuthUsername = v
+	}
+	if v, ok := attrs[AttrDepth]; ok {
+		depth, err := strconv.Atoi(v)
+		if err != nil || depth < 0 {
+			return nil, errors.Errorf("invalid git depth %q", v)
+		}
+		id.Depth = &depth
+	}
 
 	return id, nil
 }
@@ -198,6 +212,9 @@ func (gs *gitSourceHandler) shaToCacheKey(sha string) string {
 	key := sha
 	if gs.src.KeepGitDir {
 		key += ".git"
+		if depth := gs.src.FetchDepth(); depth != 1 {
+			key += ".depth" + strconv.Itoa(depth)
+		}
 	}
 	if gs.src.Subdir != "" {
 		key += ":" + gs.src.Subdir
@@ -238,6 +255,10 @@ func (gs *gitSourceHandler) getAuthToken(ctx context.Context, g session.Group) e
 	if err != nil {
 		return err
 	}
+	username := defaultAuthUsername
+	if gs.src.AuthUsername != "" {
+		username = gs.src.AuthUsername
+	}
 	return gs.sm.Any(ctx, g, func(ctx context.Context, _ string, caller session.Caller) error {
 		for _, s := range sec {
 			dt, err := secrets.GetSecret(ctx, caller, s.name)
@@ -248,7 +269,7 @@ func (gs *gitSourceHandler) getAuthToken(ctx context.Context, g session.Group) e
 				return err
 			}
 			if s.token {
-				dt = []byte("basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("x-access-token:%s", dt))))
+				dt = []byte("basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, dt))))
 			}
 			gs.auth = []string{"-c", "http." + tokenScope(gs.src.Remote) + ".extraheader=Authorization: " + string(dt)}
 			break
@@ -257,6 +278,26 @@ func (gs *gitSourceHandler) getAuthToken(ctx context.Context, g session.Group) e
 	})
 }
 
+// depthArgs returns the args used to fetch a non-commit ref into the shared
+// bare repo at gitDir, honoring the requested history depth.
+func (gs *gitSourceHandler) depthArgs(gitDir string) []string {
+	if !gs.src.KeepGitDir {
+		return []string{"--depth=1", "--no-tags"}
+	}
+	switch depth := gs.src.FetchDepth(); depth {
+	case 1:
+		return []string{"--depth=1", "--no-tags"}
+	case 0:
+		args := []string{"--tags"}
+		if _, err := os.Lstat(filepath.Join(gitDir, "shallow")); err == nil {
+			args = append(args, "--unshallow")
+		}
+		return args
+	default:
+		return []string{"--depth=" + strconv.Itoa(depth), "--tags"}
+	}
+}
+
 func (gs *gitSourceHandler) mountSSHAuthSock(ctx context.Context, sshID string, g session.Group) (string, func() error, error) {
 	var caller session.Caller
 	err := gs.sm.Any(ctx, g, func(ctx context.Context, _ string, c session.Caller) error {
@@ -514,7 +555,7 @@ func (gs *gitSourceHandler) Snapshot(ctx context.Context, g session.Group) (out
 
 		args := []string{"fetch"}
 		if !isCommitSHA(ref) { // TODO: find a branch from ls-remote?
-			args = append(args, "--depth=1", "--no-tags")
+			args = append(args, gs.depthArgs(gitDir)...)
 		} else {
 			if _, err := os.Lstat(filepath.Join(gitDir, "shallow")); err == nil {
 				args = append(args, "--unshallow")
@@ -604,7 +645,17 @@ func (gs *gitSourceHandler) Snapshot(ctx context.Context, g session.Group) (out
 		default:
 			pullref += ":" + pullref
 		}
-		_, err = checkoutGit.run(ctx, "fetch", "-u", "--depth=1", "origin", pullref)
+		fetchArgs := []string{"fetch", "-u"}
+		if depth := gs.src.FetchDepth(); depth > 0 {
+			fetchArgs = append(fetchArgs, "--depth="+strconv.Itoa(depth))
+		}
+		if gs.src.FetchDepth() != 1 {
+			// fetch tags along with any extra history so that tools like `git
+			// describe` work in the checkout
+			fetchArgs = append(fetchArgs, "--tags")
+		}
+		fetchArgs = append(fetchArgs, "origin", pullref)
+		_, err = checkoutGit.run(ctx, fetchArgs...)
 		if err != nil {
 			return nil, err
 		}
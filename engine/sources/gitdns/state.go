@@ -2,6 +2,7 @@ package gitdns
 
 import (
 	"path"
+	"strconv"
 
 	"github.com/moby/buildkit/client/llb"
 	"github.com/moby/buildkit/solver/pb"
@@ -10,6 +11,18 @@ import (
 	"github.com/pkg/errors"
 )
 
+// AuthUsername is a git option setting the username that accompanies the auth
+// token secret during basic HTTP authorization.
+type AuthUsername string
+
+func (AuthUsername) SetGitOption(*llb.GitInfo) {}
+
+// Depth is a git option setting the number of commits of history to fetch
+// when keeping the .git directory. Zero fetches the full history.
+type Depth int
+
+func (Depth) SetGitOption(*llb.GitInfo) {}
+
 // Git is a helper mimicking the llb.Git function, but with the ability to
 // set additional attributes.
 func Git(url, ref string, namespace string, opts ...llb.GitOption) llb.State {
@@ -41,12 +54,23 @@ func Git(url, ref string, namespace string, opts ...llb.GitOption) llb.State {
 		AuthHeaderSecret: "GIT_AUTH_HEADER",
 		AuthTokenSecret:  "GIT_AUTH_TOKEN",
 	}
+	var authUsername AuthUsername
+	var depth *Depth
 	for _, o := range opts {
+		switch o := o.(type) {
+		case AuthUsername:
+			authUsername = o
+		case Depth:
+			depth = &o
+		}
 		o.SetGitOption(gi)
 	}
 	attrs := map[string]string{}
 	if gi.KeepGitDir {
 		attrs[pb.AttrKeepGitDir] = "true"
+		if depth != nil {
+			attrs[AttrDepth] = strconv.Itoa(int(*depth))
+		}
 	}
 	if url != "" {
 		attrs[pb.AttrFullRemoteURL] = url
@@ -57,6 +81,9 @@ func Git(url, ref string, namespace string, opts ...llb.GitOption) llb.State {
 	if gi.AuthHeaderSecret != "" {
 		attrs[pb.AttrAuthHeaderSecret] = gi.AuthHeaderSecret
 	}
+	if authUsername != "" {
+		attrs[AttrAuthUsername] = string(authUsername)
+	}
 	if remote != nil && remote.Scheme == gitutil.SSHProtocol {
 		if gi.KnownSSHHosts != "" {
 			attrs[pb.AttrKnownSSHHosts] = gi.KnownSSHHosts
@@ -4,10 +4,20 @@ import (
 	bkhttp "github.com/moby/buildkit/source/http"
 )
 
-const AttrDNSNamespace = "dagger.dns.namespace"
+const (
+	AttrDNSNamespace         = "dagger.dns.namespace"
+	AttrHTTPHeaders          = "dagger.http.headers"
+	AttrHTTPAuthHeaderSecret = "dagger.http.authheadersecret"
+)
 
 type HTTPIdentifier struct {
 	bkhttp.HTTPIdentifier
 
 	Namespace string
+
+	// Headers are additional headers sent with every request for the URL.
+	Headers []Header
+	// AuthHeaderSecret is the name of a session secret whose value is sent
+	// as the Authorization header.
+	AuthHeaderSecret string
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/moby/buildkit/cache"
 	"github.com/moby/buildkit/executor/oci"
 	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/secrets"
 	"github.com/moby/buildkit/snapshot"
 	"github.com/moby/buildkit/solver"
 	"github.com/moby/buildkit/solver/pb"
@@ -79,6 +80,14 @@ func (hs *httpSource) Identifier(scheme, ref string, attrs map[string]string, pl
 	if v, ok := attrs[AttrDNSNamespace]; ok {
 		id.Namespace = v
 	}
+	if v, ok := attrs[AttrHTTPHeaders]; ok {
+		if err := json.Unmarshal([]byte(v), &id.Headers); err != nil {
+			return nil, errors.Wrapf(err, "invalid http headers %q", v)
+		}
+	}
+	if v, ok := attrs[AttrHTTPAuthHeaderSecret]; ok {
+		id.AuthHeaderSecret = v
+	}
 
 	return id, nil
 }
@@ -116,17 +125,45 @@ func (hs *httpSourceHandler) client(g session.Group) *http.Client {
 	return &http.Client{Transport: newTransport(hs.transport, hs.sm, g, &dns)}
 }
 
+// newRequest returns a GET request for the source URL, including any
+// configured headers and authorization.
+func (hs *httpSourceHandler) newRequest(ctx context.Context, g session.Group) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", hs.src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hs.src.Headers {
+		req.Header.Add(h.Name, h.Value)
+	}
+	if hs.src.AuthHeaderSecret != "" {
+		err := hs.sm.Any(ctx, g, func(ctx context.Context, _ string, caller session.Caller) error {
+			dt, err := secrets.GetSecret(ctx, caller, hs.src.AuthHeaderSecret)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Authorization", string(dt))
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get http auth header secret")
+		}
+	}
+	return req, nil
+}
+
 // urlHash is internal hash the etag is stored by that doesn't leak outside
 // this package.
 func (hs *httpSourceHandler) urlHash() (digest.Digest, error) {
 	dt, err := json.Marshal(struct {
 		Filename       string
 		Perm, UID, GID int
+		Headers        []Header `json:",omitempty"`
 	}{
 		Filename: getFileName(hs.src.URL, hs.src.Filename, nil),
 		Perm:     hs.src.Perm,
 		UID:      hs.src.UID,
 		GID:      hs.src.GID,
+		Headers:  hs.src.Headers,
 	})
 	if err != nil {
 		return "", err
@@ -139,7 +176,8 @@ func (hs *httpSourceHandler) formatCacheKey(filename string, dgst digest.Digest,
 		Filename       string
 		Perm, UID, GID int
 		Checksum       digest.Digest
-		LastModTime    string `json:",omitempty"`
+		LastModTime    string   `json:",omitempty"`
+		Headers        []Header `json:",omitempty"`
 	}{
 		Filename:    filename,
 		Perm:        hs.src.Perm,
@@ -147,6 +185,7 @@ func (hs *httpSourceHandler) formatCacheKey(filename string, dgst digest.Digest,
 		GID:         hs.src.GID,
 		Checksum:    dgst,
 		LastModTime: lastModTime,
+		Headers:     hs.src.Headers,
 	})
 	if err != nil {
 		return dgst
@@ -171,11 +210,10 @@ func (hs *httpSourceHandler) CacheKey(ctx context.Context, g session.Group, inde
 		return "", "", nil, false, errors.Wrapf(err, "failed to search metadata for %s", uh)
 	}
 
-	req, err := http.NewRequest("GET", hs.src.URL, nil)
+	req, err := hs.newRequest(ctx, g)
 	if err != nil {
 		return "", "", nil, false, err
 	}
-	req = req.WithContext(ctx)
 	m := map[string]cacheRefMetadata{}
 
 	// If we request a single ETag in 'If-None-Match', some servers omit the
@@ -422,11 +460,10 @@ func (hs *httpSourceHandler) Snapshot(ctx context.Context, g session.Group) (cac
 		}
 	}
 
-	req, err := http.NewRequest("GET", hs.src.URL, nil)
+	req, err := hs.newRequest(ctx, g)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
 
 	client := hs.client(g)
 
@@ -437,6 +474,9 @@ func (hs *httpSourceHandler) Snapshot(ctx context.Context, g session.Group) (cac
 	defer func() {
 		_ = resp.Body.Close()
 	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return nil, errors.Errorf("invalid response status %d", resp.StatusCode)
+	}
 
 	ref, dgst, err := hs.save(ctx, resp, g)
 	if err != nil {
@@ -1,17 +1,40 @@
 package httpdns
 
 import (
+	"encoding/json"
 	"strconv"
 
 	"github.com/moby/buildkit/client/llb"
 	"github.com/moby/buildkit/solver/pb"
 )
 
+// Header is an HTTPOption that adds a header to the request.
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (Header) SetHTTPOption(*llb.HTTPInfo) {}
+
+// AuthHeaderSecret is an HTTPOption that sends the value of the named secret
+// as the Authorization header.
+type AuthHeaderSecret string
+
+func (AuthHeaderSecret) SetHTTPOption(*llb.HTTPInfo) {}
+
 // HTTP is a helper mimicking the llb.HTTP function, but with the ability to
 // set additional attributes.
 func HTTP(url string, namespace string, opts ...llb.HTTPOption) llb.State {
 	hi := &llb.HTTPInfo{}
+	var headers []Header
+	var authHeaderSecret AuthHeaderSecret
 	for _, o := range opts {
+		switch o := o.(type) {
+		case Header:
+			headers = append(headers, o)
+		case AuthHeaderSecret:
+			authHeaderSecret = o
+		}
 		o.SetHTTPOption(hi)
 	}
 	attrs := map[string]string{}
@@ -30,6 +53,14 @@ func HTTP(url string, namespace string, opts ...llb.HTTPOption) llb.State {
 	if hi.GID != 0 {
 		attrs[pb.AttrHTTPGID] = strconv.Itoa(hi.GID)
 	}
+	if len(headers) > 0 {
+		// marshaling a slice of plain string structs can't fail
+		dt, _ := json.Marshal(headers)
+		attrs[AttrHTTPHeaders] = string(dt)
+	}
+	if authHeaderSecret != "" {
+		attrs[AttrHTTPAuthHeaderSecret] = string(authHeaderSecret)
+	}
 
 	attrs[AttrDNSNamespace] = namespace
 
@@ -0,0 +1,121 @@
+package testutil
+
+import (
+	"context"
+
+	"github.com/moby/buildkit/identity"
+	"github.com/stretchr/testify/require"
+
+	"dagger.io/dagger"
+	"github.com/dagger/dagger/testctx"
+)
+
+// Fixtures are ephemeral services that a test can request, started for it
+// and stopped when it completes. Each fixture is unique to the test that
+// requested it, so tests running in parallel don't share state.
+//
+// Fixtures are reachable from any container in the test's session through
+// their endpoint, or through an alias when bound with WithServiceBinding.
+// Note that the engine itself can't resolve service hostnames when pushing
+// images, so publishing to a fixture registry with Container.publish requires
+// an engine bound to it, e.g. a dev engine.
+
+const (
+	registryImage = "registry:2"
+	minioImage    = "minio/minio"
+	minioMCImage  = "minio/mc"
+)
+
+// Registry is an ephemeral OCI registry.
+type Registry struct {
+	Service *dagger.Service
+
+	// Host is the host:port of the registry.
+	Host string
+}
+
+// StartRegistry starts an ephemeral registry for the duration of the test.
+func StartRegistry(ctx context.Context, t *testctx.T, c *dagger.Client) *Registry {
+	t.Helper()
+
+	svc := startFixture(ctx, t, c.Pipeline("registry").Container().
+		From(registryImage).
+		WithExposedPort(5000, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}),
+	)
+
+	host, err := svc.Endpoint(ctx)
+	require.NoError(t, err)
+
+	return &Registry{
+		Service: svc,
+		Host:    host,
+	}
+}
+
+// S3 is an ephemeral S3-compatible object store with a single bucket.
+type S3 struct {
+	Service *dagger.Service
+
+	// Endpoint is the http:// URL of the object store.
+	Endpoint string
+
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// StartS3 starts an ephemeral S3-compatible object store for the duration of
+// the test and creates a bucket in it.
+func StartS3(ctx context.Context, t *testctx.T, c *dagger.Client) *S3 {
+	t.Helper()
+
+	s3 := &S3{
+		Region:          "mars",
+		Bucket:          "dagger-test-" + identity.NewID(),
+		AccessKeyID:     "minioadmin",
+		SecretAccessKey: "minioadmin",
+	}
+	s3.Service = startFixture(ctx, t, c.Pipeline("s3").Container().
+		From(minioImage).
+		WithExposedPort(9000, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}).
+		WithExec([]string{"server", "/data"}),
+	)
+
+	endpoint, err := s3.Service.Endpoint(ctx, dagger.ServiceEndpointOpts{Port: 9000, Scheme: "http"})
+	require.NoError(t, err)
+	s3.Endpoint = endpoint
+
+	_, err = c.Container().From(minioMCImage).
+		WithServiceBinding("s3", s3.Service).
+		WithEnvVariable("ACCESS_KEY_ID", s3.AccessKeyID).
+		WithEnvVariable("SECRET_ACCESS_KEY", s3.SecretAccessKey).
+		WithEnvVariable("BUCKET", s3.Bucket).
+		WithEntrypoint([]string{"sh", "-e", "-c"}).
+		WithExec([]string{`mc alias set minio http://s3:9000 "$ACCESS_KEY_ID" "$SECRET_ACCESS_KEY" && mc mb "minio/$BUCKET"`}).
+		Sync(ctx)
+	require.NoError(t, err)
+
+	return s3
+}
+
+// startFixture starts a container as a service unique to the test and stops
+// it when the test completes.
+func startFixture(ctx context.Context, t *testctx.T, ctr *dagger.Container) *dagger.Service {
+	t.Helper()
+
+	svc, err := ctr.
+		WithEnvVariable("DAGGER_TEST_FIXTURE", identity.NewID()).
+		AsService().
+		Start(ctx)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		// the test's context may already be canceled
+		_, err := svc.Stop(context.Background(), dagger.ServiceStopOpts{Kill: true})
+		if err != nil {
+			t.Logf("failed to stop fixture: %s", err)
+		}
+	})
+	return svc
+}
@@ -17,6 +17,7 @@ type Client struct {
 
 	query  *querybuilder.Selection
 	client graphql.Client
+	cache  *cachingClient
 }
 
 // ClientOpt holds a client option
@@ -72,13 +73,17 @@ func Connect(ctx context.Context, opts ...ClientOpt) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	gql := errorWrappedClient{graphql.NewClient("http://"+conn.Host()+"/query", conn)}
+	var gql graphql.Client = errorWrappedClient{graphql.NewClient("http://"+conn.Host()+"/query", headerRecordingDoer{conn})}
 
 	c := &Client{
-		query:  querybuilder.Query().Client(gql),
-		client: gql,
-		conn:   conn,
+		conn: conn,
 	}
+	if cfg.QueryCacheSize > 0 {
+		c.cache = newCachingClient(gql, cfg.QueryCacheSize)
+		gql = c.cache
+	}
+	c.query = querybuilder.Query().Client(gql)
+	c.client = gql
 	return c, nil
 }
 
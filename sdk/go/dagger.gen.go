@@ -76,6 +76,19 @@ func getCustomError(err error) error {
 		return e
 	}
 
+	if typ == "UNSUPPORTED" {
+		e := &UnsupportedError{
+			original: err,
+		}
+		if capability, ok := ext["capability"].(string); ok {
+			e.Capability = EngineCapability(capability)
+		}
+		if reason, ok := ext["reason"].(string); ok {
+			e.Reason = reason
+		}
+		return e
+	}
+
 	return nil
 }
 
@@ -109,18 +122,52 @@ func (e *ExecError) Unwrap() error {
 	return e.original
 }
 
+// UnsupportedError is an API error returned when an operation requires a
+// capability the engine does not support.
+type UnsupportedError struct {
+	original   error
+	Capability EngineCapability
+	Reason     string
+}
+
+func (e *UnsupportedError) Error() string {
+	return e.original.Error()
+}
+
+func (e *UnsupportedError) Unwrap() error {
+	return e.original
+}
+
+// The `CachePruneResultID` scalar type represents an identifier for an object of type CachePruneResult.
+type CachePruneResultID string
+
+// The `CacheUsageID` scalar type represents an identifier for an object of type CacheUsage.
+type CacheUsageID string
+
 // The `CacheVolumeID` scalar type represents an identifier for an object of type CacheVolume.
 type CacheVolumeID string
 
+// The `CapabilityID` scalar type represents an identifier for an object of type Capability.
+type CapabilityID string
+
 // The `ContainerID` scalar type represents an identifier for an object of type Container.
 type ContainerID string
 
 // The `CurrentModuleID` scalar type represents an identifier for an object of type CurrentModule.
 type CurrentModuleID string
 
+// The `DiagnosticID` scalar type represents an identifier for an object of type Diagnostic.
+type DiagnosticID string
+
 // The `DirectoryID` scalar type represents an identifier for an object of type Directory.
 type DirectoryID string
 
+// The `EngineID` scalar type represents an identifier for an object of type Engine.
+type EngineID string
+
+// The `EngineSessionID` scalar type represents an identifier for an object of type EngineSession.
+type EngineSessionID string
+
 // The `EnumTypeDefID` scalar type represents an identifier for an object of type EnumTypeDef.
 type EnumTypeDefID string
 
@@ -160,6 +207,9 @@ type GitRefID string
 // The `GitRepositoryID` scalar type represents an identifier for an object of type GitRepository.
 type GitRepositoryID string
 
+// The `GitVersionID` scalar type represents an identifier for an object of type GitVersion.
+type GitVersionID string
+
 // The `HostID` scalar type represents an identifier for an object of type Host.
 type HostID string
 
@@ -216,6 +266,9 @@ type ServiceID string
 // The `SocketID` scalar type represents an identifier for an object of type Socket.
 type SocketID string
 
+// The `StatID` scalar type represents an identifier for an object of type Stat.
+type StatID string
+
 // The `TypeDefID` scalar type represents an identifier for an object of type TypeDef.
 type TypeDefID string
 
@@ -233,6 +286,15 @@ type BuildArg struct {
 	Value string `json:"value"`
 }
 
+// Key value object that represents an HTTP request header.
+type HTTPHeader struct {
+	// The header name.
+	Name string `json:"name"`
+
+	// The header value.
+	Value string `json:"value"`
+}
+
 // Key value object that represents a pipeline label.
 type PipelineLabel struct {
 	// Label name.
@@ -254,6 +316,205 @@ type PortForward struct {
 	Protocol NetworkProtocol `json:"protocol,omitempty"`
 }
 
+// A regular expression extracting diagnostics from the output of a command.
+type ProblemMatcher struct {
+	// A regular expression matched against each line of output. The named groups file, line, column, severity and message are extracted into the diagnostic.
+	Pattern string `json:"pattern"`
+
+	// The severity of diagnostics whose pattern has no severity group, or whose severity group did not match.
+	Severity string `json:"severity,omitempty"`
+}
+
+// The outcome of pruning the cache of an engine.
+type CachePruneResult struct {
+	query *querybuilder.Selection
+
+	id             *CachePruneResultID
+	prunedRecords  *int
+	reclaimedBytes *int
+}
+
+func (r *CachePruneResult) WithGraphQLQuery(q *querybuilder.Selection) *CachePruneResult {
+	return &CachePruneResult{
+		query: q,
+	}
+}
+
+// A unique identifier for this CachePruneResult.
+func (r *CachePruneResult) ID(ctx context.Context) (CachePruneResultID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response CachePruneResultID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *CachePruneResult) XXX_GraphQLType() string {
+	return "CachePruneResult"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *CachePruneResult) XXX_GraphQLIDType() string {
+	return "CachePruneResultID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *CachePruneResult) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *CachePruneResult) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+// The number of cache records removed.
+func (r *CachePruneResult) PrunedRecords(ctx context.Context) (int, error) {
+	if r.prunedRecords != nil {
+		return *r.prunedRecords, nil
+	}
+	q := r.query.Select("prunedRecords")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The disk space reclaimed, in bytes.
+func (r *CachePruneResult) ReclaimedBytes(ctx context.Context) (int, error) {
+	if r.reclaimedBytes != nil {
+		return *r.reclaimedBytes, nil
+	}
+	q := r.query.Select("reclaimedBytes")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The disk usage of the cache records of an engine of one type.
+type CacheUsage struct {
+	query *querybuilder.Selection
+
+	id               *CacheUsageID
+	reclaimableBytes *int
+	recordType       *string
+	records          *int
+	sizeBytes        *int
+}
+
+func (r *CacheUsage) WithGraphQLQuery(q *querybuilder.Selection) *CacheUsage {
+	return &CacheUsage{
+		query: q,
+	}
+}
+
+// A unique identifier for this CacheUsage.
+func (r *CacheUsage) ID(ctx context.Context) (CacheUsageID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response CacheUsageID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *CacheUsage) XXX_GraphQLType() string {
+	return "CacheUsage"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *CacheUsage) XXX_GraphQLIDType() string {
+	return "CacheUsageID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *CacheUsage) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *CacheUsage) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+// The disk space used by records of this type that are not in use, and could be pruned, in bytes.
+func (r *CacheUsage) ReclaimableBytes(ctx context.Context) (int, error) {
+	if r.reclaimableBytes != nil {
+		return *r.reclaimableBytes, nil
+	}
+	q := r.query.Select("reclaimableBytes")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The type of the records (e.g., "regular" for image layers and exec results, "exec.cachemount" for cache volumes, "source.local" for host directories, "source.git.checkout" for git checkouts).
+func (r *CacheUsage) RecordType(ctx context.Context) (string, error) {
+	if r.recordType != nil {
+		return *r.recordType, nil
+	}
+	q := r.query.Select("recordType")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The number of records of this type.
+func (r *CacheUsage) Records(ctx context.Context) (int, error) {
+	if r.records != nil {
+		return *r.records, nil
+	}
+	q := r.query.Select("records")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The disk space used by records of this type, in bytes.
+func (r *CacheUsage) SizeBytes(ctx context.Context) (int, error) {
+	if r.sizeBytes != nil {
+		return *r.sizeBytes, nil
+	}
+	q := r.query.Select("sizeBytes")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // A directory whose contents persist across runs.
 type CacheVolume struct {
 	query *querybuilder.Selection
@@ -307,19 +568,120 @@ func (r *CacheVolume) MarshalJSON() ([]byte, error) {
 	return json.Marshal(id)
 }
 
+// Whether the engine supports an optional feature.
+type Capability struct {
+	query *querybuilder.Selection
+
+	id        *CapabilityID
+	name      *EngineCapability
+	reason    *string
+	supported *bool
+}
+
+func (r *Capability) WithGraphQLQuery(q *querybuilder.Selection) *Capability {
+	return &Capability{
+		query: q,
+	}
+}
+
+// A unique identifier for this Capability.
+func (r *Capability) ID(ctx context.Context) (CapabilityID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response CapabilityID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *Capability) XXX_GraphQLType() string {
+	return "Capability"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *Capability) XXX_GraphQLIDType() string {
+	return "CapabilityID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *Capability) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *Capability) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+// The name of the capability.
+func (r *Capability) Name(ctx context.Context) (EngineCapability, error) {
+	if r.name != nil {
+		return *r.name, nil
+	}
+	q := r.query.Select("name")
+
+	var response EngineCapability
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// Why the engine does not support the capability, or an empty string if it does.
+func (r *Capability) Reason(ctx context.Context) (string, error) {
+	if r.reason != nil {
+		return *r.reason, nil
+	}
+	q := r.query.Select("reason")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// Whether the engine supports the capability.
+func (r *Capability) Supported(ctx context.Context) (bool, error) {
+	if r.supported != nil {
+		return *r.supported, nil
+	}
+	q := r.query.Select("supported")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // An OCI-compatible container, also known as a Docker container.
 type Container struct {
 	query *querybuilder.Selection
 
+	cached      *bool
 	envVariable *string
+	exitCode    *int
 	export      *string
 	id          *ContainerID
+	imageConfig *JSON
 	imageRef    *string
 	label       *string
 	platform    *Platform
 	publish     *string
 	stderr      *string
+	stderrBytes *string
 	stdout      *string
+	stdoutBytes *string
+	stopSignal  *string
 	sync        *ContainerID
 	user        *string
 	workdir     *string
@@ -360,10 +722,16 @@ type ContainerAsTarballOpts struct {
 	//
 	// If this is unset, then if a layer already has a compressed blob in the engine's cache, that will be used (this can result in a mix of compression algorithms for different layers). If this is unset and a layer has no compressed blob in the engine's cache, then it will be compressed using Gzip.
 	ForcedCompression ImageLayerCompression
+	// Compression level to use for the compressed layers, from 0 to 9 for Gzip and EStarGZ and from 0 to 22 for Zstd.
+	//
+	// If this is unset, the default level of the algorithm is used.
+	CompressionLevel int
 	// Use the specified media types for the image's layers.
 	//
 	// Defaults to OCI, which is largely compatible with most recent container runtimes, but Docker may be needed for older runtimes without OCI support.
 	MediaTypes ImageMediaTypes
+	// Make the image reproducible by setting the creation time of its config and clamping the timestamps of the files in its layers to this Unix time (e.g., the SOURCE_DATE_EPOCH of the build).
+	SourceDateEpoch int
 }
 
 // Returns a File representing the container serialized to a tarball.
@@ -378,10 +746,18 @@ func (r *Container) AsTarball(opts ...ContainerAsTarballOpts) *File {
 		if !querybuilder.IsZeroValue(opts[i].ForcedCompression) {
 			q = q.Arg("forcedCompression", opts[i].ForcedCompression)
 		}
+		// `compressionLevel` optional argument
+		if !querybuilder.IsZeroValue(opts[i].CompressionLevel) {
+			q = q.Arg("compressionLevel", opts[i].CompressionLevel)
+		}
 		// `mediaTypes` optional argument
 		if !querybuilder.IsZeroValue(opts[i].MediaTypes) {
 			q = q.Arg("mediaTypes", opts[i].MediaTypes)
 		}
+		// `sourceDateEpoch` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SourceDateEpoch) {
+			q = q.Arg("sourceDateEpoch", opts[i].SourceDateEpoch)
+		}
 	}
 
 	return &File{
@@ -434,6 +810,21 @@ func (r *Container) Build(context *Directory, opts ...ContainerBuildOpts) *Conta
 	}
 }
 
+// Returns whether the execs of the container's root filesystem are already cached, without running them.
+//
+// Other operations, like pulling images or loading host directories, are still performed, since the cache keys of the execs depend on their results.
+func (r *Container) Cached(ctx context.Context) (bool, error) {
+	if r.cached != nil {
+		return *r.cached, nil
+	}
+	q := r.query.Select("cached")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // Retrieves default arguments for future commands.
 func (r *Container) DefaultArgs(ctx context.Context) ([]string, error) {
 	q := r.query.Select("defaultArgs")
@@ -444,26 +835,61 @@ func (r *Container) DefaultArgs(ctx context.Context) ([]string, error) {
 	return response, q.Execute(ctx)
 }
 
-// Retrieves a directory at the given path.
+// The diagnostics extracted from the output streams of the last executed command by its problem matchers.
 //
-// Mounts are included.
-func (r *Container) Directory(path string) *Directory {
-	q := r.query.Select("directory")
-	q = q.Arg("path", path)
+// Will execute default command if none is set, or error if there's no default.
+func (r *Container) Diagnostics(ctx context.Context) ([]Diagnostic, error) {
+	q := r.query.Select("diagnostics")
 
-	return &Directory{
-		query: q,
+	q = q.Select("id")
+
+	type diagnostics struct {
+		Id DiagnosticID
 	}
-}
 
-// Retrieves entrypoint to be prepended to the arguments of all commands.
-func (r *Container) Entrypoint(ctx context.Context) ([]string, error) {
-	q := r.query.Select("entrypoint")
+	convert := func(fields []diagnostics) []Diagnostic {
+		out := []Diagnostic{}
 
-	var response []string
+		for i := range fields {
+			val := Diagnostic{id: &fields[i].Id}
+			val.query = q.Root().Select("loadDiagnosticFromID").Arg("id", fields[i].Id)
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []diagnostics
 
 	q = q.Bind(&response)
-	return response, q.Execute(ctx)
+
+	err := q.Execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
+// Retrieves a directory at the given path.
+//
+// Mounts are included.
+func (r *Container) Directory(path string) *Directory {
+	q := r.query.Select("directory")
+	q = q.Arg("path", path)
+
+	return &Directory{
+		query: q,
+	}
+}
+
+// Retrieves entrypoint to be prepended to the arguments of all commands.
+func (r *Container) Entrypoint(ctx context.Context) ([]string, error) {
+	q := r.query.Select("entrypoint")
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
 }
 
 // Retrieves the value of the specified environment variable.
@@ -513,6 +939,23 @@ func (r *Container) EnvVariables(ctx context.Context) ([]EnvVariable, error) {
 	return convert(response), nil
 }
 
+// The exit code of the last executed command.
+//
+// Only non-zero if the command was executed with allowFailure.
+//
+// Will execute default command if none is set, or error if there's no default.
+func (r *Container) ExitCode(ctx context.Context) (int, error) {
+	if r.exitCode != nil {
+		return *r.exitCode, nil
+	}
+	q := r.query.Select("exitCode")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // EXPERIMENTAL API! Subject to change/removal at any time.
 //
 // Configures all available GPUs on the host to be accessible to this container.
@@ -550,10 +993,16 @@ type ContainerExportOpts struct {
 	//
 	// If this is unset, then if a layer already has a compressed blob in the engine's cache, that will be used (this can result in a mix of compression algorithms for different layers). If this is unset and a layer has no compressed blob in the engine's cache, then it will be compressed using Gzip.
 	ForcedCompression ImageLayerCompression
+	// Compression level to use for the compressed layers, from 0 to 9 for Gzip and EStarGZ and from 0 to 22 for Zstd.
+	//
+	// If this is unset, the default level of the algorithm is used.
+	CompressionLevel int
 	// Use the specified media types for the exported image's layers.
 	//
 	// Defaults to OCI, which is largely compatible with most recent container runtimes, but Docker may be needed for older runtimes without OCI support.
 	MediaTypes ImageMediaTypes
+	// Make the exported image reproducible by setting the creation time of its config and clamping the timestamps of the files in its layers to this Unix time (e.g., the SOURCE_DATE_EPOCH of the build).
+	SourceDateEpoch int
 }
 
 // Writes the container as an OCI tarball to the destination file path on the host.
@@ -573,10 +1022,18 @@ func (r *Container) Export(ctx context.Context, path string, opts ...ContainerEx
 		if !querybuilder.IsZeroValue(opts[i].ForcedCompression) {
 			q = q.Arg("forcedCompression", opts[i].ForcedCompression)
 		}
+		// `compressionLevel` optional argument
+		if !querybuilder.IsZeroValue(opts[i].CompressionLevel) {
+			q = q.Arg("compressionLevel", opts[i].CompressionLevel)
+		}
 		// `mediaTypes` optional argument
 		if !querybuilder.IsZeroValue(opts[i].MediaTypes) {
 			q = q.Arg("mediaTypes", opts[i].MediaTypes)
 		}
+		// `sourceDateEpoch` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SourceDateEpoch) {
+			q = q.Arg("sourceDateEpoch", opts[i].SourceDateEpoch)
+		}
 	}
 	q = q.Arg("path", path)
 
@@ -633,9 +1090,23 @@ func (r *Container) File(path string) *File {
 	}
 }
 
+// ContainerFromOpts contains options for Container.From
+type ContainerFromOpts struct {
+	// Policy to follow when pulling the image.
+	//
+	// NEVER requires the address to be pinned by digest (e.g., "alpine@sha256:..."), since the registry can't be asked which image a tag points to.
+	PullPolicy ImagePullPolicy
+}
+
 // Initializes this container from a pulled base image.
-func (r *Container) From(address string) *Container {
+func (r *Container) From(address string, opts ...ContainerFromOpts) *Container {
 	q := r.query.Select("from")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `pullPolicy` optional argument
+		if !querybuilder.IsZeroValue(opts[i].PullPolicy) {
+			q = q.Arg("pullPolicy", opts[i].PullPolicy)
+		}
+	}
 	q = q.Arg("address", address)
 
 	return &Container{
@@ -643,6 +1114,30 @@ func (r *Container) From(address string) *Container {
 	}
 }
 
+// ContainerGraphOpts contains options for Container.Graph
+type ContainerGraphOpts struct {
+	// The format to export the graph in.
+	Format GraphFormat
+}
+
+// Evaluates the container's root filesystem and returns the graph of its operations.
+//
+// Each operation is reported with its cache status, so that accidental serialization or cache misses can be spotted.
+func (r *Container) Graph(ctx context.Context, opts ...ContainerGraphOpts) (string, error) {
+	q := r.query.Select("graph")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `format` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Format) {
+			q = q.Arg("format", opts[i].Format)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // A unique identifier for this Container.
 func (r *Container) ID(ctx context.Context) (ContainerID, error) {
 	if r.id != nil {
@@ -683,6 +1178,21 @@ func (r *Container) MarshalJSON() ([]byte, error) {
 	return json.Marshal(id)
 }
 
+// Retrieves the OCI image config of this container, serialized as JSON.
+//
+// It includes the environment variables, default arguments, entrypoint, labels, exposed ports, user and working directory set on the image.
+func (r *Container) ImageConfig(ctx context.Context) (JSON, error) {
+	if r.imageConfig != nil {
+		return *r.imageConfig, nil
+	}
+	q := r.query.Select("imageConfig")
+
+	var response JSON
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // The unique image reference which can only be retrieved immediately after the 'Container.From' call.
 func (r *Container) ImageRef(ctx context.Context) (string, error) {
 	if r.imageRef != nil {
@@ -827,10 +1337,16 @@ type ContainerPublishOpts struct {
 	//
 	// If this is unset, then if a layer already has a compressed blob in the engine's cache, that will be used (this can result in a mix of compression algorithms for different layers). If this is unset and a layer has no compressed blob in the engine's cache, then it will be compressed using Gzip.
 	ForcedCompression ImageLayerCompression
+	// Compression level to use for the compressed layers, from 0 to 9 for Gzip and EStarGZ and from 0 to 22 for Zstd.
+	//
+	// If this is unset, the default level of the algorithm is used.
+	CompressionLevel int
 	// Use the specified media types for the published image's layers.
 	//
 	// Defaults to OCI, which is largely compatible with most recent registries, but Docker may be needed for older registries without OCI support.
 	MediaTypes ImageMediaTypes
+	// Make the published image reproducible by setting the creation time of its config and clamping the timestamps of the files in its layers to this Unix time (e.g., the SOURCE_DATE_EPOCH of the build).
+	SourceDateEpoch int
 }
 
 // Publishes this container as a new image to the specified address.
@@ -852,10 +1368,18 @@ func (r *Container) Publish(ctx context.Context, address string, opts ...Contain
 		if !querybuilder.IsZeroValue(opts[i].ForcedCompression) {
 			q = q.Arg("forcedCompression", opts[i].ForcedCompression)
 		}
+		// `compressionLevel` optional argument
+		if !querybuilder.IsZeroValue(opts[i].CompressionLevel) {
+			q = q.Arg("compressionLevel", opts[i].CompressionLevel)
+		}
 		// `mediaTypes` optional argument
 		if !querybuilder.IsZeroValue(opts[i].MediaTypes) {
 			q = q.Arg("mediaTypes", opts[i].MediaTypes)
 		}
+		// `sourceDateEpoch` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SourceDateEpoch) {
+			q = q.Arg("sourceDateEpoch", opts[i].SourceDateEpoch)
+		}
 	}
 	q = q.Arg("address", address)
 
@@ -877,6 +1401,8 @@ func (r *Container) Rootfs() *Directory {
 // The error stream of the last executed command.
 //
 // Will execute default command if none is set, or error if there's no default.
+//
+// Bytes that aren't valid UTF-8 are replaced with the Unicode replacement character (U+FFFD). Use stderrBytes to retrieve binary output as is.
 func (r *Container) Stderr(ctx context.Context) (string, error) {
 	if r.stderr != nil {
 		return *r.stderr, nil
@@ -889,9 +1415,28 @@ func (r *Container) Stderr(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx)
 }
 
+// The error stream of the last executed command, base64-encoded.
+//
+// Will execute default command if none is set, or error if there's no default.
+//
+// The stream is returned byte for byte, so binary output is not altered. It is limited to 128 MiB, before encoding.
+func (r *Container) StderrBytes(ctx context.Context) (string, error) {
+	if r.stderrBytes != nil {
+		return *r.stderrBytes, nil
+	}
+	q := r.query.Select("stderrBytes")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // The output stream of the last executed command.
 //
 // Will execute default command if none is set, or error if there's no default.
+//
+// Bytes that aren't valid UTF-8 are replaced with the Unicode replacement character (U+FFFD). Use stdoutBytes to retrieve binary output as is.
 func (r *Container) Stdout(ctx context.Context) (string, error) {
 	if r.stdout != nil {
 		return *r.stdout, nil
@@ -904,6 +1449,36 @@ func (r *Container) Stdout(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx)
 }
 
+// The output stream of the last executed command, base64-encoded.
+//
+// Will execute default command if none is set, or error if there's no default.
+//
+// The stream is returned byte for byte, so binary output is not altered. It is limited to 128 MiB, before encoding.
+func (r *Container) StdoutBytes(ctx context.Context) (string, error) {
+	if r.stdoutBytes != nil {
+		return *r.stdoutBytes, nil
+	}
+	q := r.query.Select("stdoutBytes")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// Retrieves the signal sent to stop containers run from the image.
+func (r *Container) StopSignal(ctx context.Context) (string, error) {
+	if r.stopSignal != nil {
+		return *r.stopSignal, nil
+	}
+	q := r.query.Select("stopSignal")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // Forces evaluation of the pipeline in the engine.
 //
 // It doesn't run the default command if no exec has been set.
@@ -961,6 +1536,58 @@ func (r *Container) User(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx)
 }
 
+// Retrieves the paths declared as volumes in the image config.
+func (r *Container) Volumes(ctx context.Context) ([]string, error) {
+	q := r.query.Select("volumes")
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// Retrieves this container plus the given OCI annotation, set on the manifest of the image when it's published or exported.
+//
+// The annotations of the container the platform variants are published with are also set on the index of multi-platform images.
+func (r *Container) WithAnnotation(name string, value string) *Container {
+	q := r.query.Select("withAnnotation")
+	q = q.Arg("name", name)
+	q = q.Arg("value", value)
+
+	return &Container{
+		query: q,
+	}
+}
+
+// ContainerWithDNSOpts contains options for Container.WithDNS
+type ContainerWithDNSOpts struct {
+	// IP addresses of the nameservers to use instead of the engine's.
+	Nameservers []string
+	// Domains to search before the engine's (e.g., "corp.example.com").
+	SearchDomains []string
+}
+
+// Retrieves this container with the given DNS configuration for its commands.
+//
+// Nameservers replace the ones of the engine, so hostnames of services bound to the container can only be resolved if they forward to the engine's resolver.
+func (r *Container) WithDNS(opts ...ContainerWithDNSOpts) *Container {
+	q := r.query.Select("withDNS")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `nameservers` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Nameservers) {
+			q = q.Arg("nameservers", opts[i].Nameservers)
+		}
+		// `searchDomains` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SearchDomains) {
+			q = q.Arg("searchDomains", opts[i].SearchDomains)
+		}
+	}
+
+	return &Container{
+		query: q,
+	}
+}
+
 // Configures default arguments for future commands.
 func (r *Container) WithDefaultArgs(args []string) *Container {
 	q := r.query.Select("withDefaultArgs")
@@ -1001,6 +1628,18 @@ func (r *Container) WithDefaultTerminalCmd(args []string, opts ...ContainerWithD
 	}
 }
 
+// Retrieves this container with a host device exposed to its commands at the same path, e.g. to run VMs or FUSE filesystems.
+//
+// The engine must allow the device, by being started with --allow-device, or else commands fail to run.
+func (r *Container) WithDevice(path string) *Container {
+	q := r.query.Select("withDevice")
+	q = q.Arg("path", path)
+
+	return &Container{
+		query: q,
+	}
+}
+
 // ContainerWithDirectoryOpts contains options for Container.WithDirectory
 type ContainerWithDirectoryOpts struct {
 	// Patterns to exclude in the written directory (e.g. ["node_modules/**", ".gitignore", ".git/"]).
@@ -1102,6 +1741,36 @@ type ContainerWithExecOpts struct {
 	ExperimentalPrivilegedNesting bool
 	// Execute the command with all root capabilities. This is similar to running a command with "sudo" or executing "docker run" with the "--privileged" flag. Containerization does not provide any security guarantees when using this option. It should only be used when absolutely necessary and only with trusted commands.
 	InsecureRootCapabilities bool
+	// Execute the command again rather than using a cached result, e.g. for deployments.
+	//
+	// The command runs again each time the resulting container is evaluated; anything depending on its result is executed again too.
+	NoCache bool
+	// Return the resulting container even if the command exits with a non-zero code, rather than failing.
+	//
+	// The exit code is available from the exitCode field, along with the output streams.
+	AllowFailure bool
+	// Limit the number of CPUs the command can use (e.g., 1.5), or 0 for no limit.
+	Cpus float64
+	// Limit the memory the command can use in bytes, or 0 for no limit.
+	//
+	// The command is killed if it exceeds the limit.
+	MemoryBytes int
+	// Limit the bytes of stdout and stderr captured of the command and streamed to the client, or 0 for no limit of its own.
+	//
+	// Output beyond the limit is dropped and a truncation message is appended, without interrupting the command. The session's and the engine's limits apply if they're lower.
+	MaxOutputBytes int
+	// Execute the command without network access, e.g. to verify that a build is hermetic.
+	//
+	// Only the loopback interface is available; bound services and the Dagger API can't be reached.
+	NoNetwork bool
+	// Mount the container's root filesystem read-only, e.g. to verify that the command doesn't mutate the container's state.
+	//
+	// Only mounts are writable, like temporary directories from withMountedTemp or cache volumes; the resulting container's root filesystem is left unchanged.
+	ReadOnlyRootfs bool
+	// Regular expressions extracting diagnostics from the command's output (e.g., for IDE or CI annotations).
+	//
+	// The diagnostics are available from the diagnostics field.
+	ProblemMatchers []ProblemMatcher
 }
 
 // Retrieves this container after executing the specified command inside it.
@@ -1132,6 +1801,38 @@ func (r *Container) WithExec(args []string, opts ...ContainerWithExecOpts) *Cont
 		if !querybuilder.IsZeroValue(opts[i].InsecureRootCapabilities) {
 			q = q.Arg("insecureRootCapabilities", opts[i].InsecureRootCapabilities)
 		}
+		// `noCache` optional argument
+		if !querybuilder.IsZeroValue(opts[i].NoCache) {
+			q = q.Arg("noCache", opts[i].NoCache)
+		}
+		// `allowFailure` optional argument
+		if !querybuilder.IsZeroValue(opts[i].AllowFailure) {
+			q = q.Arg("allowFailure", opts[i].AllowFailure)
+		}
+		// `cpus` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Cpus) {
+			q = q.Arg("cpus", opts[i].Cpus)
+		}
+		// `memoryBytes` optional argument
+		if !querybuilder.IsZeroValue(opts[i].MemoryBytes) {
+			q = q.Arg("memoryBytes", opts[i].MemoryBytes)
+		}
+		// `maxOutputBytes` optional argument
+		if !querybuilder.IsZeroValue(opts[i].MaxOutputBytes) {
+			q = q.Arg("maxOutputBytes", opts[i].MaxOutputBytes)
+		}
+		// `noNetwork` optional argument
+		if !querybuilder.IsZeroValue(opts[i].NoNetwork) {
+			q = q.Arg("noNetwork", opts[i].NoNetwork)
+		}
+		// `readOnlyRootfs` optional argument
+		if !querybuilder.IsZeroValue(opts[i].ReadOnlyRootfs) {
+			q = q.Arg("readOnlyRootfs", opts[i].ReadOnlyRootfs)
+		}
+		// `problemMatchers` optional argument
+		if !querybuilder.IsZeroValue(opts[i].ProblemMatchers) {
+			q = q.Arg("problemMatchers", opts[i].ProblemMatchers)
+		}
 	}
 	q = q.Arg("args", args)
 
@@ -1180,6 +1881,19 @@ func (r *Container) WithExposedPort(port int, opts ...ContainerWithExposedPortOp
 	}
 }
 
+// Retrieves this container with an extra entry in the hosts file of its commands, like docker run --add-host.
+//
+// Any existing entry for the same hostname is replaced.
+func (r *Container) WithExtraHost(name string, ip string) *Container {
+	q := r.query.Select("withExtraHost")
+	q = q.Arg("name", name)
+	q = q.Arg("ip", ip)
+
+	return &Container{
+		query: q,
+	}
+}
+
 // ContainerWithFileOpts contains options for Container.WithFile
 type ContainerWithFileOpts struct {
 	// Permission given to the copied file (e.g., 0600).
@@ -1281,6 +1995,10 @@ type ContainerWithMountedCacheOpts struct {
 	//
 	// If the group is omitted, it defaults to the same as the user.
 	Owner string
+	// Permission given to the mounted cache directory (e.g., 0777).
+	//
+	// Like owner, it does not have any effect if/when the cache has already been created.
+	Permissions int
 }
 
 // Retrieves this container plus a cache volume mounted at the given path.
@@ -1300,6 +2018,10 @@ func (r *Container) WithMountedCache(path string, cache *CacheVolume, opts ...Co
 		if !querybuilder.IsZeroValue(opts[i].Owner) {
 			q = q.Arg("owner", opts[i].Owner)
 		}
+		// `permissions` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Permissions) {
+			q = q.Arg("permissions", opts[i].Permissions)
+		}
 	}
 	q = q.Arg("path", path)
 	q = q.Arg("cache", cache)
@@ -1411,6 +2133,38 @@ func (r *Container) WithMountedTemp(path string) *Container {
 	}
 }
 
+// ContainerWithNewDirectoryOpts contains options for Container.WithNewDirectory
+type ContainerWithNewDirectoryOpts struct {
+	// Permission granted to the created directory (e.g., 0777).
+	Permissions int
+	// A user:group to set for the directory.
+	//
+	// The user and group can either be an ID (1000:1000) or a name (foo:bar).
+	//
+	// If the group is omitted, it defaults to the same as the user.
+	Owner string
+}
+
+// Retrieves this container plus a new directory created at the given path.
+func (r *Container) WithNewDirectory(path string, opts ...ContainerWithNewDirectoryOpts) *Container {
+	q := r.query.Select("withNewDirectory")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `permissions` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Permissions) {
+			q = q.Arg("permissions", opts[i].Permissions)
+		}
+		// `owner` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Owner) {
+			q = q.Arg("owner", opts[i].Owner)
+		}
+	}
+	q = q.Arg("path", path)
+
+	return &Container{
+		query: q,
+	}
+}
+
 // ContainerWithNewFileOpts contains options for Container.WithNewFile
 type ContainerWithNewFileOpts struct {
 	// Content of the file to write (e.g., "Hello world!").
@@ -1503,6 +2257,29 @@ func (r *Container) WithServiceBinding(alias string, service *Service) *Containe
 	}
 }
 
+// Retrieves this container with a different stop signal.
+//
+// The signal is sent to the main process of containers run from the published image when they are stopped.
+func (r *Container) WithStopSignal(signal string) *Container {
+	q := r.query.Select("withStopSignal")
+	q = q.Arg("signal", signal)
+
+	return &Container{
+		query: q,
+	}
+}
+
+// Retrieves this container plus a new symlink at the given path.
+func (r *Container) WithSymlink(target string, linkName string) *Container {
+	q := r.query.Select("withSymlink")
+	q = q.Arg("target", target)
+	q = q.Arg("linkName", linkName)
+
+	return &Container{
+		query: q,
+	}
+}
+
 // ContainerWithUnixSocketOpts contains options for Container.WithUnixSocket
 type ContainerWithUnixSocketOpts struct {
 	// A user:group to set for the mounted socket.
@@ -1541,6 +2318,18 @@ func (r *Container) WithUser(name string) *Container {
 	}
 }
 
+// Retrieves this container plus the given path declared as a volume.
+//
+// The declaration is only image metadata: it doesn't mount anything in the container, but tells runtimes of the published image to store the path outside of the container's filesystem.
+func (r *Container) WithVolume(path string) *Container {
+	q := r.query.Select("withVolume")
+	q = q.Arg("path", path)
+
+	return &Container{
+		query: q,
+	}
+}
+
 // Retrieves this container with a different working directory.
 func (r *Container) WithWorkdir(path string) *Container {
 	q := r.query.Select("withWorkdir")
@@ -1551,6 +2340,16 @@ func (r *Container) WithWorkdir(path string) *Container {
 	}
 }
 
+// Retrieves this container minus the given OCI annotation.
+func (r *Container) WithoutAnnotation(name string) *Container {
+	q := r.query.Select("withoutAnnotation")
+	q = q.Arg("name", name)
+
+	return &Container{
+		query: q,
+	}
+}
+
 // Retrieves this container with unset default arguments for future commands.
 func (r *Container) WithoutDefaultArgs() *Container {
 	q := r.query.Select("withoutDefaultArgs")
@@ -1684,6 +2483,17 @@ func (r *Container) WithoutSecretVariable(name string) *Container {
 	}
 }
 
+// Retrieves this container with an unset stop signal.
+//
+// Should default to "SIGTERM".
+func (r *Container) WithoutStopSignal() *Container {
+	q := r.query.Select("withoutStopSignal")
+
+	return &Container{
+		query: q,
+	}
+}
+
 // Retrieves this container with a previously added Unix socket removed.
 func (r *Container) WithoutUnixSocket(path string) *Container {
 	q := r.query.Select("withoutUnixSocket")
@@ -1705,6 +2515,16 @@ func (r *Container) WithoutUser() *Container {
 	}
 }
 
+// Retrieves this container minus the given volume declaration.
+func (r *Container) WithoutVolume(path string) *Container {
+	q := r.query.Select("withoutVolume")
+	q = q.Arg("path", path)
+
+	return &Container{
+		query: q,
+	}
+}
+
 // Retrieves this container with an unset working directory.
 //
 // Should default to "/".
@@ -1843,25 +2663,149 @@ func (r *CurrentModule) WorkdirFile(path string) *File {
 	}
 }
 
-// A directory.
-type Directory struct {
+// A structured diagnostic extracted from the output of a command.
+type Diagnostic struct {
 	query *querybuilder.Selection
 
-	export *string
-	id     *DirectoryID
-	sync   *DirectoryID
-}
-type WithDirectoryFunc func(r *Directory) *Directory
-
-// With calls the provided function with current Directory.
-//
-// This is useful for reusability and readability by not breaking the calling chain.
-func (r *Directory) With(f WithDirectoryFunc) *Directory {
-	return f(r)
+	column   *int
+	file     *string
+	id       *DiagnosticID
+	line     *int
+	message  *string
+	severity *string
 }
 
-func (r *Directory) WithGraphQLQuery(q *querybuilder.Selection) *Directory {
-	return &Directory{
+func (r *Diagnostic) WithGraphQLQuery(q *querybuilder.Selection) *Diagnostic {
+	return &Diagnostic{
+		query: q,
+	}
+}
+
+// The column the diagnostic refers to, or 0 if unknown.
+func (r *Diagnostic) Column(ctx context.Context) (int, error) {
+	if r.column != nil {
+		return *r.column, nil
+	}
+	q := r.query.Select("column")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The file the diagnostic refers to, or an empty string if unknown.
+func (r *Diagnostic) File(ctx context.Context) (string, error) {
+	if r.file != nil {
+		return *r.file, nil
+	}
+	q := r.query.Select("file")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A unique identifier for this Diagnostic.
+func (r *Diagnostic) ID(ctx context.Context) (DiagnosticID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response DiagnosticID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *Diagnostic) XXX_GraphQLType() string {
+	return "Diagnostic"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *Diagnostic) XXX_GraphQLIDType() string {
+	return "DiagnosticID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *Diagnostic) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *Diagnostic) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+// The line the diagnostic refers to, or 0 if unknown.
+func (r *Diagnostic) Line(ctx context.Context) (int, error) {
+	if r.line != nil {
+		return *r.line, nil
+	}
+	q := r.query.Select("line")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The message of the diagnostic.
+func (r *Diagnostic) Message(ctx context.Context) (string, error) {
+	if r.message != nil {
+		return *r.message, nil
+	}
+	q := r.query.Select("message")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The severity of the diagnostic (e.g., "error", "warning").
+func (r *Diagnostic) Severity(ctx context.Context) (string, error) {
+	if r.severity != nil {
+		return *r.severity, nil
+	}
+	q := r.query.Select("severity")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A directory.
+type Directory struct {
+	query *querybuilder.Selection
+
+	cached *bool
+	export *string
+	id     *DirectoryID
+	sync   *DirectoryID
+}
+type WithDirectoryFunc func(r *Directory) *Directory
+
+// With calls the provided function with current Directory.
+//
+// This is useful for reusability and readability by not breaking the calling chain.
+func (r *Directory) With(f WithDirectoryFunc) *Directory {
+	return f(r)
+}
+
+func (r *Directory) WithGraphQLQuery(q *querybuilder.Selection) *Directory {
+	return &Directory{
 		query: q,
 	}
 }
@@ -1891,6 +2835,21 @@ func (r *Directory) AsModule(opts ...DirectoryAsModuleOpts) *Module {
 	}
 }
 
+// Returns whether the execs the directory is made of are already cached, without running them.
+//
+// Other operations, like pulling images or loading host directories, are still performed, since the cache keys of the execs depend on their results.
+func (r *Directory) Cached(ctx context.Context) (bool, error) {
+	if r.cached != nil {
+		return *r.cached, nil
+	}
+	q := r.query.Select("cached")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // Gets the difference between this directory and an another directory.
 func (r *Directory) Diff(other *Directory) *Directory {
 	assertNotNil("other", other)
@@ -1963,6 +2922,10 @@ func (r *Directory) DockerBuild(opts ...DirectoryDockerBuildOpts) *Container {
 type DirectoryEntriesOpts struct {
 	// Location of the directory to look at (e.g., "/src").
 	Path string
+	// List the entries of subdirectories too, with paths relative to the directory looked at.
+	Recursive bool
+	// Only list the entries whose path matches the pattern (e.g., "**/*.go").
+	Pattern string
 }
 
 // Returns a list of files and directories at the given path.
@@ -1973,6 +2936,14 @@ func (r *Directory) Entries(ctx context.Context, opts ...DirectoryEntriesOpts) (
 		if !querybuilder.IsZeroValue(opts[i].Path) {
 			q = q.Arg("path", opts[i].Path)
 		}
+		// `recursive` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Recursive) {
+			q = q.Arg("recursive", opts[i].Recursive)
+		}
+		// `pattern` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Pattern) {
+			q = q.Arg("pattern", opts[i].Pattern)
+		}
 	}
 
 	var response []string
@@ -2017,6 +2988,35 @@ func (r *Directory) File(path string) *File {
 	}
 }
 
+// DirectoryGitVersionOpts contains options for Directory.GitVersion
+type DirectoryGitVersionOpts struct {
+	// Location of the repository within the directory.
+	Path string
+	// Prefix of the tags to consider, which must be followed by a semver version.
+	Prefix string
+}
+
+// Computes version information from the git tags and history of the repository in this directory.
+//
+// The directory must include the .git directory, e.g. by querying the repository with keepGitDir.
+func (r *Directory) GitVersion(opts ...DirectoryGitVersionOpts) *GitVersion {
+	q := r.query.Select("gitVersion")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `path` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Path) {
+			q = q.Arg("path", opts[i].Path)
+		}
+		// `prefix` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Prefix) {
+			q = q.Arg("prefix", opts[i].Prefix)
+		}
+	}
+
+	return &GitVersion{
+		query: q,
+	}
+}
+
 // Returns a list of files and directories that matche the given pattern.
 func (r *Directory) Glob(ctx context.Context, pattern string) ([]string, error) {
 	q := r.query.Select("glob")
@@ -2028,6 +3028,30 @@ func (r *Directory) Glob(ctx context.Context, pattern string) ([]string, error)
 	return response, q.Execute(ctx)
 }
 
+// DirectoryGraphOpts contains options for Directory.Graph
+type DirectoryGraphOpts struct {
+	// The format to export the graph in.
+	Format GraphFormat
+}
+
+// Evaluates the directory and returns the graph of its operations.
+//
+// Each operation is reported with its cache status, so that accidental serialization or cache misses can be spotted.
+func (r *Directory) Graph(ctx context.Context, opts ...DirectoryGraphOpts) (string, error) {
+	q := r.query.Select("graph")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `format` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Format) {
+			q = q.Arg("format", opts[i].Format)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // A unique identifier for this Directory.
 func (r *Directory) ID(ctx context.Context) (DirectoryID, error) {
 	if r.id != nil {
@@ -2096,7 +3120,19 @@ func (r *Directory) Pipeline(name string, opts ...DirectoryPipelineOpts) *Direct
 	}
 }
 
+// Returns information about the file or directory at the given path.
+func (r *Directory) Stat(path string) *Stat {
+	q := r.query.Select("stat")
+	q = q.Arg("path", path)
+
+	return &Stat{
+		query: q,
+	}
+}
+
 // Force evaluation in the engine.
+//
+// Modifications of the result are built on top of the evaluated directory, so syncing it before branching off divergent modifications evaluates it only once.
 func (r *Directory) Sync(ctx context.Context) (*Directory, error) {
 	q := r.query.Select("sync")
 
@@ -2243,57 +3279,458 @@ func (r *Directory) WithNewDirectory(path string, opts ...DirectoryWithNewDirect
 	}
 }
 
-// DirectoryWithNewFileOpts contains options for Directory.WithNewFile
-type DirectoryWithNewFileOpts struct {
-	// Permission given to the copied file (e.g., 0600).
-	Permissions int
+// DirectoryWithNewFileOpts contains options for Directory.WithNewFile
+type DirectoryWithNewFileOpts struct {
+	// Permission given to the copied file (e.g., 0600).
+	Permissions int
+}
+
+// Retrieves this directory plus a new file written at the given path.
+func (r *Directory) WithNewFile(path string, contents string, opts ...DirectoryWithNewFileOpts) *Directory {
+	q := r.query.Select("withNewFile")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `permissions` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Permissions) {
+			q = q.Arg("permissions", opts[i].Permissions)
+		}
+	}
+	q = q.Arg("path", path)
+	q = q.Arg("contents", contents)
+
+	return &Directory{
+		query: q,
+	}
+}
+
+// Retrieves this directory with the owner of the file or directory at the given path changed, recursively.
+func (r *Directory) WithOwner(path string, owner string) *Directory {
+	q := r.query.Select("withOwner")
+	q = q.Arg("path", path)
+	q = q.Arg("owner", owner)
+
+	return &Directory{
+		query: q,
+	}
+}
+
+// Retrieves this directory with the permissions of the file or directory at the given path changed, recursively.
+func (r *Directory) WithPermissions(path string, permissions int) *Directory {
+	q := r.query.Select("withPermissions")
+	q = q.Arg("path", path)
+	q = q.Arg("permissions", permissions)
+
+	return &Directory{
+		query: q,
+	}
+}
+
+// Retrieves this directory plus a new symlink at the given path.
+func (r *Directory) WithSymlink(target string, linkName string) *Directory {
+	q := r.query.Select("withSymlink")
+	q = q.Arg("target", target)
+	q = q.Arg("linkName", linkName)
+
+	return &Directory{
+		query: q,
+	}
+}
+
+// Retrieves this directory with all file/dir timestamps set to the given time.
+func (r *Directory) WithTimestamps(timestamp int) *Directory {
+	q := r.query.Select("withTimestamps")
+	q = q.Arg("timestamp", timestamp)
+
+	return &Directory{
+		query: q,
+	}
+}
+
+// Retrieves this directory with the directory at the given path removed.
+func (r *Directory) WithoutDirectory(path string) *Directory {
+	q := r.query.Select("withoutDirectory")
+	q = q.Arg("path", path)
+
+	return &Directory{
+		query: q,
+	}
+}
+
+// Retrieves this directory with the entries matching any of the given patterns removed.
+func (r *Directory) WithoutEntries(patterns []string) *Directory {
+	q := r.query.Select("withoutEntries")
+	q = q.Arg("patterns", patterns)
+
+	return &Directory{
+		query: q,
+	}
+}
+
+// Retrieves this directory with the file at the given path removed.
+func (r *Directory) WithoutFile(path string) *Directory {
+	q := r.query.Select("withoutFile")
+	q = q.Arg("path", path)
+
+	return &Directory{
+		query: q,
+	}
+}
+
+// The Dagger engine serving the current session.
+type Engine struct {
+	query *querybuilder.Selection
+
+	buildkitVersion *string
+	id              *EngineID
+	ping            *Void
+	removeSession   *Void
+	version         *string
+}
+
+func (r *Engine) WithGraphQLQuery(q *querybuilder.Selection) *Engine {
+	return &Engine{
+		query: q,
+	}
+}
+
+// The version of Buildkit embedded in the engine (e.g., "v0.14.1").
+//
+// The optional features supported by the engine are reported by "capabilities".
+func (r *Engine) BuildkitVersion(ctx context.Context) (string, error) {
+	if r.buildkitVersion != nil {
+		return *r.buildkitVersion, nil
+	}
+	q := r.query.Select("buildkitVersion")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The disk usage of the engine's cache, by type of cache record, from the largest to the smallest.
+func (r *Engine) CacheUsage(ctx context.Context) ([]CacheUsage, error) {
+	q := r.query.Select("cacheUsage")
+
+	q = q.Select("id")
+
+	type cacheUsage struct {
+		Id CacheUsageID
+	}
+
+	convert := func(fields []cacheUsage) []CacheUsage {
+		out := []CacheUsage{}
+
+		for i := range fields {
+			val := CacheUsage{id: &fields[i].Id}
+			val.query = q.Root().Select("loadCacheUsageFromID").Arg("id", fields[i].Id)
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []cacheUsage
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
+// A unique identifier for this Engine.
+func (r *Engine) ID(ctx context.Context) (EngineID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response EngineID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *Engine) XXX_GraphQLType() string {
+	return "Engine"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *Engine) XXX_GraphQLIDType() string {
+	return "EngineID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *Engine) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *Engine) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+// EnginePruneOpts contains options for Engine.Prune
+type EnginePruneOpts struct {
+	// Only prune records matching any of these filters (e.g., "type==exec.cachemount", "type==source.local").
+	Filter []string
+	// Stop pruning once the matching records use at most this many bytes.
+	//
+	// 0 prunes all of them.
+	KeepBytes int
+	// Only prune records that have not been used for this long (e.g., "48h").
+	KeepDuration string
+	// Also prune records that are shared or internal to the engine.
+	All bool
+}
+
+// Removes cache records that are not in use from the engine, returning the disk space reclaimed.
+//
+// Only admins of the engine can prune its cache, which is shared by every session.
+func (r *Engine) Prune(opts ...EnginePruneOpts) *CachePruneResult {
+	q := r.query.Select("prune")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `filter` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Filter) {
+			q = q.Arg("filter", opts[i].Filter)
+		}
+		// `keepBytes` optional argument
+		if !querybuilder.IsZeroValue(opts[i].KeepBytes) {
+			q = q.Arg("keepBytes", opts[i].KeepBytes)
+		}
+		// `keepDuration` optional argument
+		if !querybuilder.IsZeroValue(opts[i].KeepDuration) {
+			q = q.Arg("keepDuration", opts[i].KeepDuration)
+		}
+		// `all` optional argument
+		if !querybuilder.IsZeroValue(opts[i].All) {
+			q = q.Arg("all", opts[i].All)
+		}
+	}
+
+	return &CachePruneResult{
+		query: q,
+	}
+}
+
+// Checks that the engine is healthy, returning an error describing the problem otherwise.
+func (r *Engine) Ping(ctx context.Context) (Void, error) {
+	if r.ping != nil {
+		return *r.ping, nil
+	}
+	q := r.query.Select("ping")
+
+	var response Void
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// Forcibly removes a session of the engine, stopping its services and releasing its containers.
+//
+// This is meant to clean up the sessions left behind by clients that are no longer running. Only the sessions listed by "sessions" can be removed.
+func (r *Engine) RemoveSession(ctx context.Context, sessionID string) (Void, error) {
+	if r.removeSession != nil {
+		return *r.removeSession, nil
+	}
+	q := r.query.Select("removeSession")
+	q = q.Arg("sessionID", sessionID)
+
+	var response Void
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The sessions of the engine the client may manage: every session for admins of the engine, or else those started with the same credentials.
+//
+// Sessions whose client stopped sending heartbeats, e.g. because it crashed, are removed automatically along with their services.
+func (r *Engine) Sessions(ctx context.Context) ([]EngineSession, error) {
+	q := r.query.Select("sessions")
+
+	q = q.Select("id")
+
+	type sessions struct {
+		Id EngineSessionID
+	}
+
+	convert := func(fields []sessions) []EngineSession {
+		out := []EngineSession{}
+
+		for i := range fields {
+			val := EngineSession{id: &fields[i].Id}
+			val.query = q.Root().Select("loadEngineSessionFromID").Arg("id", fields[i].Id)
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []sessions
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
+// The version of the engine.
+func (r *Engine) Version(ctx context.Context) (string, error) {
+	if r.version != nil {
+		return *r.version, nil
+	}
+	q := r.query.Select("version")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A session of a Dagger engine, holding the state of a client and its services.
+type EngineSession struct {
+	query *querybuilder.Selection
+
+	clientHostname *string
+	current        *bool
+	id             *EngineSessionID
+	lastHeartbeat  *string
+	sessionID      *string
+	stale          *bool
+}
+
+func (r *EngineSession) WithGraphQLQuery(q *querybuilder.Selection) *EngineSession {
+	return &EngineSession{
+		query: q,
+	}
+}
+
+// The hostname of the client that started the session.
+func (r *EngineSession) ClientHostname(ctx context.Context) (string, error) {
+	if r.clientHostname != nil {
+		return *r.clientHostname, nil
+	}
+	q := r.query.Select("clientHostname")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// Whether this is the current session.
+func (r *EngineSession) Current(ctx context.Context) (bool, error) {
+	if r.current != nil {
+		return *r.current, nil
+	}
+	q := r.query.Select("current")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A unique identifier for this EngineSession.
+func (r *EngineSession) ID(ctx context.Context) (EngineSessionID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response EngineSessionID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *EngineSession) XXX_GraphQLType() string {
+	return "EngineSession"
 }
 
-// Retrieves this directory plus a new file written at the given path.
-func (r *Directory) WithNewFile(path string, contents string, opts ...DirectoryWithNewFileOpts) *Directory {
-	q := r.query.Select("withNewFile")
-	for i := len(opts) - 1; i >= 0; i-- {
-		// `permissions` optional argument
-		if !querybuilder.IsZeroValue(opts[i].Permissions) {
-			q = q.Arg("permissions", opts[i].Permissions)
-		}
-	}
-	q = q.Arg("path", path)
-	q = q.Arg("contents", contents)
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *EngineSession) XXX_GraphQLIDType() string {
+	return "EngineSessionID"
+}
 
-	return &Directory{
-		query: q,
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *EngineSession) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
 	}
+	return string(id), nil
 }
 
-// Retrieves this directory with all file/dir timestamps set to the given time.
-func (r *Directory) WithTimestamps(timestamp int) *Directory {
-	q := r.query.Select("withTimestamps")
-	q = q.Arg("timestamp", timestamp)
+func (r *EngineSession) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
 
-	return &Directory{
-		query: q,
+// When the client last sent a heartbeat, in RFC 3339 format, or an empty string if it does not send heartbeats.
+func (r *EngineSession) LastHeartbeat(ctx context.Context) (string, error) {
+	if r.lastHeartbeat != nil {
+		return *r.lastHeartbeat, nil
 	}
+	q := r.query.Select("lastHeartbeat")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
 }
 
-// Retrieves this directory with the directory at the given path removed.
-func (r *Directory) WithoutDirectory(path string) *Directory {
-	q := r.query.Select("withoutDirectory")
-	q = q.Arg("path", path)
+// The hostnames of the services running in the session.
+func (r *EngineSession) Services(ctx context.Context) ([]string, error) {
+	q := r.query.Select("services")
 
-	return &Directory{
-		query: q,
-	}
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
 }
 
-// Retrieves this directory with the file at the given path removed.
-func (r *Directory) WithoutFile(path string) *Directory {
-	q := r.query.Select("withoutFile")
-	q = q.Arg("path", path)
+// The ID of the session.
+func (r *EngineSession) SessionID(ctx context.Context) (string, error) {
+	if r.sessionID != nil {
+		return *r.sessionID, nil
+	}
+	q := r.query.Select("sessionID")
 
-	return &Directory{
-		query: q,
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// Whether the client stopped sending heartbeats, in which case the session is about to be removed.
+func (r *EngineSession) Stale(ctx context.Context) (bool, error) {
+	if r.stale != nil {
+		return *r.stale, nil
 	}
+	q := r.query.Select("stale")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
 }
 
 // A definition of a custom enum defined in a Module.
@@ -2682,9 +4119,13 @@ func (r *FieldTypeDef) TypeDef() *TypeDef {
 type File struct {
 	query *querybuilder.Selection
 
+	cached   *bool
 	contents *string
+	digest   *string
 	export   *string
 	id       *FileID
+	modTime  *int
+	mode     *int
 	name     *string
 	size     *int
 	sync     *FileID
@@ -2704,6 +4145,21 @@ func (r *File) WithGraphQLQuery(q *querybuilder.Selection) *File {
 	}
 }
 
+// Returns whether the execs the file is made of are already cached, without running them.
+//
+// Other operations, like pulling images or loading host directories, are still performed, since the cache keys of the execs depend on their results.
+func (r *File) Cached(ctx context.Context) (bool, error) {
+	if r.cached != nil {
+		return *r.cached, nil
+	}
+	q := r.query.Select("cached")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // Retrieves the contents of the file.
 func (r *File) Contents(ctx context.Context) (string, error) {
 	if r.contents != nil {
@@ -2717,6 +4173,31 @@ func (r *File) Contents(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx)
 }
 
+// FileDigestOpts contains options for File.Digest
+type FileDigestOpts struct {
+	// Hash algorithm to use.
+	Algorithm DigestAlgorithm
+}
+
+// Computes the digest of the contents of the file (e.g., "sha256:...").
+func (r *File) Digest(ctx context.Context, opts ...FileDigestOpts) (string, error) {
+	if r.digest != nil {
+		return *r.digest, nil
+	}
+	q := r.query.Select("digest")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `algorithm` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Algorithm) {
+			q = q.Arg("algorithm", opts[i].Algorithm)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // FileExportOpts contains options for File.Export
 type FileExportOpts struct {
 	// If allowParentDirPath is true, the path argument can be a directory path, in which case the file will be created in that directory.
@@ -2743,6 +4224,30 @@ func (r *File) Export(ctx context.Context, path string, opts ...FileExportOpts)
 	return response, q.Execute(ctx)
 }
 
+// FileGraphOpts contains options for File.Graph
+type FileGraphOpts struct {
+	// The format to export the graph in.
+	Format GraphFormat
+}
+
+// Evaluates the file and returns the graph of its operations.
+//
+// Each operation is reported with its cache status, so that accidental serialization or cache misses can be spotted.
+func (r *File) Graph(ctx context.Context, opts ...FileGraphOpts) (string, error) {
+	q := r.query.Select("graph")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `format` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Format) {
+			q = q.Arg("format", opts[i].Format)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // A unique identifier for this File.
 func (r *File) ID(ctx context.Context) (FileID, error) {
 	if r.id != nil {
@@ -2783,6 +4288,32 @@ func (r *File) MarshalJSON() ([]byte, error) {
 	return json.Marshal(id)
 }
 
+// Retrieves the last modification time of the file, in seconds following Unix epoch.
+func (r *File) ModTime(ctx context.Context) (int, error) {
+	if r.modTime != nil {
+		return *r.modTime, nil
+	}
+	q := r.query.Select("modTime")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// Retrieves the permission bits of the file (e.g., 0644).
+func (r *File) Mode(ctx context.Context) (int, error) {
+	if r.mode != nil {
+		return *r.mode, nil
+	}
+	q := r.query.Select("mode")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // Retrieves the name of the file.
 func (r *File) Name(ctx context.Context) (string, error) {
 	if r.name != nil {
@@ -3668,6 +5199,10 @@ type GitRefTreeOpts struct {
 	SSHKnownHosts string
 	// DEPRECATED: This option should be passed to `git` instead.
 	SSHAuthSocket *Socket
+	// The number of commits of history to fetch.
+	//
+	// Only has an effect when the repository was queried with keepGitDir. Tags are fetched along with the history when set to anything other than 1, so that tools like "git describe" work. Set to -1 to fetch the full history.
+	Depth int
 }
 
 // The filesystem tree at this ref.
@@ -3682,6 +5217,10 @@ func (r *GitRef) Tree(opts ...GitRefTreeOpts) *Directory {
 		if !querybuilder.IsZeroValue(opts[i].SSHAuthSocket) {
 			q = q.Arg("sshAuthSocket", opts[i].SSHAuthSocket)
 		}
+		// `depth` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Depth) {
+			q = q.Arg("depth", opts[i].Depth)
+		}
 	}
 
 	return &Directory{
@@ -3810,10 +5349,24 @@ func (r *GitRepository) WithAuthHeader(header *Secret) *GitRepository {
 	}
 }
 
+// GitRepositoryWithAuthTokenOpts contains options for GitRepository.WithAuthToken
+type GitRepositoryWithAuthTokenOpts struct {
+	// Username to send along with the token during basic HTTP Authorization.
+	//
+	// Defaults to "x-access-token", which is accepted by GitHub. Other remotes may require a specific value (e.g. "gitlab-ci-token" for GitLab CI job tokens, or "x-token-auth" for Bitbucket).
+	Username string
+}
+
 // Token to authenticate the remote with.
-func (r *GitRepository) WithAuthToken(token *Secret) *GitRepository {
+func (r *GitRepository) WithAuthToken(token *Secret, opts ...GitRepositoryWithAuthTokenOpts) *GitRepository {
 	assertNotNil("token", token)
 	q := r.query.Select("withAuthToken")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `username` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Username) {
+			q = q.Arg("username", opts[i].Username)
+		}
+	}
 	q = q.Arg("token", token)
 
 	return &GitRepository{
@@ -3821,6 +5374,143 @@ func (r *GitRepository) WithAuthToken(token *Secret) *GitRepository {
 	}
 }
 
+// Version information derived from the tags and history of a git repository.
+type GitVersion struct {
+	query *querybuilder.Selection
+
+	commit   *string
+	describe *string
+	distance *int
+	id       *GitVersionID
+	next     *string
+	tag      *string
+	version  *string
+}
+
+func (r *GitVersion) WithGraphQLQuery(q *querybuilder.Selection) *GitVersion {
+	return &GitVersion{
+		query: q,
+	}
+}
+
+// The commit checked out in the repository.
+func (r *GitVersion) Commit(ctx context.Context) (string, error) {
+	if r.commit != nil {
+		return *r.commit, nil
+	}
+	q := r.query.Select("commit")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A description of the commit in the style of "git describe --tags" (e.g., "v1.2.3-4-gabcdef0").
+func (r *GitVersion) Describe(ctx context.Context) (string, error) {
+	if r.describe != nil {
+		return *r.describe, nil
+	}
+	q := r.query.Select("describe")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The number of commits between the nearest tag and the commit.
+func (r *GitVersion) Distance(ctx context.Context) (int, error) {
+	if r.distance != nil {
+		return *r.distance, nil
+	}
+	q := r.query.Select("distance")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A unique identifier for this GitVersion.
+func (r *GitVersion) ID(ctx context.Context) (GitVersionID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response GitVersionID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *GitVersion) XXX_GraphQLType() string {
+	return "GitVersion"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *GitVersion) XXX_GraphQLIDType() string {
+	return "GitVersionID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *GitVersion) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *GitVersion) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+// The next version according to the conventional commits since the nearest tag, without prefix. Equal to version if the commit is tagged.
+func (r *GitVersion) Next(ctx context.Context) (string, error) {
+	if r.next != nil {
+		return *r.next, nil
+	}
+	q := r.query.Select("next")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The nearest semver tag reachable from the commit, or an empty string if there is none.
+func (r *GitVersion) Tag(ctx context.Context) (string, error) {
+	if r.tag != nil {
+		return *r.tag, nil
+	}
+	q := r.query.Select("tag")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The version of the nearest tag, without its prefix (e.g., "1.2.3"). "0.0.0" if there is no tag.
+func (r *GitVersion) Version(ctx context.Context) (string, error) {
+	if r.version != nil {
+		return *r.version, nil
+	}
+	q := r.query.Select("version")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // Information about the host environment.
 type Host struct {
 	query *querybuilder.Selection
@@ -5692,17 +7382,62 @@ func (r *Client) BuiltinContainer(digest string) *Container {
 	q := r.query.Select("builtinContainer")
 	q = q.Arg("digest", digest)
 
-	return &Container{
-		query: q,
+	return &Container{
+		query: q,
+	}
+}
+
+// Constructs a cache volume for a given cache key.
+func (r *Client) CacheVolume(key string) *CacheVolume {
+	q := r.query.Select("cacheVolume")
+	q = q.Arg("key", key)
+
+	return &CacheVolume{
+		query: q,
+	}
+}
+
+// Reports whether the engine supports each of its optional features.
+func (r *Client) Capabilities(ctx context.Context) ([]Capability, error) {
+	q := r.query.Select("capabilities")
+
+	q = q.Select("id")
+
+	type capabilities struct {
+		Id CapabilityID
+	}
+
+	convert := func(fields []capabilities) []Capability {
+		out := []Capability{}
+
+		for i := range fields {
+			val := Capability{id: &fields[i].Id}
+			val.query = q.Root().Select("loadCapabilityFromID").Arg("id", fields[i].Id)
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []capabilities
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx)
+	if err != nil {
+		return nil, err
 	}
+
+	return convert(response), nil
 }
 
-// Constructs a cache volume for a given cache key.
-func (r *Client) CacheVolume(key string) *CacheVolume {
-	q := r.query.Select("cacheVolume")
-	q = q.Arg("key", key)
+// Reports whether the engine supports an optional feature.
+//
+// Operations using an unsupported feature fail with an error whose "_type" extension is "UNSUPPORTED".
+func (r *Client) Capability(name EngineCapability) *Capability {
+	q := r.query.Select("capability")
+	q = q.Arg("name", name)
 
-	return &CacheVolume{
+	return &Capability{
 		query: q,
 	}
 }
@@ -5736,6 +7471,22 @@ func (r *Client) Container(opts ...ContainerOpts) *Container {
 	}
 }
 
+// Copies an image, including all of its platforms, from one address to another using only registry APIs, without pulling its layers into the engine.
+//
+// Layers are mounted from the source repository when both addresses are on the same registry, and streamed between the registries otherwise.
+//
+// Returns the fully qualified ref of the copied image.
+func (r *Client) CopyImage(ctx context.Context, source string, destination string) (string, error) {
+	q := r.query.Select("copyImage")
+	q = q.Arg("source", source)
+	q = q.Arg("destination", destination)
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // The FunctionCall context that the SDK caller is currently executing in.
 //
 // If the caller is not currently executing in a function, this will return an error.
@@ -5799,6 +7550,17 @@ func (r *Client) DefaultPlatform(ctx context.Context) (Platform, error) {
 	return response, q.Execute(ctx)
 }
 
+// Retrieves a named default value configured for the project (e.g., an APK mirror), or null if it isn't set.
+func (r *Client) DefaultValue(ctx context.Context, name string) (string, error) {
+	q := r.query.Select("defaultValue")
+	q = q.Arg("name", name)
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // DirectoryOpts contains options for Client.Directory
 type DirectoryOpts struct {
 	// DEPRECATED: Use `loadDirectoryFromID` instead.
@@ -5820,6 +7582,15 @@ func (r *Client) Directory(opts ...DirectoryOpts) *Directory {
 	}
 }
 
+// Queries the engine serving the current session.
+func (r *Client) Engine() *Engine {
+	q := r.query.Select("engine")
+
+	return &Engine{
+		query: q,
+	}
+}
+
 // Deprecated: Use LoadFileFromID instead.
 func (r *Client) File(id FileID) *File {
 	q := r.query.Select("file")
@@ -5904,6 +7675,14 @@ func (r *Client) Host() *Host {
 
 // HTTPOpts contains options for Client.HTTP
 type HTTPOpts struct {
+	// Expected digest of the content (e.g., "sha256:...").
+	//
+	// If the downloaded content does not match, the query fails.
+	Checksum string
+	// Additional headers to send with the request.
+	Headers []HTTPHeader
+	// Secret used to populate the Authorization header of the request.
+	AuthHeader *Secret
 	// A service which must be started before the URL is fetched.
 	ExperimentalServiceHost *Service
 }
@@ -5912,6 +7691,18 @@ type HTTPOpts struct {
 func (r *Client) HTTP(url string, opts ...HTTPOpts) *File {
 	q := r.query.Select("http")
 	for i := len(opts) - 1; i >= 0; i-- {
+		// `checksum` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Checksum) {
+			q = q.Arg("checksum", opts[i].Checksum)
+		}
+		// `headers` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Headers) {
+			q = q.Arg("headers", opts[i].Headers)
+		}
+		// `authHeader` optional argument
+		if !querybuilder.IsZeroValue(opts[i].AuthHeader) {
+			q = q.Arg("authHeader", opts[i].AuthHeader)
+		}
 		// `experimentalServiceHost` optional argument
 		if !querybuilder.IsZeroValue(opts[i].ExperimentalServiceHost) {
 			q = q.Arg("experimentalServiceHost", opts[i].ExperimentalServiceHost)
@@ -5924,6 +7715,26 @@ func (r *Client) HTTP(url string, opts ...HTTPOpts) *File {
 	}
 }
 
+// Load a CachePruneResult from its ID.
+func (r *Client) LoadCachePruneResultFromID(id CachePruneResultID) *CachePruneResult {
+	q := r.query.Select("loadCachePruneResultFromID")
+	q = q.Arg("id", id)
+
+	return &CachePruneResult{
+		query: q,
+	}
+}
+
+// Load a CacheUsage from its ID.
+func (r *Client) LoadCacheUsageFromID(id CacheUsageID) *CacheUsage {
+	q := r.query.Select("loadCacheUsageFromID")
+	q = q.Arg("id", id)
+
+	return &CacheUsage{
+		query: q,
+	}
+}
+
 // Load a CacheVolume from its ID.
 func (r *Client) LoadCacheVolumeFromID(id CacheVolumeID) *CacheVolume {
 	q := r.query.Select("loadCacheVolumeFromID")
@@ -5934,6 +7745,16 @@ func (r *Client) LoadCacheVolumeFromID(id CacheVolumeID) *CacheVolume {
 	}
 }
 
+// Load a Capability from its ID.
+func (r *Client) LoadCapabilityFromID(id CapabilityID) *Capability {
+	q := r.query.Select("loadCapabilityFromID")
+	q = q.Arg("id", id)
+
+	return &Capability{
+		query: q,
+	}
+}
+
 // Load a Container from its ID.
 func (r *Client) LoadContainerFromID(id ContainerID) *Container {
 	q := r.query.Select("loadContainerFromID")
@@ -5954,6 +7775,16 @@ func (r *Client) LoadCurrentModuleFromID(id CurrentModuleID) *CurrentModule {
 	}
 }
 
+// Load a Diagnostic from its ID.
+func (r *Client) LoadDiagnosticFromID(id DiagnosticID) *Diagnostic {
+	q := r.query.Select("loadDiagnosticFromID")
+	q = q.Arg("id", id)
+
+	return &Diagnostic{
+		query: q,
+	}
+}
+
 // Load a Directory from its ID.
 func (r *Client) LoadDirectoryFromID(id DirectoryID) *Directory {
 	q := r.query.Select("loadDirectoryFromID")
@@ -5964,6 +7795,26 @@ func (r *Client) LoadDirectoryFromID(id DirectoryID) *Directory {
 	}
 }
 
+// Load a Engine from its ID.
+func (r *Client) LoadEngineFromID(id EngineID) *Engine {
+	q := r.query.Select("loadEngineFromID")
+	q = q.Arg("id", id)
+
+	return &Engine{
+		query: q,
+	}
+}
+
+// Load a EngineSession from its ID.
+func (r *Client) LoadEngineSessionFromID(id EngineSessionID) *EngineSession {
+	q := r.query.Select("loadEngineSessionFromID")
+	q = q.Arg("id", id)
+
+	return &EngineSession{
+		query: q,
+	}
+}
+
 // Load a EnumTypeDef from its ID.
 func (r *Client) LoadEnumTypeDefFromID(id EnumTypeDefID) *EnumTypeDef {
 	q := r.query.Select("loadEnumTypeDefFromID")
@@ -6094,6 +7945,16 @@ func (r *Client) LoadGitRepositoryFromID(id GitRepositoryID) *GitRepository {
 	}
 }
 
+// Load a GitVersion from its ID.
+func (r *Client) LoadGitVersionFromID(id GitVersionID) *GitVersion {
+	q := r.query.Select("loadGitVersionFromID")
+	q = q.Arg("id", id)
+
+	return &GitVersion{
+		query: q,
+	}
+}
+
 // Load a Host from its ID.
 func (r *Client) LoadHostFromID(id HostID) *Host {
 	q := r.query.Select("loadHostFromID")
@@ -6254,6 +8115,16 @@ func (r *Client) LoadSocketFromID(id SocketID) *Socket {
 	}
 }
 
+// Load a Stat from its ID.
+func (r *Client) LoadStatFromID(id StatID) *Stat {
+	q := r.query.Select("loadStatFromID")
+	q = q.Arg("id", id)
+
+	return &Stat{
+		query: q,
+	}
+}
+
 // Load a TypeDef from its ID.
 func (r *Client) LoadTypeDefFromID(id TypeDefID) *TypeDef {
 	q := r.query.Select("loadTypeDefFromID")
@@ -6393,6 +8264,39 @@ func (r *Client) Socket(id SocketID) *Socket {
 	}
 }
 
+// ToolOpts contains options for Client.Tool
+type ToolOpts struct {
+	// Platform of the executable. Defaults to the platform of the engine.
+	Platform Platform
+	// Expected digest of the release artifact (e.g., "sha256:...").
+	//
+	// Defaults to the checksum pinned by the engine, which is required if the engine doesn't pin the release.
+	Checksum string
+}
+
+// Returns the executable of a pinned release of a well-known tool (e.g., "kubectl", "helm", "golangci-lint").
+//
+// The release is downloaded from the tool's official location and verified against its checksum, pinned by the engine.
+func (r *Client) Tool(name string, version string, opts ...ToolOpts) *File {
+	q := r.query.Select("tool")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `platform` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Platform) {
+			q = q.Arg("platform", opts[i].Platform)
+		}
+		// `checksum` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Checksum) {
+			q = q.Arg("checksum", opts[i].Checksum)
+		}
+	}
+	q = q.Arg("name", name)
+	q = q.Arg("version", version)
+
+	return &File{
+		query: q,
+	}
+}
+
 // Create a new TypeDef.
 func (r *Client) TypeDef() *TypeDef {
 	q := r.query.Select("typeDef")
@@ -6841,6 +8745,171 @@ func (r *Socket) MarshalJSON() ([]byte, error) {
 	return json.Marshal(id)
 }
 
+// Information about a file or directory.
+type Stat struct {
+	query *querybuilder.Selection
+
+	gid           *int
+	id            *StatID
+	modTime       *int
+	mode          *int
+	name          *string
+	size          *int
+	symlinkTarget *string
+	type_         *FileType
+	uid           *int
+}
+
+func (r *Stat) WithGraphQLQuery(q *querybuilder.Selection) *Stat {
+	return &Stat{
+		query: q,
+	}
+}
+
+// The group ID owning the entry.
+func (r *Stat) Gid(ctx context.Context) (int, error) {
+	if r.gid != nil {
+		return *r.gid, nil
+	}
+	q := r.query.Select("gid")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A unique identifier for this Stat.
+func (r *Stat) ID(ctx context.Context) (StatID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response StatID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *Stat) XXX_GraphQLType() string {
+	return "Stat"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *Stat) XXX_GraphQLIDType() string {
+	return "StatID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *Stat) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *Stat) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+// The last modification time of the entry, in seconds following Unix epoch.
+func (r *Stat) ModTime(ctx context.Context) (int, error) {
+	if r.modTime != nil {
+		return *r.modTime, nil
+	}
+	q := r.query.Select("modTime")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The permission bits of the entry (e.g., 0644).
+func (r *Stat) Mode(ctx context.Context) (int, error) {
+	if r.mode != nil {
+		return *r.mode, nil
+	}
+	q := r.query.Select("mode")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The name of the entry.
+func (r *Stat) Name(ctx context.Context) (string, error) {
+	if r.name != nil {
+		return *r.name, nil
+	}
+	q := r.query.Select("name")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The size of the entry, in bytes.
+func (r *Stat) Size(ctx context.Context) (int, error) {
+	if r.size != nil {
+		return *r.size, nil
+	}
+	q := r.query.Select("size")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The location the entry points to if it is a symlink, or an empty string otherwise.
+func (r *Stat) SymlinkTarget(ctx context.Context) (string, error) {
+	if r.symlinkTarget != nil {
+		return *r.symlinkTarget, nil
+	}
+	q := r.query.Select("symlinkTarget")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The type of the entry.
+func (r *Stat) Type(ctx context.Context) (FileType, error) {
+	if r.type_ != nil {
+		return *r.type_, nil
+	}
+	q := r.query.Select("type")
+
+	var response FileType
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The user ID owning the entry.
+func (r *Stat) UID(ctx context.Context) (int, error) {
+	if r.uid != nil {
+		return *r.uid, nil
+	}
+	q := r.query.Select("uid")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // A definition of a parameter or return type in a Module.
 type TypeDef struct {
 	query *querybuilder.Selection
@@ -7187,6 +9256,60 @@ const (
 	Shared CacheSharingMode = "SHARED"
 )
 
+type DigestAlgorithm string
+
+func (DigestAlgorithm) IsEnum() {}
+
+const (
+	Sha256 DigestAlgorithm = "SHA256"
+
+	Sha512 DigestAlgorithm = "SHA512"
+)
+
+type EngineCapability string
+
+func (EngineCapability) IsEnum() {}
+
+const (
+	// Exposing GPUs to containers.
+	Gpu EngineCapability = "GPU"
+
+	// Executing commands with all root capabilities.
+	Privileged EngineCapability = "PRIVILEGED"
+
+	// Executing commands in Windows containers.
+	Windows EngineCapability = "WINDOWS"
+
+	// Exposing host devices to containers.
+	Devices EngineCapability = "DEVICES"
+)
+
+type FileType string
+
+func (FileType) IsEnum() {}
+
+const (
+	Dir FileType = "DIR"
+
+	Other FileType = "OTHER"
+
+	Regular FileType = "REGULAR"
+
+	Symlink FileType = "SYMLINK"
+)
+
+type GraphFormat string
+
+func (GraphFormat) IsEnum() {}
+
+const (
+	// The Graphviz DOT language.
+	Dot GraphFormat = "DOT"
+
+	// A JSON object listing the vertexes of the graph, inputs first.
+	Json GraphFormat = "JSON"
+)
+
 type ImageLayerCompression string
 
 func (ImageLayerCompression) IsEnum() {}
@@ -7211,6 +9334,21 @@ const (
 	Ocimediatypes ImageMediaTypes = "OCIMediaTypes"
 )
 
+type ImagePullPolicy string
+
+func (ImagePullPolicy) IsEnum() {}
+
+const (
+	// Always resolve the address against the registry and pull the image.
+	Always ImagePullPolicy = "ALWAYS"
+
+	// Resolve the address against the registry and only pull missing layers.
+	IfNotPresent ImagePullPolicy = "IF_NOT_PRESENT"
+
+	// Never contact the registry, failing if the image isn't already in the engine.
+	Never ImagePullPolicy = "NEVER"
+)
+
 type ModuleSourceKind string
 
 func (ModuleSourceKind) IsEnum() {}
@@ -26,10 +26,15 @@ type Config struct {
 	LogOutput  io.Writer
 	RunnerHost string
 	Conn       EngineConn
+
+	// Number of query results memoized by the client, or 0 to disable
+	// memoization. Not used to connect, but set by the same options.
+	QueryCacheSize int
 }
 
 type ConnectParams struct {
 	Port         int    `json:"port"`
+	SocketPath   string `json:"-"` // set from $DAGGER_SESSION_SOCKET
 	SessionToken string `json:"session_token"`
 }
 
@@ -39,7 +44,7 @@ func Get(ctx context.Context, cfg *Config) (EngineConn, error) {
 		return cfg.Conn, nil
 	}
 
-	// Try DAGGER_SESSION_PORT next
+	// Try DAGGER_SESSION_SOCKET or DAGGER_SESSION_PORT next
 	conn, ok, err := FromSessionEnv()
 	if err != nil {
 		return nil, err
@@ -78,6 +83,9 @@ func fallbackSpanContext(ctx context.Context) context.Context {
 func defaultHTTPClient(p *ConnectParams) *http.Client {
 	dialTransport := &http.Transport{
 		DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			if p.SocketPath != "" {
+				return net.Dial("unix", p.SocketPath)
+			}
 			return net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", p.Port))
 		},
 	}
@@ -8,6 +8,25 @@ import (
 )
 
 func FromSessionEnv() (EngineConn, bool, error) {
+	if socketPath, ok := os.LookupEnv("DAGGER_SESSION_SOCKET"); ok {
+		sessionToken := os.Getenv("DAGGER_SESSION_TOKEN")
+		if sessionToken == "" {
+			return nil, false, fmt.Errorf("DAGGER_SESSION_TOKEN must be set when using DAGGER_SESSION_SOCKET")
+		}
+
+		httpClient := defaultHTTPClient(&ConnectParams{
+			SocketPath:   socketPath,
+			SessionToken: sessionToken,
+		})
+
+		return &sessionEnvConn{
+			Client: httpClient,
+			// the host is only used to build request URLs; requests are always
+			// dialed to the socket
+			host: "dagger",
+		}, true, nil
+	}
+
 	portStr, ok := os.LookupEnv("DAGGER_SESSION_PORT")
 	if !ok {
 		return nil, false, nil
@@ -88,7 +88,12 @@ func startCLISession(ctx context.Context, binPath string, cfg *Config) (_ Engine
 	env := os.Environ()
 
 	if cfg.RunnerHost != "" {
-		env = append(env, "_EXPERIMENTAL_DAGGER_RUNNER_HOST="+cfg.RunnerHost)
+		// DAGGER_HOST takes precedence over the legacy variable, so set both
+		// for the explicit host to win over the environment with any CLI
+		env = append(env,
+			"DAGGER_HOST="+cfg.RunnerHost,
+			"_EXPERIMENTAL_DAGGER_RUNNER_HOST="+cfg.RunnerHost,
+		)
 	}
 
 	// detect $TRACEPARENT set by 'dagger run'
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 
@@ -125,16 +126,22 @@ func (s *Selection) Build(ctx context.Context) (string, error) {
 		b.WriteString(sel.name)
 
 		if len(sel.args) > 0 {
+			// sort the arguments so that the same selection always builds the
+			// same query
+			names := make([]string, 0, len(sel.args))
+			for name := range sel.args {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
 			b.WriteRune('(')
-			i := 0
-			for name, arg := range sel.args {
+			for i, name := range names {
 				if i > 0 {
 					b.WriteString(", ")
 				}
 				b.WriteString(name)
 				b.WriteRune(':')
-				b.WriteString(arg.marshalled)
-				i++
+				b.WriteString(sel.args[name].marshalled)
 			}
 			b.WriteRune(')')
 		}
@@ -0,0 +1,173 @@
+package dagger
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/Khan/genqlient/graphql"
+
+	"dagger.io/dagger/internal/engineconn"
+)
+
+// DefaultQueryCacheSize is the number of query results memoized by a client
+// created with WithQueryCache(0).
+const DefaultQueryCacheSize = 1000
+
+// queryPureHeader is set to "true" by the engine on responses to queries
+// whose results won't change if they're queried again.
+const queryPureHeader = "X-Dagger-Query-Pure"
+
+// WithQueryCache memoizes the results of queries in the client, so that
+// querying the same fields of the same objects again doesn't make another
+// request to the engine, e.g. the stdout of an exec queried by several
+// helper functions.
+//
+// Only the results the engine reports as pure are memoized, i.e. those not
+// depending on an impure field such as reading from the host or on a module
+// function, and failed queries are never memoized. Engines that don't report
+// purity get no memoization at all. The least recently used results are
+// evicted once size results are memoized, or DefaultQueryCacheSize if size
+// is 0. Use Client.ClearQueryCache to invalidate all the results.
+func WithQueryCache(size int) ClientOpt {
+	return clientOptFunc(func(cfg *engineconn.Config) {
+		if size <= 0 {
+			size = DefaultQueryCacheSize
+		}
+		cfg.QueryCacheSize = size
+	})
+}
+
+// ClearQueryCache invalidates all the query results memoized by the client.
+func (c *Client) ClearQueryCache() {
+	if c.cache != nil {
+		c.cache.clear()
+	}
+}
+
+type queryCacheKey struct {
+	query     string
+	variables string
+	opName    string
+}
+
+type queryCacheEntry struct {
+	key  queryCacheKey
+	data json.RawMessage
+}
+
+// cachingClient is a graphql.Client memoizing the results of cacheable
+// queries in an LRU cache.
+type cachingClient struct {
+	graphql.Client
+
+	size    int
+	mu      sync.Mutex
+	entries map[queryCacheKey]*list.Element
+	lru     *list.List
+}
+
+func newCachingClient(client graphql.Client, size int) *cachingClient {
+	return &cachingClient{
+		Client:  client,
+		size:    size,
+		entries: map[queryCacheKey]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func (c *cachingClient) MakeRequest(ctx context.Context, req *graphql.Request, resp *graphql.Response) error {
+	variables, err := json.Marshal(req.Variables)
+	if err != nil {
+		return c.Client.MakeRequest(ctx, req, resp)
+	}
+	key := queryCacheKey{
+		query:     req.Query,
+		variables: string(variables),
+		opName:    req.OpName,
+	}
+
+	if data, ok := c.get(key); ok {
+		return unmarshalQueryData(data, resp)
+	}
+
+	var data json.RawMessage
+	rawResp := &graphql.Response{
+		Data:       &data,
+		Extensions: resp.Extensions,
+	}
+	header := http.Header{}
+	if err := c.Client.MakeRequest(context.WithValue(ctx, responseHeaderKey{}, &header), req, rawResp); err != nil {
+		resp.Errors = rawResp.Errors
+		resp.Extensions = rawResp.Extensions
+		return err
+	}
+	resp.Extensions = rawResp.Extensions
+	if len(rawResp.Errors) == 0 && header.Get(queryPureHeader) == "true" {
+		c.put(key, data)
+	}
+	return unmarshalQueryData(data, resp)
+}
+
+func unmarshalQueryData(data json.RawMessage, resp *graphql.Response) error {
+	if resp.Data == nil {
+		return nil
+	}
+	return json.Unmarshal(data, resp.Data)
+}
+
+func (c *cachingClient) get(key queryCacheKey) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*queryCacheEntry).data, true
+}
+
+func (c *cachingClient) put(key queryCacheKey, data json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*queryCacheEntry).data = data
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&queryCacheEntry{key: key, data: data})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*queryCacheEntry).key)
+	}
+}
+
+func (c *cachingClient) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[queryCacheKey]*list.Element{}
+	c.lru.Init()
+}
+
+type responseHeaderKey struct{}
+
+// headerRecordingDoer is a graphql.Doer storing the header of each response
+// in the *http.Header of its request context, if any, so that the caching
+// client can tell whether the engine reported the result as pure.
+type headerRecordingDoer struct {
+	graphql.Doer
+}
+
+func (d headerRecordingDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := d.Doer.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if header, ok := req.Context().Value(responseHeaderKey{}).(*http.Header); ok {
+		*header = resp.Header
+	}
+	return resp, nil
+}
@@ -0,0 +1,71 @@
+package dagger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Khan/genqlient/graphql"
+	"github.com/stretchr/testify/require"
+)
+
+// countingClient reports every query containing "stdout" as pure, like an
+// engine would.
+type countingClient struct {
+	requests int
+}
+
+func (c *countingClient) MakeRequest(ctx context.Context, req *graphql.Request, resp *graphql.Response) error {
+	c.requests++
+	if header, ok := ctx.Value(responseHeaderKey{}).(*http.Header); ok && strings.Contains(req.Query, "stdout") {
+		header.Set(queryPureHeader, "true")
+	}
+	return json.Unmarshal([]byte(`{"container":{"stdout":"hello"}}`), resp.Data)
+}
+
+func TestQueryCache(t *testing.T) {
+	ctx := context.Background()
+
+	query := func(t *testing.T, client graphql.Client, q string) {
+		t.Helper()
+		var data struct {
+			Container struct {
+				Stdout string
+			}
+		}
+		err := client.MakeRequest(ctx, &graphql.Request{Query: q}, &graphql.Response{Data: &data})
+		require.NoError(t, err)
+		require.Equal(t, "hello", data.Container.Stdout)
+	}
+
+	t.Run("cacheable", func(t *testing.T) {
+		upstream := &countingClient{}
+		client := newCachingClient(upstream, 10)
+		query(t, client, `query{container{stdout}}`)
+		query(t, client, `query{container{stdout}}`)
+		require.Equal(t, 1, upstream.requests)
+
+		client.clear()
+		query(t, client, `query{container{stdout}}`)
+		require.Equal(t, 2, upstream.requests)
+	})
+
+	t.Run("uncacheable", func(t *testing.T) {
+		upstream := &countingClient{}
+		client := newCachingClient(upstream, 10)
+		query(t, client, `query{container{export(path:"out.tar")}}`)
+		query(t, client, `query{container{export(path:"out.tar")}}`)
+		require.Equal(t, 2, upstream.requests)
+	})
+
+	t.Run("evicted", func(t *testing.T) {
+		upstream := &countingClient{}
+		client := newCachingClient(upstream, 1)
+		query(t, client, `query{container{stdout}}`)
+		query(t, client, `query{container{a:stdout}}`)
+		query(t, client, `query{container{stdout}}`)
+		require.Equal(t, 3, upstream.requests)
+	})
+}